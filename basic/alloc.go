@@ -0,0 +1,162 @@
+package basic
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+// the longest string a program may make when the interpreter's options don't say.
+const DEFAULT_MAX_STRING_LENGTH = 1 << 26
+
+// error a value gives when it's bigger than the interpreter's options allow, reported as its
+// code rather than ERR_BUILTIN when a builtin made it.
+type limitError_t struct {
+	code    ErrorCode_t
+	details string
+}
+
+func (err *limitError_t) Error() string {
+	return err.details
+}
+
+// turns the error into a RuntimeError at tok.
+func (err *limitError_t) at(tok Token_t) error {
+	return &RuntimeError_t{Code: err.code, Details: err.details, Pos: tok.pos}
+}
+
+// roughly what values take up in memory. Every value is a Result_t, a list points at each of
+// its elements, and a dict keeps each key twice, in its map and in its order, next to the
+// pointer to its value and the map's own bookkeeping.
+const (
+	resultSize    = int(unsafe.Sizeof(Result_t{}))
+	pointerSize   = int(unsafe.Sizeof(&Result_t{}))
+	dictEntrySize = pointerSize + 2*int(unsafe.Sizeof("")) + 8
+)
+
+// checks a list or dict of length elements, or a string of length bytes, is within the
+// interpreter's MaxListLength or MaxStringLength, and counts a new value of that size towards
+// the MaxAllocated of this Run: its Result_t, plus the bytes of a string or what a list or dict
+// takes to point at its elements. The elements are counted by allocateResult, once each.
+// Returns a limitError_t if it's too big.
+func (interp *Interpreter_t) allocate(resultType resultType_t, length int) error {
+	size := resultSize
+	switch resultType {
+	case LIST_RESULT, DICT_RESULT:
+		limit := interp.opts.MaxListLength
+		if limit <= 0 {
+			limit = DEFAULT_MAX_LIST_LENGTH
+		}
+		if length > limit {
+			return &limitError_t{code: ERR_LIST_LIMIT, details: fmt.Sprintf("a %s of %d elements is longer than the limit of %d", resultType, length, limit)}
+		}
+		if resultType == DICT_RESULT {
+			size += dictEntrySize * length
+		} else {
+			size += pointerSize * length
+		}
+	case STRING_RESULT:
+		limit := interp.opts.MaxStringLength
+		if limit <= 0 {
+			limit = DEFAULT_MAX_STRING_LENGTH
+		}
+		if length > limit {
+			return &limitError_t{code: ERR_MEMORY_LIMIT, details: fmt.Sprintf("a string of %d bytes is longer than the limit of %d", length, limit)}
+		}
+		size += length
+	}
+	return interp.count(size)
+}
+
+// counts size bytes towards the MaxAllocated of this Run, returning a limitError_t once
+// it's gone over.
+func (interp *Interpreter_t) count(size int) error {
+	interp.allocated += size
+	if interp.opts.MaxAllocated > 0 && interp.allocated > interp.opts.MaxAllocated {
+		return interp.memoryLimitError()
+	}
+	return nil
+}
+
+// the error for a Run that's gone over its MaxAllocated.
+func (interp *Interpreter_t) memoryLimitError() *limitError_t {
+	return &limitError_t{code: ERR_MEMORY_LIMIT, details: fmt.Sprintf("program made more than the limit of %d bytes of strings and lists", interp.opts.MaxAllocated)}
+}
+
+// does what allocate does for a value that's already been made, and for every value in it
+// that hasn't been counted yet, so a list counts the numbers MAP made for it but not the ones
+// SORT moved from the list it sorted. Numbers and other small values on their own are free:
+// they only count once something holds on to them.
+func (interp *Interpreter_t) allocateResult(res *Result_t) error {
+	switch res.ResultType {
+	case LIST_RESULT, DICT_RESULT, STRING_RESULT, MATRIX_RESULT, RECORD_RESULT:
+		return interp.allocateValue(res)
+	}
+	return nil
+}
+
+// counts res and what it holds towards MaxAllocated if nothing has counted it yet.
+func (interp *Interpreter_t) allocateValue(res *Result_t) error {
+	if res == nil || !res.markCharged() {
+		return nil
+	}
+	var elems []*Result_t
+	var err error
+	switch res.ResultType {
+	case LIST_RESULT:
+		elems, err = res.Lres, interp.allocate(LIST_RESULT, len(res.Lres))
+	case DICT_RESULT:
+		for _, key := range res.Dres.keys {
+			elems = append(elems, res.Dres.values[key])
+		}
+		err = interp.allocate(DICT_RESULT, res.Dres.Len())
+	case STRING_RESULT:
+		err = interp.allocate(STRING_RESULT, len(res.Sres))
+	case MATRIX_RESULT:
+		err = interp.count(resultSize + 8*len(res.Mres.data))
+	case RECORD_RESULT:
+		elems, err = res.Rres.values, interp.count(resultSize+pointerSize*len(res.Rres.values))
+	default:
+		err = interp.count(resultSize)
+	}
+	for _, elem := range elems {
+		if err != nil {
+			break
+		}
+		err = interp.allocateValue(elem)
+	}
+	return err
+}
+
+// marks res as counted towards MaxAllocated, reporting whether it wasn't already. Values can
+// be shared between interpreters, so this is atomic.
+func (res *Result_t) markCharged() bool {
+	return atomic.CompareAndSwapUint32(&res.charged, 0, 1)
+}
+
+// does what allocateResult does for what a node evaluated to, turning a limitError_t into a
+// RuntimeError at tok. Errors from evaluating it are passed on as they are.
+func (interp *Interpreter_t) charge(res *Result_t, err error, tok Token_t) (*Result_t, error) {
+	if err != nil {
+		return nil, err
+	}
+	var limitErr *limitError_t
+	if err := interp.allocateResult(res); errors.As(err, &limitErr) {
+		return nil, limitErr.at(tok)
+	}
+	return res, nil
+}
+
+// wraps a builtin so what it gives back is checked and counted by allocateResult.
+func (interp *Interpreter_t) sized(fn BuiltinFunc_t) BuiltinFunc_t {
+	return func(args []*Result_t) (*Result_t, error) {
+		res, err := fn(args)
+		if err != nil {
+			return nil, err
+		} else if err := interp.allocateResult(res); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+}
@@ -0,0 +1,116 @@
+package basic
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+)
+
+// runs src with opts and returns the code of the RuntimeError it fails with, or "" if it doesn't.
+func runErrorCode(t *testing.T, opts Options_t, src string) ErrorCode_t {
+	t.Helper()
+	_, err := NewInterpreter(opts).Run(src, t.Name())
+	if err == nil {
+		return ""
+	}
+	var runtimeErr *RuntimeError_t
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("%q: got %T %s, want a RuntimeError", src, err, err)
+	}
+	return runtimeErr.Code
+}
+
+func TestAllocationLimits(t *testing.T) {
+	opts := Options_t{MaxListLength: 10000, MaxStringLength: 1000, MaxAllocated: 1 << 20}
+	tests := []struct {
+		src  string
+		want ErrorCode_t
+	}{
+		{`LEN(FOLD(LAMBDA(a, b, a + a), [1], RANGE(0, 13)))`, ""},
+		{`LEN(FOLD(LAMBDA(a, b, a + a), [1], RANGE(0, 14)))`, ERR_LIST_LIMIT},
+		{`LEN(FOLD(LAMBDA(a, b, a + a), "x", RANGE(0, 9)))`, ""},
+		{`LEN(FOLD(LAMBDA(a, b, a + a), "x", RANGE(0, 10)))`, ERR_MEMORY_LIMIT},
+		{`RANGE(0, 10001)`, ERR_LIST_LIMIT},
+		{`LEN(RANGE(0, 5000))`, ""},
+		{`LEN(RANGE(0, 7000))`, ERR_MEMORY_LIMIT},
+		{`x, y = [RANGE(0, 1000), 0]` + "\n" + `LEN(MAP(LAMBDA(i, x[1:]), RANGE(0, 200)))`, ERR_MEMORY_LIMIT},
+		{`x, y = [RANGE(0, 500), 0]` + "\n" + `LEN(MAP(LAMBDA(i, x + x), RANGE(0, 200)))`, ERR_MEMORY_LIMIT},
+		{`x, y = [RANGE(0, 500), 0]` + "\n" + `LEN(MAP(LAMBDA(i, x * 2), RANGE(0, 100)))`, ERR_MEMORY_LIMIT},
+		{`f, g = [MAP, 0]` + "\n" + `LEN(f(LAMBDA(i, i), RANGE(0, 1000)))`, ""},
+		{`LEN(MAP(LAMBDA(i, [i]), RANGE(0, 5000)))`, ERR_MEMORY_LIMIT},
+		{`LEN(MAP(LAMBDA(i, {"key": i}), RANGE(0, 5000)))`, ERR_MEMORY_LIMIT},
+		{`x, y = [RANGE(0, 3000), 0]` + "\n" + `LEN(MAP(LAMBDA(i, LEN(SORT(x))), RANGE(0, 10)))`, ""}, // the sorted lists share x's numbers
+	}
+	for _, test := range tests {
+		if got := runErrorCode(t, opts, test.src); got != test.want {
+			t.Errorf("%q: got error code %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestAllocationCountsPerRun(t *testing.T) {
+	interp := NewInterpreter(Options_t{MaxAllocated: 200000})
+	for i := 0; i < 3; i++ {
+		if _, err := interp.Run(`LEN(RANGE(0, 1000))`, t.Name()); err != nil {
+			t.Fatalf("run %d: %s", i+1, err)
+		}
+	}
+}
+
+// measures how much the heap grows while run runs, once the garbage's been collected.
+func heapGrowth(run func()) int {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	run()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	return int(after.HeapAlloc) - int(before.HeapAlloc)
+}
+
+func TestAllocationMatchesHeap(t *testing.T) {
+	srcs := []string{
+		`RANGE(0, 200000)`,
+		`MAP(LAMBDA(i, [i, i * 2]), RANGE(0, 50000))`,
+		`MAP(LAMBDA(i, {"key": i}), RANGE(0, 50000))`,
+		`MAP(LAMBDA(i, HEX$(i)), RANGE(0, 100000))`,
+		`FOLD(LAMBDA(a, b, a + a), [1], RANGE(0, 18))`,
+		`RANGE(0, 100000) * 2`,
+	}
+	for _, src := range srcs {
+		interp := NewInterpreter(Options_t{})
+		var res *Result_t
+		grew := heapGrowth(func() {
+			var err error
+			if res, err = interp.Run(src, t.Name()); err != nil {
+				t.Fatalf("%q: %s", src, err)
+			}
+		})
+		if grew > 2*interp.allocated {
+			t.Errorf("%q: the heap grew %d bytes, more than twice the %d counted", src, grew, interp.allocated)
+		}
+		runtime.KeepAlive(res)
+	}
+}
+
+func TestAllocationLimitBoundsHeap(t *testing.T) {
+	const limit = 8 << 20
+	srcs := []string{
+		`LEN(MAP(LAMBDA(i, RANGE(0, 1000)), RANGE(0, 10000)))`,
+		`LEN(MAP(LAMBDA(i, [i, [i], {"key": i}]), RANGE(0, 1000000)))`,
+		`LEN(FOLD(LAMBDA(a, b, a + [a]), [], RANGE(0, 1000000)))`,
+	}
+	for _, src := range srcs {
+		var code ErrorCode_t
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		before := stats.TotalAlloc
+		code = runErrorCode(t, Options_t{MaxAllocated: limit}, src)
+		runtime.ReadMemStats(&stats)
+		if code != ERR_MEMORY_LIMIT {
+			t.Errorf("%q: got error code %q, want %q", src, code, ERR_MEMORY_LIMIT)
+		} else if made := stats.TotalAlloc - before; made > 4*limit {
+			t.Errorf("%q: made %d bytes before stopping, more than 4 times the limit of %d", src, made, limit)
+		}
+	}
+}
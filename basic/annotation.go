@@ -0,0 +1,135 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the type names annotations can use besides the names of TYPEs and ENUMs, keyed by upper case
+// name, and the type each means: the name TYPE() gives values of it, or "number" for ints and
+// floats alike and "any" for anything at all.
+var annotationTypes = map[string]string{
+	"INT":      "int",
+	"INTEGER":  "int",
+	"FLOAT":    "float",
+	"NUMBER":   "number",
+	"STRING":   "string",
+	"LIST":     "list",
+	"DICT":     "dict",
+	"FUNCTION": "function",
+	"MATRIX":   "matrix",
+	"NIL":      "nil",
+	"ANY":      "any",
+}
+
+// builds and returns an Annotation node from what a factor is made of and the AS after it,
+// like x AS INT, or LAMBDA(x AS INT, x / 2) AS FLOAT for what a LAMBDA gives back.
+func (parser *parser_t) annotation(target *Node_t) (*Node_t, error) {
+	ret := &Node_t{nodeType: ANNOTATION, tok: parser.currentToken, left: target}
+	parser.advance()
+	if parser.currentToken.tokenType != IDENTIFIER {
+		return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected a type after AS, like INT or the name of a TYPE", Pos: parser.currentToken.pos}
+	}
+	ret.ops = []Token_t{parser.currentToken}
+	parser.advance()
+	return ret, nil
+}
+
+// gets the type an annotation names, like "int" for AS INTEGER, or the name of a TYPE or ENUM
+// as it's written.
+func annotationType(name string) string {
+	if typ, ok := annotationTypes[strings.ToUpper(name)]; ok {
+		return typ
+	}
+	return name
+}
+
+// writes a type the way an annotation names it, like INT, or Point for a TYPE.
+func annotationName(typ string) string {
+	if annotationTypes[strings.ToUpper(typ)] == typ {
+		return strings.ToUpper(typ)
+	}
+	return typ
+}
+
+// gets what an annotated parameter or field is called, without its annotation, and the type it
+// has ("" if it hasn't got one).
+func unannotated(node *Node_t) (*Node_t, string) {
+	if node.nodeType == ANNOTATION {
+		return node.left, annotationType(node.ops[0].strVal)
+	}
+	return node, ""
+}
+
+// returns true if a node is a call to LAMBDA, making a function.
+func isLambda(node *Node_t) bool {
+	return node.nodeType == CALL && node.left == nil && strings.EqualFold(node.tok.strVal, "LAMBDA")
+}
+
+// returns true if a value is of the type an annotation names. An int is a float too, as
+// numbers are everywhere else, and a member of an ENUM is an int as well as a member.
+func conforms(value *Result_t, typ string) bool {
+	switch typ {
+	case "", "any":
+		return true
+	case "number", "float":
+		return value.IsNumber()
+	case "int":
+		return value.ResultType == INTEGER
+	}
+	return typeName(value) == typ || value.ResultType.String() == typ
+}
+
+// checks that a value is of the type an annotation names, giving it back as one: an int
+// annotated as a float becomes one. what says what the value is, for the error.
+func annotated(value *Result_t, typ string, what string) (*Result_t, error) {
+	if !conforms(value, typ) {
+		return nil, typeErrorf("%s is a %s, not a %s", what, typeName(value), typ)
+	} else if typ == "float" && value.ResultType == INTEGER {
+		return NewFloat(float64(value.Ires)), nil
+	}
+	return value, nil
+}
+
+// checks the arguments of a call against the types of the parameters they're for, giving them
+// back as those types.
+func annotatedArgs(params []Param_t, args []*Result_t) ([]*Result_t, error) {
+	ret := append([]*Result_t{}, args...)
+	for i, param := range params {
+		if param.Type == "" || i >= len(ret) {
+			continue
+		}
+		var err error
+		if ret[i], err = annotated(ret[i], param.Type, fmt.Sprintf("argument %d (%s)", i+1, param.Name)); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// evaluates an ANNOTATION node: the value it annotates, which has to be of the type it names.
+// An annotated LAMBDA is a function whose calls have to give back that type.
+func (node *Node_t) evaluateAnnotation(interp *Interpreter_t) (*Result_t, error) {
+	typ := annotationType(node.ops[0].strVal)
+	value, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	} else if !isLambda(node.left) {
+		value, err = annotated(value, typ, formatExpr(node.left))
+		if err != nil {
+			return nil, &RuntimeError_t{Code: ERR_TYPE, Details: err.Error(), Pos: node.tok.pos}
+		}
+		return value, nil
+	}
+
+	lambda := *value.Fnres
+	lambda.Returns, lambda.source = typ, node
+	lambda.call = func(args []*Result_t) (*Result_t, error) {
+		res, err := value.Fnres.call(args)
+		if err != nil {
+			return nil, err
+		}
+		return annotated(res, typ, "its result")
+	}
+	return NewFunction(&lambda), nil
+}
@@ -0,0 +1,87 @@
+package basic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+)
+
+// where the interpreter writes an event for every evaluation, for when what formulas work out
+// has to be accounted for, like in billing. The methods match *slog.Logger's, so one can be
+// set as it is: args are keys each followed by its value. Info gets the evaluations that work,
+// and Error the ones that fail. Implementations must be safe for concurrent use if the
+// interpreters using them are.
+//
+// Every event has the keys "source_sha256" (of the source, in hex), "file", "duration" (a
+// time.Duration) and "result" (the result's type, or "" if it failed). Failed ones also have
+// "error" and "codes", the error codes joined with commas. The tags from the options and from
+// WithAuditTags come after these, sorted by key.
+type AuditLog_t interface {
+	Info(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// key the audit tags of a single evaluation are kept under in a context.
+type auditTagsKey_t struct{}
+
+// gets a context that adds tags to the AuditLog event of a RunContext run with it, like the
+// customer the run is billed to. Tags already in ctx are kept, unless tags replaces them.
+func WithAuditTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string)
+	if outer, ok := ctx.Value(auditTagsKey_t{}).(map[string]string); ok {
+		for key, value := range outer {
+			merged[key] = value
+		}
+	}
+	for key, value := range tags {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, auditTagsKey_t{}, merged)
+}
+
+// writes the AuditLog event for an evaluation of src that started at start, if there's an AuditLog.
+func (interp *Interpreter_t) audit(ctx context.Context, src string, fn string, start time.Time, res *Result_t, err error) {
+	if interp.opts.AuditLog == nil {
+		return
+	}
+	sum := sha256.Sum256([]byte(src))
+	args := []interface{}{"source_sha256", hex.EncodeToString(sum[:]), "file", fn, "duration", time.Since(start)}
+	if err == nil {
+		args = append(args, "result", res.ResultType.String())
+	} else {
+		codes := make([]string, 0)
+		for _, diag := range Diagnostics(err) {
+			if diag.Code != "" {
+				codes = append(codes, diag.Code)
+			}
+		}
+		args = append(args, "result", "", "error", err.Error(), "codes", strings.Join(codes, ","))
+	}
+
+	tags := make(map[string]string)
+	for key, value := range interp.opts.AuditTags {
+		tags[key] = value
+	}
+	if extra, ok := ctx.Value(auditTagsKey_t{}).(map[string]string); ok {
+		for key, value := range extra {
+			tags[key] = value
+		}
+	}
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, key, tags[key])
+	}
+
+	if err != nil {
+		interp.opts.AuditLog.Error("evaluation failed", args...)
+	} else {
+		interp.opts.AuditLog.Info("evaluation", args...)
+	}
+}
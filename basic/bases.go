@@ -0,0 +1,41 @@
+package basic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// wraps strconv.FormatInt as a builtin writing an int in the given base, in upper case.
+// Negative numbers keep their sign, so HEX$(-255) is "-FF".
+func baseBuiltin(base int) BuiltinFunc_t {
+	return func(args []*Result_t) (*Result_t, error) {
+		if args[0].ResultType != INTEGER {
+			return nil, typeErrorf("expected an int, not %s", args[0].ResultType)
+		}
+		return NewString(strings.ToUpper(strconv.FormatInt(args[0].Ires, base))), nil
+	}
+}
+
+// the base conversion functions. The ones ending in $ make strings, like in other BASICs.
+func init() {
+	RegisterBuiltin("HEX$", 1, baseBuiltin(16))
+	RegisterBuiltin("OCT$", 1, baseBuiltin(8))
+	RegisterBuiltin("BIN$", 1, baseBuiltin(2))
+	RegisterBuiltin("PARSEINT", 2, func(args []*Result_t) (*Result_t, error) { // PARSEINT(s, base), base 0 goes by a 0x, 0o or 0b prefix
+		if args[0].ResultType != STRING_RESULT {
+			return nil, typeErrorf("the first argument has to be a string, not %s", args[0].ResultType)
+		} else if args[1].ResultType != INTEGER {
+			return nil, typeErrorf("the base has to be an int, not %s", args[1].ResultType)
+		}
+		base := args[1].Ires
+		if base != 0 && (base < 2 || base > 36) {
+			return nil, fmt.Errorf("base %d is out of range, it has to be 0 or from 2 to 36", base)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(args[0].Sres), int(base), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s isn't an integer in base %d", quoteString(args[0].Sres), base)
+		}
+		return NewInt(n), nil
+	})
+}
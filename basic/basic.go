@@ -1,31 +1,300 @@
 package basic
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"image"
+	"image/color"
+	"io"
 	"math"
 	"strconv"
+	"strings"
+	"time"
 )
 
-//enumerated type for token type
-type tokenType_t int
+// enumerated type for token type, like INT or ADD
+type TokenType_t int
 
 const (
-	INT tokenType_t = iota
+	INT TokenType_t = iota
 	FLOAT
+	IDENTIFIER
 	ADD
 	SUB
 	MUL
 	DIV
 	LPAREN
 	RPAREN
-	EOF
+	COLON
+	COMMA
+	NEWLINE
+	STRING
+	LBRACKET
+	RBRACKET
+	LBRACE
+	RBRACE
+	LT       // <
+	GT       // >
+	LE       // <=
+	GE       // >=
+	EQ       // =
+	NE       // <>
+	POW      // ^
+	APPROX   // ~=
+	PARAM    // ?name, a placeholder
+	COALESCE // ??
+	SAFE_DOT // ?.
+	ELLIPSIS // ...
+	DOT      // ., as in p.x
+	EOF      // the end of the source, which the parser stops at
 )
 
-// pretty much the only exported function, and it runs all of the code.
-// Takes in a string, returns the root node.
-// next step is to make it return some sort of EvalResult struct or something.
+// gets the name of this token type, like "ADD" or "LPAREN". A value that isn't one of the
+// token types is named by its number, like "TokenType_t(99)".
+func (tokenType TokenType_t) String() string {
+	names := [31]string{"INT", "FLOAT", "IDENTIFIER", "ADD", "SUB", "MUL", "DIV", "LPAREN", "RPAREN", "COLON", "COMMA", "NEWLINE", "STRING", "LBRACKET", "RBRACKET", "LBRACE", "RBRACE", "LT", "GT", "LE", "GE", "EQ", "NE", "POW", "APPROX", "PARAM", "COALESCE", "SAFE_DOT", "ELLIPSIS", "DOT", "EOF"}
+	if tokenType < 0 || int(tokenType) >= len(names) {
+		return fmt.Sprintf("TokenType_t(%d)", int(tokenType))
+	}
+	return names[tokenType]
+}
+
+// runs all of the code: lexes, parses and evaluates the string,
+// returning the result of the evaluation. Uses the default options.
 func Run(txt string, fn string) (*Result_t, error) {
+	return NewInterpreter(Options_t{}).Run(txt, fn)
+}
+
+// settings for an interpreter. The zero value gives the default behaviour.
+type Options_t struct {
+	DisabledWarnings []WarningType_t   // warnings that won't be reported. Every warning is on by default.
+	Strict           bool              // strict mode: warnings about sloppy code become errors, even if they're disabled, and variables that are never bound are reported before running.
+	MaxSourceBytes   int               // longest source accepted, in bytes. 0 means no limit.
+	MaxTokens        int               // most tokens the lexer will make (not counting EOF) before giving up. 0 means no limit.
+	MaxTokenLength   int               // longest number, name or string literal the lexer will make, in bytes. 0 means DEFAULT_MAX_TOKEN_LENGTH.
+	MaxSteps         int               // most nodes a single Run may evaluate. 0 means no limit.
+	MaxCallDepth     int               // most calls of LAMBDAs a Run may have going at once, one inside another. 0 means DEFAULT_MAX_CALL_DEPTH.
+	Timeout          time.Duration     // longest a single Run may spend evaluating. 0 means no limit.
+	Progress         ProgressFunc_t    // told how far a Run has got every ProgressEvery steps, and can stop it. nil turns it off.
+	ProgressEvery    int               // how many steps apart Progress is told. 0 means DEFAULT_PROGRESS_EVERY.
+	Angle            AngleMode_t       // the unit SIN, COS, TAN and ATN work in. OPTION ANGLE changes it.
+	Notation         Notation_t        // how Display writes numbers. OPTION NOTATION changes it.
+	Radix            Radix_t           // the base Display writes ints in, whatever the Notation. OPTION BASE changes it.
+	ScientificAbove  float64           // floats at least this big are written in scientific notation, like 1e+20. 0 means DEFAULT_SCIENTIFIC_ABOVE.
+	ScientificBelow  float64           // floats other than 0 smaller than this are written in scientific notation too. 0 means DEFAULT_SCIENTIFIC_BELOW.
+	Decimals         int               // how many decimals Display writes floats with, like 2.500000 for 6. 0 means as few as read back as the same float, like 2.5.
+	Epsilon          float64           // relative tolerance = and <> compare floats with, like 1e-9. 0 means exactly. OPTION EQUALITY APPROX sets DEFAULT_EPSILON.
+	Grouping         bool              // whether Display separates thousands with commas, like 1,234,567. OPTION GROUPING changes it.
+	IgnoreCase       bool              // whether comparisons, IN, SORT and SORTBY treat strings that only differ in case as equal. OPTION COMPARE TEXT sets it and OPTION COMPARE BINARY clears it.
+	Metrics          Metrics_t         // where to report counts and latencies. nil turns metrics off.
+	AuditLog         AuditLog_t        // told about every Run and EvalWith, for audit trails. nil turns it off.
+	AuditTags        map[string]string // added to every AuditLog event, like which service is running the formulas.
+	Tracer           Tracer_t          // told about every node as it's evaluated. nil turns tracing off.
+	Stdin            io.Reader         // where programs read from. nil means os.Stdin.
+	Stdout           io.Writer         // where programs write, like PLOT's charts. nil means os.Stdout.
+	Stderr           io.Writer         // where programs write diagnostics. nil means os.Stderr.
+	FileSystem       FileSystem_t      // the files IMPORT reads modules from. nil means the OS's.
+	MaxListLength    int               // longest list or dict a program may make, like with RANGE or +. 0 means DEFAULT_MAX_LIST_LENGTH.
+	MaxStringLength  int               // longest string a program may make, in bytes. 0 means DEFAULT_MAX_STRING_LENGTH.
+	MaxAllocated     int               // most bytes of strings, lists and what's in them a single Run may make, counting ones it's dropped since. 0 means no limit.
+	Rounding         RoundingMode_t    // how ROUND breaks ties. The zero value is ROUND_HALF_UP.
+	Symbolic         bool              // whether an expression reading unbound variables gives back a simplified expression, like 2 * x + 6, rather than an error.
+	ImportPaths      []string          // directories IMPORT searches for modules after the importing file's own.
+	DisableImports   bool              // whether IMPORT is refused, for sandboxes that mustn't read files.
+	Permissions      *Permissions_t    // what programs may reach outside the interpreter, like files and the network. nil allows everything.
+	HTTPTimeout      time.Duration     // longest HTTPGET$ and HTTPPOST$ may wait for a response. 0 means DEFAULT_HTTP_TIMEOUT.
+	MaxResponseBytes int               // longest response body HTTPGET$ and HTTPPOST$ accept. 0 means DEFAULT_MAX_RESPONSE_BYTES.
+	SQLDriver        string            // the database/sql driver DBOPEN uses, which the host registers. "" means "sqlite3" or else "sqlite".
+	Prelude          string            // BASIC source of a dict of functions bound as variables before the first Run. "" means StandardPrelude.
+	NoPrelude        bool              // whether to skip loading a prelude at all.
+	Audio            Audio_t           // what plays SOUND and BEEP. nil rings the terminal bell on Stdout.
+	Locale           string            // language of error and warning messages, like "de" or "fr_FR.UTF-8". "" means the BASIC_LANG or LANG environment variable.
+}
+
+// Interpreter struct. Holds the options, the variables and anything collected while evaluating.
+type Interpreter_t struct {
+	opts      Options_t
+	vars      map[string]*Result_t
+	warnings  []Warning_t
+	steps     int                   // nodes evaluated so far in this Run
+	deadline  time.Time             // when this Run has to stop evaluating, zero if there is no timeout
+	started   time.Time             // when this Run started evaluating, for Progress
+	ctx       context.Context       // cancels this Run when it's done, nil outside RunContext
+	modules   map[string]*Result_t  // results of the modules imported so far, keyed by absolute path. Shared with the modules' own interpreters
+	importing []string              // the modules being imported, outermost first, to catch one that imports itself
+	preluded  bool                  // whether the prelude has been loaded
+	memory    []byte                // what PEEK and POKE use, nil until Memory makes it
+	canvas    *image.RGBA           // what the graphics statements draw on, nil until one does
+	pen       color.RGBA            // the colour they draw in, zero until COLOR sets one
+	shapes    []shape_t             // everything drawn on the canvas, in order, for SVG
+	turtle    turtle_t              // the turtle FORWARD, TURN, PENUP and PENDOWN move
+	timer     *timer_t              // the subroutine ON TIMER runs, nil if there isn't one. Only lasts for one Run
+	locale    string                // the language errors and warnings are translated into, worked out from the options
+	definedAt map[string]Position_t // where each variable the program bound was first bound, for undefined-variable hints
+	host      map[string]hostVar_t  // read-only and computed variables the embedder set, which programs can't bind
+	exec      *Execution_t          // the Execution running on this interpreter, nil in a plain Run or while it's paused
+	params    map[string]*Result_t  // the values of the placeholders of the Program_t being run, nil outside RunProgram
+	databases map[int64]*sql.DB     // the databases DBOPEN opened, by handle, nil until it does
+	lastDB    int64                 // the handle DBOPEN gave last
+	allocated int                   // bytes of strings, lists and what's in them this Run has made, for MaxAllocated
+	depth     int                   // calls of LAMBDAs going at the moment, for MaxCallDepth
+	importer  *Interpreter_t        // the interpreter IMPORTing this one as a module, whose Run's limits it counts against, nil if it isn't one
+}
+
+// constructor for Interpreter objects
+func NewInterpreter(opts Options_t) *Interpreter_t {
+	return &Interpreter_t{opts: opts, vars: make(map[string]*Result_t), modules: make(map[string]*Result_t), locale: normalizeLocale(opts.Locale), definedAt: make(map[string]Position_t), host: make(map[string]hostVar_t)}
+}
+
+// sets a variable that programs run by this interpreter can read.
+func (interp *Interpreter_t) SetVar(name string, value *Result_t) {
+	interp.vars[name] = value
+}
+
+// gets the value of a variable, and whether it's set at all.
+func (interp *Interpreter_t) GetVar(name string) (*Result_t, bool) {
+	value, ok := interp.vars[name]
+	return value, ok
+}
+
+// starts counting steps and time for a new evaluation, which starts at start.
+// A module carries on from where the Run importing it has got to instead.
+func (interp *Interpreter_t) resetLimits(start time.Time) {
+	if importer := interp.importer; importer != nil {
+		interp.steps, interp.allocated, interp.started, interp.deadline = importer.steps, importer.allocated, importer.started, importer.deadline
+		return
+	}
+	interp.steps = 0
+	interp.allocated = 0
+	interp.started = start
+	interp.deadline = time.Time{}
+	if interp.opts.Timeout > 0 {
+		interp.deadline = start.Add(interp.opts.Timeout)
+	}
+}
+
+// counts one evaluation step, failing once the run goes over MaxSteps or past its deadline.
+func (interp *Interpreter_t) step(node *Node_t) error {
+	interp.steps++
+	if interp.opts.MaxSteps > 0 && interp.steps > interp.opts.MaxSteps {
+		return &RuntimeError_t{Code: ERR_STEP_LIMIT, Details: fmt.Sprintf("program took more than the limit of %d steps", interp.opts.MaxSteps), Pos: node.tok.pos}
+	}
+	// checking the clock is slow compared to a step, so only do it every so often
+	if !interp.deadline.IsZero() && interp.steps%256 == 0 && time.Now().After(interp.deadline) {
+		return &RuntimeError_t{Code: ERR_STEP_LIMIT, Details: fmt.Sprintf("program ran longer than the limit of %s", interp.opts.Timeout), Pos: node.tok.pos}
+	}
+	if interp.ctx != nil && interp.steps%256 == 0 {
+		select {
+		case <-interp.ctx.Done():
+			return &RuntimeError_t{Code: ERR_STEP_LIMIT, Details: cancelledDetails(interp.ctx.Err()), Pos: node.tok.pos}
+		default:
+		}
+	}
+	if interp.opts.Progress != nil {
+		if err := interp.reportProgress(node); err != nil {
+			return err
+		}
+	}
+	if interp.exec != nil {
+		return interp.exec.tick()
+	}
+	return nil
+}
+
+// says why a Run stopped when its context was done with err.
+func cancelledDetails(err error) string {
+	if err == context.DeadlineExceeded {
+		return "program ran past its deadline"
+	}
+	return "program was cancelled"
+}
+
+// runs all of the code using this interpreter's options. Any warnings raised along the
+// way are returned in the result's Warnings.
+func (interp *Interpreter_t) Run(txt string, fn string) (*Result_t, error) {
+	return interp.RunContext(context.Background(), txt, fn)
+}
+
+// runs all of the code like Run, but stops with an error if ctx is cancelled or its deadline
+// passes before the program ends. Whatever the program wrote out before then stays written.
+// Audit tags added to ctx with WithAuditTags go in the AuditLog's event for this run.
+func (interp *Interpreter_t) RunContext(ctx context.Context, txt string, fn string) (*Result_t, error) {
+	start := time.Now()
+	res, err := interp.runContext(ctx, txt, fn)
+	interp.audit(ctx, txt, fn, start, res, err)
+	return res, err
+}
+
+// does the work of RunContext.
+func (interp *Interpreter_t) runContext(ctx context.Context, txt string, fn string) (*Result_t, error) {
+	start := time.Now()
+	ret, err := parse(txt, fn, interp.opts)
+	interp.observe(METRIC_PARSE_TIME, start)
+	if err != nil {
+		interp.countEvaluation(err)
+		return nil, Localize(err, interp.locale)
+	}
+	return interp.runNode(ctx, ret, nil)
+}
+
+// does the work of RunContext and RunProgram once the source is parsed, with the values of any placeholders.
+func (interp *Interpreter_t) runNode(ctx context.Context, ret *Node_t, params map[string]*Result_t) (*Result_t, error) {
+	if !interp.preluded && !interp.opts.NoPrelude {
+		interp.preluded = true
+		if err := interp.loadPrelude(); err != nil {
+			interp.countEvaluation(err)
+			return nil, Localize(err, interp.locale)
+		}
+	}
+
+	if interp.opts.Strict && !interp.opts.Symbolic { // symbolic mode wants unbound variables
+		if err := interp.checkUndefined(ret); err != nil {
+			interp.countEvaluation(err)
+			return nil, Localize(err, interp.locale)
+		}
+	}
+
+	interp.warnings = nil
+	interp.checkUnused(ret)
+	start := time.Now()
+	interp.resetLimits(start)
+	interp.timer = nil
+	interp.ctx, interp.params = ctx, params
+	res, err := ret.evaluate(interp)
+	interp.ctx, interp.params = nil, nil
+	interp.timer = nil
+	if err != nil && interp.opts.Symbolic {
+		res, err = interp.symbolicResult(ret, err)
+	}
+	interp.observe(METRIC_EVAL_TIME, start)
+	interp.countEvaluation(err)
+	if err != nil {
+		return nil, Localize(err, interp.locale)
+	}
+
+	res.Warnings = localizeWarnings(interp.warnings, interp.locale)
+	return res, nil
+}
+
+// lexes and parses the string without evaluating it, returning the root node of the AST.
+func Parse(txt string, fn string) (*Node_t, error) {
+	return parse(txt, fn, Options_t{})
+}
+
+// lexes and parses the string, enforcing the size limits in opts.
+func parse(txt string, fn string, opts Options_t) (*Node_t, error) {
+	if opts.MaxSourceBytes > 0 && len(txt) > opts.MaxSourceBytes {
+		pos := newPosition(fn, txt)
+		pos.advance(0)
+		return nil, &LexError_t{Code: ERR_LIMIT, Details: fmt.Sprintf("source is %d bytes, more than the limit of %d", len(txt), opts.MaxSourceBytes), Pos: pos}
+	}
+
 	lex := newLexer(txt, fn)
+	lex.maxTokens = opts.MaxTokens
+	if opts.MaxTokenLength > 0 {
+		lex.maxTokenLength = opts.MaxTokenLength
+	}
 	tokens, err := lex.makeTokens()
 	if err != nil {
 		return nil, err
@@ -36,40 +305,66 @@ func Run(txt string, fn string) (*Result_t, error) {
 	if err != nil {
 		return nil, err
 	}
+	return ret, nil
+}
 
-	res, err := ret.evaluate()
-	if err != nil {
-		return nil, err
+// lexes the string, returning its tokens (without the EOF marker the parser uses).
+// On an illegal character, the tokens before it are returned along with the error.
+func Tokenize(txt string, fn string) ([]Token_t, error) {
+	tokens, err := newLexer(txt, fn).makeTokens()
+	if err == nil {
+		tokens = tokens[:len(tokens)-1]
 	}
-
-	return res, nil
-
+	return tokens, err
 }
 
 // struct for the token.
-type token_t struct {
-	tokenType tokenType_t
-	intVal    int32
+type Token_t struct {
+	tokenType TokenType_t
+	intVal    int64
 	floatVal  float64 // GACK! I don't like having to keep 2 different values.
-	pos       position_t
+	strVal    string  // name of an identifier
+	pos       Position_t
+	end       int // index of the byte just after the token
+}
+
+// gets the type of this token, like INT or ADD.
+func (token Token_t) Type() TokenType_t {
+	return token.tokenType
+}
+
+// gets where this token starts.
+func (token Token_t) Pos() Position_t {
+	return token.pos
+}
+
+// gets the byte offset just after this token, so the token is the source from Pos().Offset() to End().
+func (token Token_t) End() int {
+	return token.end
 }
 
 // gets the string representation of this token
 // for example, an integer token with value of 50 would return "INT:50"
 // a non-value token (operator token) simply returns it's operator name, like "ADD" or "LPAREN"
-func (token token_t) String() string {
+func (token Token_t) String() string {
 	switch token.tokenType {
 	case INT:
-		return "INT: " + strconv.FormatInt(int64(token.intVal), 10)
+		return "INT: " + strconv.FormatInt(token.intVal, 10)
 	case FLOAT:
 		return "FLOAT: " + strconv.FormatFloat(token.floatVal, 'f', -1, 64)
+	case IDENTIFIER:
+		return "IDENTIFIER: " + token.strVal
+	case PARAM:
+		return "PARAM: " + token.strVal
+	case STRING:
+		return "STRING: " + quoteString(token.strVal)
 	default:
-		return [8]string{"INT", "FLOAT", "ADD", "SUB", "MUL", "DIV", "LPAREN", "RPAREN"}[int(token.tokenType)]
+		return token.tokenType.String()
 	}
 }
 
 // Position struct used to store data about something's position in a file.
-type position_t struct {
+type Position_t struct {
 	index    int
 	line     int
 	col      int
@@ -77,18 +372,39 @@ type position_t struct {
 	fileText string
 }
 
+// gets the line number (starting at 0) of this position.
+func (pos Position_t) Line() int {
+	return pos.line
+}
+
+// gets the column (starting at 0) of this position.
+func (pos Position_t) Col() int {
+	return pos.col
+}
+
+// gets the byte offset of this position in the source text, counting from 0. Editors can
+// use it to find the text a diagnostic is about without working out where lines start.
+func (pos Position_t) Offset() int {
+	return pos.index
+}
+
+// gets the name of the file this position is in.
+func (pos Position_t) Filename() string {
+	return pos.filename
+}
+
 // returns a String representation of this position in the form "line 25, column 4 in filename.txt"
-func (pos position_t) String() string {
+func (pos Position_t) String() string {
 	return fmt.Sprintf("line %d, col %d in file %s", pos.line, pos.col, pos.filename)
 }
 
 // constructor for Position objects
-func newPosition(name, txt string) position_t {
-	return position_t{index: -1, line: 0, col: -1, filename: name, fileText: txt}
+func newPosition(name, txt string) Position_t {
+	return Position_t{index: -1, line: 0, col: -1, filename: name, fileText: txt}
 }
 
 // Advances this position by incrementing index and col. Wraps over to next line if the current char is a newline.
-func (pos *position_t) advance(current byte) {
+func (pos *Position_t) advance(current byte) {
 	pos.index += 1
 	pos.col += 1
 
@@ -99,20 +415,30 @@ func (pos *position_t) advance(current byte) {
 }
 
 // Copy constructor for position object.
-func (source *position_t) copy() *position_t {
-	return &position_t{index: source.index, line: source.line, col: source.col, filename: source.filename, fileText: source.fileText}
+func (source *Position_t) copy() *Position_t {
+	return &Position_t{index: source.index, line: source.line, col: source.col, filename: source.filename, fileText: source.fileText}
 }
 
+// longest number, name or string literal the lexer makes unless Options_t says otherwise, in bytes.
+// Nothing anyone writes by hand comes close, but a megabyte of digits would otherwise be
+// parsed and quoted back in the error.
+const DEFAULT_MAX_TOKEN_LENGTH = 1 << 20
+
 // Lexer struct. The lexer goes through a string and produces a list of tokens out of it.
 type lexer_t struct {
-	text        string
-	pos         position_t
-	currentChar byte
+	text           string
+	pos            Position_t
+	currentChar    byte
+	maxTokens      int // 0 means no limit
+	maxTokenLength int
 }
 
 // constructor for Lexer object
 func newLexer(initStr, filename string) *lexer_t {
-	ret := &lexer_t{text: initStr, pos: newPosition(filename, initStr), currentChar: 0}
+	ret := &lexer_t{text: initStr, pos: newPosition(filename, initStr), currentChar: 0, maxTokenLength: DEFAULT_MAX_TOKEN_LENGTH}
+	if strings.HasPrefix(initStr, "\uFEFF") { // skip a UTF-8 byte order mark, but keep the index pointing into the original text
+		ret.pos.index += len("\uFEFF")
+	}
 	ret.advance()
 	return ret
 }
@@ -128,53 +454,155 @@ func (lexer *lexer_t) advance() {
 }
 
 // makes and returns a list of tokens using the lexer's text.
-func (lexer *lexer_t) makeTokens() ([]token_t, error) {
-	ret := make([]token_t, 0)
+// On an illegal character, the tokens made before it are returned along with the error.
+func (lexer *lexer_t) makeTokens() ([]Token_t, error) {
+	ret := make([]Token_t, 0)
 
 	for {
+		if len(ret) > 0 && ret[len(ret)-1].end == 0 { // the last token was made in the previous pass, so it ends right here
+			ret[len(ret)-1].end = lexer.pos.index
+		}
+		if lexer.maxTokens > 0 && len(ret) > lexer.maxTokens {
+			return nil, &LexError_t{Code: ERR_LIMIT, Details: fmt.Sprintf("source has more than the limit of %d tokens", lexer.maxTokens), Pos: ret[len(ret)-1].pos}
+		}
 		if lexer.currentChar == 0 {
 			break
-		} else if lexer.currentChar == ' ' || lexer.currentChar == '\t' { // skip spaces and tabs
+		} else if lexer.currentChar == ' ' || lexer.currentChar == '\t' || lexer.currentChar == '\r' { // skip spaces, tabs and the \r of a \r\n line ending
+			lexer.advance()
+		} else if lexer.currentChar == '\n' {
+			ret = append(ret, Token_t{tokenType: NEWLINE, pos: *lexer.pos.copy()})
 			lexer.advance()
 		} else if lexer.currentChar >= '0' && lexer.currentChar <= '9' { // digit, signinfying number literal
-			ret = append(ret, lexer.makeNumber())
+			tok, err := lexer.makeNumber()
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, tok)
+		} else if isLetter(lexer.currentChar) { // letter or underscore, signifying an identifier
+			tok, err := lexer.makeIdentifier()
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, tok)
+		} else if lexer.currentChar == '"' {
+			tok, err := lexer.makeString()
+			if err != nil {
+				return ret, err
+			}
+			ret = append(ret, tok)
 		} else if lexer.currentChar == '+' {
-			ret = append(ret, token_t{tokenType: ADD, pos: *lexer.pos.copy()})
+			ret = append(ret, Token_t{tokenType: ADD, pos: *lexer.pos.copy()})
 			lexer.advance()
 		} else if lexer.currentChar == '-' {
-			ret = append(ret, token_t{tokenType: SUB, pos: *lexer.pos.copy()})
+			ret = append(ret, Token_t{tokenType: SUB, pos: *lexer.pos.copy()})
 			lexer.advance()
 		} else if lexer.currentChar == '*' {
-			ret = append(ret, token_t{tokenType: MUL, pos: *lexer.pos.copy()})
+			ret = append(ret, Token_t{tokenType: MUL, pos: *lexer.pos.copy()})
 			lexer.advance()
 		} else if lexer.currentChar == '/' {
-			ret = append(ret, token_t{tokenType: DIV, pos: *lexer.pos.copy()})
+			ret = append(ret, Token_t{tokenType: DIV, pos: *lexer.pos.copy()})
+			lexer.advance()
+		} else if lexer.currentChar == '^' {
+			ret = append(ret, Token_t{tokenType: POW, pos: *lexer.pos.copy()})
+			lexer.advance()
+		} else if lexer.currentChar == '<' || lexer.currentChar == '>' || lexer.currentChar == '=' {
+			ret = append(ret, lexer.makeComparison())
+		} else if lexer.currentChar == '?' && lexer.pos.index+1 < len(lexer.text) && (lexer.text[lexer.pos.index+1] == '?' || lexer.text[lexer.pos.index+1] == '.') {
+			tok := Token_t{tokenType: COALESCE, pos: *lexer.pos.copy()}
+			lexer.advance()
+			if lexer.currentChar == '.' {
+				tok.tokenType = SAFE_DOT
+			}
+			lexer.advance()
+			ret = append(ret, tok)
+		} else if lexer.currentChar == '?' && lexer.pos.index+1 < len(lexer.text) && isLetter(lexer.text[lexer.pos.index+1]) {
+			pos := lexer.pos.copy()
+			lexer.advance()
+			tok, err := lexer.makeIdentifier()
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, Token_t{tokenType: PARAM, strVal: tok.strVal, pos: *pos})
+		} else if strings.HasPrefix(lexer.text[lexer.pos.index:], "...") {
+			ret = append(ret, Token_t{tokenType: ELLIPSIS, pos: *lexer.pos.copy()})
+			lexer.advance()
+			lexer.advance()
+			lexer.advance()
+		} else if lexer.currentChar == '.' {
+			ret = append(ret, Token_t{tokenType: DOT, pos: *lexer.pos.copy()})
+			lexer.advance()
+		} else if lexer.currentChar == '~' && lexer.pos.index+1 < len(lexer.text) && lexer.text[lexer.pos.index+1] == '=' {
+			ret = append(ret, Token_t{tokenType: APPROX, pos: *lexer.pos.copy()})
+			lexer.advance()
 			lexer.advance()
 		} else if lexer.currentChar == '(' {
-			ret = append(ret, token_t{tokenType: LPAREN, pos: *lexer.pos.copy()})
+			ret = append(ret, Token_t{tokenType: LPAREN, pos: *lexer.pos.copy()})
 			lexer.advance()
 		} else if lexer.currentChar == ')' {
-			ret = append(ret, token_t{tokenType: RPAREN, pos: *lexer.pos.copy()})
+			ret = append(ret, Token_t{tokenType: RPAREN, pos: *lexer.pos.copy()})
 			lexer.advance()
-		} else { // some other character that isn't implemented
-			badChar := lexer.currentChar
+		} else if lexer.currentChar == ':' {
+			ret = append(ret, Token_t{tokenType: COLON, pos: *lexer.pos.copy()})
+			lexer.advance()
+		} else if lexer.currentChar == ',' {
+			ret = append(ret, Token_t{tokenType: COMMA, pos: *lexer.pos.copy()})
 			lexer.advance()
-			return nil, fmt.Errorf("illegal character '%c' at %s", badChar, lexer.pos)
+		} else if lexer.currentChar == '[' {
+			ret = append(ret, Token_t{tokenType: LBRACKET, pos: *lexer.pos.copy()})
+			lexer.advance()
+		} else if lexer.currentChar == ']' {
+			ret = append(ret, Token_t{tokenType: RBRACKET, pos: *lexer.pos.copy()})
+			lexer.advance()
+		} else if lexer.currentChar == '{' {
+			ret = append(ret, Token_t{tokenType: LBRACE, pos: *lexer.pos.copy()})
+			lexer.advance()
+		} else if lexer.currentChar == '}' {
+			ret = append(ret, Token_t{tokenType: RBRACE, pos: *lexer.pos.copy()})
+			lexer.advance()
+		} else { // some other character that isn't implemented
+			return ret, &LexError_t{Code: ERR_ILLEGAL_CHAR, Details: fmt.Sprintf("illegal character '%c'", lexer.currentChar), Pos: *lexer.pos.copy()}
 		}
 	}
 
-	ret = append(ret, token_t{tokenType: EOF, pos: *lexer.pos.copy()}) // finish off with an EOF
+	ret = append(ret, Token_t{tokenType: EOF, pos: *lexer.pos.copy(), end: lexer.pos.index}) // finish off with an EOF
 
 	return ret, nil
 }
 
+// makes a comparison operator token out of the one or two characters starting at currentChar:
+// <, >, =, <=, >= or <>.
+func (lexer *lexer_t) makeComparison() Token_t {
+	pos := lexer.pos.copy()
+	first := lexer.currentChar
+	lexer.advance()
+	if first == '<' && lexer.currentChar == '=' {
+		lexer.advance()
+		return Token_t{tokenType: LE, pos: *pos}
+	} else if first == '<' && lexer.currentChar == '>' {
+		lexer.advance()
+		return Token_t{tokenType: NE, pos: *pos}
+	} else if first == '>' && lexer.currentChar == '=' {
+		lexer.advance()
+		return Token_t{tokenType: GE, pos: *pos}
+	} else if first == '<' {
+		return Token_t{tokenType: LT, pos: *pos}
+	} else if first == '>' {
+		return Token_t{tokenType: GT, pos: *pos}
+	}
+	return Token_t{tokenType: EQ, pos: *pos}
+}
+
 // parses the number in the string starting at currentChar.
-// can parse an int (a sequence of base-10 digits) or a floating point (a sequence of base-10 digits with 1 decimal point)
+// can parse an int (a sequence of base-10 digits) or a floating point (a sequence of base-10 digits with 1 decimal point,
+// an exponent like e-7, or both)
 // any decimal points after the first one are ignored (and signal end of token)
-func (lexer *lexer_t) makeNumber() token_t {
-	numStr := ""
+// returns a LexError if the literal can't be converted, for example an integer that doesn't fit in 64 bits,
+// or if it's longer than the lexer's limit.
+// The literal is sliced out of the text rather than built up a digit at a time, so long ones don't cost quadratic time.
+func (lexer *lexer_t) makeNumber() (Token_t, error) {
 	decimalPoints := 0
 	pos := lexer.pos.copy()
+	start := lexer.pos.index
 	for {
 		if lexer.currentChar != '.' && !(lexer.currentChar >= '0' && lexer.currentChar <= '9') {
 			break
@@ -182,46 +610,351 @@ func (lexer *lexer_t) makeNumber() token_t {
 			if decimalPoints == 1 {
 				break
 			}
-			numStr += "."
 			decimalPoints += 1
-		} else {
-			numStr += string(lexer.currentChar)
+		}
+		if err := lexer.checkLength(start, pos, "number is longer than the limit of %d bytes"); err != nil {
+			return Token_t{}, err
+		}
+		lexer.advance()
+	}
+	exponent := lexer.exponentLength()
+	for i := 0; i < exponent; i++ {
+		if err := lexer.checkLength(start, pos, "number is longer than the limit of %d bytes"); err != nil {
+			return Token_t{}, err
 		}
 		lexer.advance()
 	}
+	numStr := lexer.text[start:lexer.pos.index]
 
-	if decimalPoints == 0 {
-		i, _ := strconv.Atoi(numStr)
-		return token_t{tokenType: INT, intVal: int32(i), pos: *pos}
+	if decimalPoints == 0 && exponent == 0 {
+		i, err := strconv.ParseInt(numStr, 10, 64)
+		if err != nil {
+			return Token_t{}, &LexError_t{Code: ERR_LITERAL_RANGE, Details: fmt.Sprintf("integer literal %s overflows 64 bits", abbreviatedLiteral(numStr)), Pos: *pos}
+		}
+		return Token_t{tokenType: INT, intVal: i, pos: *pos}, nil
 	} else {
-		f, _ := strconv.ParseFloat(numStr, 64)
-		return token_t{tokenType: FLOAT, floatVal: f, pos: *pos}
+		f, err := strconv.ParseFloat(numStr, 64)
+		if err != nil {
+			return Token_t{}, &LexError_t{Code: ERR_LITERAL_RANGE, Details: fmt.Sprintf("float literal %s is out of range", abbreviatedLiteral(numStr)), Pos: *pos}
+		}
+		return Token_t{tokenType: FLOAT, floatVal: f, pos: *pos}, nil
 	}
 }
 
-type nodeType_t int
+// gets how many bytes long the exponent starting at currentChar is, like 3 for e-7, or 0 if
+// there isn't one there. An e that isn't followed by digits is left for the next token.
+func (lexer *lexer_t) exponentLength() int {
+	if lexer.currentChar != 'e' && lexer.currentChar != 'E' {
+		return 0
+	}
+	i := lexer.pos.index + 1
+	if i < len(lexer.text) && (lexer.text[i] == '+' || lexer.text[i] == '-') {
+		i++
+	}
+	digits := i
+	for i < len(lexer.text) && lexer.text[i] >= '0' && lexer.text[i] <= '9' {
+		i++
+	}
+	if i == digits {
+		return 0
+	}
+	return i - lexer.pos.index
+}
+
+// returns a LexError if the token starting at start would be longer than the lexer's limit once
+// it takes currentChar too. format says what's too long, with a %d for the limit.
+func (lexer *lexer_t) checkLength(start int, pos *Position_t, format string) error {
+	if lexer.pos.index-start >= lexer.maxTokenLength {
+		return &LexError_t{Code: ERR_LIMIT, Details: fmt.Sprintf(format, lexer.maxTokenLength), Pos: *pos}
+	}
+	return nil
+}
+
+// shortens a long literal for an error message, keeping its first digits and how long it was.
+func abbreviatedLiteral(literal string) string {
+	if len(literal) <= 40 {
+		return literal
+	}
+	return fmt.Sprintf("%s... (%d characters)", literal[:20], len(literal))
+}
+
+// returns true for characters that can start an identifier: letters and underscores
+func isLetter(char byte) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || char == '_'
+}
+
+// makes an identifier token out of the letters, digits and underscores starting at currentChar.
+// Like in other BASICs, the name can end in a $, as the functions that make strings do (HEX$).
+// returns a LexError if the name is longer than the lexer's limit.
+func (lexer *lexer_t) makeIdentifier() (Token_t, error) {
+	pos := lexer.pos.copy()
+	start := lexer.pos.index
+	for isLetter(lexer.currentChar) || (lexer.currentChar >= '0' && lexer.currentChar <= '9') {
+		if err := lexer.checkLength(start, pos, "name is longer than the limit of %d bytes"); err != nil {
+			return Token_t{}, err
+		}
+		lexer.advance()
+	}
+	if lexer.currentChar == '$' {
+		lexer.advance()
+	}
+	return Token_t{tokenType: IDENTIFIER, strVal: lexer.text[start:lexer.pos.index], pos: *pos}, nil
+}
+
+// makes a string literal token out of the text between the '"' at currentChar and the next
+// lone '"'. Like in other BASICs, a quote inside the string is written twice: "say ""hi""".
+// Strings can't span lines, or be longer than the lexer's limit.
+func (lexer *lexer_t) makeString() (Token_t, error) {
+	pos := lexer.pos.copy()
+	start := lexer.pos.index
+	var str strings.Builder
+	lexer.advance()
+	for {
+		if lexer.currentChar == 0 || lexer.currentChar == '\n' {
+			return Token_t{}, &LexError_t{Code: ERR_UNTERMINATED_STRING, Details: "string literal is never closed", Pos: *pos}
+		} else if err := lexer.checkLength(start, pos, "string literal is longer than the limit of %d bytes"); err != nil {
+			return Token_t{}, err
+		}
+		if lexer.currentChar == '"' {
+			lexer.advance()
+			if lexer.currentChar != '"' {
+				return Token_t{tokenType: STRING, strVal: str.String(), pos: *pos}, nil
+			}
+		}
+		str.WriteByte(lexer.currentChar)
+		lexer.advance()
+	}
+}
+
+// kind of an AST node. Binary operations are split by precedence level, so a
+// `+` or `-` tree is an EXPRESSION and a `*` or `/` tree is a TERM.
+type NodeType_t int
 
 // enum to signal node type
 const (
-	FACTOR nodeType_t = iota
+	FACTOR NodeType_t = iota
 	TERM
 	EXPRESSION
 	UNARY_OP
+	VAR_ACCESS
+	CALL
+	STATEMENTS
+	LIST             // list literal, its elements are the args
+	INDEX            // left[right]
+	SLICE            // left[args[0]:args[1]], either bound can be nil
+	DICT             // dict literal, its args are keys and values, alternating
+	FOR_EACH         // FOR EACH tok IN left, running the statements for every item
+	COMPARISON       // left tok right, where tok is one of <, >, <=, >=, =, <> and ~=
+	COMPARISON_CHAIN // args[0] ops[0] args[1] ops[1] args[2] ..., like 1 <= x <= 10
+	OPTION           // OPTION ops[0] ops[1], like OPTION ANGLE DEGREES
+	POWER            // left ^ right. Powers group to the right, so 2^3^2 is 2^(3^2)
+	IMPORT           // IMPORT tok AS ops[0], where tok is the path and AS is optional
+	POKE             // POKE args[0], args[1]: writes the value args[1] to the address args[0]
+	COMMAND          // tok args[0], args[1], ..., where tok is a command like PSET or BEEP
+	ON_TIMER         // ON TIMER(args[0]) GOSUB args[1], where args[1] is a function to run every args[0] seconds
+	YIELD            // YIELD left, where left is the optional value handed back to Resume
+	PARAM_ACCESS     // ?tok, a placeholder a Program_t's Bind gives a value
+	COALESCE_OP      // left ?? right: right if left is NIL, and left otherwise
+	SAFE_ACCESS      // left?.ops[0]: the entry of the dict left called ops[0], or NIL if left is NIL or hasn't got one
+	IN_OP            // left IN right, where right is a list, string or dict
+	BETWEEN_OP       // left BETWEEN args[0] AND args[1], where ops[0] is the AND
+	UNPACK           // ops[0], ops[1], ... tok left: binds the variables to the elements of the list left, where tok is the =
+	REST_PARAM       // left..., the last parameter of a LAMBDA, which collects the arguments past the others into a list
+	TYPE_DEF         // TYPE tok, then its members args up to END TYPE: fields like x or x = 0, and operators like "+" = LAMBDA(...)
+	ENUM_DEF         // ENUM tok, then its members args up to END ENUM, like RED or GREEN = 5
+	FIELD_ACCESS     // left.ops[0]: the field of the record left called ops[0], or the entry of the dict
+	MATCH_EXPR       // MATCH left, then for each CASE ops[i] the pattern args[3i], the guard args[3i+1] (nil if there isn't one) and the value args[3i+2]
+	ANNOTATION       // left AS ops[0]: left, which has to be of the type ops[0] names. For a LAMBDA, it's the type its calls give back
 	NODE_ERR
 )
 
+// gets the name of this node type, like "TERM" or "UNARY_OP"
+func (nodeType NodeType_t) String() string {
+	names := [34]string{"FACTOR", "TERM", "EXPRESSION", "UNARY_OP", "VAR_ACCESS", "CALL", "STATEMENTS", "LIST", "INDEX", "SLICE", "DICT", "FOR_EACH", "COMPARISON", "COMPARISON_CHAIN", "OPTION", "POWER", "IMPORT", "POKE", "COMMAND", "ON_TIMER", "YIELD", "PARAM_ACCESS", "COALESCE_OP", "SAFE_ACCESS", "IN_OP", "BETWEEN_OP", "UNPACK", "REST_PARAM", "TYPE_DEF", "ENUM_DEF", "FIELD_ACCESS", "MATCH_EXPR", "ANNOTATION", "NODE_ERR"}
+	if nodeType < 0 || int(nodeType) >= len(names) {
+		return fmt.Sprintf("NodeType_t(%d)", int(nodeType))
+	}
+	return names[nodeType]
+}
+
 // Nodes used to build the Abstract Syntax Tree (AST)
-type node_t struct {
-	nodeType nodeType_t
-	left     *node_t
-	tok      token_t
-	right    *node_t
+type Node_t struct {
+	nodeType   NodeType_t
+	left       *Node_t
+	tok        Token_t
+	right      *Node_t
+	statements []*Node_t // only used by STATEMENTS and FOR_EACH nodes
+	args       []*Node_t // only used by CALL, LIST, SLICE, DICT, COMPARISON_CHAIN, BETWEEN_OP, POKE, COMMAND, ON_TIMER, TYPE_DEF, ENUM_DEF and MATCH_EXPR nodes
+	ops        []Token_t // only used by COMPARISON_CHAIN, SAFE_ACCESS, FIELD_ACCESS, MATCH_EXPR, ANNOTATION, BETWEEN_OP, UNPACK, OPTION and IMPORT nodes
+}
+
+// gets the kind of this node.
+func (node *Node_t) Kind() NodeType_t {
+	return node.nodeType
+}
+
+// gets the left child of a binary operation, the operand of a unary operation, what an
+// INDEX or SLICE node takes part of, or the index a CALL node gets its function from. nil for factors.
+func (node *Node_t) Left() *Node_t {
+	return node.left
+}
+
+// gets the right child of a binary operation, or the index of an INDEX node. nil for factors and unary operations.
+func (node *Node_t) Right() *Node_t {
+	return node.right
+}
+
+// gets the name of the variable read by a VAR_ACCESS node, of the function called by a CALL node
+// (how it's written, like m["f"], if it comes from an index), of the loop variable of a FOR_EACH node
+// or of the namespace an IMPORT node binds. "" for any other node.
+func (node *Node_t) Name() string {
+	if node.nodeType == IMPORT {
+		return importName(node)
+	} else if node.nodeType != VAR_ACCESS && node.nodeType != CALL && node.nodeType != FOR_EACH {
+		return ""
+	}
+	return node.tok.strVal
+}
+
+// gets the arguments of a CALL node or the elements of a LIST node, in order. For a SLICE node,
+// it's the start and end bounds, either of which is nil if it was left out, and for a DICT node
+// the keys and values, alternating. nil for any other node.
+func (node *Node_t) Args() []*Node_t {
+	return node.args
+}
+
+// gets the statements of a STATEMENTS node, or the body of a FOR_EACH node, in order. nil for any other node.
+func (node *Node_t) Statements() []*Node_t {
+	return node.statements
+}
+
+// gets the token this node was built from: the literal, the name, or the operator.
+func (node *Node_t) Token() Token_t {
+	return node.tok
+}
+
+// gets the byte offsets where the source of this node starts and just past where it ends.
+// Brackets around the node aren't counted, since the tree doesn't keep them.
+func (node *Node_t) Span() (int, int) {
+	start, end := span(node)
+	return start.index, end
+}
+
+// gets the operator of a binary or unary operation, like ADD or MUL.
+// for a factor this is the literal's type (INT, FLOAT or STRING).
+func (node *Node_t) Op() TokenType_t {
+	return node.tok.tokenType
+}
+
+// gets the operators of a COMPARISON_CHAIN node, in order: ops[i] compares args[i] with args[i+1].
+// For an OPTION node, it's the setting's name and value, and for an IMPORT node with AS,
+// the name after it. nil for any other node.
+func (node *Node_t) Ops() []Token_t {
+	return node.ops
+}
+
+// gets the value of a literal as a Result. nil for anything that isn't a factor.
+func (node *Node_t) Value() *Result_t {
+	if node.nodeType != FACTOR {
+		return nil
+	}
+	if node.tok.tokenType == INT {
+		return NewInt(node.tok.intVal)
+	} else if node.tok.tokenType == STRING {
+		return NewString(node.tok.strVal)
+	}
+	return NewFloat(node.tok.floatVal)
+}
+
+// walks the AST rooted at node in depth-first order, calling visit on each node before its children.
+// If visit returns false, the children of that node are skipped.
+func Walk(node *Node_t, visit func(*Node_t) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+	Walk(node.left, visit)
+	Walk(node.right, visit)
+	for _, stmt := range node.statements {
+		Walk(stmt, visit)
+	}
+	for _, arg := range node.args {
+		Walk(arg, visit)
+	}
 }
 
 // Recursively generate a String representation of this node.
-func (node *node_t) String() string {
-	if node.nodeType == FACTOR {
+func (node *Node_t) String() string {
+	if node.nodeType == STATEMENTS {
+		strs := make([]string, len(node.statements))
+		for i, stmt := range node.statements {
+			strs[i] = stmt.String()
+		}
+		return "[" + strings.Join(strs, ", ") + "]"
+	} else if node.nodeType == FOR_EACH {
+		strs := make([]string, len(node.statements))
+		for i, stmt := range node.statements {
+			strs[i] = stmt.String()
+		}
+		return fmt.Sprintf("(FOR_EACH %s, %s, [%s])", node.tok.strVal, node.left.String(), strings.Join(strs, ", "))
+	} else if node.nodeType == OPTION {
+		return fmt.Sprintf("(OPTION %s %s)", node.ops[0].strVal, node.ops[1].strVal)
+	} else if node.nodeType == IMPORT {
+		return fmt.Sprintf("(IMPORT %s AS %s)", quoteString(node.tok.strVal), importName(node))
+	} else if node.nodeType == POKE {
+		return fmt.Sprintf("(POKE %s, %s)", node.args[0].String(), node.args[1].String())
+	} else if node.nodeType == ON_TIMER {
+		return fmt.Sprintf("(ON_TIMER %s, %s)", node.args[0].String(), node.args[1].String())
+	} else if node.nodeType == YIELD && node.left != nil {
+		return fmt.Sprintf("(YIELD %s)", node.left.String())
+	} else if node.nodeType == YIELD {
+		return "(YIELD)"
+	} else if node.nodeType == COMMAND {
+		strs := make([]string, len(node.args))
+		for i, arg := range node.args {
+			strs[i] = arg.String()
+		}
+		return "(" + strings.TrimSpace(strings.ToUpper(node.tok.strVal)+" "+strings.Join(strs, ", ")) + ")"
+	} else if node.nodeType == FACTOR || node.nodeType == VAR_ACCESS || node.nodeType == PARAM_ACCESS {
 		return node.tok.String()
+	} else if node.nodeType == CALL || node.nodeType == LIST || node.nodeType == SLICE || node.nodeType == DICT || node.nodeType == COMPARISON_CHAIN || node.nodeType == TYPE_DEF || node.nodeType == ENUM_DEF || node.nodeType == MATCH_EXPR {
+		strs := make([]string, len(node.args))
+		for i, arg := range node.args {
+			strs[i] = "nil"
+			if arg != nil {
+				strs[i] = arg.String()
+			}
+		}
+		if node.nodeType == LIST || node.nodeType == DICT {
+			return fmt.Sprintf("(%s, [%s])", node.nodeType, strings.Join(strs, ", "))
+		} else if node.nodeType == COMPARISON_CHAIN {
+			for i, op := range node.ops {
+				strs[i+1] = op.String() + " " + strs[i+1]
+			}
+			return fmt.Sprintf("(COMPARISON_CHAIN %s)", strings.Join(strs, ", "))
+		} else if node.nodeType == SLICE {
+			return fmt.Sprintf("(SLICE %s, [%s])", node.left.String(), strings.Join(strs, ", "))
+		} else if node.nodeType == TYPE_DEF || node.nodeType == ENUM_DEF {
+			return fmt.Sprintf("(%s %s, [%s])", node.nodeType, node.tok.strVal, strings.Join(strs, ", "))
+		} else if node.nodeType == MATCH_EXPR {
+			return fmt.Sprintf("(MATCH_EXPR %s, [%s])", node.left.String(), strings.Join(strs, ", "))
+		}
+		return fmt.Sprintf("(CALL %s, [%s])", node.tok.strVal, strings.Join(strs, ", "))
+	} else if node.nodeType == INDEX {
+		return fmt.Sprintf("(INDEX %s, %s)", node.left.String(), node.right.String())
+	} else if node.nodeType == SAFE_ACCESS {
+		return fmt.Sprintf("(SAFE_ACCESS %s, %s)", node.left.String(), node.ops[0].strVal)
+	} else if node.nodeType == FIELD_ACCESS {
+		return fmt.Sprintf("(FIELD_ACCESS %s, %s)", node.left.String(), node.ops[0].strVal)
+	} else if node.nodeType == ANNOTATION {
+		return fmt.Sprintf("(ANNOTATION %s, %s)", node.left.String(), node.ops[0].strVal)
+	} else if node.nodeType == REST_PARAM {
+		return fmt.Sprintf("(REST_PARAM %s)", node.left.String())
+	} else if node.nodeType == UNPACK {
+		return fmt.Sprintf("(UNPACK [%s], %s)", unpackNames(node), node.left.String())
+	} else if node.nodeType == IN_OP {
+		return fmt.Sprintf("(IN_OP %s, %s)", node.left.String(), node.right.String())
+	} else if node.nodeType == BETWEEN_OP {
+		return fmt.Sprintf("(BETWEEN_OP %s, %s, %s)", node.left.String(), node.args[0].String(), node.args[1].String())
 	} else if node.nodeType == UNARY_OP {
 		return fmt.Sprintf("(%s, %s)", node.tok.String(), node.left.String())
 	} else {
@@ -232,13 +965,13 @@ func (node *node_t) String() string {
 // parser_t class. This takes a sequence of tokens and builds
 // an abstract syntax tree from them.
 type parser_t struct {
-	tokens       []token_t
+	tokens       []Token_t
 	idx          int
-	currentToken token_t
+	currentToken Token_t
 }
 
 // constructor
-func newParser(toks []token_t) *parser_t {
+func newParser(toks []Token_t) *parser_t {
 	ret := parser_t{tokens: toks, idx: -1}
 	ret.advance()
 	return &ret
@@ -253,7 +986,7 @@ func (parser *parser_t) advance() {
 }
 
 // builds and returns a Factor node using the rules laid out in grammar.txt
-func (parser *parser_t) factor() (*node_t, error) {
+func (parser *parser_t) factor() (*Node_t, error) {
 
 	if parser.currentToken.tokenType == ADD || parser.currentToken.tokenType == SUB { // Unary operation case-- something like -2
 		op := parser.currentToken
@@ -262,12 +995,36 @@ func (parser *parser_t) factor() (*node_t, error) {
 		if err != nil {
 			return nil, err
 		}
-		ret := node_t{nodeType: UNARY_OP, tok: op, left: factor}
+		ret := Node_t{nodeType: UNARY_OP, tok: op, left: factor}
 		return &ret, nil
+	}
+	atom, err := parser.atom()
+	if err != nil {
+		return atom, err
+	}
+	base, err := parser.postfix(atom)
+	if err == nil && isKeyword(parser.currentToken, "AS") {
+		base, err = parser.annotation(base)
+	}
+	if err != nil || parser.currentToken.tokenType != POW {
+		return base, err
+	}
+	// a power binds tighter than a sign on its left but takes one on its right, so -2^2 is -(2^2) and 2^-1 works
+	operator := parser.currentToken
+	parser.advance()
+	exponent, err := parser.factor()
+	if err != nil {
+		return nil, err
+	}
+	return &Node_t{nodeType: POWER, left: base, tok: operator, right: exponent}, nil
+}
 
-	} else if parser.currentToken.tokenType == LPAREN { // Parentheses signify the expression case--there's an expression in parentheses.
+// builds and returns what a factor applies its unary operators and postfixes to:
+// an expression in parentheses, a literal, a variable, a call or a MATCH.
+func (parser *parser_t) atom() (*Node_t, error) {
+	if parser.currentToken.tokenType == LPAREN { // Parentheses signify the expression case--there's an expression in parentheses.
 		parser.advance()
-		expr, err := parser.expression()
+		expr, err := parser.comparison()
 		if err != nil {
 			return nil, err
 		}
@@ -275,18 +1032,184 @@ func (parser *parser_t) factor() (*node_t, error) {
 			parser.advance()
 			return expr, nil
 		} else {
-			return &node_t{nodeType: NODE_ERR}, fmt.Errorf("expected ')' at %s", parser.currentToken.pos.String())
+			return &Node_t{nodeType: NODE_ERR}, &ParseError_t{Code: ERR_EXPECTED_RPAREN, Details: "expected ')'", Pos: parser.currentToken.pos}
 		}
-	} else if parser.currentToken.tokenType == INT || parser.currentToken.tokenType == FLOAT { // number literal case
-		ret := node_t{nodeType: FACTOR, tok: parser.currentToken}
+	} else if parser.currentToken.tokenType == INT || parser.currentToken.tokenType == FLOAT || parser.currentToken.tokenType == STRING { // literal case
+		ret := Node_t{nodeType: FACTOR, tok: parser.currentToken}
 		parser.advance()
 		return &ret, nil
+	} else if isMatch(parser.tokens, parser.idx) {
+		return parser.match()
+	} else if parser.currentToken.tokenType == IDENTIFIER { // variable or function call case
+		ret := Node_t{nodeType: VAR_ACCESS, tok: parser.currentToken}
+		parser.advance()
+		if parser.currentToken.tokenType == LPAREN {
+			return parser.call(ret.tok)
+		}
+		return &ret, nil
+	} else if parser.currentToken.tokenType == PARAM { // placeholder case
+		ret := Node_t{nodeType: PARAM_ACCESS, tok: parser.currentToken}
+		parser.advance()
+		return &ret, nil
+	} else if parser.currentToken.tokenType == LBRACKET { // list literal case
+		return parser.list()
+	} else if parser.currentToken.tokenType == LBRACE { // dict literal case
+		return parser.dict()
 	}
-	return &node_t{nodeType: NODE_ERR}, fmt.Errorf("expected factor at %s", parser.currentToken.pos.String())
+	return &Node_t{nodeType: NODE_ERR}, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected factor", Pos: parser.currentToken.pos}
+}
+
+// builds and returns a Call node. The current token is the '(' after the function's name.
+func (parser *parser_t) call(name Token_t) (*Node_t, error) {
+	ret := &Node_t{nodeType: CALL, tok: name, args: make([]*Node_t, 0)}
+	parser.advance()
+	if parser.currentToken.tokenType == RPAREN { // no arguments
+		parser.advance()
+		return ret, nil
+	}
+	for {
+		arg, err := parser.comparison()
+		if err != nil {
+			return nil, err
+		}
+		if parser.currentToken.tokenType == ELLIPSIS {
+			if arg, err = parser.restParam(ret, arg); err != nil {
+				return nil, err
+			}
+		}
+		ret.args = append(ret.args, arg)
+		if parser.currentToken.tokenType == COMMA {
+			parser.advance()
+		} else if parser.currentToken.tokenType == RPAREN {
+			parser.advance()
+			return ret, nil
+		} else {
+			return &Node_t{nodeType: NODE_ERR}, &ParseError_t{Code: ERR_EXPECTED_RPAREN, Details: "expected ',' or ')'", Pos: parser.currentToken.pos}
+		}
+	}
+}
+
+// builds and returns a List node. The current token is the '['.
+func (parser *parser_t) list() (*Node_t, error) {
+	ret := &Node_t{nodeType: LIST, tok: parser.currentToken, args: make([]*Node_t, 0)}
+	parser.advance()
+	if parser.currentToken.tokenType == RBRACKET { // empty list
+		parser.advance()
+		return ret, nil
+	}
+	for {
+		elem, err := parser.comparison()
+		if err != nil {
+			return nil, err
+		}
+		ret.args = append(ret.args, elem)
+		if parser.currentToken.tokenType == COMMA {
+			parser.advance()
+		} else if parser.currentToken.tokenType == RBRACKET {
+			parser.advance()
+			return ret, nil
+		} else {
+			return &Node_t{nodeType: NODE_ERR}, &ParseError_t{Code: ERR_EXPECTED_RBRACKET, Details: "expected ',' or ']'", Pos: parser.currentToken.pos}
+		}
+	}
+}
+
+// builds and returns a Dict node. The current token is the '{'.
+func (parser *parser_t) dict() (*Node_t, error) {
+	ret := &Node_t{nodeType: DICT, tok: parser.currentToken, args: make([]*Node_t, 0)}
+	parser.advance()
+	if parser.currentToken.tokenType == RBRACE { // empty dict
+		parser.advance()
+		return ret, nil
+	}
+	for {
+		key, err := parser.comparison()
+		if err != nil {
+			return nil, err
+		}
+		if parser.currentToken.tokenType != COLON {
+			return &Node_t{nodeType: NODE_ERR}, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected ':' after a dict key", Pos: parser.currentToken.pos}
+		}
+		parser.advance()
+		value, err := parser.comparison()
+		if err != nil {
+			return nil, err
+		}
+		ret.args = append(ret.args, key, value)
+		if parser.currentToken.tokenType == COMMA {
+			parser.advance()
+		} else if parser.currentToken.tokenType == RBRACE {
+			parser.advance()
+			return ret, nil
+		} else {
+			return &Node_t{nodeType: NODE_ERR}, &ParseError_t{Code: ERR_EXPECTED_RBRACE, Details: "expected ',' or '}'", Pos: parser.currentToken.pos}
+		}
+	}
+}
+
+// applies any indexes, slices, fields and ?. lookups written after an atom, like a[1], a[2:][0], p.x or a?.b.
+// The current token is the one after the atom.
+func (parser *parser_t) postfix(target *Node_t) (*Node_t, error) {
+	for parser.currentToken.tokenType == LBRACKET || parser.currentToken.tokenType == SAFE_DOT || parser.currentToken.tokenType == DOT || (parser.currentToken.tokenType == LPAREN && (target.nodeType == INDEX || target.nodeType == SAFE_ACCESS || target.nodeType == FIELD_ACCESS)) {
+		if parser.currentToken.tokenType == SAFE_DOT || parser.currentToken.tokenType == DOT { // a?.key or p.field
+			dot := parser.currentToken
+			parser.advance()
+			if parser.currentToken.tokenType != IDENTIFIER && dot.tokenType == DOT {
+				return &Node_t{nodeType: NODE_ERR}, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected a field after '.'", Pos: parser.currentToken.pos}
+			} else if parser.currentToken.tokenType != IDENTIFIER {
+				return &Node_t{nodeType: NODE_ERR}, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected a key after '?.'", Pos: parser.currentToken.pos}
+			}
+			target = &Node_t{nodeType: SAFE_ACCESS, tok: dot, left: target, ops: []Token_t{parser.currentToken}}
+			if dot.tokenType == DOT {
+				target.nodeType = FIELD_ACCESS
+			}
+			parser.advance()
+			continue
+		} else if parser.currentToken.tokenType == LPAREN { // calling a function kept in a dict or list, like m["f"](x)
+			callee := Token_t{tokenType: IDENTIFIER, strVal: formatExpr(target), pos: target.tok.pos}
+			call, err := parser.call(callee)
+			if err != nil {
+				return call, err
+			}
+			call.left = target
+			target = call
+			continue
+		}
+		bracket := parser.currentToken
+		parser.advance()
+		var start, end *Node_t
+		var err error
+		if parser.currentToken.tokenType != COLON {
+			start, err = parser.comparison()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if parser.currentToken.tokenType == RBRACKET { // a[i]
+			parser.advance()
+			target = &Node_t{nodeType: INDEX, tok: bracket, left: target, right: start}
+			continue
+		} else if parser.currentToken.tokenType != COLON {
+			return &Node_t{nodeType: NODE_ERR}, &ParseError_t{Code: ERR_EXPECTED_RBRACKET, Details: "expected ':' or ']'", Pos: parser.currentToken.pos}
+		}
+		parser.advance()
+		if parser.currentToken.tokenType != RBRACKET {
+			end, err = parser.comparison()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if parser.currentToken.tokenType != RBRACKET {
+			return &Node_t{nodeType: NODE_ERR}, &ParseError_t{Code: ERR_EXPECTED_RBRACKET, Details: "expected ']'", Pos: parser.currentToken.pos}
+		}
+		parser.advance()
+		target = &Node_t{nodeType: SLICE, tok: bracket, left: target, args: []*Node_t{start, end}}
+	}
+	return target, nil
 }
 
 // builds and returns a Term node
-func (parser *parser_t) term() (*node_t, error) {
+func (parser *parser_t) term() (*Node_t, error) {
 	left, err := parser.factor()
 	if err != nil {
 		return nil, err
@@ -302,14 +1225,55 @@ func (parser *parser_t) term() (*node_t, error) {
 		if err != nil {
 			return nil, err
 		}
-		left = &node_t{nodeType: TERM, left: left, tok: operator, right: right}
+		left = &Node_t{nodeType: TERM, left: left, tok: operator, right: right}
 	}
 
 	return left, nil
 }
 
+// returns true for the tokens of the comparison operators.
+func isComparison(tokenType TokenType_t) bool {
+	return tokenType == LT || tokenType == GT || tokenType == LE || tokenType == GE || tokenType == EQ || tokenType == NE || tokenType == APPROX
+}
+
+// builds and returns a Comparison node, or just the expression if there's no comparison.
+// Comparisons bind looser than everything else, so a + 1 < b * 2 compares the two sides.
+// More than one comparison in a row makes a Comparison Chain, so 1 <= x <= 10 means
+// (1 <= x) AND (x <= 10) rather than comparing the result of 1 <= x with 10.
+func (parser *parser_t) comparison() (*Node_t, error) {
+	left, err := parser.coalesce()
+	if err != nil {
+		return nil, err
+	} else if ret, ok, err := parser.membership(left); ok {
+		return ret, err
+	} else if !isComparison(parser.currentToken.tokenType) {
+		return left, nil
+	}
+	operator := parser.currentToken
+	parser.advance()
+	right, err := parser.coalesce()
+	if err != nil {
+		return nil, err
+	}
+	if !isComparison(parser.currentToken.tokenType) {
+		return &Node_t{nodeType: COMPARISON, left: left, tok: operator, right: right}, nil
+	}
+
+	ret := &Node_t{nodeType: COMPARISON_CHAIN, tok: operator, args: []*Node_t{left, right}, ops: []Token_t{operator}}
+	for isComparison(parser.currentToken.tokenType) {
+		ret.ops = append(ret.ops, parser.currentToken)
+		parser.advance()
+		operand, err := parser.coalesce()
+		if err != nil {
+			return nil, err
+		}
+		ret.args = append(ret.args, operand)
+	}
+	return ret, nil
+}
+
 // builds and returns an Expression node
-func (parser *parser_t) expression() (*node_t, error) {
+func (parser *parser_t) expression() (*Node_t, error) {
 	left, err := parser.term()
 	if err != nil {
 		return nil, err
@@ -325,47 +1289,347 @@ func (parser *parser_t) expression() (*node_t, error) {
 		if err != nil {
 			return nil, err
 		}
-		left = &node_t{nodeType: TERM, left: left, tok: operator, right: right}
+		left = &Node_t{nodeType: EXPRESSION, left: left, tok: operator, right: right}
 	}
 
 	return left, nil
 }
 
-// wrapper for parsing expression (kicks off recursion). Also checks for EOF.
-func (parser *parser_t) parse() (*node_t, error) {
-	ret, err := parser.expression()
-	if err == nil && parser.currentToken.tokenType != EOF {
-		err = fmt.Errorf("exprected operator at %s", parser.currentToken.pos.String())
+// returns true if the token ends a statement (a newline or a colon)
+func isSeparator(tok Token_t) bool {
+	return tok.tokenType == NEWLINE || tok.tokenType == COLON
+}
+
+// skips over any statement separators at the current token
+func (parser *parser_t) skipSeparators() {
+	for isSeparator(parser.currentToken) {
+		parser.advance()
+	}
+}
+
+// error recovery: skips tokens up to the next statement separator (or EOF) so parsing can pick up
+// again at the start of the next statement.
+func (parser *parser_t) synchronize() {
+	for !isSeparator(parser.currentToken) && parser.currentToken.tokenType != EOF {
+		parser.advance()
+	}
+}
+
+// returns true if the token is the keyword, in any case. Keywords aren't reserved: they're
+// only keywords where a statement expects them, and can be variable names anywhere else.
+func isKeyword(tok Token_t, keyword string) bool {
+	return tok.tokenType == IDENTIFIER && strings.EqualFold(tok.strVal, keyword)
+}
+
+// builds and returns a single statement: a FOR EACH loop, an OPTION, an IMPORT, a POKE,
+// an ON TIMER, a YIELD, a command like PSET, an unpacking like lo, hi = MINMAX(xs) or an expression.
+func (parser *parser_t) statement() (*Node_t, error) {
+	var ret *Node_t
+	var err error
+	if isKeyword(parser.currentToken, "FOR") {
+		ret, err = parser.forEach()
+	} else if isKeyword(parser.currentToken, "OPTION") {
+		ret, err = parser.option()
+	} else if isKeyword(parser.currentToken, "IMPORT") && parser.idx+1 < len(parser.tokens) && parser.tokens[parser.idx+1].tokenType == STRING {
+		ret, err = parser.importModule()
+	} else if isPoke(parser.tokens, parser.idx) {
+		ret, err = parser.poke()
+	} else if isOnTimer(parser.tokens, parser.idx) {
+		ret, err = parser.onTimer()
+	} else if isYield(parser.tokens, parser.idx) {
+		ret, err = parser.yield()
+	} else if isCommand(parser.tokens, parser.idx) {
+		ret, err = parser.command()
+	} else if isUnpack(parser.tokens, parser.idx) {
+		ret, err = parser.unpack()
+	} else if isDeclaration(parser.tokens, parser.idx, "TYPE") {
+		ret, err = parser.typeDef()
+	} else if isDeclaration(parser.tokens, parser.idx, "ENUM") {
+		ret, err = parser.enumDef()
+	} else {
+		ret, err = parser.comparison()
+	}
+	if err == nil && !isSeparator(parser.currentToken) && parser.currentToken.tokenType != EOF {
+		err = &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected operator", Pos: parser.currentToken.pos}
 	}
 	return ret, err
 }
 
+// builds and returns a For Each node: FOR EACH name IN expr, then statements up to NEXT.
+// The NEXT can repeat the variable's name, like NEXT item.
+func (parser *parser_t) forEach() (*Node_t, error) {
+	parser.advance()
+	if !isKeyword(parser.currentToken, "EACH") {
+		return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected EACH after FOR", Pos: parser.currentToken.pos}
+	}
+	parser.advance()
+	if parser.currentToken.tokenType != IDENTIFIER {
+		return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected a variable name after FOR EACH", Pos: parser.currentToken.pos}
+	}
+	ret := &Node_t{nodeType: FOR_EACH, tok: parser.currentToken}
+	parser.advance()
+	if !isKeyword(parser.currentToken, "IN") {
+		return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: fmt.Sprintf("expected IN after FOR EACH %s", ret.tok.strVal), Pos: parser.currentToken.pos}
+	}
+	parser.advance()
+	collection, err := parser.comparison()
+	if err != nil {
+		return nil, err
+	}
+	ret.left = collection
+	if !isSeparator(parser.currentToken) {
+		return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected a new line or ':' before the body of the loop", Pos: parser.currentToken.pos}
+	}
+
+	parser.skipSeparators()
+	for !isKeyword(parser.currentToken, "NEXT") {
+		if parser.currentToken.tokenType == EOF {
+			return nil, &ParseError_t{Code: ERR_EXPECTED_NEXT, Details: fmt.Sprintf("FOR EACH %s has no NEXT", ret.tok.strVal), Pos: ret.tok.pos}
+		}
+		stmt, err := parser.statement()
+		if err != nil {
+			return nil, err
+		}
+		ret.statements = append(ret.statements, stmt)
+		parser.skipSeparators()
+	}
+	parser.advance()
+	if parser.currentToken.tokenType == IDENTIFIER {
+		if parser.currentToken.strVal != ret.tok.strVal {
+			return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: fmt.Sprintf("NEXT %s doesn't match FOR EACH %s", parser.currentToken.strVal, ret.tok.strVal), Pos: parser.currentToken.pos}
+		}
+		parser.advance()
+	}
+	return ret, nil
+}
+
+// builds and returns a Statements node out of every statement up to EOF.
+// A statement that fails to parse doesn't stop the parser: the error is recorded, the parser
+// skips to the next separator and carries on, so all of the errors come back together.
+func (parser *parser_t) statements() (*Node_t, error) {
+	ret := &Node_t{nodeType: STATEMENTS, tok: parser.currentToken}
+	errs := ErrorList_t{}
+
+	parser.skipSeparators()
+	for parser.currentToken.tokenType != EOF {
+		stmt, err := parser.statement()
+		if err != nil {
+			errs = append(errs, err)
+			parser.synchronize()
+		} else {
+			ret.statements = append(ret.statements, stmt)
+		}
+		parser.skipSeparators()
+	}
+
+	if len(errs) == 1 {
+		return nil, errs[0]
+	} else if len(errs) > 1 {
+		return nil, errs
+	}
+	if len(ret.statements) == 0 { // empty program, let statement() report what's missing
+		_, err := parser.statement()
+		return nil, err
+	}
+	return ret, nil
+}
+
+// wrapper for parsing statements (kicks off recursion).
+// A program with only one statement parses to the node of that statement.
+func (parser *parser_t) parse() (*Node_t, error) {
+	ret, err := parser.statements()
+	if err != nil {
+		return nil, err
+	}
+	if len(ret.statements) == 1 {
+		return ret.statements[0], nil
+	}
+	return ret, nil
+}
+
+// list of errors, used when more than one thing went wrong (for example, several statements failed to parse).
+type ErrorList_t []error
+
+// returns every error in the list, one per line.
+func (errs ErrorList_t) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
 type resultType_t int
 
 // enum for result types.
 const (
 	INTEGER resultType_t = iota
 	FLOATING
+	STRING_RESULT
+	LIST_RESULT
+	FUNCTION_RESULT
+	DICT_RESULT
+	MATRIX_RESULT
+	SYMBOLIC_RESULT
+	NIL_RESULT
+	RECORD_RESULT
 )
 
+// gets the name of this type as used in error messages, like "int" or "list".
+func (resultType resultType_t) String() string {
+	names := [10]string{"int", "float", "string", "list", "function", "dict", "matrix", "expression", "nil", "record"}
+	if resultType < 0 || int(resultType) >= len(names) {
+		return fmt.Sprintf("resultType_t(%d)", int(resultType))
+	}
+	return names[resultType]
+}
+
 // container for Results.
+// Compatibility note: Ires used to be an int32. It is an int64 now, so code that
+// assigned it to an int32 needs an explicit conversion (and should check the range).
 type Result_t struct {
 	ResultType resultType_t
-	Ires       int32 // GACK! Any way to just use a single return or something like that?
+	Ires       int64  // GACK! Any way to just use a single return or something like that?
+	Enum       string // only used by ints that are members of an ENUM: its name
 	Fres       float64
+	Sres       string      // only used by strings
+	Lres       []*Result_t // only used by lists. Lists are values: nothing changes one once it's made.
+	Fnres      *Function_t // only used by functions
+	Dres       *Dict_t     // only used by dicts
+	Mres       *Matrix_t   // only used by matrices
+	Rres       *Record_t   // only used by records
+	Xres       *Node_t     // only used by symbolic results: the simplified expression
+	Warnings   []Warning_t // non-fatal diagnostics raised while getting this result
+	charged    uint32      // set once the value's been counted towards a Run's MaxAllocated, so it's only counted once
+}
+
+// makes an integer Result
+func NewInt(i int64) *Result_t {
+	return &Result_t{ResultType: INTEGER, Ires: i, Fres: float64(i)} // set the float value too in case we have to upcast to float
+}
+
+// makes a float Result
+func NewFloat(f float64) *Result_t {
+	return &Result_t{ResultType: FLOATING, Fres: f}
+}
+
+// makes a string Result
+func NewString(s string) *Result_t {
+	return &Result_t{ResultType: STRING_RESULT, Sres: s}
+}
+
+// makes a list Result holding the given elements
+func NewList(elems []*Result_t) *Result_t {
+	return &Result_t{ResultType: LIST_RESULT, Lres: elems}
+}
+
+// returns true for int and float results, which arithmetic works on.
+func (res *Result_t) IsNumber() bool {
+	return res.ResultType == INTEGER || res.ResultType == FLOATING
+}
+
+// gets the value of this result as a plain Go value: an int64, float64, string, or
+// []interface{} of those for a list, map[string]interface{} for a dict or record and [][]float64 for a matrix.
+// Functions come back as their ValueString, and NIL as nil.
+// Handy for encoding results as JSON.
+func (res *Result_t) Interface() interface{} {
+	switch res.ResultType {
+	case INTEGER:
+		return res.Ires
+	case STRING_RESULT:
+		return res.Sres
+	case LIST_RESULT:
+		elems := make([]interface{}, len(res.Lres))
+		for i, elem := range res.Lres {
+			elems[i] = elem.Interface()
+		}
+		return elems
+	case FUNCTION_RESULT:
+		return res.ValueString()
+	case DICT_RESULT:
+		entries := make(map[string]interface{}, len(res.Dres.keys))
+		for key, value := range res.Dres.values {
+			entries[key] = value.Interface()
+		}
+		return entries
+	case MATRIX_RESULT:
+		return res.Mres.Slices()
+	case SYMBOLIC_RESULT:
+		return res.ValueString()
+	case NIL_RESULT:
+		return nil
+	case RECORD_RESULT:
+		fields := make(map[string]interface{}, len(res.Rres.values))
+		for i, value := range res.Rres.values {
+			fields[res.Rres.Type.Fields[i].Name] = value.Interface()
+		}
+		return fields
+	default:
+		return res.Fres
+	}
 }
 
 // returns a String representation of this result.
 func (res *Result_t) String() string {
-	if res.ResultType == INTEGER {
-		return fmt.Sprintf("Result: %d", res.Ires)
-	} else {
-		return fmt.Sprintf("Result: %f", res.Fres)
+	return "Result: " + res.ValueString()
+}
+
+// returns just the value of this result as a string, like "50" or "2.5", or like "1e+20" for
+// floats too big or small to write out in full. Floats are written with as few digits as read
+// back as the same float, and always with a point or an exponent, so they read back as floats.
+// Strings come back as they are; strings inside lists are quoted, like [1, "a"].
+func (res *Result_t) ValueString() string {
+	return res.formatValue(func(num *Result_t) string {
+		if num.ResultType == INTEGER {
+			return strconv.FormatInt(num.Ires, 10)
+		}
+		return formatFloat(num.Fres, DEFAULT_SCIENTIFIC_ABOVE, DEFAULT_SCIENTIFIC_BELOW, -1)
+	})
+}
+
+// does the work of ValueString, with number writing the ints and floats.
+func (res *Result_t) formatValue(number func(num *Result_t) string) string {
+	switch res.ResultType {
+	case STRING_RESULT:
+		return res.Sres
+	case LIST_RESULT:
+		elems := make([]string, len(res.Lres))
+		for i, elem := range res.Lres {
+			if elem.ResultType == STRING_RESULT {
+				elems[i] = quoteString(elem.Sres)
+			} else {
+				elems[i] = elem.formatValue(number)
+			}
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case FUNCTION_RESULT:
+		return "<function " + res.Fnres.Name + ">"
+	case DICT_RESULT:
+		entries := make([]string, len(res.Dres.keys))
+		for i, key := range res.Dres.keys {
+			value := res.Dres.values[key]
+			if value.ResultType == STRING_RESULT {
+				entries[i] = quoteString(key) + ": " + quoteString(value.Sres)
+			} else {
+				entries[i] = quoteString(key) + ": " + value.formatValue(number)
+			}
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case MATRIX_RESULT:
+		return res.Mres.String()
+	case SYMBOLIC_RESULT:
+		return Format(res.Xres)
+	case NIL_RESULT:
+		return "NIL"
+	case RECORD_RESULT:
+		return res.Rres.format(number)
+	default:
+		return number(res)
 	}
 }
 
 // absolute value of an int
-func abs(num int32) int32 {
+func abs(num int64) int64 {
 	if num < 0 {
 		return -1 * num
 	}
@@ -373,7 +1637,7 @@ func abs(num int32) int32 {
 }
 
 // performs the given operation on the given integers and returns the result.
-func intop(left, right int32, op tokenType_t) int32 {
+func intop(left, right int64, op TokenType_t) int64 {
 	switch op {
 	case ADD:
 		return left + right
@@ -382,14 +1646,14 @@ func intop(left, right int32, op tokenType_t) int32 {
 	case MUL:
 		return left * right
 	case DIV:
-		return left / right // TODO: add div by 0 check
+		return left / right // division by 0 is checked by evaluate
 	default:
 		return 0
 	}
 }
 
 // GACK! Literally the exact same as intop, just for floats.
-func floatop(left, right float64, op tokenType_t) float64 {
+func floatop(left, right float64, op TokenType_t) float64 {
 	switch op {
 	case ADD:
 		return left + right
@@ -398,26 +1662,148 @@ func floatop(left, right float64, op tokenType_t) float64 {
 	case MUL:
 		return left * right
 	case DIV:
-		return left / right // TODO: add div by 0 check
+		return left / right // division by 0 is checked by evaluate
 	default:
 		return 0
 	}
 }
 
+// evaluates the arguments of a call, or the elements of a list, in order.
+func (interp *Interpreter_t) evaluateArgs(node *Node_t) ([]*Result_t, error) {
+	ret := make([]*Result_t, len(node.args))
+	for i, arg := range node.args {
+		res, err := arg.evaluate(interp)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = res
+	}
+	return ret, nil
+}
+
 // recursively evaluate a node, returning result struct
-func (node *node_t) evaluate() (*Result_t, error) {
+func (node *Node_t) evaluate(interp *Interpreter_t) (*Result_t, error) {
+	if err := interp.step(node); err != nil {
+		return nil, err
+	}
+	if tracer := interp.opts.Tracer; tracer != nil {
+		tracer.Enter(node)
+		res, err := node.evaluateNode(interp)
+		tracer.Exit(node, res, err)
+		return res, err
+	}
+	return node.evaluateNode(interp)
+}
+
+// evaluates one node, calling evaluate for its children.
+func (node *Node_t) evaluateNode(interp *Interpreter_t) (*Result_t, error) {
 	switch node.nodeType {
-	case FACTOR: // base case, just return a result with
-		if node.tok.tokenType == INT {
-			return &Result_t{ResultType: INTEGER, Ires: node.tok.intVal, Fres: float64(node.tok.intVal)}, nil // set the float value too in case we have to upcast to float
-		} else {
-			return &Result_t{ResultType: FLOATING, Fres: node.tok.floatVal}, nil
+	case FACTOR: // base case, just return a result with the literal's value
+		return node.Value(), nil // the float value is set too in case we have to upcast to float
+	case VAR_ACCESS: // look the variable up, falling back to a builtin used as a function value
+		if value, ok, err := interp.hostValue(node.tok.strVal, node.tok); ok {
+			return value, err
+		}
+		value, ok := interp.vars[node.tok.strVal]
+		if !ok && isNilName(node.tok.strVal) {
+			return NewNil(), nil
+		} else if !ok {
+			value, ok = interp.builtinValue(node.tok.strVal)
+		}
+		if !ok {
+			return nil, undefinedVar(node.tok, interp.scope(), interp.locale)
+		}
+		return value, nil
+	case CALL: // evaluate the arguments, then call the function in the variable or the builtin
+		if node.left != nil { // the function comes from an index, like m["f"](x)
+			return node.callIndexed(interp)
+		}
+		if special, ok := specialForms[strings.ToUpper(node.tok.strVal)]; ok {
+			res, err := special(interp, node)
+			return interp.charge(res, err, node.tok)
+		}
+		var callee *Function_t
+		if value, ok, err := interp.hostValue(node.tok.strVal, node.tok); err != nil {
+			return nil, err
+		} else if ok && value.ResultType == FUNCTION_RESULT {
+			callee = value.Fnres
+		} else if value, ok := interp.vars[node.tok.strVal]; ok && value.ResultType == FUNCTION_RESULT {
+			callee = value.Fnres
+		}
+		if callee != nil {
+			args, err := interp.callArgs(node, callee.Params)
+			if err != nil {
+				return nil, err
+			}
+			return callValue(node.tok, callee, args)
+		}
+		args, err := interp.callArgs(node, builtinParams(node.tok.strVal))
+		if err != nil {
+			return nil, err
 		}
+		return interp.callBuiltin(node.tok, args)
+	case LIST: // evaluate the elements in order
+		elems, err := interp.evaluateArgs(node)
+		if err != nil {
+			return nil, err
+		}
+		return interp.charge(NewList(elems), nil, node.tok)
+	case DICT:
+		return node.evaluateDict(interp)
+	case INDEX:
+		return node.evaluateIndex(interp)
+	case SLICE:
+		return node.evaluateSlice(interp)
+	case FOR_EACH:
+		return node.evaluateForEach(interp)
+	case COMPARISON:
+		return node.evaluateComparison(interp)
+	case COMPARISON_CHAIN:
+		return node.evaluateChain(interp)
+	case OPTION:
+		return node.evaluateOption(interp)
+	case IMPORT:
+		return node.evaluateImport(interp)
+	case POKE:
+		return node.evaluatePoke(interp)
+	case COMMAND:
+		return node.evaluateCommand(interp)
+	case ON_TIMER:
+		return node.evaluateOnTimer(interp)
+	case YIELD:
+		return node.evaluateYield(interp)
+	case PARAM_ACCESS:
+		return node.evaluateParam(interp)
+	case COALESCE_OP:
+		return node.evaluateCoalesce(interp)
+	case SAFE_ACCESS:
+		return node.evaluateSafeAccess(interp)
+	case IN_OP:
+		return node.evaluateIn(interp)
+	case BETWEEN_OP:
+		return node.evaluateBetween(interp)
+	case UNPACK:
+		return node.evaluateUnpack(interp)
+	case TYPE_DEF:
+		return node.evaluateTypeDef(interp)
+	case ENUM_DEF:
+		return node.evaluateEnumDef(interp)
+	case MATCH_EXPR:
+		return node.evaluateMatch(interp)
+	case FIELD_ACCESS:
+		return node.evaluateField(interp)
+	case ANNOTATION:
+		return node.evaluateAnnotation(interp)
+	case POWER:
+		return node.evaluatePower(interp)
 	case UNARY_OP: // case of an unary operation, need to evaluate child then apply unary operation
-		factorRes, err := node.left.evaluate()
+		factorRes, err := node.left.evaluate(interp)
 		if err != nil {
 			return nil, err
 		}
+		if !factorRes.IsNumber() {
+			return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't apply unary %s to a value of type %s", tokenSymbol(node.tok.tokenType), factorRes.ResultType), Pos: node.tok.pos}
+		}
 		if node.tok.tokenType == SUB { // negative sign
 			if factorRes.ResultType == INTEGER { // GACK! Any way to make this work for both ints and floats?
 				return &Result_t{ResultType: INTEGER, Ires: -1 * factorRes.Ires, Fres: -1 * float64(factorRes.Ires)}, nil // set the float value too in case we have to upcast to float
@@ -431,25 +1817,61 @@ func (node *node_t) evaluate() (*Result_t, error) {
 				return &Result_t{ResultType: FLOATING, Fres: math.Abs(factorRes.Fres)}, nil
 			}
 		}
+	case STATEMENTS: // evaluate each statement in order, the program's result is the last one
+		var ret *Result_t
+		for _, stmt := range node.statements {
+			if err := interp.checkTimer(); err != nil {
+				return nil, err
+			}
+			res, err := stmt.evaluate(interp)
+			if err != nil {
+				return nil, err
+			}
+			ret = res
+		}
+		return ret, nil
 	case TERM, EXPRESSION: // both terms and expressions are binary operations. We need to evaluate both children, then apply the operation
-		leftRes, err := node.left.evaluate()
+		leftRes, err := node.left.evaluate(interp)
 		if err != nil {
 			return nil, err
 		}
-		rightRes, err := node.right.evaluate()
+		rightRes, err := node.right.evaluate(interp)
 		if err != nil {
 			return nil, err
 		}
+		if res, ok, err := overloaded(leftRes, rightRes, node.tok); ok {
+			return res, err
+		} else if leftRes.ResultType == MATRIX_RESULT || rightRes.ResultType == MATRIX_RESULT {
+			res, err := matrixOp(leftRes, rightRes, node.tok)
+			return interp.charge(res, err, node.tok)
+		} else if isVectorOp(leftRes, rightRes, node.tok) {
+			res, err := vectorOp(leftRes, rightRes, node.tok)
+			return interp.charge(res, err, node.tok)
+		} else if !leftRes.IsNumber() || !rightRes.IsNumber() {
+			return interp.joinValues(leftRes, rightRes, node.tok)
+		}
+		if node.tok.tokenType == DIV && rightRes.Fres == 0 { // the float value is set for integers too
+			return nil, &RuntimeError_t{Code: ERR_DIVISION_BY_ZERO, Details: "division by zero", Pos: node.tok.pos}
+		}
 		ret := &Result_t{ResultType: INTEGER} // default to integer
 		if leftRes.ResultType == FLOATING || rightRes.ResultType == FLOATING {
+			if err := interp.checkPromotion(node, leftRes, rightRes); err != nil {
+				return nil, err
+			}
 			ret.Fres = floatop(leftRes.Fres, rightRes.Fres, node.tok.tokenType)
 			ret.ResultType = FLOATING
 			return ret, nil
 		}
+		if node.tok.tokenType == DIV && rightRes.Ires != 0 && leftRes.Ires%rightRes.Ires != 0 {
+			err = interp.warn(INTEGER_DIVISION, node.tok.pos, "integer division %d / %d truncates to %d", leftRes.Ires, rightRes.Ires, leftRes.Ires/rightRes.Ires)
+			if err != nil {
+				return nil, err
+			}
+		}
 		// GACK! Any way to make this work for both ints and floats?
 		ret.Ires = intop(leftRes.Ires, rightRes.Ires, node.tok.tokenType)
 		ret.Fres = float64(ret.Ires)
 		return ret, nil
 	}
-	return nil, fmt.Errorf("evaluation error at %s", node.tok.pos.String())
+	return nil, &RuntimeError_t{Code: ERR_EVALUATION, Details: "evaluation error", Pos: node.tok.pos}
 }
@@ -0,0 +1,95 @@
+package basic
+
+import "testing"
+
+func TestNodeTypeString(t *testing.T) {
+	if got := NODE_ERR.String(); got != "NODE_ERR" {
+		t.Errorf("NODE_ERR: got %q", got)
+	}
+	if got := NodeType_t(-1).String(); got != "NodeType_t(-1)" {
+		t.Errorf("an unknown node type: got %q", got)
+	}
+}
+
+func TestResultTypeString(t *testing.T) {
+	if got := RECORD_RESULT.String(); got != "record" {
+		t.Errorf("RECORD_RESULT: got %q", got)
+	}
+	if got := resultType_t(42).String(); got != "resultType_t(42)" {
+		t.Errorf("an unknown result type: got %q", got)
+	}
+}
+
+// runs src and gives back what its value prints as, failing the test if it doesn't run.
+func runValue(t *testing.T, src string) string {
+	t.Helper()
+	res, err := NewInterpreter(Options_t{}).Run(src, t.Name())
+	if err != nil {
+		t.Fatalf("%q: %s", src, err)
+	}
+	return res.ValueString()
+}
+
+func TestSlicing(t *testing.T) {
+	for src, want := range map[string]string{
+		`[1, 2, 3, 4, 5][1:4]`: `[2, 3, 4]`,
+		`[1, 2, 3, 4, 5][:3]`:  `[1, 2, 3]`,
+		`[1, 2, 3, 4, 5][2:]`:  `[3, 4, 5]`,
+		`[1, 2, 3, 4, 5][-2:]`: `[4, 5]`,
+		`[1, 2, 3][1:1]`:       `[]`,
+		`"héllo"[1:3]`:         `él`,
+		`"hello"[:-1]`:         `hell`,
+	} {
+		if got := runValue(t, src); got != want {
+			t.Errorf("%q: got %s, want %s", src, got, want)
+		}
+	}
+	for src, want := range map[string]ErrorCode_t{
+		`[1, 2, 3][1:5]`:   ERR_INDEX,
+		`[1, 2, 3][2:1]`:   ERR_INDEX,
+		`[1, 2, 3][-4:]`:   ERR_INDEX,
+		`"abc"[0:"x"]`:     ERR_TYPE,
+		`42[0:1]`:          ERR_TYPE,
+		`[1, 2, 3][0.5:2]`: ERR_TYPE,
+	} {
+		if got := runErrorCode(t, Options_t{}, src); got != want {
+			t.Errorf("%q: got error code %q, want %q", src, got, want)
+		}
+	}
+}
+
+func TestLen(t *testing.T) {
+	for src, want := range map[string]string{
+		`LEN("héllo")`:           `5`,
+		`LEN("")`:                `0`,
+		`LEN([1, [2, 3], "x"])`:  `3`,
+		`LEN({"a": 1, "b": 2})`:  `2`,
+		`LEN({"a": 1, "a": 2})`:  `1`,
+		`LEN(RANGE(0, 10)[2:5])`: `3`,
+	} {
+		if got := runValue(t, src); got != want {
+			t.Errorf("%q: got %s, want %s", src, got, want)
+		}
+	}
+	for _, src := range []string{`LEN(42)`, `LEN(1.5)`, `LEN(LAMBDA(x, x))`} {
+		if got := runErrorCode(t, Options_t{}, src); got != ERR_TYPE {
+			t.Errorf("%q: got error code %q, want %q", src, got, ERR_TYPE)
+		}
+	}
+}
+
+func TestForEach(t *testing.T) {
+	for src, want := range map[string]string{
+		"total, seen = [0, []]\nFOR EACH x IN [1, 2, 3]\ntotal, seen = [total + x, seen + [x]]\nNEXT x\n[total, seen]": `[6, [1, 2, 3]]`,
+		"seen, n = [[], 0]\nFOR EACH k IN {\"b\": 1, \"a\": 2}\nseen, n = [seen + [k], 0]\nNEXT k\nseen":               `["b", "a"]`,
+		"seen, n = [[], 0]\nFOR EACH c IN \"héy\"\nseen, n = [seen + [c], 0]\nNEXT c\nseen":                            `["h", "é", "y"]`,
+		"seen, n = [[], 0]\nFOR EACH x IN []\nseen, n = [seen + [x], 0]\nNEXT x\nseen":                                 `[]`,
+	} {
+		if got := runValue(t, src); got != want {
+			t.Errorf("%q: got %s, want %s", src, got, want)
+		}
+	}
+	if got := runErrorCode(t, Options_t{}, "FOR EACH x IN 42\nNEXT x"); got != ERR_TYPE {
+		t.Errorf("iterating an int: got error code %q, want %q", got, ERR_TYPE)
+	}
+}
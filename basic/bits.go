@@ -0,0 +1,75 @@
+package basic
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// checks that every argument of a bit builtin is an int.
+func intArgs(args []*Result_t) error {
+	for i, arg := range args {
+		if arg.ResultType != INTEGER {
+			return typeErrorf("argument %d is a %s, not an int", i+1, arg.ResultType)
+		}
+	}
+	return nil
+}
+
+// wraps a function of an int and a bit position (0 is the lowest bit) as a builtin.
+func bitBuiltin(fn func(n uint64, bit uint) uint64) BuiltinFunc_t {
+	return func(args []*Result_t) (*Result_t, error) {
+		if err := intArgs(args); err != nil {
+			return nil, err
+		} else if args[1].Ires < 0 || args[1].Ires > 63 {
+			return nil, fmt.Errorf("bit %d is out of range, it has to be from 0 to 63", args[1].Ires)
+		}
+		return NewInt(int64(fn(uint64(args[0].Ires), uint(args[1].Ires)))), nil
+	}
+}
+
+// wraps a rotation as a builtin taking ROTL(n, count[, width]). The rotation is within the
+// lowest width bits (64 if it's left out), so ROTL(129, 1, 8) is 3, like in an 8-bit register.
+func rotateBuiltin(left bool) BuiltinFunc_t {
+	return func(args []*Result_t) (*Result_t, error) {
+		if len(args) != 2 && len(args) != 3 {
+			return nil, fmt.Errorf("takes 2 or 3 argument(s), got %d", len(args))
+		} else if err := intArgs(args); err != nil {
+			return nil, err
+		}
+		width := int64(64)
+		if len(args) == 3 {
+			width = args[2].Ires
+		}
+		if width < 1 || width > 64 {
+			return nil, fmt.Errorf("width %d is out of range, it has to be from 1 to 64", width)
+		}
+		n, count := uint64(args[0].Ires), args[1].Ires%width
+		if !left {
+			count = (width - count) % width
+		}
+		if count < 0 {
+			count += width
+		}
+		if width == 64 {
+			return NewInt(int64(bits.RotateLeft64(n, int(count)))), nil
+		}
+		mask := uint64(1)<<uint(width) - 1
+		n &= mask
+		return NewInt(int64((n<<uint(count) | n>>uint(width-count)) & mask)), nil
+	}
+}
+
+// the bit manipulation functions, for composing register values and flags.
+func init() {
+	RegisterBuiltin("SETBIT", 2, bitBuiltin(func(n uint64, bit uint) uint64 { return n | 1<<bit }))
+	RegisterBuiltin("CLEARBIT", 2, bitBuiltin(func(n uint64, bit uint) uint64 { return n &^ (1 << bit) }))
+	RegisterBuiltin("TESTBIT", 2, bitBuiltin(func(n uint64, bit uint) uint64 { return n >> bit & 1 }))
+	RegisterBuiltin("POPCOUNT", 1, func(args []*Result_t) (*Result_t, error) { // the number of bits set, counting the sign bit of negative numbers
+		if err := intArgs(args); err != nil {
+			return nil, err
+		}
+		return NewInt(int64(bits.OnesCount64(uint64(args[0].Ires)))), nil
+	})
+	RegisterBuiltin("ROTL", -1, rotateBuiltin(true))
+	RegisterBuiltin("ROTR", -1, rotateBuiltin(false))
+}
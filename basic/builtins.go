@@ -0,0 +1,181 @@
+package basic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// signature of a builtin function. Arguments are already evaluated; an error is reported as a
+// runtime error at the call.
+type BuiltinFunc_t func(args []*Result_t) (*Result_t, error)
+
+// a registered builtin: the function and how many arguments it takes (negative for any number).
+type builtin_t struct {
+	arity   int
+	fn      BuiltinFunc_t
+	params  []Param_t     // nil unless it was registered with RegisterBuiltinParams
+	timeout time.Duration // longest a call may take, 0 for no limit
+}
+
+var (
+	builtinsMu sync.RWMutex
+	builtins   = make(map[string]builtin_t) // keyed by upper case name; builtin names are case-insensitive
+)
+
+// adds a builtin function that every interpreter can call, replacing any builtin with the same name.
+// arity is the number of arguments it takes, or negative if it takes any number.
+// This is the extension point for builtin libraries: a library (or a Go plugin loaded with
+// LoadPlugin) calls RegisterBuiltin from its init function.
+func RegisterBuiltin(name string, arity int, fn BuiltinFunc_t) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+	builtins[strings.ToUpper(name)] = builtin_t{arity: arity, fn: fn}
+}
+
+// gets the names of every registered builtin, sorted.
+func Builtins() []string {
+	builtinsMu.RLock()
+	defer builtinsMu.RUnlock()
+	ret := make([]string, 0, len(builtins))
+	for name := range builtins {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// error a builtin returns when it's given a value of the wrong type, reported as ERR_TYPE
+// rather than ERR_BUILTIN.
+type typeError_t struct {
+	details string
+}
+
+func (err *typeError_t) Error() string {
+	return err.details
+}
+
+func typeErrorf(format string, args ...interface{}) error {
+	return &typeError_t{details: fmt.Sprintf(format, args...)}
+}
+
+// turns an error from a builtin called by name into a RuntimeError at the call.
+func builtinError(name Token_t, err error) error {
+	var runtimeErr *RuntimeError_t
+	var typeErr *typeError_t
+	var dimensionErr *dimensionError_t
+	var timeoutErr *timeoutError_t
+	var limitErr *limitError_t
+	var stoppedErr *stoppedError_t
+	if errors.As(err, &runtimeErr) {
+		return err
+	} else if errors.As(err, &stoppedErr) {
+		return &RuntimeError_t{Code: ERR_STEP_LIMIT, Details: stoppedErr.details, Pos: name.pos}
+	} else if errors.As(err, &limitErr) {
+		return &RuntimeError_t{Code: limitErr.code, Details: fmt.Sprintf("%s: %s", name.strVal, err), Pos: name.pos}
+	} else if errors.As(err, &timeoutErr) {
+		return &RuntimeError_t{Code: ERR_BUILTIN_TIMEOUT, Details: fmt.Sprintf("%s: %s", name.strVal, err), Pos: name.pos}
+	} else if errors.As(err, &typeErr) {
+		return &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("%s: %s", name.strVal, err), Pos: name.pos}
+	} else if errors.As(err, &dimensionErr) {
+		return &RuntimeError_t{Code: ERR_DIMENSION, Details: fmt.Sprintf("%s: %s", name.strVal, err), Pos: name.pos}
+	}
+	return &RuntimeError_t{Code: ERR_BUILTIN, Details: fmt.Sprintf("%s: %s", name.strVal, err), Pos: name.pos}
+}
+
+// calls the builtin named by the token, turning whatever goes wrong into a RuntimeError at the call.
+func (interp *Interpreter_t) callBuiltin(name Token_t, args []*Result_t) (*Result_t, error) {
+	builtinsMu.RLock()
+	builtin, ok := builtins[strings.ToUpper(name.strVal)]
+	builtinsMu.RUnlock()
+	if !ok {
+		return nil, &RuntimeError_t{Code: ERR_UNDEFINED_FUNC, Details: fmt.Sprintf("function %s is not defined", name.strVal), Pos: name.pos}
+	}
+	if builtin.arity >= 0 && len(args) != builtin.arity {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes %d argument(s), got %d", name.strVal, builtin.arity, len(args)), Pos: name.pos}
+	}
+
+	res, err := interp.sized(interp.timed(builtin.timeout, interp.collated(strings.ToUpper(name.strVal), interp.angled(strings.ToUpper(name.strVal), builtin.fn))))(args)
+	if err != nil {
+		return nil, builtinError(name, err)
+	}
+	return res, nil
+}
+
+// wraps a builtin that only works on numbers, so it fails cleanly when given a string or list.
+func numberBuiltin(fn BuiltinFunc_t) BuiltinFunc_t {
+	return func(args []*Result_t) (*Result_t, error) {
+		for i, arg := range args {
+			if !arg.IsNumber() {
+				return nil, typeErrorf("argument %d is a %s, not a number", i+1, arg.ResultType)
+			}
+		}
+		return fn(args)
+	}
+}
+
+// wraps a float function of one argument as a builtin.
+func floatBuiltin(fn func(float64) float64) BuiltinFunc_t {
+	return numberBuiltin(func(args []*Result_t) (*Result_t, error) {
+		return NewFloat(fn(args[0].Fres)), nil // the float value is set for integers too
+	})
+}
+
+// the classic BASIC functions.
+func init() {
+	RegisterBuiltin("ABS", 1, numberBuiltin(func(args []*Result_t) (*Result_t, error) {
+		if args[0].ResultType == INTEGER {
+			return NewInt(abs(args[0].Ires)), nil
+		}
+		return NewFloat(math.Abs(args[0].Fres)), nil
+	}))
+	RegisterBuiltin("SGN", 1, numberBuiltin(func(args []*Result_t) (*Result_t, error) {
+		if args[0].Fres > 0 {
+			return NewInt(1), nil
+		} else if args[0].Fres < 0 {
+			return NewInt(-1), nil
+		}
+		return NewInt(0), nil
+	}))
+	RegisterBuiltin("INT", 1, numberBuiltin(func(args []*Result_t) (*Result_t, error) { // rounds down, like in every BASIC
+		if args[0].ResultType == INTEGER {
+			return NewInt(args[0].Ires), nil // a plain int, even from a member of an ENUM
+		}
+		f := math.Floor(args[0].Fres)
+		if f < math.MinInt64 || f >= math.MaxInt64 || math.IsNaN(f) {
+			return nil, fmt.Errorf("%g doesn't fit in an integer", args[0].Fres)
+		}
+		return NewInt(int64(f)), nil
+	}))
+	RegisterBuiltin("FLOAT", 1, numberBuiltin(func(args []*Result_t) (*Result_t, error) { // the explicit conversion strict mode wants before mixing with floats
+		return NewFloat(args[0].Fres), nil // the float value is set for integers too
+	}))
+	RegisterBuiltin("TYPE", 1, func(args []*Result_t) (*Result_t, error) {
+		return NewString(typeName(args[0])), nil
+	})
+	RegisterBuiltin("SQR", 1, numberBuiltin(func(args []*Result_t) (*Result_t, error) {
+		if args[0].Fres < 0 {
+			return nil, fmt.Errorf("square root of negative number %g", args[0].Fres)
+		}
+		return NewFloat(math.Sqrt(args[0].Fres)), nil
+	}))
+	RegisterBuiltin("LOG", 1, numberBuiltin(func(args []*Result_t) (*Result_t, error) {
+		if args[0].Fres <= 0 {
+			return nil, fmt.Errorf("logarithm of non-positive number %g", args[0].Fres)
+		}
+		return NewFloat(math.Log(args[0].Fres)), nil
+	}))
+	RegisterBuiltin("EXP", 1, floatBuiltin(math.Exp))
+	RegisterBuiltin("SIN", 1, floatBuiltin(math.Sin))
+	RegisterBuiltin("COS", 1, floatBuiltin(math.Cos))
+	RegisterBuiltin("TAN", 1, floatBuiltin(math.Tan))
+	RegisterBuiltin("ATN", 1, floatBuiltin(math.Atan))
+	RegisterBuiltin("RND", 0, func(args []*Result_t) (*Result_t, error) {
+		return NewFloat(rand.Float64()), nil
+	})
+}
@@ -0,0 +1,190 @@
+package basic
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// version of the bundle format, written in every manifest. ReadBundle refuses newer ones.
+const BUNDLE_FORMAT = 1
+
+// name of the manifest inside a bundle's archive.
+const BUNDLE_MANIFEST = "manifest.json"
+
+// a program with every module it IMPORTs, to run anywhere without the files it came from.
+// Paths are relative to the directory of the program, so IMPORTs find their modules in the
+// bundle just like they did on disk, except for IMPORTs of an absolute path, which keep it.
+type Bundle_t struct {
+	Main        string            // path of the program, like "main.bas"
+	ImportPaths []string          // where IMPORT looks after the importing file's directory, relative to the program's
+	Files       map[string]string // source of the program and its modules, by path
+}
+
+// what a bundle's manifest says: its format, what to run and a SHA-256 of each file, so a
+// bundle that's been tampered with or damaged isn't run.
+type bundleManifest_t struct {
+	Format      int               `json:"format"`
+	Main        string            `json:"main"`
+	ImportPaths []string          `json:"import_paths"`
+	Files       map[string]string `json:"files"` // path -> hex SHA-256 of the file
+}
+
+// bundles the program at path with every module it IMPORTs, and the modules those IMPORT,
+// found the way running it with this interpreter's options would find them.
+func (interp *Interpreter_t) Bundle(path string) (*Bundle_t, error) {
+	dir := interp.resolvePath(filepath.Dir(path))
+	ret := &Bundle_t{Main: filepath.Base(path), Files: make(map[string]string)}
+	for _, importPath := range interp.opts.ImportPaths {
+		rel, err := filepath.Rel(dir, interp.resolvePath(importPath))
+		if err != nil {
+			return nil, err
+		}
+		ret.ImportPaths = append(ret.ImportPaths, filepath.ToSlash(rel))
+	}
+
+	path = interp.resolvePath(path)
+	pending := []string{path}
+	keys := map[string]string{path: ret.Main} // where each file found so far goes in the bundle, by the path IMPORT finds it at
+	for len(pending) > 0 {
+		file := pending[0]
+		pending = pending[1:]
+		src, err := interp.FileSystem().ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		node, err := parse(string(src), file, interp.opts)
+		if err != nil {
+			return nil, Localize(err, interp.locale)
+		}
+		ret.Files[keys[file]] = string(src)
+
+		var walkErr error
+		Walk(node, func(n *Node_t) bool {
+			if n.nodeType != IMPORT || walkErr != nil {
+				return walkErr == nil
+			}
+			module, err := interp.findModule(n)
+			if err != nil {
+				walkErr = Localize(err, interp.locale)
+				return false
+			} else if _, ok := keys[module]; ok {
+				return true
+			}
+			key := filepath.ToSlash(n.tok.strVal) // found by its own path when it's run, not relative to the program
+			if !filepath.IsAbs(n.tok.strVal) {
+				rel, err := filepath.Rel(dir, module)
+				if err != nil {
+					walkErr = err
+					return false
+				}
+				key = filepath.ToSlash(rel)
+			}
+			keys[module] = key
+			pending = append(pending, module)
+			return true
+		})
+		if walkErr != nil {
+			return nil, walkErr
+		}
+	}
+	return ret, nil
+}
+
+// writes the bundle as a zip archive of its files and a manifest. The same bundle always
+// makes the same bytes, so a bundle can be checked into a build or compared by its hash.
+func (bundle *Bundle_t) Write(w io.Writer) error {
+	manifest := bundleManifest_t{Format: BUNDLE_FORMAT, Main: bundle.Main, ImportPaths: bundle.ImportPaths, Files: make(map[string]string)}
+	paths := make([]string, 0, len(bundle.Files))
+	for path, src := range bundle.Files {
+		sum := sha256.Sum256([]byte(src))
+		manifest.Files[path] = hex.EncodeToString(sum[:])
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	write := func(name string, contents []byte) error {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)} // the earliest time zip can hold, so the bytes don't depend on when it's written
+		file, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(contents)
+		return err
+	}
+	if err := write(BUNDLE_MANIFEST, data); err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := write(path, []byte(bundle.Files[path])); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// reads a bundle Write wrote, checking every file against the manifest.
+func ReadBundle(data []byte) (*Bundle_t, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle: %w", err)
+	}
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		contents, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[file.Name] = string(contents)
+	}
+
+	manifestData, ok := files[BUNDLE_MANIFEST]
+	if !ok {
+		return nil, fmt.Errorf("not a bundle: there's no %s", BUNDLE_MANIFEST)
+	}
+	var manifest bundleManifest_t
+	if err := json.Unmarshal([]byte(manifestData), &manifest); err != nil {
+		return nil, fmt.Errorf("bad bundle manifest: %w", err)
+	} else if manifest.Format > BUNDLE_FORMAT {
+		return nil, fmt.Errorf("bundle is format %d, newer than this go-basic can run (%d)", manifest.Format, BUNDLE_FORMAT)
+	}
+	ret := &Bundle_t{Main: manifest.Main, ImportPaths: manifest.ImportPaths, Files: make(map[string]string)}
+	for path, sum := range manifest.Files {
+		src, ok := files[path]
+		if !ok {
+			return nil, fmt.Errorf("bundle is missing %s", path)
+		} else if actual := sha256.Sum256([]byte(src)); hex.EncodeToString(actual[:]) != sum {
+			return nil, fmt.Errorf("bundle's %s doesn't match its manifest", path)
+		}
+		ret.Files[path] = src
+	}
+	if _, ok := ret.Files[ret.Main]; !ok {
+		return nil, fmt.Errorf("bundle is missing %s", ret.Main)
+	}
+	return ret, nil
+}
+
+// gets options that run the bundle's program from its own files: opts with its FileSystem and
+// ImportPaths replaced by the bundle's.
+func (bundle *Bundle_t) Options(opts Options_t) Options_t {
+	opts.FileSystem = MemoryFileSystem_t(bundle.Files)
+	opts.ImportPaths = bundle.ImportPaths
+	return opts
+}
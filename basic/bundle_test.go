@@ -0,0 +1,92 @@
+package basic
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// a program in app/ that IMPORTs a module next to it, which IMPORTs one from an import path.
+var bundledFiles = MemoryFileSystem_t{
+	"app/main.bas":   "IMPORT \"shapes.bas\"\nshapes[\"area\"](3)",
+	"app/shapes.bas": "IMPORT \"maths.bas\"\n{\"area\": LAMBDA(r, maths[\"pi\"] * r * r)}",
+	"lib/maths.bas":  `{"pi": 3}`,
+	"app/unused.bas": `1 / 0`,
+}
+
+func TestBundle(t *testing.T) {
+	interp := NewInterpreter(Options_t{FileSystem: bundledFiles, ImportPaths: []string{"lib"}})
+	bundle, err := interp.Bundle("app/main.bas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Main != "main.bas" || len(bundle.Files) != 3 || bundle.Files["../lib/maths.bas"] == "" {
+		t.Errorf("got a bundle of %q with files %v and import paths %v", bundle.Main, bundle.Files, bundle.ImportPaths)
+	}
+
+	var first, second bytes.Buffer
+	if err := bundle.Write(&first); err != nil {
+		t.Fatal(err)
+	} else if err := bundle.Write(&second); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("writing the same bundle twice made different bytes")
+	}
+
+	read, err := ReadBundle(first.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := NewInterpreter(read.Options(Options_t{})).Run(read.Files[read.Main], read.Main)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Ires != 27 {
+		t.Errorf("running the bundle: got %s, want 27", res.ValueString())
+	}
+}
+
+func TestBundleMissingModule(t *testing.T) {
+	files := MemoryFileSystem_t{"main.bas": `IMPORT "nowhere.bas"`}
+	if _, err := NewInterpreter(Options_t{FileSystem: files}).Bundle("main.bas"); err == nil {
+		t.Errorf("bundling a program whose module is missing: got no error")
+	}
+}
+
+// writes a zip archive of the given files, for bundles Write wouldn't make.
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, contents := range files {
+		file, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write([]byte(contents))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadBundleErrors(t *testing.T) {
+	const sumOf1 = "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b" // SHA-256 of "1"
+	for name, test := range map[string]struct {
+		data []byte
+		want string
+	}{
+		"not a zip":     {[]byte("1 + 1"), "not a bundle"},
+		"no manifest":   {zipFiles(t, map[string]string{"main.bas": "1"}), "no manifest.json"},
+		"bad manifest":  {zipFiles(t, map[string]string{BUNDLE_MANIFEST: "{"}), "bad bundle manifest"},
+		"newer format":  {zipFiles(t, map[string]string{BUNDLE_MANIFEST: `{"format": 99, "main": "main.bas"}`}), "format 99"},
+		"missing file":  {zipFiles(t, map[string]string{BUNDLE_MANIFEST: `{"format": 1, "main": "main.bas", "files": {"main.bas": "` + sumOf1 + `"}}`}), "missing main.bas"},
+		"tampered file": {zipFiles(t, map[string]string{BUNDLE_MANIFEST: `{"format": 1, "main": "main.bas", "files": {"main.bas": "` + sumOf1 + `"}}`, "main.bas": "2"}), "doesn't match"},
+		"missing main":  {zipFiles(t, map[string]string{BUNDLE_MANIFEST: `{"format": 1, "main": "main.bas", "files": {}}`, "main.bas": "1"}), "missing main.bas"},
+	} {
+		if _, err := ReadBundle(test.data); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got %v, want an error saying %q", name, err, test.want)
+		}
+	}
+}
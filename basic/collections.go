@@ -0,0 +1,269 @@
+package basic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// writes a string the way it would be written as a literal, with quotes doubled: "say ""hi""".
+func quoteString(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// applies + to two values that aren't both numbers. Strings join with strings and lists
+// with lists; anything else is a type error. What they'd join into is checked against the
+// interpreter's limits before it's made.
+func (interp *Interpreter_t) joinValues(left *Result_t, right *Result_t, op Token_t) (*Result_t, error) {
+	if op.tokenType == ADD && left.ResultType == right.ResultType {
+		var limitErr *limitError_t
+		if left.ResultType == STRING_RESULT {
+			if err := interp.allocate(STRING_RESULT, len(left.Sres)+len(right.Sres)); errors.As(err, &limitErr) {
+				return nil, limitErr.at(op)
+			}
+			res := NewString(left.Sres + right.Sres)
+			res.markCharged()
+			return res, nil
+		} else if left.ResultType == LIST_RESULT {
+			if err := interp.allocate(LIST_RESULT, len(left.Lres)+len(right.Lres)); errors.As(err, &limitErr) {
+				return nil, limitErr.at(op)
+			}
+			elems := make([]*Result_t, 0, len(left.Lres)+len(right.Lres))
+			res := NewList(append(append(elems, left.Lres...), right.Lres...))
+			res.markCharged() // its elements were counted with the lists they came from
+			return res, nil
+		}
+	}
+	return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't apply %s to values of type %s and %s", tokenSymbol(op.tokenType), left.ResultType, right.ResultType), Pos: op.pos}
+}
+
+// evaluates an INDEX node: the element of a list or the character of a string at a position
+// counted from 0, or the value of a dict for a key. Negative positions count from the end, so
+// a[-1] is the last element. A character comes back as a one character string; like slices,
+// strings are indexed by character, not byte.
+func (node *Node_t) evaluateIndex(interp *Interpreter_t) (*Result_t, error) {
+	target, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	index, err := node.right.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	if target.ResultType == DICT_RESULT {
+		if index.ResultType != STRING_RESULT {
+			return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("dict key must be a string, not %s", index.ResultType), Pos: node.right.tok.pos}
+		}
+		value, ok := target.Dres.Get(index.Sres)
+		if !ok {
+			return nil, &RuntimeError_t{Code: ERR_INDEX, Details: fmt.Sprintf("key %s is not in the dict", quoteString(index.Sres)), Pos: node.tok.pos}
+		}
+		return value, nil
+	} else if target.ResultType != LIST_RESULT && target.ResultType != STRING_RESULT {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't index a value of type %s", target.ResultType), Pos: node.tok.pos}
+	} else if index.ResultType != INTEGER {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("%s index must be an int, not %s", target.ResultType, index.ResultType), Pos: node.right.tok.pos}
+	}
+
+	var runes []rune
+	length := len(target.Lres)
+	if target.ResultType == STRING_RESULT {
+		runes = []rune(target.Sres)
+		length = len(runes)
+	}
+	i := index.Ires
+	if i < 0 {
+		i += int64(length)
+	}
+	if i < 0 || i >= int64(length) {
+		return nil, &RuntimeError_t{Code: ERR_INDEX, Details: fmt.Sprintf("index %d is out of range for a %s of length %d", index.Ires, target.ResultType, length), Pos: node.tok.pos}
+	}
+	if target.ResultType == STRING_RESULT {
+		return NewString(string(runes[i])), nil
+	}
+	return target.Lres[i], nil
+}
+
+// evaluates a SLICE node: the part of a list or string from start up to (not including) end.
+// Positions count from 0, negative ones count from the end, a missing start means the
+// beginning and a missing end means the end, so a[:3] is the first three and a[-2:] the
+// last two. Strings are sliced by character, not byte.
+func (node *Node_t) evaluateSlice(interp *Interpreter_t) (*Result_t, error) {
+	target, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	var length int
+	switch target.ResultType {
+	case LIST_RESULT:
+		length = len(target.Lres)
+	case STRING_RESULT:
+		length = len([]rune(target.Sres))
+	default:
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't slice a value of type %s", target.ResultType), Pos: node.tok.pos}
+	}
+
+	bounds := [2]int64{0, int64(length)}
+	for i, bound := range node.args {
+		if bound == nil {
+			continue
+		}
+		res, err := bound.evaluate(interp)
+		if err != nil {
+			return nil, err
+		} else if res.ResultType != INTEGER {
+			return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("slice bounds must be ints, not %s", res.ResultType), Pos: bound.tok.pos}
+		}
+		bounds[i] = res.Ires
+		if bounds[i] < 0 {
+			bounds[i] += int64(length)
+		}
+	}
+	start, end := bounds[0], bounds[1]
+	if start < 0 || end > int64(length) || start > end {
+		return nil, &RuntimeError_t{Code: ERR_INDEX, Details: fmt.Sprintf("slice [%d:%d] is out of range for a %s of length %d", start, end, target.ResultType, length), Pos: node.tok.pos}
+	}
+
+	if target.ResultType == STRING_RESULT {
+		return interp.charge(NewString(string([]rune(target.Sres)[start:end])), nil, node.tok)
+	}
+	return interp.charge(NewList(target.Lres[start:end:end]), nil, node.tok) // capped, so appending to the slice can't write into the original
+}
+
+// a dict's entries. Keys are strings and keep the order they were first added in,
+// so dicts print and iterate the same way every time.
+type Dict_t struct {
+	keys   []string
+	values map[string]*Result_t
+}
+
+// makes a dict Result out of matching keys and values. A key given twice keeps
+// its first position and its last value.
+func NewDict(keys []string, values []*Result_t) *Result_t {
+	dict := &Dict_t{keys: make([]string, 0, len(keys)), values: make(map[string]*Result_t, len(keys))}
+	for i, key := range keys {
+		if _, ok := dict.values[key]; !ok {
+			dict.keys = append(dict.keys, key)
+		}
+		dict.values[key] = values[i]
+	}
+	return &Result_t{ResultType: DICT_RESULT, Dres: dict}
+}
+
+// gets the keys, in order.
+func (dict *Dict_t) Keys() []string {
+	return append([]string(nil), dict.keys...)
+}
+
+// gets the value for a key, and whether it's there at all.
+func (dict *Dict_t) Get(key string) (*Result_t, bool) {
+	value, ok := dict.values[key]
+	return value, ok
+}
+
+// gets the number of entries.
+func (dict *Dict_t) Len() int {
+	return len(dict.keys)
+}
+
+// evaluates a DICT node: keys and values in the order they're written.
+func (node *Node_t) evaluateDict(interp *Interpreter_t) (*Result_t, error) {
+	keys := make([]string, 0, len(node.args)/2)
+	values := make([]*Result_t, 0, len(node.args)/2)
+	for i := 0; i < len(node.args); i += 2 {
+		key, err := node.args[i].evaluate(interp)
+		if err != nil {
+			return nil, err
+		} else if key.ResultType != STRING_RESULT {
+			return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("dict key must be a string, not %s", key.ResultType), Pos: node.args[i].tok.pos}
+		}
+		value, err := node.args[i+1].evaluate(interp)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key.Sres)
+		values = append(values, value)
+	}
+	return interp.charge(NewDict(keys, values), nil, node.tok)
+}
+
+// gets the size of a collection, the canonical way to ask: characters for a string,
+// elements for a list and keys for a dict.
+func length(res *Result_t) (int, error) {
+	switch res.ResultType {
+	case STRING_RESULT:
+		return len([]rune(res.Sres)), nil
+	case LIST_RESULT:
+		return len(res.Lres), nil
+	case DICT_RESULT:
+		return res.Dres.Len(), nil
+	default:
+		return 0, typeErrorf("a value of type %s has no length", res.ResultType)
+	}
+}
+
+func init() {
+	RegisterBuiltin("LEN", 1, func(args []*Result_t) (*Result_t, error) {
+		n, err := length(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return NewInt(int64(n)), nil
+	})
+}
+
+// the longest list RANGE makes when the interpreter's options don't say.
+const DEFAULT_MAX_LIST_LENGTH = 10000000
+
+// evaluates RANGE(start, stop[, step]): the numbers from start up to (not including) stop,
+// step apart. step defaults to 1 and can be negative to count down. The list is made of
+// ints if every argument is an int, floats otherwise.
+func (interp *Interpreter_t) rangeCall(node *Node_t) (*Result_t, error) {
+	args, err := interp.evaluateArgs(node)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 2 && len(args) != 3 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 2 or 3 argument(s), got %d", node.tok.strVal, len(args)), Pos: node.tok.pos}
+	}
+	step := NewInt(1)
+	if len(args) == 3 {
+		step = args[2]
+	}
+	allInts := step.ResultType == INTEGER
+	for i, arg := range append(args[:2], step) {
+		if !arg.IsNumber() {
+			return nil, builtinError(node.tok, typeErrorf("argument %d is a %s, not a number", i+1, arg.ResultType))
+		}
+		allInts = allInts && arg.ResultType == INTEGER
+	}
+	start, stop := args[0], args[1]
+	if step.Fres == 0 {
+		return nil, builtinError(node.tok, fmt.Errorf("step can't be 0"))
+	}
+
+	count := math.Ceil((stop.Fres - start.Fres) / step.Fres)
+	if count < 0 || math.IsNaN(count) {
+		count = 0
+	}
+	limit := interp.opts.MaxListLength
+	if limit <= 0 {
+		limit = DEFAULT_MAX_LIST_LENGTH
+	}
+	if count > float64(limit) {
+		return nil, &RuntimeError_t{Code: ERR_LIST_LIMIT, Details: fmt.Sprintf("%s would make %.0f elements, more than the limit of %d", node.tok.strVal, count, limit), Pos: node.tok.pos}
+	} else if interp.opts.MaxAllocated > 0 && count*float64(resultSize+pointerSize) > float64(interp.opts.MaxAllocated-interp.allocated) {
+		return nil, interp.memoryLimitError().at(node.tok) // before making it, not once charge counts it
+	}
+
+	elems := make([]*Result_t, int(count))
+	for i := range elems {
+		if allInts {
+			elems[i] = NewInt(start.Ires + int64(i)*step.Ires)
+		} else {
+			elems[i] = NewFloat(start.Fres + float64(i)*step.Fres)
+		}
+	}
+	return NewList(elems), nil
+}
@@ -0,0 +1,98 @@
+package basic
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// a statement made of a name and some numbers, like PSET 10, 20 or BEEP: how many numbers it
+// takes and what it does with them. Commands are keyed by upper case name in commands.
+type command_t struct {
+	arities []int
+	run     func(interp *Interpreter_t, args []float64) error
+	draws   bool // whether it draws on the canvas, so its numbers are coordinates
+}
+
+// the commands, which the files that implement them add to from their init functions.
+var commands = make(map[string]command_t)
+
+// returns true if tokens[i] starts a command, like PSET 10, 20, rather than an expression using
+// a variable with the same name: the word has to be followed by something that starts a number,
+// or for a command that takes no numbers, like PENUP, end the statement.
+func isCommand(tokens []Token_t, i int) bool {
+	command, ok := commands[strings.ToUpper(tokens[i].strVal)]
+	if !ok || tokens[i].tokenType != IDENTIFIER || i+1 >= len(tokens) {
+		return false
+	}
+	switch tokens[i+1].tokenType {
+	case INT, FLOAT, IDENTIFIER, LPAREN, SUB:
+		return true
+	case NEWLINE, COLON, EOF:
+		return command.arities[0] == 0
+	}
+	return false
+}
+
+// builds and returns a Command node: the command's name, then its numbers split by commas.
+func (parser *parser_t) command() (*Node_t, error) {
+	ret := &Node_t{nodeType: COMMAND, tok: parser.currentToken, args: make([]*Node_t, 0)}
+	parser.advance()
+	if isSeparator(parser.currentToken) || parser.currentToken.tokenType == EOF {
+		return ret, nil
+	}
+	for {
+		arg, err := parser.comparison()
+		if err != nil {
+			return nil, err
+		}
+		ret.args = append(ret.args, arg)
+		if parser.currentToken.tokenType != COMMA {
+			return ret, nil
+		}
+		parser.advance()
+	}
+}
+
+// evaluates a COMMAND node, running the command. Its value is 0.
+func (node *Node_t) evaluateCommand(interp *Interpreter_t) (*Result_t, error) {
+	name := strings.ToUpper(node.tok.strVal)
+	command := commands[name]
+	arityOk := false
+	for _, arity := range command.arities {
+		arityOk = arityOk || arity == len(node.args)
+	}
+	if !arityOk {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes %s number(s), got %d", name, joinInts(command.arities, " or "), len(node.args)), Pos: node.tok.pos}
+	}
+
+	args, err := interp.evaluateArgs(node)
+	if err != nil {
+		return nil, err
+	}
+	nums := make([]float64, len(args))
+	for i, arg := range args {
+		if !arg.IsNumber() {
+			return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("%s: argument %d is a %s, not a number", name, i+1, arg.ResultType), Pos: node.tok.pos}
+		} else if command.draws && math.Abs(arg.Fres) > MAX_COORDINATE { // the float value is set for integers too
+			return nil, &RuntimeError_t{Code: ERR_BUILTIN, Details: fmt.Sprintf("%s: argument %d is further from 0 than %d", name, i+1, MAX_COORDINATE), Pos: node.tok.pos}
+		}
+		nums[i] = arg.Fres
+	}
+	if command.draws && interp.canvas == nil {
+		commands["SCREEN"].run(interp, []float64{DEFAULT_SCREEN_WIDTH, DEFAULT_SCREEN_HEIGHT})
+	}
+	if err := command.run(interp, nums); err != nil {
+		return nil, &RuntimeError_t{Code: ERR_BUILTIN, Details: fmt.Sprintf("%s: %s", name, err), Pos: node.tok.pos}
+	}
+	return NewInt(0), nil
+}
+
+// writes the ints out with a separator, like "1 or 3".
+func joinInts(nums []int, sep string) string {
+	strs := make([]string, len(nums))
+	for i, num := range nums {
+		strs[i] = fmt.Sprint(num)
+	}
+	return strings.Join(strs, sep)
+}
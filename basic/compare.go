@@ -0,0 +1,209 @@
+package basic
+
+import (
+	"fmt"
+	"math"
+)
+
+// the relative tolerance ~= compares floats with, unless the Epsilon option says otherwise.
+// Small enough that only rounding errors, like 0.1 + 0.2 against 0.3, are forgiven.
+const DEFAULT_EPSILON = 1e-9
+
+// how far apart two numbers may be and still be equal: within Abs of each other, or within
+// Rel times the bigger of the two. The zero value means they have to be exactly equal.
+// IgnoreCase lets strings that only differ in case be equal too.
+type Tolerance_t struct {
+	Abs        float64
+	Rel        float64
+	IgnoreCase bool
+}
+
+// evaluates a COMPARISON node to 1 if it holds and 0 if it doesn't. Numbers and strings can
+// be ordered with <, >, <= and >=; any two values can be compared with =, <> and ~=, and values
+// of different types are never equal (except ints and floats, which compare as numbers).
+func (node *Node_t) evaluateComparison(interp *Interpreter_t) (*Result_t, error) {
+	left, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	right, err := node.right.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	holds, err := interp.compareAt(left, right, node.tok)
+	if err != nil {
+		return nil, err
+	}
+	return boolResult(holds), nil
+}
+
+// evaluates a COMPARISON_CHAIN node like 1 <= x <= 10 as if it were (1 <= x) AND (x <= 10),
+// but with every operand evaluated at most once. It stops at the first comparison that doesn't
+// hold, without evaluating the rest, and is 0 then and 1 if they all hold.
+func (node *Node_t) evaluateChain(interp *Interpreter_t) (*Result_t, error) {
+	left, err := node.args[0].evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range node.ops {
+		right, err := node.args[i+1].evaluate(interp)
+		if err != nil {
+			return nil, err
+		}
+		holds, err := interp.compareAt(left, right, op)
+		if err != nil {
+			return nil, err
+		} else if !holds {
+			return boolResult(false), nil
+		}
+		left = right
+	}
+	return boolResult(true), nil
+}
+
+// gets how = compares values in this interpreter: floats within Epsilon, and strings
+// ignoring case if IgnoreCase is on.
+func (interp *Interpreter_t) tolerance() Tolerance_t {
+	return Tolerance_t{Rel: interp.opts.Epsilon, IgnoreCase: interp.opts.IgnoreCase}
+}
+
+// like compare, with the interpreter's tolerance, turning a type error into a RuntimeError at the operator.
+func (interp *Interpreter_t) compareAt(left *Result_t, right *Result_t, op Token_t) (bool, error) {
+	if res, ok, err := overloaded(left, right, op); ok && err != nil {
+		return false, err
+	} else if ok {
+		holds, err := truthy(res)
+		if err != nil {
+			return false, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("%s: %s", tokenSymbol(op.tokenType), err), Pos: op.pos}
+		}
+		return holds, nil
+	}
+	if err := interp.checkEnums(left, right, op); err != nil {
+		return false, err
+	} else if err := interp.checkFloatEquality(left, right, op); err != nil {
+		return false, err
+	}
+	tol := interp.tolerance()
+	if op.tokenType == APPROX && tol.Rel == 0 {
+		tol.Rel = DEFAULT_EPSILON
+	}
+	holds, err := compare(left, right, op.tokenType, tol)
+	if err != nil {
+		return false, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't apply %s to values of type %s and %s", tokenSymbol(op.tokenType), left.ResultType, right.ResultType), Pos: op.pos}
+	}
+	return holds, nil
+}
+
+// makes the result of a comparison: 1 if it holds, 0 if it doesn't.
+func boolResult(holds bool) *Result_t {
+	if holds {
+		return NewInt(1)
+	}
+	return NewInt(0)
+}
+
+// works out whether left op right holds, for one of the comparison operators. Floats only
+// have to be within tol of each other to be equal, and strings are ordered ignoring case if tol says so.
+func compare(left *Result_t, right *Result_t, op TokenType_t, tol Tolerance_t) (bool, error) {
+	if op == EQ || op == NE || op == APPROX {
+		return valuesWithin(left, right, tol) == (op != NE), nil
+	}
+	cmp, err := compareValues(left, right, tol.IgnoreCase)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case LT:
+		return cmp < 0, nil
+	case GT:
+		return cmp > 0, nil
+	case LE:
+		return cmp <= 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// returns true if res and other are the same value, the way = compares them, so host programs
+// don't have to look at the fields of each type. Hash agrees with it.
+func (res *Result_t) Equal(other *Result_t) bool {
+	return valuesEqual(res, other)
+}
+
+// like Equal, but floats, including the ones inside lists, dicts and matrices, only have to
+// be within tol of the numbers they're compared with. Two ints still have to be exactly equal.
+func (res *Result_t) EqualWithin(other *Result_t, tol Tolerance_t) bool {
+	return valuesWithin(res, other, tol)
+}
+
+// returns true if the two values are the same. Numbers are compared by value whatever their
+// type, and collections element by element.
+func valuesEqual(a *Result_t, b *Result_t) bool {
+	return valuesWithin(a, b, Tolerance_t{})
+}
+
+// returns true if two numbers are within tol of each other.
+func numbersWithin(a float64, b float64, tol Tolerance_t) bool {
+	diff := math.Abs(a - b)
+	return diff <= tol.Abs || diff <= tol.Rel*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// does the work of valuesEqual, letting numbers be within tol of each other.
+func valuesWithin(a *Result_t, b *Result_t, tol Tolerance_t) bool {
+	if a.IsNumber() && b.IsNumber() {
+		cmp, _ := compareValues(a, b, false)
+		return cmp == 0 || (tol != Tolerance_t{} && (a.ResultType == FLOATING || b.ResultType == FLOATING) && numbersWithin(a.Fres, b.Fres, tol))
+	} else if a.ResultType != b.ResultType {
+		return false
+	}
+	switch a.ResultType {
+	case LIST_RESULT:
+		if len(a.Lres) != len(b.Lres) {
+			return false
+		}
+		for i := range a.Lres {
+			if !valuesWithin(a.Lres[i], b.Lres[i], tol) {
+				return false
+			}
+		}
+		return true
+	case DICT_RESULT:
+		if a.Dres.Len() != b.Dres.Len() {
+			return false
+		}
+		for _, key := range a.Dres.keys {
+			value, ok := b.Dres.Get(key)
+			if !ok || !valuesWithin(a.Dres.values[key], value, tol) {
+				return false
+			}
+		}
+		return true
+	case MATRIX_RESULT:
+		if a.Mres.rows != b.Mres.rows || a.Mres.cols != b.Mres.cols {
+			return false
+		}
+		for i, x := range a.Mres.data {
+			y := b.Mres.data[i]
+			if x != y && !(math.IsNaN(x) && math.IsNaN(y)) && !numbersWithin(x, y, tol) { // NaNs are equal, like they are outside matrices
+				return false
+			}
+		}
+		return true
+	case STRING_RESULT:
+		return compareText(a.Sres, b.Sres, tol.IgnoreCase) == 0
+	case RECORD_RESULT: // records of a TYPE declared again are still the same type, if it has the same fields
+		if !a.Rres.Type.sameAs(b.Rres.Type) {
+			return false
+		}
+		for i := range a.Rres.values {
+			if !valuesWithin(a.Rres.values[i], b.Rres.values[i], tol) {
+				return false
+			}
+		}
+		return true
+	case FUNCTION_RESULT: // a builtin is made into a new value every time it's named
+		return a.Fnres == b.Fnres || (a.Fnres.Name == b.Fnres.Name && a.Fnres.Name != "LAMBDA")
+	default: // symbolic expressions
+		return a.ValueString() == b.ValueString()
+	}
+}
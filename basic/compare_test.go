@@ -0,0 +1,54 @@
+package basic
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	list := func(elems ...*Result_t) *Result_t { return NewList(elems) }
+	tests := []struct {
+		a, b *Result_t
+		want bool
+	}{
+		{NewInt(2), NewFloat(2), true},
+		{NewInt(2), NewInt(3), false},
+		{NewFloat(math.NaN()), NewFloat(math.NaN()), true},
+		{NewString("a"), NewString("a"), true},
+		{NewString("a"), NewString("A"), false},
+		{NewString("1"), NewInt(1), false},
+		{list(NewInt(1), NewString("x")), list(NewFloat(1), NewString("x")), true},
+		{list(NewInt(1)), list(NewInt(1), NewInt(2)), false},
+		{NewDict([]string{"a", "b"}, []*Result_t{NewInt(1), NewInt(2)}), NewDict([]string{"b", "a"}, []*Result_t{NewInt(2), NewInt(1)}), true},
+		{NewDict([]string{"a"}, []*Result_t{NewInt(1)}), NewDict([]string{"a"}, []*Result_t{NewInt(2)}), false},
+		{NewNil(), NewNil(), true},
+	}
+	for _, test := range tests {
+		if got := test.a.Equal(test.b); got != test.want {
+			t.Errorf("%s.Equal(%s): got %v, want %v", test.a.ValueString(), test.b.ValueString(), got, test.want)
+		} else if got := test.b.Equal(test.a); got != test.want {
+			t.Errorf("%s.Equal(%s): got %v, want %v", test.b.ValueString(), test.a.ValueString(), got, test.want)
+		}
+	}
+}
+
+func TestEqualWithin(t *testing.T) {
+	tests := []struct {
+		a, b *Result_t
+		tol  Tolerance_t
+		want bool
+	}{
+		{NewFloat(math.Nextafter(0.3, 1)), NewFloat(0.3), Tolerance_t{}, false},
+		{NewFloat(math.Nextafter(0.3, 1)), NewFloat(0.3), Tolerance_t{Abs: 1e-9}, true},
+		{NewFloat(1000), NewFloat(1001), Tolerance_t{Rel: 0.01}, true},
+		{NewFloat(1000), NewFloat(1100), Tolerance_t{Rel: 0.01}, false},
+		{NewInt(1), NewInt(2), Tolerance_t{Abs: 5}, false}, // ints are exact
+		{NewList([]*Result_t{NewFloat(1.0000001)}), NewList([]*Result_t{NewInt(1)}), Tolerance_t{Abs: 1e-6}, true},
+		{NewString("Hello"), NewString("hello"), Tolerance_t{IgnoreCase: true}, true},
+	}
+	for _, test := range tests {
+		if got := test.a.EqualWithin(test.b, test.tol); got != test.want {
+			t.Errorf("%s.EqualWithin(%s, %+v): got %v, want %v", test.a.ValueString(), test.b.ValueString(), test.tol, got, test.want)
+		}
+	}
+}
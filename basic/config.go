@@ -0,0 +1,86 @@
+package basic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// walks a decoded configuration document and replaces every string starting with "=" by the
+// value of the expression after it, evaluated with EvalWith against env. A string starting
+// with "==" is left as a literal string with the first "=" removed.
+//
+// doc is what encoding/json (or a YAML library) decodes into an interface{}: maps with string
+// or interface{} keys, []interface{} and scalars. The document is copied, not changed in place.
+// Values come back as int64, float64, string or []interface{}. Errors say where in the
+// document the expression was, like $.servers[2].timeout.
+func ResolveDocument(doc interface{}, env interface{}) (interface{}, error) {
+	return NewInterpreter(Options_t{}).ResolveDocument(doc, env)
+}
+
+// like ResolveDocument, using this interpreter's options and variables.
+func (interp *Interpreter_t) ResolveDocument(doc interface{}, env interface{}) (interface{}, error) {
+	return interp.resolve(doc, env, "$")
+}
+
+// like ResolveDocument, for a JSON document. The result is JSON too.
+func ResolveJSON(data []byte, env interface{}) ([]byte, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	resolved, err := ResolveDocument(doc, env)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resolved)
+}
+
+// resolves one value of the document. path is where it is, for error messages.
+func (interp *Interpreter_t) resolve(value interface{}, env interface{}, path string) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "==") {
+			return v[1:], nil
+		} else if !strings.HasPrefix(v, "=") {
+			return v, nil
+		}
+		res, err := interp.EvalWith(v[1:], env)
+		if err != nil {
+			return nil, fmt.Errorf("expression at %s: %w", path, err)
+		}
+		return res.Interface(), nil
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			resolved, err := interp.resolve(elem, env, path+"."+key)
+			if err != nil {
+				return nil, err
+			}
+			ret[key] = resolved
+		}
+		return ret, nil
+	case map[interface{}]interface{}: // what some YAML libraries decode mappings into
+		ret := make(map[interface{}]interface{}, len(v))
+		for key, elem := range v {
+			resolved, err := interp.resolve(elem, env, fmt.Sprintf("%s.%v", path, key))
+			if err != nil {
+				return nil, err
+			}
+			ret[key] = resolved
+		}
+		return ret, nil
+	case []interface{}:
+		ret := make([]interface{}, len(v))
+		for i, elem := range v {
+			resolved, err := interp.resolve(elem, env, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			ret[i] = resolved
+		}
+		return ret, nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,269 @@
+package basic
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// the database/sql drivers DBOPEN looks for when the options don't name one, in order. go-basic
+// doesn't link a SQLite driver in itself: the host imports one, like github.com/mattn/go-sqlite3
+// (registered as "sqlite3") or modernc.org/sqlite ("sqlite").
+var sqliteDrivers = []string{"sqlite3", "sqlite"}
+
+// gets the name of the driver DBOPEN opens databases with, or an error if none is registered.
+func (interp *Interpreter_t) sqlDriver() (string, error) {
+	drivers := sql.Drivers()
+	candidates := sqliteDrivers
+	if interp.opts.SQLDriver != "" {
+		candidates = []string{interp.opts.SQLDriver}
+	}
+	for _, candidate := range candidates {
+		i := sort.SearchStrings(drivers, candidate) // sql.Drivers is sorted
+		if i < len(drivers) && drivers[i] == candidate {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("there's no %s driver registered, the program running go-basic has to import one", strings.Join(candidates, " or "))
+}
+
+// evaluates DBOPEN(path): opens the SQLite database in the file at path, giving back a handle
+// for DBQUERY and DBCLOSE. The file has to be under the ReadRoots of the interpreter's
+// Permissions, and is opened read-only unless it's under the WriteRoots too. A path with a ?
+// or # in it is refused, since SQLite would read what follows as options for opening it.
+func (interp *Interpreter_t) dbOpenCall(node *Node_t) (*Result_t, error) {
+	if len(node.args) != 1 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 1 argument(s), got %d", node.tok.strVal, len(node.args)), Pos: node.tok.pos}
+	}
+	arg, err := node.args[0].evaluate(interp)
+	if err != nil {
+		return nil, err
+	} else if arg.ResultType != STRING_RESULT {
+		return nil, builtinError(node.tok, typeErrorf("argument 1 is a %s, not a string", arg.ResultType))
+	} else if interp.opts.FileSystem != nil {
+		return nil, builtinError(node.tok, fmt.Errorf("databases can only be opened from the OS's files"))
+	} else if strings.ContainsAny(arg.Sres, "?#") {
+		return nil, builtinError(node.tok, fmt.Errorf("database path %s can't have a ? or # in it", quoteString(arg.Sres)))
+	} else if err := interp.checkRead(arg.Sres, node.tok); err != nil {
+		return nil, err
+	}
+	driver, err := interp.sqlDriver()
+	if err != nil {
+		return nil, builtinError(node.tok, err)
+	}
+
+	mode := "rwc"
+	if interp.checkWrite(arg.Sres, node.tok) != nil {
+		mode = "ro"
+	}
+	path := filepath.ToSlash(arg.Sres)
+	if filepath.IsAbs(arg.Sres) && !strings.HasPrefix(path, "/") { // like C:/data.db, which is file:///C:/data.db as a URI
+		path = "/" + path
+	}
+	dsn := &url.URL{Scheme: "file", Path: path, RawQuery: url.Values{"mode": {mode}}.Encode()}
+	db, err := sql.Open(driver, dsn.String())
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil {
+		return nil, builtinError(node.tok, err)
+	}
+	if interp.databases == nil {
+		interp.databases = make(map[int64]*sql.DB)
+	}
+	interp.lastDB++
+	interp.databases[interp.lastDB] = db
+	return NewInt(interp.lastDB), nil
+}
+
+// evaluates DBQUERY(db, query[, params]): runs the query on the database DBOPEN gave the
+// handle db for, with the elements of the list params for its ? placeholders, giving back
+// its rows as a list of dicts from column name to value. NULLs are NIL. Queries that would
+// reach other files than the database's, like ATTACH, are refused.
+func (interp *Interpreter_t) dbQueryCall(node *Node_t) (*Result_t, error) {
+	if len(node.args) != 2 && len(node.args) != 3 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 2 to 3 argument(s), got %d", node.tok.strVal, len(node.args)), Pos: node.tok.pos}
+	}
+	args, err := interp.evaluateArgs(node)
+	if err != nil {
+		return nil, err
+	}
+	db, err := interp.database(args[0], node.tok)
+	if err != nil {
+		return nil, err
+	} else if args[1].ResultType != STRING_RESULT {
+		return nil, builtinError(node.tok, typeErrorf("argument 2 is a %s, not a string", args[1].ResultType))
+	} else if keyword := fileKeyword(args[1].Sres); keyword != "" {
+		return nil, &RuntimeError_t{Code: ERR_PERMISSION, Details: fmt.Sprintf("%s can't run %s, it would reach files outside the database", node.tok.strVal, keyword), Pos: node.tok.pos}
+	}
+	var params []interface{}
+	if len(args) == 3 {
+		if args[2].ResultType != LIST_RESULT {
+			return nil, builtinError(node.tok, typeErrorf("argument 3 is a %s, not a list", args[2].ResultType))
+		}
+		for _, param := range args[2].Lres {
+			params = append(params, param.Interface())
+		}
+	}
+
+	ctx := interp.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rows, err := db.QueryContext(ctx, args[1].Sres, params...)
+	if err != nil {
+		return nil, builtinError(node.tok, err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, builtinError(node.tok, err)
+	}
+	limit := interp.opts.MaxListLength
+	if limit <= 0 {
+		limit = DEFAULT_MAX_LIST_LENGTH
+	}
+	ret := []*Result_t{}
+	for rows.Next() {
+		if len(ret) == limit {
+			return nil, &RuntimeError_t{Code: ERR_LIST_LIMIT, Details: fmt.Sprintf("%s gave more rows than the limit of %d", node.tok.strVal, limit), Pos: node.tok.pos}
+		}
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, builtinError(node.tok, err)
+		}
+		row := make([]*Result_t, len(columns))
+		for i, value := range values {
+			row[i] = sqlValue(value)
+		}
+		ret = append(ret, NewDict(columns, row))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, builtinError(node.tok, err)
+	}
+	return NewList(ret), nil
+}
+
+// evaluates DBCLOSE(db): closes the database DBOPEN gave the handle db for.
+func (interp *Interpreter_t) dbCloseCall(node *Node_t) (*Result_t, error) {
+	if len(node.args) != 1 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 1 argument(s), got %d", node.tok.strVal, len(node.args)), Pos: node.tok.pos}
+	}
+	arg, err := node.args[0].evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	db, err := interp.database(arg, node.tok)
+	if err != nil {
+		return nil, err
+	}
+	delete(interp.databases, arg.Ires)
+	if err := db.Close(); err != nil {
+		return nil, builtinError(node.tok, err)
+	}
+	return NewInt(0), nil
+}
+
+// the SQL keywords of statements that open or write other files than the database's: ATTACH
+// opens any file as another database, and VACUUM INTO writes a copy of it anywhere.
+var fileKeywords = map[string]bool{"ATTACH": true, "VACUUM": true}
+
+// gets the first of fileKeywords a query uses, in upper case, or "" if it uses none. Words in
+// string literals, quoted names and comments don't count.
+func fileKeyword(query string) string {
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				return ""
+			}
+			i += end + 1
+		case c == '[':
+			end := strings.IndexByte(query[i+1:], ']')
+			if end < 0 {
+				return ""
+			}
+			i += end + 1
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return ""
+			}
+			i += end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return ""
+			}
+			i += end + 3
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := i
+			for i+1 < len(query) && (query[i+1] == '_' || query[i+1] == '$' || query[i+1] >= 'a' && query[i+1] <= 'z' || query[i+1] >= 'A' && query[i+1] <= 'Z' || query[i+1] >= '0' && query[i+1] <= '9') {
+				i++
+			}
+			if word := strings.ToUpper(query[start : i+1]); fileKeywords[word] {
+				return word
+			}
+		}
+	}
+	return ""
+}
+
+// closes the databases DBOPEN opened that programs didn't close with DBCLOSE. Hosts should
+// call it once they're done with an interpreter; it can still run programs afterwards.
+func (interp *Interpreter_t) Close() error {
+	var ret error
+	for handle, db := range interp.databases {
+		if err := db.Close(); err != nil && ret == nil {
+			ret = err
+		}
+		delete(interp.databases, handle)
+	}
+	return ret
+}
+
+// gets the database a handle from DBOPEN is for.
+func (interp *Interpreter_t) database(handle *Result_t, tok Token_t) (*sql.DB, error) {
+	if handle.ResultType != INTEGER {
+		return nil, builtinError(tok, typeErrorf("argument 1 is a %s, not a database handle", handle.ResultType))
+	}
+	db, ok := interp.databases[handle.Ires]
+	if !ok {
+		return nil, builtinError(tok, fmt.Errorf("%d isn't an open database", handle.Ires))
+	}
+	return db, nil
+}
+
+// turns a value scanned from a database column into a BASIC value.
+func sqlValue(value interface{}) *Result_t {
+	switch value := value.(type) {
+	case nil:
+		return NewNil()
+	case int64:
+		return NewInt(value)
+	case float64:
+		return NewFloat(value)
+	case bool:
+		if value {
+			return NewInt(1)
+		}
+		return NewInt(0)
+	case []byte:
+		return NewString(string(value))
+	case string:
+		return NewString(value)
+	case time.Time:
+		return NewString(value.Format(time.RFC3339Nano))
+	}
+	return NewString(fmt.Sprint(value))
+}
@@ -0,0 +1,171 @@
+package basic
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// a database/sql driver that remembers what it was asked to open and run, for testing the
+// DB builtins without a real SQLite. Every query gives back no rows.
+type fakeDriver_t struct {
+	mu      sync.Mutex
+	dsns    []string
+	queries []string
+	open    int // connections that haven't been closed
+}
+
+var fakeDriver = &fakeDriver_t{}
+
+func init() {
+	sql.Register("fakesql", fakeDriver)
+}
+
+func (d *fakeDriver_t) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dsns = append(d.dsns, name)
+	d.open++
+	return fakeConn_t{d}, nil
+}
+
+type fakeConn_t struct{ driver *fakeDriver_t }
+
+func (conn fakeConn_t) Prepare(query string) (driver.Stmt, error) {
+	conn.driver.mu.Lock()
+	defer conn.driver.mu.Unlock()
+	conn.driver.queries = append(conn.driver.queries, query)
+	return fakeStmt_t{}, nil
+}
+
+func (conn fakeConn_t) Close() error {
+	conn.driver.mu.Lock()
+	defer conn.driver.mu.Unlock()
+	conn.driver.open--
+	return nil
+}
+
+func (conn fakeConn_t) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type fakeStmt_t struct{}
+
+func (fakeStmt_t) Close() error  { return nil }
+func (fakeStmt_t) NumInput() int { return -1 }
+func (fakeStmt_t) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+func (fakeStmt_t) Query(args []driver.Value) (driver.Rows, error) { return fakeRows_t{}, nil }
+
+type fakeRows_t struct{}
+
+func (fakeRows_t) Columns() []string              { return []string{"x"} }
+func (fakeRows_t) Close() error                   { return nil }
+func (fakeRows_t) Next(dest []driver.Value) error { return io.EOF }
+
+func TestDBOpenDSN(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.db")
+	readOnly := NewInterpreter(Options_t{SQLDriver: "fakesql", Permissions: &Permissions_t{ReadRoots: []string{dir}}})
+	readWrite := NewInterpreter(Options_t{SQLDriver: "fakesql", Permissions: &Permissions_t{ReadRoots: []string{dir}, WriteRoots: []string{dir}}})
+	tests := []struct {
+		interp *Interpreter_t
+		path   string
+		want   string
+	}{
+		{readOnly, path, "file://" + filepath.ToSlash(path) + "?mode=ro"},
+		{readWrite, path, "file://" + filepath.ToSlash(path) + "?mode=rwc"},
+	}
+	for _, test := range tests {
+		fakeDriver.mu.Lock()
+		fakeDriver.dsns = nil
+		fakeDriver.mu.Unlock()
+		if _, err := test.interp.Run(`DBOPEN(`+quoteString(test.path)+`)`, t.Name()); err != nil {
+			t.Fatalf("DBOPEN(%q): %s", test.path, err)
+		}
+		fakeDriver.mu.Lock()
+		if len(fakeDriver.dsns) == 0 || fakeDriver.dsns[0] != test.want {
+			t.Errorf("DBOPEN(%q) opened %q, want %q", test.path, fakeDriver.dsns, test.want)
+		}
+		fakeDriver.mu.Unlock()
+		test.interp.Close()
+	}
+
+	for _, bad := range []string{path + "?mode=rw&x=", path + "#x"} {
+		if got := runErrorCode(t, readWrite.opts, `DBOPEN(`+quoteString(bad)+`)`); got != ERR_BUILTIN {
+			t.Errorf("DBOPEN(%q): got error code %q, want %q", bad, got, ERR_BUILTIN)
+		}
+	}
+	if got := runErrorCode(t, readOnly.opts, `DBOPEN("/elsewhere/data.db")`); got != ERR_PERMISSION {
+		t.Errorf("DBOPEN outside ReadRoots: got error code %q, want %q", got, ERR_PERMISSION)
+	}
+}
+
+func TestDBQueryRefusesOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	interp := NewInterpreter(Options_t{SQLDriver: "fakesql", Permissions: &Permissions_t{ReadRoots: []string{dir}}})
+	defer interp.Close()
+	if _, err := interp.Run(`DBOPEN(`+quoteString(filepath.Join(dir, "data.db"))+`)`, t.Name()); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		want  ErrorCode_t
+	}{
+		{`SELECT x FROM t`, ""},
+		{`SELECT 'attach' AS "vacuum" -- ATTACH`, ""},
+		{`ATTACH DATABASE '/etc/passwd' AS x`, ERR_PERMISSION},
+		{`attach '/tmp/other.db' as x`, ERR_PERMISSION},
+		{`SELECT 1; /* */ Attach '/tmp/other.db' AS x`, ERR_PERMISSION},
+		{`VACUUM INTO '/tmp/copy.db'`, ERR_PERMISSION},
+	}
+	for _, test := range tests {
+		_, err := interp.Run(`DBQUERY(1, `+quoteString(test.query)+`)`, t.Name())
+		var got ErrorCode_t
+		if runtimeErr, ok := err.(*RuntimeError_t); ok {
+			got = runtimeErr.Code
+		} else if err != nil {
+			t.Fatalf("%q: %s", test.query, err)
+		}
+		if got != test.want {
+			t.Errorf("%q: got error code %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestCloseClosesDatabases(t *testing.T) {
+	fakeDriver.mu.Lock()
+	before := fakeDriver.open
+	fakeDriver.mu.Unlock()
+	interp := NewInterpreter(Options_t{SQLDriver: "fakesql"})
+	if _, err := interp.Run(`DBQUERY(DBOPEN("a.db"), "SELECT 1")`, t.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if err := interp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fakeDriver.mu.Lock()
+	defer fakeDriver.mu.Unlock()
+	if fakeDriver.open != before {
+		t.Errorf("%d connections are still open after Close", fakeDriver.open-before)
+	}
+	if len(interp.databases) != 0 {
+		t.Errorf("%d databases are still open after Close", len(interp.databases))
+	}
+}
+
+func TestManagerClosesEvictedTenants(t *testing.T) {
+	manager := NewManager(Options_t{SQLDriver: "fakesql"}, Quota_t{})
+	if _, err := manager.Run(context.Background(), "a", `DBOPEN("a.db")`); err != nil {
+		t.Fatal(err)
+	}
+	var interp *Interpreter_t
+	manager.With("a", func(i *Interpreter_t) error { interp = i; return nil })
+	manager.Evict("a")
+	if len(interp.databases) != 0 {
+		t.Errorf("%d databases are still open after the tenant was evicted", len(interp.databases))
+	}
+}
@@ -0,0 +1,85 @@
+package basic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// how a set of named expressions, like the fields of a config file, depend on each other.
+// An expression depends on another if it reads a variable with the other's name.
+type DepGraph_t struct {
+	Order  []string            // every name, ordered so that each comes after the expressions it reads
+	Deps   map[string][]string // name -> the names of the other expressions it reads, sorted
+	Inputs map[string][]string // name -> the variables it reads that aren't expressions in the set or builtins, sorted
+}
+
+// works out the dependency graph of a set of named expressions, in an order they can be
+// evaluated in. It fails if an expression doesn't parse or if some of them depend on each
+// other in a cycle, which the error spells out, like "a -> b -> a".
+func Dependencies(exprs map[string]string) (*DepGraph_t, error) {
+	names := make([]string, 0, len(exprs))
+	for name := range exprs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := &DepGraph_t{Order: make([]string, 0, len(names)), Deps: make(map[string][]string), Inputs: make(map[string][]string)}
+	interp := NewInterpreter(Options_t{})
+	for _, name := range names {
+		node, err := Parse(exprs[name], name)
+		if err != nil {
+			return nil, err
+		}
+		deps, inputs := make(map[string]bool), make(map[string]bool)
+		unbound(node, map[string]Position_t{}, func(tok Token_t, scope map[string]Position_t) {
+			if _, ok := exprs[tok.strVal]; ok {
+				deps[tok.strVal] = true
+			} else if _, ok := interp.builtinValue(tok.strVal); !ok && !isNilName(tok.strVal) {
+				inputs[tok.strVal] = true
+			}
+		})
+		ret.Deps[name], ret.Inputs[name] = sortedKeys(deps), sortedKeys(inputs)
+	}
+
+	// depth first, so a cycle shows up as a name that's still on the path
+	done := make(map[string]bool)
+	path := make([]string, 0)
+	var visit func(name string) error
+	visit = func(name string) error {
+		for i, other := range path {
+			if other == name {
+				return fmt.Errorf("expressions depend on each other in a cycle: %s", strings.Join(append(path[i:], name), " -> "))
+			}
+		}
+		if done[name] {
+			return nil
+		}
+		path = append(path, name)
+		for _, dep := range ret.Deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		done[name] = true
+		ret.Order = append(ret.Order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// gets the keys of a set, sorted.
+func sortedKeys(set map[string]bool) []string {
+	ret := make([]string, 0, len(set))
+	for key := range set {
+		ret = append(ret, key)
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,140 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// differentiates an expression with respect to the named variable, giving the simplified
+// derivative: x^2 + 3*x becomes 2 * x + 3. Every other variable is taken to be a constant.
+// Sums, products, quotients, powers, negation and +x (the absolute value) are handled, as are
+// SIN, COS, TAN, ATN, EXP, LOG, SQR, ABS and SGN of any expression.
+func Differentiate(node *Node_t, name string) (*Node_t, error) {
+	ret, err := derivative(node, name)
+	if err != nil {
+		return nil, err
+	}
+	return Simplify(ret)
+}
+
+// builds the derivative of the expression, without simplifying it.
+func derivative(node *Node_t, name string) (*Node_t, error) {
+	if !variables(node)[name] {
+		if node.nodeType == FACTOR && node.tok.tokenType == STRING || node.nodeType == LIST || node.nodeType == DICT {
+			return nil, cantDifferentiate(node)
+		}
+		return numberNode(NewInt(0)), nil
+	}
+
+	switch node.nodeType {
+	case VAR_ACCESS: // it's the variable itself, since it's read
+		return numberNode(NewInt(1)), nil
+	case UNARY_OP:
+		du, err := derivative(node.left, name)
+		if err != nil {
+			return nil, err
+		} else if node.tok.tokenType == SUB {
+			return &Node_t{nodeType: UNARY_OP, tok: node.tok, left: du}, nil
+		}
+		return binaryNode(MUL, callNode("SGN", node.left), du), nil // +u is the absolute value of u
+	case EXPRESSION, TERM:
+		du, err := derivative(node.left, name)
+		if err != nil {
+			return nil, err
+		}
+		dv, err := derivative(node.right, name)
+		if err != nil {
+			return nil, err
+		}
+		u, v := node.left, node.right
+		switch node.tok.tokenType {
+		case ADD, SUB:
+			return binaryNode(node.tok.tokenType, du, dv), nil
+		case MUL: // (uv)' = u'v + uv'
+			return binaryNode(ADD, binaryNode(MUL, du, v), binaryNode(MUL, u, dv)), nil
+		default: // (u/v)' = (u'v - uv') / v^2
+			return binaryNode(DIV, binaryNode(SUB, binaryNode(MUL, du, v), binaryNode(MUL, u, dv)), binaryNode(POW, v, numberNode(NewInt(2)))), nil
+		}
+	case POWER:
+		return powerDerivative(node, name)
+	case CALL:
+		if len(node.args) == 1 {
+			return callDerivative(node, name)
+		}
+	}
+	return nil, cantDifferentiate(node)
+}
+
+// builds the derivative of u^v.
+func powerDerivative(node *Node_t, name string) (*Node_t, error) {
+	u, v := node.left, node.right
+	du, err := derivative(u, name)
+	if err != nil {
+		return nil, err
+	}
+	if !variables(v)[name] { // (u^n)' = n u^(n-1) u'
+		lowered := binaryNode(POW, u, binaryNode(SUB, v, numberNode(NewInt(1))))
+		return binaryNode(MUL, binaryNode(MUL, v, lowered), du), nil
+	}
+	dv, err := derivative(v, name)
+	if err != nil {
+		return nil, err
+	}
+	// (u^v)' = u^v (v' LOG(u) + v u' / u)
+	inner := binaryNode(ADD, binaryNode(MUL, dv, callNode("LOG", u)), binaryNode(DIV, binaryNode(MUL, v, du), u))
+	return binaryNode(MUL, node, inner), nil
+}
+
+// builds the derivative of a builtin applied to one expression, by the chain rule.
+func callDerivative(node *Node_t, name string) (*Node_t, error) {
+	u := node.args[0]
+	du, err := derivative(u, name)
+	if err != nil {
+		return nil, err
+	}
+	var outer *Node_t // the builtin's derivative at u
+	switch strings.ToUpper(node.tok.strVal) {
+	case "SIN":
+		outer = callNode("COS", u)
+	case "COS":
+		outer = &Node_t{nodeType: UNARY_OP, tok: Token_t{tokenType: SUB}, left: callNode("SIN", u)}
+	case "TAN":
+		outer = binaryNode(DIV, numberNode(NewInt(1)), binaryNode(POW, callNode("COS", u), numberNode(NewInt(2))))
+	case "ATN":
+		outer = binaryNode(DIV, numberNode(NewInt(1)), binaryNode(ADD, numberNode(NewInt(1)), binaryNode(POW, u, numberNode(NewInt(2)))))
+	case "EXP":
+		outer = node
+	case "LOG":
+		outer = binaryNode(DIV, numberNode(NewInt(1)), u)
+	case "SQR":
+		outer = binaryNode(DIV, numberNode(NewInt(1)), binaryNode(MUL, numberNode(NewInt(2)), node))
+	case "ABS":
+		outer = callNode("SGN", u)
+	case "SGN": // flat everywhere it's defined
+		return numberNode(NewInt(0)), nil
+	default:
+		return nil, cantDifferentiate(node)
+	}
+	return binaryNode(MUL, outer, du), nil
+}
+
+// error for a part of an expression Differentiate doesn't know the derivative of.
+func cantDifferentiate(node *Node_t) error {
+	return &RuntimeError_t{Code: ERR_EVALUATION, Details: fmt.Sprintf("can't differentiate %s", Format(node)), Pos: node.tok.pos}
+}
+
+// builds a binary operation node: an EXPRESSION for + and -, a TERM for * and / and a POWER for ^.
+func binaryNode(op TokenType_t, left *Node_t, right *Node_t) *Node_t {
+	nodeType := TERM
+	if op == ADD || op == SUB {
+		nodeType = EXPRESSION
+	} else if op == POW {
+		nodeType = POWER
+	}
+	return &Node_t{nodeType: nodeType, tok: Token_t{tokenType: op}, left: left, right: right}
+}
+
+// builds a call of a builtin with one argument.
+func callNode(name string, arg *Node_t) *Node_t {
+	return &Node_t{nodeType: CALL, tok: Token_t{tokenType: IDENTIFIER, strVal: name}, args: []*Node_t{arg}}
+}
@@ -0,0 +1,46 @@
+package basic
+
+import "testing"
+
+func TestDifferentiate(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`x ^ 2 + 3 * x`, `2 * x + 3`},
+		{`5`, `0`},
+		{`y * x`, `y`},
+		{`x * x * x`, `3 * x^2`},
+		{`1 / x`, `-1 / x^2`},
+		{`SIN(x)`, `COS(x)`},
+		{`COS(2 * x)`, `-2 * SIN(2 * x)`},
+		{`EXP(x ^ 2)`, `2 * EXP(x^2) * x`},
+		{`LOG(x)`, `1 / x`},
+		{`-x`, `-1`},
+		{`2 ^ x`, `0.6931471805599453 * 2^x`},
+	}
+	for _, test := range tests {
+		node, err := Parse(test.src, t.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Differentiate(node, "x")
+		if err != nil {
+			t.Errorf("%q: %s", test.src, err)
+		} else if Format(got) != test.want {
+			t.Errorf("%q: got %q, want %q", test.src, Format(got), test.want)
+		}
+	}
+}
+
+func TestDifferentiateErrors(t *testing.T) {
+	for _, src := range []string{`x + "a"`, `[x, 1]`, `MAP(LAMBDA(y, y * x), [1])`} {
+		node, err := Parse(src, t.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Differentiate(node, "x"); err == nil {
+			t.Errorf("%q: got no error", src)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// builds and returns an Enum Def node, like
+//
+//	ENUM Color
+//	    RED
+//	    GREEN = 5
+//	    BLUE
+//	END ENUM
+//
+// A member is a name, or a name with its value like GREEN = 5.
+func (parser *parser_t) enumDef() (*Node_t, error) {
+	return parser.declaration(ENUM_DEF, "ENUM", enumMemberName, "a member, like RED or RED = 1,")
+}
+
+// gets the name of a member of an ENUM.
+func enumMemberName(member *Node_t) (string, bool) {
+	if member.nodeType == VAR_ACCESS {
+		return member.tok.strVal, true
+	} else if member.nodeType == COMPARISON && member.tok.tokenType == EQ && member.left.nodeType == VAR_ACCESS {
+		return member.left.tok.strVal, true
+	}
+	return "", false
+}
+
+// evaluates an ENUM_DEF node, binding its name to a dict of its members for the rest of the
+// program, like an IMPORT, so they're read like Color.RED. Members are ints counting up from 0,
+// or from the last one given a value. They remember their enum, so TYPE() gives its name and
+// strict mode can keep comparisons within it. Its value is the dict.
+func (node *Node_t) evaluateEnumDef(interp *Interpreter_t) (*Result_t, error) {
+	if err := interp.bindable(node.tok.strVal, node.tok); err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(node.args))
+	values := make([]*Result_t, len(node.args))
+	next := int64(0)
+	for i, member := range node.args {
+		name := member.tok
+		if member.nodeType == COMPARISON {
+			value, err := member.right.evaluate(interp)
+			if err != nil {
+				return nil, err
+			} else if value.ResultType != INTEGER {
+				return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("ENUM %s: %s has to be an int, not a %s", node.tok.strVal, member.left.tok.strVal, value.ResultType), Pos: member.tok.pos}
+			}
+			name, next = member.left.tok, value.Ires
+		}
+		keys[i], values[i] = name.strVal, newEnumMember(node.tok.strVal, next)
+		next++
+	}
+
+	ret := NewDict(keys, values)
+	interp.vars[node.tok.strVal] = ret
+	interp.define(node.tok.strVal, node.tok.pos)
+	return ret, nil
+}
+
+// makes an int Result that's a member of the named enum.
+func newEnumMember(enum string, i int64) *Result_t {
+	ret := NewInt(i)
+	ret.Enum = enum
+	return ret
+}
+
+// gets the name of the type of a value, the way TYPE() gives it: the enum of a member of one,
+// the TYPE of a record, or else the kind of value, like "int" or "list".
+func typeName(res *Result_t) string {
+	if res.Enum != "" {
+		return res.Enum
+	} else if res.ResultType == RECORD_RESULT {
+		return res.Rres.Type.Name
+	}
+	return res.ResultType.String()
+}
+
+// describes an operand of a comparison, for the warning checkEnums raises about it.
+func enumOperand(res *Result_t) string {
+	if res.Enum != "" {
+		return "a member of enum " + res.Enum
+	}
+	return "a value of type " + res.ResultType.String()
+}
+
+// warns about comparing a member of an enum with anything but a member of the same enum, like
+// Color.RED = Shape.CIRCLE or Color.RED = 0, which strict mode doesn't allow. INT() makes a
+// member a plain int, to compare it as one.
+func (interp *Interpreter_t) checkEnums(left *Result_t, right *Result_t, op Token_t) error {
+	if left.Enum == right.Enum {
+		return nil
+	}
+	operator := tokenSymbol(op.tokenType)
+	if op.tokenType == IDENTIFIER { // the BETWEEN or AND of a BETWEEN
+		operator = strings.ToUpper(op.strVal)
+	}
+	return interp.warn(ENUM_COMPARISON, op.pos, "%s compares %s with %s; write INT() around a member to compare it as a number", operator, enumOperand(left), enumOperand(right))
+}
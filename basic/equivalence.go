@@ -0,0 +1,223 @@
+package basic
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// number of random variable assignments tried by Equivalent before deciding two expressions
+// are the same. Every other one uses ints, as / truncates them: x / 2 * 2 isn't x for odd x.
+const eqTrials = 100
+
+// decides whether two expressions are semantically equivalent, like "a*(b+c)" and "a*b+a*c".
+// The expressions are first compared in a normalized form (so "b+a" matches "a+b" straight away),
+// and otherwise both are evaluated over random values of their variables, floats and ints, positive
+// and negative. Getting a different result for any assignment means they aren't equivalent; agreeing
+// on all of them means they (almost certainly) are.
+// Assignments both fail on, like dividing by zero, are skipped, but if both fail on every one there's
+// nothing to go by, and the error the first expression failed with is returned.
+// The random values are seeded, so the answer for a given pair never changes.
+func Equivalent(src1, src2 string) (bool, error) {
+	node1, err := Parse(src1, "expr1")
+	if err != nil {
+		return false, err
+	}
+	node2, err := Parse(src2, "expr2")
+	if err != nil {
+		return false, err
+	}
+
+	if normalize(node1) == normalize(node2) {
+		return true, nil
+	}
+
+	names := variables(node1)
+	for name := range variables(node2) {
+		names[name] = true
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	var firstErr error
+	evaluated := false // whether both expressions evaluated for any of the assignments
+	for trial := 0; trial < eqTrials; trial++ {
+		interp := NewInterpreter(Options_t{})
+		for name := range names {
+			if trial%2 == 0 {
+				interp.SetVar(name, NewFloat(rng.Float64()*20-10))
+			} else {
+				interp.SetVar(name, NewInt(rng.Int63n(41)-20))
+			}
+		}
+		res1, err1 := interp.runNode(context.Background(), node1, nil)
+		res2, err2 := interp.runNode(context.Background(), node2, nil)
+		if err1 != nil && err2 != nil { // both blew up (say, dividing by zero), no information here
+			if firstErr == nil {
+				firstErr = err1
+			}
+			continue
+		} else if err1 != nil || err2 != nil {
+			return false, nil
+		}
+		if res1.IsNumber() != res2.IsNumber() {
+			return false, nil
+		} else if !res1.IsNumber() {
+			if res1.ResultType != res2.ResultType || res1.ValueString() != res2.ValueString() {
+				return false, nil
+			}
+		} else if !closeEnough(res1.Fres, res2.Fres) { // the float value is set for integers too
+			return false, nil
+		}
+		evaluated = true
+	}
+	if !evaluated {
+		return false, firstErr
+	}
+	return true, nil
+}
+
+// returns true if the two floats are equal, give or take rounding errors.
+func closeEnough(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+// gets the names of all of the variables read in the AST.
+func variables(node *Node_t) map[string]bool {
+	ret := make(map[string]bool)
+	Walk(node, func(n *Node_t) bool {
+		if n.nodeType == VAR_ACCESS {
+			ret[n.tok.strVal] = true
+		}
+		return true
+	})
+	return ret
+}
+
+// builds a normalized String of the AST, where the operands of chains of + and * are
+// sorted so that the order they were written in doesn't matter.
+func normalize(node *Node_t) string {
+	switch node.nodeType {
+	case FACTOR:
+		if node.tok.tokenType == INT {
+			return strconv.FormatInt(node.tok.intVal, 10)
+		} else if node.tok.tokenType == STRING {
+			return quoteString(node.tok.strVal)
+		}
+		return strconv.FormatFloat(node.tok.floatVal, 'g', -1, 64)
+	case LIST, INDEX, SLICE, DICT, SAFE_ACCESS, FIELD_ACCESS, MATCH_EXPR, ANNOTATION: // rare enough in equations that they're compared as written
+		return Format(node)
+	case VAR_ACCESS:
+		return node.tok.strVal
+	case PARAM_ACCESS:
+		return "?" + node.tok.strVal
+	case CALL:
+		args := make([]string, len(node.args))
+		for i, arg := range node.args {
+			args[i] = normalize(arg)
+		}
+		return strings.ToUpper(node.tok.strVal) + "(" + strings.Join(args, ",") + ")"
+	case UNARY_OP:
+		return tokenSymbol(node.tok.tokenType) + "(" + normalize(node.left) + ")"
+	case COMPARISON_CHAIN:
+		ret := normalize(node.args[0])
+		for i, op := range node.ops {
+			ret += tokenSymbol(op.tokenType) + normalize(node.args[i+1])
+		}
+		return "(" + ret + ")"
+	case IN_OP:
+		return "(" + normalize(node.left) + " IN " + normalize(node.right) + ")"
+	case BETWEEN_OP:
+		return "(" + normalize(node.left) + " BETWEEN " + normalize(node.args[0]) + " AND " + normalize(node.args[1]) + ")"
+	case TERM, EXPRESSION, COMPARISON, POWER, COALESCE_OP:
+		op := node.tok.tokenType
+		if (op == MUL && !mayBeMatrix(node)) || (op == ADD && !mayBeText(node)) { // joining strings or lists and multiplying matrices aren't commutative
+			operands := flatten(node, op, nil)
+			sort.Strings(operands)
+			return "(" + strings.Join(operands, tokenSymbol(op)) + ")"
+		}
+		return "(" + normalize(node.left) + tokenSymbol(op) + normalize(node.right) + ")"
+	default:
+		return node.String()
+	}
+}
+
+// returns true if the expression could be a string or list rather than a number, which is
+// the case as soon as one appears in it literally.
+func mayBeText(node *Node_t) bool {
+	ret := false
+	Walk(node, func(n *Node_t) bool {
+		if n.nodeType == LIST || n.nodeType == DICT || (n.nodeType == FACTOR && n.tok.tokenType == STRING) {
+			ret = true
+		}
+		return !ret
+	})
+	return ret
+}
+
+// returns true if the expression could be a matrix, which is the case as soon as it calls
+// a builtin that makes one.
+func mayBeMatrix(node *Node_t) bool {
+	ret := false
+	Walk(node, func(n *Node_t) bool {
+		if n.nodeType == CALL {
+			switch strings.ToUpper(n.tok.strVal) {
+			case "MATRIX", "TRANSPOSE", "INVERSE":
+				ret = true
+			}
+		}
+		return !ret
+	})
+	return ret
+}
+
+// collects the normalized operands of a chain of the same commutative operator, like a+b+c.
+func flatten(node *Node_t, op TokenType_t, operands []string) []string {
+	if (node.nodeType == TERM || node.nodeType == EXPRESSION) && node.tok.tokenType == op {
+		operands = flatten(node.left, op, operands)
+		return flatten(node.right, op, operands)
+	}
+	return append(operands, normalize(node))
+}
+
+// gets the symbol of an operator token, like "+" for ADD
+func tokenSymbol(tokenType TokenType_t) string {
+	switch tokenType {
+	case ADD:
+		return "+"
+	case SUB:
+		return "-"
+	case MUL:
+		return "*"
+	case DIV:
+		return "/"
+	case POW:
+		return "^"
+	case LT:
+		return "<"
+	case GT:
+		return ">"
+	case LE:
+		return "<="
+	case GE:
+		return ">="
+	case EQ:
+		return "="
+	case NE:
+		return "<>"
+	case APPROX:
+		return "~="
+	case COALESCE:
+		return "??"
+	case SAFE_DOT:
+		return "?."
+	case ELLIPSIS:
+		return "..."
+	case DOT:
+		return "."
+	default:
+		return "?"
+	}
+}
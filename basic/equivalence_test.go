@@ -0,0 +1,36 @@
+package basic
+
+import "testing"
+
+func TestEquivalent(t *testing.T) {
+	tests := []struct {
+		src1, src2 string
+		want       bool
+	}{
+		{"a*(b+c)", "a*b+a*c", true},
+		{"b+a", "a+b", true},
+		{"x*2", "x+x", true},
+		{"x*2", "x+1", false},
+		{"1/x", "1/x", true},
+		{"x/2*2", "x", false}, // ints truncate
+		{"x/2.0*2", "x", true},
+		{"(2*x+4)/2", "x+2", true},
+		{"x-x", "0", true},
+	}
+	for _, test := range tests {
+		got, err := Equivalent(test.src1, test.src2)
+		if err != nil {
+			t.Errorf("Equivalent(%q, %q): %s", test.src1, test.src2, err)
+		} else if got != test.want {
+			t.Errorf("Equivalent(%q, %q) = %v, want %v", test.src1, test.src2, got, test.want)
+		}
+	}
+}
+
+func TestEquivalentFailsWhenNothingEvaluates(t *testing.T) {
+	for _, pair := range [][2]string{{"FOO(x)", "BAR(y)"}, {"1/0", "2/0"}} {
+		if got, err := Equivalent(pair[0], pair[1]); err == nil {
+			t.Errorf("Equivalent(%q, %q) = %v, want an error", pair[0], pair[1], got)
+		}
+	}
+}
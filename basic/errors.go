@@ -0,0 +1,119 @@
+package basic
+
+import "errors"
+
+// stable code identifying a kind of error. Codes never change meaning, so tooling and docs
+// can refer to them. E0xx codes are syntax errors, E1xx are runtime errors and E2xx are
+// errors that only happen in strict mode.
+type ErrorCode_t string
+
+const (
+	ERR_UNEXPECTED_TOKEN    ErrorCode_t = "E001" // the parser found a token it didn't expect
+	ERR_EXPECTED_RPAREN     ErrorCode_t = "E002" // a '(' was never closed
+	ERR_ILLEGAL_CHAR        ErrorCode_t = "E003" // the lexer found a character it can't make a token out of
+	ERR_LITERAL_RANGE       ErrorCode_t = "E004" // a number literal doesn't fit in its type
+	ERR_LIMIT               ErrorCode_t = "E005" // the source is bigger than the interpreter's options allow
+	ERR_UNTERMINATED_STRING ErrorCode_t = "E006" // a string literal has no closing '"' on its line
+	ERR_EXPECTED_RBRACKET   ErrorCode_t = "E007" // a '[' was never closed
+	ERR_EXPECTED_RBRACE     ErrorCode_t = "E008" // a '{' was never closed
+	ERR_EXPECTED_NEXT       ErrorCode_t = "E009" // a FOR EACH loop was never closed with NEXT
+	ERR_EVALUATION          ErrorCode_t = "E100" // a node couldn't be evaluated
+	ERR_DIVISION_BY_ZERO    ErrorCode_t = "E101"
+	ERR_UNDEFINED_VAR       ErrorCode_t = "E102" // a variable was read before being set
+	ERR_UNDEFINED_FUNC      ErrorCode_t = "E103" // a call to a function that doesn't exist
+	ERR_ARG_COUNT           ErrorCode_t = "E104" // a function was called with the wrong number of arguments
+	ERR_BUILTIN             ErrorCode_t = "E105" // a builtin function failed, like SQR of a negative number
+	ERR_STEP_LIMIT          ErrorCode_t = "E106" // evaluation ran for more steps or longer than the interpreter's options allow
+	ERR_TYPE                ErrorCode_t = "E107" // an operation was given a value of the wrong type, like "a" * 2
+	ERR_INDEX               ErrorCode_t = "E108" // an index or slice bound is out of range
+	ERR_LIST_LIMIT          ErrorCode_t = "E109" // a list would be longer than the interpreter's options allow
+	ERR_DIMENSION           ErrorCode_t = "E110" // matrices don't have the sizes an operation needs, like multiplying a 2x3 by a 2x3
+	ERR_IMPORT              ErrorCode_t = "E111" // a module couldn't be found or read, or imports itself
+	ERR_MEMORY              ErrorCode_t = "E112" // a PEEK or POKE address is outside memory, or a POKE value doesn't fit in a byte
+	ERR_READ_ONLY           ErrorCode_t = "E113" // a program tried to bind a variable the host made read-only, like with FOR EACH
+	ERR_PERMISSION          ErrorCode_t = "E114" // a program tried to reach something its Permissions don't grant, like a file outside ReadRoots
+	ERR_BUILTIN_TIMEOUT     ErrorCode_t = "E115" // a call to a builtin ran longer than the time limit it was registered with
+	ERR_MEMORY_LIMIT        ErrorCode_t = "E116" // a string would be longer, or a Run would make more strings and lists, than the interpreter's options allow
+	ERR_CALL_DEPTH          ErrorCode_t = "E117" // functions called each other more deeply than the interpreter's options allow
+	ERR_STRICT              ErrorCode_t = "E201" // something strict mode doesn't allow
+)
+
+// error returned when the lexer can't make a token out of the text, for example an illegal character
+// or a number literal that is out of range. Pos is where the offending text starts.
+type LexError_t struct {
+	Code    ErrorCode_t
+	Details string
+	Pos     Position_t
+	locale  string // the language Localize translated it into, "" for English
+}
+
+func (err *LexError_t) Error() string {
+	return describe(err.locale, err.Details, err.Pos, string(err.Code))
+}
+
+// error returned when the parser can't build an AST out of the tokens.
+type ParseError_t struct {
+	Code    ErrorCode_t
+	Details string
+	Pos     Position_t
+	locale  string // the language Localize translated it into, "" for English
+}
+
+func (err *ParseError_t) Error() string {
+	return describe(err.locale, err.Details, err.Pos, string(err.Code))
+}
+
+// error returned when something goes wrong while evaluating, like a division by zero.
+type RuntimeError_t struct {
+	Code    ErrorCode_t
+	Details string
+	Pos     Position_t
+	locale  string // the language Localize translated it into, "" for English
+}
+
+func (err *RuntimeError_t) Error() string {
+	return describe(err.locale, err.Details, err.Pos, string(err.Code))
+}
+
+// machine-readable form of an error or warning, meant to be marshalled to JSON.
+// Line and Col start at 0, like in Position_t, and Offset is the same place as a byte offset into the source.
+type Diagnostic_t struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"` // "error" or "warning"
+	Message  string `json:"message"`
+	Filename string `json:"file"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Offset   int    `json:"offset"`
+	End      int    `json:"end,omitempty"` // byte offset just past the code it's about, if that's known. Only lint findings have it
+}
+
+// converts an error returned by the interpreter into diagnostics, one for each error
+// in an ErrorList. Errors that don't carry a code or position are kept with just their message.
+func Diagnostics(err error) []Diagnostic_t {
+	var errs ErrorList_t
+	if errors.As(err, &errs) {
+		ret := make([]Diagnostic_t, 0, len(errs))
+		for _, e := range errs {
+			ret = append(ret, Diagnostics(e)...)
+		}
+		return ret
+	}
+
+	var lexErr *LexError_t
+	var parseErr *ParseError_t
+	var runtimeErr *RuntimeError_t
+	if errors.As(err, &lexErr) {
+		return []Diagnostic_t{newDiagnostic(lexErr.Code, lexErr.Details, lexErr.Pos)}
+	} else if errors.As(err, &parseErr) {
+		return []Diagnostic_t{newDiagnostic(parseErr.Code, parseErr.Details, parseErr.Pos)}
+	} else if errors.As(err, &runtimeErr) {
+		return []Diagnostic_t{newDiagnostic(runtimeErr.Code, runtimeErr.Details, runtimeErr.Pos)}
+	}
+	return []Diagnostic_t{{Severity: "error", Message: err.Error()}}
+}
+
+// constructor for error Diagnostics
+func newDiagnostic(code ErrorCode_t, details string, pos Position_t) Diagnostic_t {
+	return Diagnostic_t{Code: string(code), Severity: "error", Message: details, Filename: pos.filename, Line: pos.line, Col: pos.col, Offset: pos.index}
+}
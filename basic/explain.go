@@ -0,0 +1,167 @@
+package basic
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// one step of an explanation, like "3 * 4 = 12".
+type Step_t struct {
+	Text string
+	Pos  Position_t // where the operator or call that this step works out is
+}
+
+// the steps taken to evaluate a program, in the order they happened, and its result.
+type Explanation_t struct {
+	Steps  []Step_t
+	Result *Result_t // nil if the evaluation failed
+}
+
+// evaluates src and explains how, one operation per step. Literals and variables
+// don't get steps of their own; their values show up in the steps that use them.
+// If the evaluation fails, the explanation holds the steps taken before it did.
+func Explain(src string, fn string) (*Explanation_t, error) {
+	return NewInterpreter(Options_t{}).Explain(src, fn)
+}
+
+// like Explain, using this interpreter's options and variables. A Tracer set in the
+// options still sees every node.
+func (interp *Interpreter_t) Explain(src string, fn string) (*Explanation_t, error) {
+	explainer := &explainer_t{next: interp.opts.Tracer, values: make(map[*Node_t]*Result_t)}
+	interp.opts.Tracer = explainer
+	defer func() { interp.opts.Tracer = explainer.next }()
+
+	res, err := interp.Run(src, fn)
+	return &Explanation_t{Steps: explainer.steps, Result: res}, err
+}
+
+// gets the steps as plain text, one per line, ending with the result.
+func (exp *Explanation_t) String() string {
+	var out strings.Builder
+	for _, step := range exp.Steps {
+		out.WriteString(step.Text + "\n")
+	}
+	if exp.Result != nil {
+		out.WriteString("Result: " + explainValue(exp.Result) + "\n")
+	}
+	return out.String()
+}
+
+// gets the steps as a numbered Markdown list, followed by the result in bold.
+func (exp *Explanation_t) Markdown() string {
+	var out strings.Builder
+	for i, step := range exp.Steps {
+		fmt.Fprintf(&out, "%d. `%s`\n", i+1, step.Text)
+	}
+	if exp.Result != nil {
+		fmt.Fprintf(&out, "\n**Result: %s**\n", explainValue(exp.Result))
+	}
+	return out.String()
+}
+
+// gets the steps as an HTML ordered list, followed by the result.
+func (exp *Explanation_t) HTML() string {
+	var out strings.Builder
+	out.WriteString("<ol class=\"steps\">\n")
+	for _, step := range exp.Steps {
+		fmt.Fprintf(&out, "<li><code>%s</code></li>\n", html.EscapeString(step.Text))
+	}
+	out.WriteString("</ol>\n")
+	if exp.Result != nil {
+		fmt.Fprintf(&out, "<p class=\"result\">Result: %s</p>\n", html.EscapeString(explainValue(exp.Result)))
+	}
+	return out.String()
+}
+
+// Tracer that writes a step for every operation. It has to remember each node's
+// value until its parent finishes, since that's when the operands are shown.
+type explainer_t struct {
+	next   Tracer_t // the tracer that was set before, if any
+	values map[*Node_t]*Result_t
+	steps  []Step_t
+}
+
+func (explainer *explainer_t) Enter(node *Node_t) {
+	if explainer.next != nil {
+		explainer.next.Enter(node)
+	}
+}
+
+func (explainer *explainer_t) Exit(node *Node_t, res *Result_t, err error) {
+	if explainer.next != nil {
+		explainer.next.Exit(node, res, err)
+	}
+	if err != nil {
+		return
+	}
+	explainer.values[node] = res
+
+	var text string
+	switch node.nodeType {
+	case TERM, EXPRESSION, COMPARISON, POWER, COALESCE_OP:
+		text = fmt.Sprintf("%s %s %s", explainer.operand(node.left), tokenSymbol(node.tok.tokenType), explainer.operand(node.right))
+	case COMPARISON_CHAIN: // only as far as it got before a comparison didn't hold
+		text = explainer.operand(node.args[0])
+		for i, op := range node.ops {
+			if _, ok := explainer.values[node.args[i+1]]; !ok {
+				break
+			}
+			text += fmt.Sprintf(" %s %s", tokenSymbol(op.tokenType), explainer.operand(node.args[i+1]))
+		}
+	case IN_OP:
+		text = fmt.Sprintf("%s IN %s", explainer.operand(node.left), explainer.operand(node.right))
+	case BETWEEN_OP:
+		text = fmt.Sprintf("%s BETWEEN %s AND %s", explainer.operand(node.left), explainer.operand(node.args[0]), explainer.operand(node.args[1]))
+	case UNARY_OP:
+		if node.left.nodeType == FACTOR || node.tok.tokenType == ADD {
+			return // -5 or +x isn't worth a step
+		}
+		text = tokenSymbol(node.tok.tokenType) + explainer.operand(node.left)
+	case CALL:
+		args := make([]string, len(node.args))
+		for i, arg := range node.args {
+			value, ok := explainer.values[arg]
+			if !ok { // a special form like LAMBDA, which doesn't evaluate its arguments
+				return
+			}
+			args[i] = explainValue(value)
+		}
+		text = fmt.Sprintf("%s(%s)", node.tok.strVal, strings.Join(args, ", "))
+	default:
+		return
+	}
+	explainer.steps = append(explainer.steps, Step_t{Text: text + " = " + explainValue(res), Pos: node.tok.pos})
+}
+
+// gets how an operand is written in a step. Negative numbers are bracketed so
+// "3 - -4" reads as "3 - (-4)".
+func (explainer *explainer_t) operand(node *Node_t) string {
+	value := explainValue(explainer.values[node])
+	if strings.HasPrefix(value, "-") {
+		return "(" + value + ")"
+	}
+	return value
+}
+
+// gets a value the way a person would write it: no trailing zeros on floats, and strings
+// in quotes so they don't look like numbers.
+func explainValue(res *Result_t) string {
+	switch res.ResultType {
+	case INTEGER:
+		return strconv.FormatInt(res.Ires, 10)
+	case FLOATING:
+		return strconv.FormatFloat(res.Fres, 'f', -1, 64)
+	case STRING_RESULT:
+		return quoteString(res.Sres)
+	case FUNCTION_RESULT, DICT_RESULT, MATRIX_RESULT, SYMBOLIC_RESULT, NIL_RESULT, RECORD_RESULT:
+		return res.ValueString()
+	default:
+		elems := make([]string, len(res.Lres))
+		for i, elem := range res.Lres {
+			elems[i] = explainValue(elem)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	}
+}
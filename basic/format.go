@@ -0,0 +1,200 @@
+package basic
+
+import (
+	"strconv"
+	"strings"
+)
+
+// pretty-prints the AST in the canonical style: one statement per line, loop bodies indented,
+// a space on each side of binary operators and only the parentheses needed to keep the tree the same.
+// Parsing the output gives back the same AST, so formatting is idempotent.
+func Format(node *Node_t) string {
+	if node.nodeType == STATEMENTS {
+		lines := make([]string, len(node.statements))
+		for i, stmt := range node.statements {
+			lines[i] = Format(stmt)
+		}
+		return strings.Join(lines, "\n")
+	} else if node.nodeType == FOR_EACH {
+		lines := []string{"FOR EACH " + node.tok.strVal + " IN " + formatExpr(node.left)}
+		for _, stmt := range node.statements {
+			for _, line := range strings.Split(Format(stmt), "\n") {
+				lines = append(lines, FORMAT_INDENT+line)
+			}
+		}
+		return strings.Join(append(lines, "NEXT "+node.tok.strVal), "\n")
+	} else if node.nodeType == OPTION {
+		return "OPTION " + strings.ToUpper(node.ops[0].strVal) + " " + strings.ToUpper(node.ops[1].strVal)
+	} else if node.nodeType == IMPORT && len(node.ops) > 0 {
+		return "IMPORT " + quoteString(node.tok.strVal) + " AS " + node.ops[0].strVal
+	} else if node.nodeType == IMPORT {
+		return "IMPORT " + quoteString(node.tok.strVal)
+	} else if node.nodeType == TYPE_DEF {
+		return formatDeclaration(node, "TYPE")
+	} else if node.nodeType == ENUM_DEF {
+		return formatDeclaration(node, "ENUM")
+	} else if node.nodeType == UNPACK {
+		return unpackNames(node) + " = " + formatExpr(node.left)
+	} else if node.nodeType == POKE {
+		return "POKE " + formatExpr(node.args[0]) + ", " + formatExpr(node.args[1])
+	} else if node.nodeType == YIELD && node.left != nil {
+		return "YIELD " + formatExpr(node.left)
+	} else if node.nodeType == YIELD {
+		return "YIELD"
+	} else if node.nodeType == ON_TIMER {
+		return "ON TIMER(" + formatExpr(node.args[0]) + ") GOSUB " + formatExpr(node.args[1])
+	} else if node.nodeType == COMMAND {
+		args := make([]string, len(node.args))
+		for i, arg := range node.args {
+			args[i] = formatExpr(arg)
+		}
+		return strings.TrimSpace(strings.ToUpper(node.tok.strVal) + " " + strings.Join(args, ", "))
+	}
+	return formatExpr(node)
+}
+
+// what the body of a loop is indented by.
+const FORMAT_INDENT = "    "
+
+// formats a single expression.
+func formatExpr(node *Node_t) string {
+	switch node.nodeType {
+	case FACTOR:
+		if node.tok.tokenType == INT {
+			return strconv.FormatInt(node.tok.intVal, 10)
+		} else if node.tok.tokenType == STRING {
+			return quoteString(node.tok.strVal)
+		}
+		// the shortest form that reads back the same, like 1e+300 rather than 300 digits
+		ret := strconv.FormatFloat(node.tok.floatVal, 'g', -1, 64)
+		if !strings.ContainsAny(ret, ".e") { // keep it a float literal when it's read back
+			ret += ".0"
+		}
+		return ret
+	case VAR_ACCESS:
+		return node.tok.strVal
+	case PARAM_ACCESS:
+		return "?" + node.tok.strVal
+	case CALL:
+		args := make([]string, len(node.args))
+		for i, arg := range node.args {
+			args[i] = formatExpr(arg)
+		}
+		if node.left != nil { // a function from an index, like m["f"](x)
+			return formatTarget(node.left) + "(" + strings.Join(args, ", ") + ")"
+		}
+		return node.tok.strVal + "(" + strings.Join(args, ", ") + ")"
+	case LIST:
+		elems := make([]string, len(node.args))
+		for i, elem := range node.args {
+			elems[i] = formatExpr(elem)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case DICT:
+		entries := make([]string, 0, len(node.args)/2)
+		for i := 0; i < len(node.args); i += 2 {
+			entries = append(entries, formatExpr(node.args[i])+": "+formatExpr(node.args[i+1]))
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case INDEX:
+		return formatTarget(node.left) + "[" + formatExpr(node.right) + "]"
+	case SAFE_ACCESS:
+		return formatTarget(node.left) + "?." + node.ops[0].strVal
+	case FIELD_ACCESS:
+		return formatTarget(node.left) + "." + node.ops[0].strVal
+	case ANNOTATION:
+		return formatTarget(node.left) + " AS " + node.ops[0].strVal
+	case MATCH_EXPR:
+		return formatMatch(node)
+	case REST_PARAM:
+		return formatExpr(node.left) + "..."
+	case SLICE:
+		bounds := make([]string, 2)
+		for i, bound := range node.args {
+			if bound != nil {
+				bounds[i] = formatExpr(bound)
+			}
+		}
+		return formatTarget(node.left) + "[" + bounds[0] + ":" + bounds[1] + "]"
+	case COMPARISON_CHAIN:
+		parts := make([]string, 0, 2*len(node.args)-1)
+		for i, operand := range node.args {
+			if i > 0 {
+				parts = append(parts, tokenSymbol(node.ops[i-1].tokenType))
+			}
+			if precedence(operand) == precedence(node) {
+				parts = append(parts, "("+formatExpr(operand)+")")
+			} else {
+				parts = append(parts, formatExpr(operand))
+			}
+		}
+		return strings.Join(parts, " ")
+	case IN_OP:
+		return formatOperand(node.left) + " IN " + formatOperand(node.right)
+	case BETWEEN_OP:
+		return formatOperand(node.left) + " BETWEEN " + formatOperand(node.args[0]) + " AND " + formatOperand(node.args[1])
+	case UNARY_OP:
+		operand := formatExpr(node.left)
+		if precedence(node.left) > 0 && node.left.nodeType != POWER { // -x^2 is already -(x^2)
+			operand = "(" + operand + ")"
+		}
+		return tokenSymbol(node.tok.tokenType) + operand
+	case POWER: // written without spaces, like x^2. Powers group to the right, so it's the left side that needs parentheses at the same level
+		left := formatExpr(node.left)
+		if precedence(node.left) > 0 || node.left.nodeType == UNARY_OP {
+			left = "(" + left + ")"
+		}
+		right := formatExpr(node.right)
+		if precedence(node.right) > 0 && precedence(node.right) < precedence(node) {
+			right = "(" + right + ")"
+		}
+		return left + "^" + right
+	case TERM, EXPRESSION, COMPARISON, COALESCE_OP:
+		left := formatExpr(node.left)
+		if precedence(node.left) > 0 && (precedence(node.left) < precedence(node) || precedence(node.left) == 1) { // comparisons in a row make a chain
+			left = "(" + left + ")"
+		}
+		right := formatExpr(node.right)
+		if precedence(node.right) > 0 && precedence(node.right) <= precedence(node) { // operators group to the left, so the right side needs them even at the same level
+			right = "(" + right + ")"
+		}
+		return left + " " + tokenSymbol(node.tok.tokenType) + " " + right
+	default:
+		return node.String()
+	}
+}
+
+// formats an operand of IN or BETWEEN, which only take operands that bind tighter than comparisons.
+func formatOperand(node *Node_t) string {
+	if precedence(node) == 1 {
+		return "(" + formatExpr(node) + ")"
+	}
+	return formatExpr(node)
+}
+
+// formats what an index or slice applies to. Operators bind looser than [], so an
+// operation there needs parentheses: (a + b)[0], (-a)[0].
+func formatTarget(node *Node_t) string {
+	if precedence(node) > 0 || node.nodeType == UNARY_OP || node.nodeType == ANNOTATION {
+		return "(" + formatExpr(node) + ")"
+	}
+	return formatExpr(node)
+}
+
+// gets how tightly a binary operation binds (higher binds tighter). 0 for anything that isn't a binary operation.
+func precedence(node *Node_t) int {
+	switch node.nodeType {
+	case COMPARISON, COMPARISON_CHAIN, IN_OP, BETWEEN_OP:
+		return 1
+	case COALESCE_OP:
+		return 2
+	case EXPRESSION:
+		return 3
+	case TERM:
+		return 4
+	case POWER:
+		return 5
+	default:
+		return 0
+	}
+}
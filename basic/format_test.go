@@ -0,0 +1,30 @@
+package basic
+
+import "testing"
+
+func TestFormatFloats(t *testing.T) {
+	for src, want := range map[string]string{
+		"2.5":                        "2.5",
+		"3.0":                        "3.0",
+		"100000.0":                   "100000.0",
+		"1e+300":                     "1e+300",
+		"1.5e-07":                    "1.5e-07",
+		"0.00000015":                 "1.5e-07",
+		"123456789012345678901234.0": "1.2345678901234569e+23",
+	} {
+		node, err := Parse(src, t.Name())
+		if err != nil {
+			t.Fatalf("%q: %s", src, err)
+		}
+		got := Format(node)
+		if got != want {
+			t.Errorf("Format(%q): got %q, want %q", src, got, want)
+		}
+		reread, err := Parse(got, t.Name())
+		if err != nil {
+			t.Errorf("%q doesn't read back: %s", got, err)
+		} else if reread.tok.tokenType != FLOAT || reread.tok.floatVal != node.tok.floatVal {
+			t.Errorf("%q reads back as %s, not the float %v", got, reread, node.tok.floatVal)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package basic
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// a function as a value, so it can be passed to builtins like MAP. Either a builtin (named
+// without brackets, like ABS) or a lambda made with LAMBDA(x, y, body).
+type Function_t struct {
+	Name    string    // the builtin's name, or "LAMBDA"
+	Arity   int       // number of arguments it takes, negative for any number
+	Params  []Param_t // the names of its parameters and their defaults, nil if it only takes arguments by position
+	Doc     string    // what HELP says about it: a lambda's docstring, or a builtin's doc
+	Returns string    // the type its calls have to give back, from an annotation like LAMBDA(...) AS INT. "" if it hasn't got one
+	call    func(args []*Result_t) (*Result_t, error)
+	source  *Node_t       // the LAMBDA call a lambda was made by, or the TYPE a constructor was, so a Snapshot can save it. nil for builtins
+	record  *RecordType_t // the type a constructor makes records of, nil for other functions
+}
+
+// calls the function with already evaluated arguments. Parameters with defaults can be left off the end.
+func (fn *Function_t) Call(args []*Result_t) (*Result_t, error) {
+	args = withDefaults(args, fn.Params)
+	if fn.Arity >= 0 && len(args) != fn.Arity {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", fn.Name, fn.Arity, len(args))
+	}
+	return fn.call(args)
+}
+
+// makes a function Result
+func NewFunction(fn *Function_t) *Result_t {
+	return &Result_t{ResultType: FUNCTION_RESULT, Fnres: fn}
+}
+
+// calls that need the interpreter itself, mostly because they don't evaluate their
+// arguments first, keyed by upper case name.
+var specialForms map[string]func(interp *Interpreter_t, node *Node_t) (*Result_t, error)
+
+func init() {
+	specialForms = map[string]func(interp *Interpreter_t, node *Node_t) (*Result_t, error){
+		"PLOT":      (*Interpreter_t).plotCall,     // evaluates its first argument once per point
+		"LAMBDA":    (*Interpreter_t).lambdaCall,   // doesn't evaluate anything until it's called
+		"RANGE":     (*Interpreter_t).rangeCall,    // checks the interpreter's MaxListLength
+		"IIF":       (*Interpreter_t).iifCall,      // only evaluates the branch it takes
+		"ROUND":     (*Interpreter_t).roundCall,    // breaks ties the way the interpreter's options say
+		"PEEK":      (*Interpreter_t).peekCall,     // reads the interpreter's memory
+		"HELP":      (*Interpreter_t).helpCall,     // looks up variables by name
+		"VARS":      (*Interpreter_t).varsCall,     // lists the interpreter's variables
+		"FUNCS":     (*Interpreter_t).funcsCall,    // lists the interpreter's functions
+		"DESCRIBE":  (*Interpreter_t).describeCall, // looks up variables by name
+		"ENVIRON$":  (*Interpreter_t).environCall,  // checks the interpreter's Permissions
+		"HTTPGET$":  (*Interpreter_t).httpGetCall,  // checks the interpreter's Permissions and limits
+		"HTTPPOST$": (*Interpreter_t).httpPostCall, // checks the interpreter's Permissions and limits
+		"DBOPEN":    (*Interpreter_t).dbOpenCall,   // checks the interpreter's Permissions and keeps the database
+		"DBQUERY":   (*Interpreter_t).dbQueryCall,  // uses a database the interpreter keeps
+		"DBCLOSE":   (*Interpreter_t).dbCloseCall,  // closes a database the interpreter keeps
+	}
+}
+
+// evaluates LAMBDA(param, ..., body) into a function value. Calling it sets the parameters
+// as variables, evaluates the body and puts the variables back the way they were. Parameters
+// can have defaults, like LAMBDA(x, scale = 1, x * scale). A string before the parameters is
+// its docstring, for HELP, like LAMBDA("the square of x", x, x * x). Parameters can be annotated
+// with the type their arguments have to be, like LAMBDA(x AS INT, x * x).
+func (interp *Interpreter_t) lambdaCall(node *Node_t) (*Result_t, error) {
+	if len(node.args) == 0 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s needs at least a body", node.tok.strVal), Pos: node.tok.pos}
+	}
+	fnParams, names, err := interp.lambdaParams(node)
+	if err != nil {
+		return nil, err
+	}
+	params := make([]string, len(names))
+	for i, name := range names {
+		params[i] = name.strVal
+	}
+	body := node.args[len(node.args)-1]
+	doc := ""
+	if hasDocstring(node) {
+		doc = node.args[0].tok.strVal
+	}
+
+	arity := len(params)
+	if arity > 0 && fnParams[arity-1].Rest {
+		arity = -1 // Call packs the arguments into one per parameter
+	}
+	return NewFunction(&Function_t{Name: "LAMBDA", Arity: arity, Params: fnParams, Doc: doc, source: node, call: func(args []*Result_t) (*Result_t, error) {
+		if len(args) != len(params) {
+			return nil, fmt.Errorf("takes at least %d argument(s), got %d", len(params)-1, len(args))
+		}
+		if err := interp.enterCall(); err != nil {
+			return nil, err
+		}
+		defer func() { interp.depth-- }()
+		args, err := annotatedArgs(fnParams, args)
+		if err != nil {
+			return nil, err
+		}
+		for i, param := range params {
+			if err := interp.bindable(param, names[i]); err != nil {
+				return nil, err
+			}
+		}
+		old := make([]*Result_t, len(params))
+		for i, param := range params {
+			old[i] = interp.vars[param]
+			interp.vars[param] = args[i]
+			interp.define(param, names[i].pos)
+		}
+		defer func() {
+			for i, param := range params {
+				if old[i] == nil {
+					interp.undefine(param)
+				} else {
+					interp.vars[param] = old[i]
+				}
+			}
+		}()
+		return body.evaluate(interp)
+	}}), nil
+}
+
+// evaluates IIF(condition, then, else): then if the condition is true, else otherwise.
+// The other branch isn't evaluated at all, so IIF(x = 0, 0, 1 / x) can't divide by zero.
+func (interp *Interpreter_t) iifCall(node *Node_t) (*Result_t, error) {
+	if len(node.args) != 3 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 3 argument(s), got %d", node.tok.strVal, len(node.args)), Pos: node.tok.pos}
+	}
+	cond, err := node.args[0].evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := truthy(cond)
+	if err != nil {
+		return nil, builtinError(node.tok, err)
+	}
+	if ok {
+		return node.args[1].evaluate(interp)
+	}
+	return node.args[2].evaluate(interp)
+}
+
+// gets a builtin as a function value, for when its name is used without brackets.
+func (interp *Interpreter_t) builtinValue(name string) (*Result_t, bool) {
+	builtinsMu.RLock()
+	builtin, ok := builtins[strings.ToUpper(name)]
+	doc := builtinDocs[strings.ToUpper(name)]
+	builtinsMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return NewFunction(&Function_t{Name: strings.ToUpper(name), Arity: builtin.arity, Params: builtin.params, Doc: doc, call: interp.sized(interp.timed(builtin.timeout, interp.collated(strings.ToUpper(name), interp.angled(strings.ToUpper(name), builtin.fn))))}), true
+}
+
+// calls the function an indexed CALL node gets from its dict or list, like m["f"](x).
+func (node *Node_t) callIndexed(interp *Interpreter_t) (*Result_t, error) {
+	callee, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	if callee.ResultType != FUNCTION_RESULT {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("%s is a %s, not a function", node.tok.strVal, callee.ResultType), Pos: node.tok.pos}
+	}
+	args, err := interp.callArgs(node, callee.Fnres.Params)
+	if err != nil {
+		return nil, err
+	}
+	return callValue(node.tok, callee.Fnres, args)
+}
+
+// deepest LAMBDA calls can go, one inside another, unless Options_t says otherwise. Far short
+// of running out of Go stack, which kills the whole process rather than failing the Run.
+const DEFAULT_MAX_CALL_DEPTH = 10000
+
+// counts a LAMBDA call starting, failing if that makes more than MaxCallDepth going at once,
+// like with endless recursion. The caller takes it off interp.depth once the call's done.
+func (interp *Interpreter_t) enterCall() error {
+	limit := interp.opts.MaxCallDepth
+	if limit <= 0 {
+		limit = DEFAULT_MAX_CALL_DEPTH
+	}
+	if interp.depth >= limit {
+		return &limitError_t{code: ERR_CALL_DEPTH, details: fmt.Sprintf("calls went more than the limit of %d deep", limit)}
+	}
+	interp.depth++
+	return nil
+}
+
+// calls a function value stored in a variable, turning what goes wrong into a RuntimeError at the call.
+func callValue(name Token_t, fn *Function_t, args []*Result_t) (*Result_t, error) {
+	if fn.Arity >= 0 && len(args) != fn.Arity {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes %d argument(s), got %d", name.strVal, fn.Arity, len(args)), Pos: name.pos}
+	}
+	res, err := fn.call(args)
+	if err != nil {
+		return nil, builtinError(name, err)
+	}
+	return res, nil
+}
+
+// decides whether a value counts as true: any number but 0.
+func truthy(res *Result_t) (bool, error) {
+	if !res.IsNumber() {
+		return false, typeErrorf("a value of type %s can't be used as a condition", res.ResultType)
+	}
+	return res.Fres != 0, nil // the float value is set for integers too
+}
+
+// gets the arguments of a higher-order builtin: a function then a list.
+func fnAndList(fnArg *Result_t, listArg *Result_t) (*Function_t, []*Result_t, error) {
+	if fnArg.ResultType != FUNCTION_RESULT {
+		return nil, nil, typeErrorf("the first argument has to be a function, not %s", fnArg.ResultType)
+	} else if listArg.ResultType != LIST_RESULT {
+		return nil, nil, typeErrorf("the last argument has to be a list, not %s", listArg.ResultType)
+	}
+	return fnArg.Fnres, listArg.Lres, nil
+}
+
+// the higher-order list functions.
+func init() {
+	RegisterBuiltin("MAP", 2, func(args []*Result_t) (*Result_t, error) { // MAP(fn, list): fn of every element
+		fn, list, err := fnAndList(args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		ret := make([]*Result_t, len(list))
+		for i, elem := range list {
+			ret[i], err = fn.Call([]*Result_t{elem})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return NewList(ret), nil
+	})
+	RegisterBuiltin("FILTER", 2, func(args []*Result_t) (*Result_t, error) { // FILTER(fn, list): the elements fn is true for
+		fn, list, err := fnAndList(args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		ret := make([]*Result_t, 0, len(list))
+		for _, elem := range list {
+			keep, err := fn.Call([]*Result_t{elem})
+			if err != nil {
+				return nil, err
+			}
+			ok, err := truthy(keep)
+			if err != nil {
+				return nil, err
+			} else if ok {
+				ret = append(ret, elem)
+			}
+		}
+		return NewList(ret), nil
+	})
+	RegisterBuiltin("FOLD", 3, func(args []*Result_t) (*Result_t, error) { // FOLD(fn, init, list): fn(...fn(fn(init, a), b)..., z)
+		fn, list, err := fnAndList(args[0], args[2])
+		if err != nil {
+			return nil, err
+		}
+		return fold(fn, args[1], list)
+	})
+	RegisterBuiltin("REDUCE", 2, func(args []*Result_t) (*Result_t, error) { // REDUCE(fn, list): FOLD starting from the first element
+		fn, list, err := fnAndList(args[0], args[1])
+		if err != nil {
+			return nil, err
+		} else if len(list) == 0 {
+			return nil, fmt.Errorf("can't reduce an empty list, use FOLD with a starting value")
+		}
+		return fold(fn, list[0], list[1:])
+	})
+}
+
+// combines the elements of list into acc with fn, from left to right.
+func fold(fn *Function_t, acc *Result_t, list []*Result_t) (*Result_t, error) {
+	for _, elem := range list {
+		var err error
+		acc, err = fn.Call([]*Result_t{acc, elem})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
+
+// calls a BASIC function from Go, like interp.Call("AREA", 3, 4), so a host can use functions
+// a program defines as entry points. name is a variable holding a function, like one from the
+// prelude, a builtin, or a function in a dict such as an imported module, written "shapes.AREA".
+// Arguments are Go numbers, strings, slices and maps, converted the way EvalWith converts
+// variables, or *Result_t values passed as they are. The call counts against the interpreter's limits.
+func (interp *Interpreter_t) Call(name string, args ...interface{}) (*Result_t, error) {
+	if !interp.preluded && !interp.opts.NoPrelude {
+		interp.preluded = true
+		if err := interp.loadPrelude(); err != nil {
+			return nil, Localize(err, interp.locale)
+		}
+	}
+	fn, err := interp.lookupFunction(name)
+	if err != nil {
+		return nil, err
+	} else if fn.Arity >= 0 && len(args) != fn.Arity {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", name, fn.Arity, len(args))
+	}
+	values := make([]*Result_t, len(args))
+	for i, arg := range args {
+		if value, ok := arg.(*Result_t); ok {
+			values[i] = value
+		} else if values[i], err = toResult(reflect.ValueOf(arg)); err != nil {
+			return nil, fmt.Errorf("%s: argument %d: %w", name, i+1, err)
+		}
+	}
+	interp.resetLimits(time.Now())
+	res, err := fn.Call(values)
+	if err != nil {
+		var runtimeErr *RuntimeError_t
+		if !errors.As(err, &runtimeErr) {
+			err = fmt.Errorf("%s: %w", name, err)
+		}
+		return nil, Localize(err, interp.locale)
+	}
+	return res, nil
+}
+
+// finds the function Call calls: a variable (or a host variable) holding one, a builtin, or
+// one inside a dict, with the keys after dots.
+func (interp *Interpreter_t) lookupFunction(name string) (*Function_t, error) {
+	path := strings.Split(name, ".")
+	value, ok, err := interp.hostValue(path[0], Token_t{strVal: path[0]})
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		value, ok = interp.vars[path[0]]
+	}
+	if !ok && len(path) == 1 {
+		value, ok = interp.builtinValue(path[0])
+	}
+	if !ok {
+		return nil, fmt.Errorf("function %s is not defined", path[0])
+	}
+	for i, key := range path[1:] {
+		if value.ResultType != DICT_RESULT {
+			return nil, fmt.Errorf("%s is a %s, not a dict", strings.Join(path[:i+1], "."), value.ResultType)
+		} else if value, ok = value.Dres.Get(key); !ok {
+			return nil, fmt.Errorf("%s has no key %s", strings.Join(path[:i+1], "."), key)
+		}
+	}
+	if value.ResultType != FUNCTION_RESULT {
+		return nil, fmt.Errorf("%s is a %s, not a function", name, value.ResultType)
+	}
+	return value.Fnres, nil
+}
@@ -0,0 +1,61 @@
+package basic
+
+import "testing"
+
+func TestCallDepth(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		opts Options_t
+		want ErrorCode_t
+	}{
+		{`MAP(LAMBDA(f, f(f)), [LAMBDA(f, f(f))])`, Options_t{}, ERR_CALL_DEPTH},
+		{`f, g = [LAMBDA(f, MAP(f, [f])), 0]` + "\n" + `f(f)`, Options_t{}, ERR_CALL_DEPTH},
+		{`f, g = [LAMBDA(f, n, IIF(n = 0, 0, f(f, n - 1))), 0]` + "\n" + `f(f, 500)`, Options_t{}, ""},
+		{`f, g = [LAMBDA(f, n, IIF(n = 0, 0, f(f, n - 1))), 0]` + "\n" + `f(f, 500)`, Options_t{MaxCallDepth: 100}, ERR_CALL_DEPTH},
+	} {
+		if got := runErrorCode(t, test.opts, test.src); got != test.want {
+			t.Errorf("%q: got error code %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestCallDepthUnwinds(t *testing.T) {
+	interp := NewInterpreter(Options_t{MaxCallDepth: 100})
+	if _, err := interp.Run(`MAP(LAMBDA(f, f(f)), [LAMBDA(f, f(f))])`, t.Name()); err == nil {
+		t.Fatal("endless recursion didn't fail")
+	} else if interp.depth != 0 {
+		t.Errorf("left the depth at %d", interp.depth)
+	}
+	if _, err := interp.Run(`f, g = [LAMBDA(f, n, IIF(n = 0, 0, f(f, n - 1))), 0]`+"\n"+`f(f, 90)`, t.Name()); err != nil {
+		t.Errorf("the next Run: %s", err)
+	}
+}
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	for src, want := range map[string]string{
+		`MAP(LAMBDA(x, x * x), [1, 2, 3])`:                    `[1, 4, 9]`,
+		`MAP(LAMBDA(x, x), [])`:                               `[]`,
+		`FILTER(LAMBDA(x, x > 1), [3, 1, 2])`:                 `[3, 2]`,
+		`FOLD(LAMBDA(acc, x, acc + x), 10, [1, 2, 3])`:        `16`,
+		`FOLD(LAMBDA(acc, x, acc + x), "", ["a", "b"])`:       `ab`,
+		`REDUCE(LAMBDA(a, b, IIF(a > b, a, b)), [3, 9, 4])`:   `9`,
+		`MAP(ABS, [-1, 2])`:                                   `[1, 2]`,
+		`f, n = [LAMBDA(x, x + n), 5]` + "\n" + `MAP(f, [1])`: `[6]`,
+	} {
+		if got := runValue(t, src); got != want {
+			t.Errorf("%q: got %s, want %s", src, got, want)
+		}
+	}
+	for src, want := range map[string]ErrorCode_t{
+		`MAP(1, [1])`:                     ERR_TYPE,
+		`MAP(LAMBDA(x, x), 1)`:            ERR_TYPE,
+		`MAP(LAMBDA(x, y, x), [1])`:       ERR_BUILTIN, // MAP failed, calling it
+		`FILTER(LAMBDA(x, "yes"), [1])`:   ERR_TYPE,
+		`REDUCE(LAMBDA(a, b, a + b), [])`: ERR_BUILTIN,
+		`MAP(LAMBDA(x, 1 / x), [1, 0])`:   ERR_DIVISION_BY_ZERO,
+	} {
+		if got := runErrorCode(t, Options_t{}, src); got != want {
+			t.Errorf("%q: got error code %q, want %q", src, got, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package basic
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// size of the canvas drawn on before any SCREEN statement, like a classic 320x200 graphics mode.
+const (
+	DEFAULT_SCREEN_WIDTH  = 320
+	DEFAULT_SCREEN_HEIGHT = 200
+	MAX_SCREEN_SIZE       = 4096  // widest or tallest canvas SCREEN will make
+	MAX_COORDINATE        = 65536 // furthest from 0 a coordinate can be, which keeps lines and circles quick to draw
+)
+
+// the 16 classic colours COLOR picks from when it's given one number.
+var palette = [16]color.RGBA{
+	{0, 0, 0, 255}, {0, 0, 170, 255}, {0, 170, 0, 255}, {0, 170, 170, 255},
+	{170, 0, 0, 255}, {170, 0, 170, 255}, {170, 85, 0, 255}, {170, 170, 170, 255},
+	{85, 85, 85, 255}, {85, 85, 255, 255}, {85, 255, 85, 255}, {85, 255, 255, 255},
+	{255, 85, 85, 255}, {255, 85, 255, 255}, {255, 255, 85, 255}, {255, 255, 255, 255},
+}
+
+// a shape drawn on the canvas, kept so the drawing can be written as an SVG as well.
+type shape_t struct {
+	kind   string // the SVG element: rect for a PSET, line or circle
+	coords []int  // x and y for a rect, x1, y1, x2 and y2 for a line and x, y and radius for a circle
+	color  color.RGBA
+}
+
+// the graphics statements. All but SCREEN draw, and make a canvas of the default size if there isn't one yet.
+func init() {
+	graphics := map[string]command_t{
+		"SCREEN": {arities: []int{2}, run: func(interp *Interpreter_t, coords []float64) error { // SCREEN width, height: a new, black canvas
+			args := ints(coords)
+			if args[0] < 1 || args[1] < 1 || args[0] > MAX_SCREEN_SIZE || args[1] > MAX_SCREEN_SIZE {
+				return fmt.Errorf("size %dx%d isn't between 1x1 and %dx%d", args[0], args[1], MAX_SCREEN_SIZE, MAX_SCREEN_SIZE)
+			}
+			interp.canvas = image.NewRGBA(image.Rect(0, 0, args[0], args[1]))
+			draw.Draw(interp.canvas, interp.canvas.Bounds(), &image.Uniform{C: palette[0]}, image.Point{}, draw.Src)
+			interp.shapes = nil
+			interp.turtle = turtle_t{x: float64(args[0]) / 2, y: float64(args[1]) / 2}
+			return nil
+		}},
+		"COLOR": {draws: true, arities: []int{1, 3}, run: func(interp *Interpreter_t, coords []float64) error { // COLOR index or COLOR red, green, blue
+			args := ints(coords)
+			if len(args) == 1 {
+				if args[0] < 0 || args[0] >= len(palette) {
+					return fmt.Errorf("colour %d isn't between 0 and %d", args[0], len(palette)-1)
+				}
+				interp.pen = palette[args[0]]
+				return nil
+			}
+			for _, arg := range args {
+				if arg < 0 || arg > 255 {
+					return fmt.Errorf("colour component %d isn't between 0 and 255", arg)
+				}
+			}
+			interp.pen = color.RGBA{uint8(args[0]), uint8(args[1]), uint8(args[2]), 255}
+			return nil
+		}},
+		"PSET": {draws: true, arities: []int{2}, run: func(interp *Interpreter_t, coords []float64) error { // PSET x, y
+			args := ints(coords)
+			interp.plot(args[0], args[1])
+			interp.shapes = append(interp.shapes, shape_t{kind: "rect", coords: args, color: interp.penColor()})
+			return nil
+		}},
+		"LINE": {draws: true, arities: []int{4}, run: func(interp *Interpreter_t, coords []float64) error { // LINE x1, y1, x2, y2
+			args := ints(coords)
+			interp.line(args[0], args[1], args[2], args[3])
+			return nil
+		}},
+		"CIRCLE": {draws: true, arities: []int{3}, run: func(interp *Interpreter_t, coords []float64) error { // CIRCLE x, y, radius
+			args := ints(coords)
+			if args[2] < 0 {
+				return fmt.Errorf("radius %d is negative", args[2])
+			}
+			interp.circle(args[0], args[1], args[2])
+			interp.shapes = append(interp.shapes, shape_t{kind: "circle", coords: args, color: interp.penColor()})
+			return nil
+		}},
+		"FORWARD": {draws: true, arities: []int{1}, run: func(interp *Interpreter_t, args []float64) error { // FORWARD distance: moves the turtle, drawing if its pen is down
+			return interp.forward(args[0])
+		}},
+		"TURN": {draws: true, arities: []int{1}, run: func(interp *Interpreter_t, args []float64) error { // TURN degrees: clockwise, or anticlockwise if negative
+			interp.turtle.heading = math.Mod(interp.turtle.heading+args[0], 360)
+			return nil
+		}},
+		"PENUP": {draws: true, arities: []int{0}, run: func(interp *Interpreter_t, args []float64) error { // PENUP: the turtle moves without drawing
+			interp.turtle.penUp = true
+			return nil
+		}},
+		"PENDOWN": {draws: true, arities: []int{0}, run: func(interp *Interpreter_t, args []float64) error { // PENDOWN: the turtle draws as it moves again
+			interp.turtle.penUp = false
+			return nil
+		}},
+	}
+	for name, graphic := range graphics {
+		commands[name] = graphic
+	}
+}
+
+// rounds the numbers a graphics statement was given to whole pixels.
+func ints(args []float64) []int {
+	ret := make([]int, len(args))
+	for i, arg := range args {
+		ret[i] = int(math.Round(arg))
+	}
+	return ret
+}
+
+// gets what the program has drawn, or nil if it hasn't drawn anything. Embedders can save it with
+// image/png, or draw on it before a Run. It keeps its contents from one Run to the next.
+func (interp *Interpreter_t) Canvas() *image.RGBA {
+	return interp.canvas
+}
+
+// sets a pixel to the pen colour. Pixels off the canvas are ignored, so shapes are clipped at its edges.
+func (interp *Interpreter_t) plot(x int, y int) {
+	if (image.Point{X: x, Y: y}).In(interp.canvas.Bounds()) {
+		interp.canvas.SetRGBA(x, y, interp.penColor())
+	}
+}
+
+// gets the colour drawing uses: white until a COLOR statement changes it.
+func (interp *Interpreter_t) penColor() color.RGBA {
+	if interp.pen == (color.RGBA{}) {
+		return palette[15]
+	}
+	return interp.pen
+}
+
+// draws a line with Bresenham's algorithm, both ends included.
+func (interp *Interpreter_t) line(x0 int, y0 int, x1 int, y1 int) {
+	interp.shapes = append(interp.shapes, shape_t{kind: "line", coords: []int{x0, y0, x1, y1}, color: interp.penColor()})
+	dx, dy := abs(int64(x1-x0)), -abs(int64(y1-y0))
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	diff := dx + dy
+	for {
+		interp.plot(x0, y0)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		twice := 2 * diff
+		if twice >= dy {
+			diff += dy
+			x0 += sx
+		}
+		if twice <= dx {
+			diff += dx
+			y0 += sy
+		}
+	}
+}
+
+// draws the outline of a circle with the midpoint algorithm, one octant at a time.
+func (interp *Interpreter_t) circle(cx int, cy int, r int) {
+	x, y, diff := r, 0, 1-r
+	for x >= y {
+		for _, p := range [][2]int{{x, y}, {y, x}, {-y, x}, {-x, y}, {-x, -y}, {-y, -x}, {y, -x}, {x, -y}} {
+			interp.plot(cx+p[0], cy+p[1])
+		}
+		y++
+		if diff < 0 {
+			diff += 2*y + 1
+		} else {
+			x--
+			diff += 2*(y-x) + 1
+		}
+	}
+}
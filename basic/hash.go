@@ -0,0 +1,87 @@
+package basic
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"math"
+)
+
+// gets a hash of the value that's the same every time, in every process, for values that are
+// Equal, so host programs can use it to dedupe or memoize results. Numbers hash by value, so 2
+// and 2.0 hash the same, and a dict's hash doesn't depend on the order of its keys. LAMBDAs all
+// hash the same, as they're only equal to themselves.
+func (res *Result_t) Hash() uint64 {
+	h := fnv.New64a()
+	writeHash(h, res)
+	return h.Sum64()
+}
+
+// writes what the hash of a value is made from.
+func writeHash(w io.Writer, res *Result_t) {
+	if res.IsNumber() {
+		w.Write([]byte{'n'})
+		writeFloat(w, res.Fres)
+		return
+	}
+	w.Write([]byte(res.ResultType.String()))
+	switch res.ResultType {
+	case STRING_RESULT:
+		writeString(w, res.Sres)
+	case LIST_RESULT:
+		writeUint(w, uint64(len(res.Lres)))
+		for _, elem := range res.Lres {
+			writeHash(w, elem)
+		}
+	case DICT_RESULT: // the entries' hashes are added up, so the order doesn't matter
+		var sum uint64
+		for _, key := range res.Dres.keys {
+			h := fnv.New64a()
+			writeString(h, key)
+			writeHash(h, res.Dres.values[key])
+			sum += h.Sum64()
+		}
+		writeUint(w, uint64(len(res.Dres.keys)))
+		writeUint(w, sum)
+	case MATRIX_RESULT:
+		writeUint(w, uint64(res.Mres.rows))
+		writeUint(w, uint64(res.Mres.cols))
+		for _, elem := range res.Mres.data {
+			writeFloat(w, elem)
+		}
+	case RECORD_RESULT:
+		writeString(w, res.Rres.Type.Name)
+		writeUint(w, uint64(len(res.Rres.values)))
+		for i, value := range res.Rres.values {
+			writeString(w, res.Rres.Type.Fields[i].Name)
+			writeHash(w, value)
+		}
+	case FUNCTION_RESULT:
+		writeString(w, res.Fnres.Name)
+	default:
+		writeString(w, res.ValueString())
+	}
+}
+
+// writes a float for a hash, with the values that compare equal, like 0 and -0, or any two NaNs, written the same.
+func writeFloat(w io.Writer, f float64) {
+	if f == 0 {
+		f = 0
+	} else if math.IsNaN(f) {
+		f = math.NaN()
+	}
+	writeUint(w, math.Float64bits(f))
+}
+
+// writes a number for a hash, always as 8 bytes.
+func writeUint(w io.Writer, u uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], u)
+	w.Write(buf[:])
+}
+
+// writes a string for a hash, with its length first so that strings next to each other can't run together.
+func writeString(w io.Writer, s string) {
+	writeUint(w, uint64(len(s)))
+	w.Write([]byte(s))
+}
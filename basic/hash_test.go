@@ -0,0 +1,50 @@
+package basic
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	same := [][2]*Result_t{
+		{NewInt(2), NewFloat(2)},
+		{NewFloat(0), NewFloat(math.Copysign(0, -1))},
+		{NewFloat(math.NaN()), NewFloat(math.NaN())},
+		{NewDict([]string{"a", "b"}, []*Result_t{NewInt(1), NewInt(2)}), NewDict([]string{"b", "a"}, []*Result_t{NewInt(2), NewInt(1)})},
+		{NewList([]*Result_t{NewInt(1), NewString("x")}), NewList([]*Result_t{NewFloat(1), NewString("x")})},
+	}
+	for _, pair := range same {
+		if !pair[0].Equal(pair[1]) {
+			t.Errorf("%s and %s aren't Equal", pair[0].ValueString(), pair[1].ValueString())
+		} else if pair[0].Hash() != pair[1].Hash() {
+			t.Errorf("%s and %s are Equal but hash differently", pair[0].ValueString(), pair[1].ValueString())
+		}
+	}
+
+	different := []*Result_t{
+		NewInt(1),
+		NewInt(2),
+		NewString("1"),
+		NewString("ab"),
+		NewList([]*Result_t{NewString("a"), NewString("b")}),
+		NewList([]*Result_t{NewString("ab")}),
+		NewList([]*Result_t{NewString("a"), NewString("b"), NewString("")}),
+		NewDict([]string{"a"}, []*Result_t{NewString("b")}),
+		NewNil(),
+	}
+	seen := map[uint64]*Result_t{}
+	for _, res := range different {
+		if other, ok := seen[res.Hash()]; ok {
+			t.Errorf("%s and %s hash the same", res.ValueString(), other.ValueString())
+		}
+		seen[res.Hash()] = res
+	}
+}
+
+func TestHashIsStable(t *testing.T) {
+	// the hash is the same in every process, so hosts can store it; this pins it down
+	res := NewList([]*Result_t{NewInt(1), NewString("x")})
+	if got, want := res.Hash(), uint64(0xe3262ec71e623e6a); got != want {
+		t.Errorf("got %#x, want %#x", got, want)
+	}
+}
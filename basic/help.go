@@ -0,0 +1,172 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// what HELP says about the builtins, keyed by upper case name like builtins. Each starts with
+// how it's called. Guarded by builtinsMu.
+var builtinDocs = map[string]string{
+	"ABS":       "ABS(x)\nthe absolute value of x.",
+	"SGN":       "SGN(x)\n1 if x is positive, -1 if it's negative and 0 if it's 0.",
+	"INT":       "INT(x)\nx rounded down to an int, like in every BASIC. A member of an ENUM comes back as a plain int.",
+	"FLOAT":     "FLOAT(x)\nx as a float, the explicit conversion strict mode wants before mixing ints with floats.",
+	"TYPE":      "TYPE(x)\nthe name of the type of x, like \"int\" or \"list\", or the name of its TYPE or ENUM.",
+	"SQR":       "SQR(x)\nthe square root of x, which can't be negative.",
+	"LOG":       "LOG(x)\nthe natural logarithm of x, which has to be positive.",
+	"EXP":       "EXP(x)\ne to the power of x.",
+	"SIN":       "SIN(x)\nthe sine of x, in the units OPTION ANGLE says.",
+	"COS":       "COS(x)\nthe cosine of x, in the units OPTION ANGLE says.",
+	"TAN":       "TAN(x)\nthe tangent of x, in the units OPTION ANGLE says.",
+	"ATN":       "ATN(x)\nthe arctangent of x, in the units OPTION ANGLE says.",
+	"RND":       "RND()\na random float from 0 up to (not including) 1.",
+	"DEG":       "DEG(x)\nx radians in degrees.",
+	"RAD":       "RAD(x)\nx degrees in radians.",
+	"LEN":       "LEN(x)\nthe number of characters of a string or elements of a list or dict.",
+	"HEX$":      "HEX$(n)\nthe int n written in hexadecimal, like \"FF\".",
+	"OCT$":      "OCT$(n)\nthe int n written in octal.",
+	"BIN$":      "BIN$(n)\nthe int n written in binary.",
+	"PARSEINT":  "PARSEINT(s, base)\nthe int the string s writes in base. Base 0 goes by a 0x, 0o or 0b prefix.",
+	"SETBIT":    "SETBIT(n, bit)\nn with the bit set.",
+	"CLEARBIT":  "CLEARBIT(n, bit)\nn with the bit cleared.",
+	"TESTBIT":   "TESTBIT(n, bit)\n1 if the bit of n is set, 0 if it isn't.",
+	"POPCOUNT":  "POPCOUNT(n)\nthe number of bits set in n.",
+	"ROTL":      "ROTL(n, count[, width])\nn rotated left by count bits, within its lowest width bits (64 if they're left out).",
+	"ROTR":      "ROTR(n, count[, width])\nn rotated right by count bits, within its lowest width bits (64 if they're left out).",
+	"MAP":       "MAP(fn, list)\nthe list of fn of every element.",
+	"FILTER":    "FILTER(fn, list)\nthe elements fn is true for.",
+	"FOLD":      "FOLD(fn, init, list)\nfn(...fn(fn(init, a), b)..., z) for the elements a to z.",
+	"REDUCE":    "REDUCE(fn, list)\nFOLD starting from the first element.",
+	"SORT":      "SORT(list, descending = 0)\nthe list sorted, smallest first unless descending.",
+	"SORTBY":    "SORTBY(list, key, descending = 0)\nthe list sorted by the function key of each element.",
+	"MINMAX":    "MINMAX(list)\nthe smallest and largest elements, as a list of two to unpack, like lo, hi = MINMAX(xs).",
+	"MATRIX":    "MATRIX(rows)\na matrix made from a list of rows, like MATRIX([[1, 2], [3, 4]]).",
+	"TRANSPOSE": "TRANSPOSE(m)\nthe matrix m with its rows and columns swapped.",
+	"DET":       "DET(m)\nthe determinant of the square matrix m.",
+	"INVERSE":   "INVERSE(m)\nthe inverse of the square matrix m.",
+	"DOT":       "DOT(a, b)\nthe sum of the products of the elements of two vectors.",
+	"CROSS":     "CROSS(a, b)\nthe cross product of two 3D vectors.",
+	"NORM":      "NORM(a)\nthe length of the vector a.",
+	"FORMAT$":   "FORMAT$(x[, decimals[, separator]])\nx with its thousands separated, by \",\" unless separator says otherwise.",
+	"LAMBDA":    "LAMBDA([\"doc\", ]param, ..., body)\na function of the parameters, which can have defaults like scale = 1 and types like x AS INT. A string before them documents it for HELP, and an AS after the LAMBDA says what type it gives back.",
+	"IIF":       "IIF(condition, then, else)\nthen if the condition is true, else otherwise. Only the one it takes is evaluated.",
+	"RANGE":     "RANGE(start, stop[, step])\nthe numbers from start up to (not including) stop, step apart.",
+	"ROUND":     "ROUND(x[, ndigits])\nx rounded to ndigits decimal places, breaking ties the way OPTION ROUND says.",
+	"PLOT":      "PLOT(expr, variable, lo, hi)\nprints a chart of expr as the variable goes from lo to hi.",
+	"PEEK":      "PEEK(address)\nthe byte at the address in memory.",
+	"VARS":      "VARS()\na dict of every variable that isn't a function, by name.",
+	"FUNCS":     "FUNCS()\nthe names of every function there is to call, the program's and the builtins, in order.",
+	"DESCRIBE":  "DESCRIBE(name)\na dict with the name, kind and type of a variable or builtin, and its value, or its arity, params and doc if it's a function.",
+	"ENVIRON$":  "ENVIRON$(name)\nthe value of the environment variable called name, or \"\" if it isn't set. It needs the env permission.",
+	"HTTPGET$":  "HTTPGET$(url)\nthe body of the response to a GET of url. It needs the network permission.",
+	"HTTPPOST$": "HTTPPOST$(url, body[, contentType])\nthe body of the response to POSTing body to url, as text/plain unless contentType says otherwise. It needs the network permission.",
+	"DBOPEN":    "DBOPEN(path)\nopens the SQLite database in the file at path, giving a handle for DBQUERY. It needs the file under the read roots, and it's read-only unless it's under the write roots too.",
+	"DBQUERY":   "DBQUERY(db, query[, params])\nthe rows of the query on the database db, as a list of dicts from column name to value, with the list params filling in its ? placeholders.",
+	"DBCLOSE":   "DBCLOSE(db)\ncloses the database db.",
+	"HELP":      "HELP(name)\nwhat there is to say about a function or TYPE, given by name like HELP(\"SORT\") or as a value like HELP(SORT).",
+}
+
+// documents a builtin for HELP and the REPL's :help, replacing what it said before. The doc
+// should start with how it's called, like "CLAMP(x, lo, hi)", on a line of its own.
+func DocumentBuiltin(name string, doc string) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+	builtinDocs[strings.ToUpper(name)] = doc
+}
+
+// gets what a builtin is documented as, and whether it's documented.
+func builtinDoc(name string) (string, bool) {
+	builtinsMu.RLock()
+	defer builtinsMu.RUnlock()
+	doc, ok := builtinDocs[strings.ToUpper(name)]
+	return doc, ok
+}
+
+// gets what HELP says about the function or TYPE called name: a variable holding a function,
+// like a LAMBDA with a docstring, or a builtin. It's an error if there's nothing called that.
+func (interp *Interpreter_t) Help(name string) (string, error) {
+	if value, ok, err := interp.hostValue(name, Token_t{strVal: name}); err == nil && ok && value.ResultType == FUNCTION_RESULT {
+		return helpText(name, value.Fnres), nil
+	} else if value, ok := interp.vars[name]; ok && value.ResultType == FUNCTION_RESULT {
+		return helpText(name, value.Fnres), nil
+	} else if value, ok := interp.builtinValue(name); ok {
+		return helpText(value.Fnres.Name, value.Fnres), nil
+	} else if doc, ok := builtinDoc(name); ok {
+		return doc, nil
+	}
+	return "", fmt.Errorf("there's no function called %s to help with", name)
+}
+
+// writes what HELP says about a function: how it's called, with the types it's annotated with,
+// and its docstring, for a LAMBDA or the constructor of a TYPE, or the doc of a builtin.
+func helpText(name string, fn *Function_t) string {
+	if fn.source == nil && fn.Doc != "" {
+		return fn.Doc
+	} else if fn.source == nil && fn.Arity < 0 {
+		return fmt.Sprintf("%s takes any number of arguments", name)
+	} else if fn.source == nil {
+		return fmt.Sprintf("%s takes %d argument(s)", name, fn.Arity)
+	}
+	params := make([]string, len(fn.Params))
+	for i, param := range fn.Params {
+		params[i] = param.Name
+		if param.Rest {
+			params[i] += "..."
+		} else if param.Type != "" {
+			params[i] += " AS " + annotationName(param.Type)
+		}
+		if param.Default != nil {
+			params[i] += " = " + explainValue(param.Default)
+		}
+	}
+	ret := name + "(" + strings.Join(params, ", ") + ")"
+	if fn.Returns != "" && fn.record == nil {
+		ret += " AS " + annotationName(fn.Returns)
+	}
+	if fn.Doc != "" {
+		ret += "\n" + fn.Doc
+	}
+	return ret
+}
+
+// evaluates HELP(name): what HELP says about the function or TYPE called name, or about a
+// function value, like HELP(SORT) or HELP(Point).
+func (interp *Interpreter_t) helpCall(node *Node_t) (*Result_t, error) {
+	if len(node.args) != 1 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 1 argument(s), got %d", node.tok.strVal, len(node.args)), Pos: node.tok.pos}
+	}
+	arg, err := node.args[0].evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	switch arg.ResultType {
+	case STRING_RESULT:
+		text, err := interp.Help(arg.Sres)
+		if err != nil {
+			return nil, &RuntimeError_t{Code: ERR_UNDEFINED_FUNC, Details: err.Error(), Pos: node.args[0].tok.pos}
+		}
+		return NewString(text), nil
+	case FUNCTION_RESULT:
+		name := arg.Fnres.Name
+		if node.args[0].nodeType == VAR_ACCESS && arg.Fnres.source != nil {
+			name = node.args[0].tok.strVal // a LAMBDA is called by the variable it's in
+		}
+		return NewString(helpText(name, arg.Fnres)), nil
+	}
+	return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("%s needs a name or a function, not a %s", node.tok.strVal, arg.ResultType), Pos: node.tok.pos}
+}
+
+// returns true if a LAMBDA starts with a docstring: a string literal before its parameters
+// and body, like LAMBDA("the square of x", x, x * x).
+func hasDocstring(node *Node_t) bool {
+	return len(node.args) >= 2 && node.args[0].nodeType == FACTOR && node.args[0].tok.tokenType == STRING
+}
+
+// gets the arguments of a LAMBDA after its docstring, if it has one: its parameters and body.
+func lambdaArgs(node *Node_t) []*Node_t {
+	if hasDocstring(node) {
+		return node.args[1:]
+	}
+	return node.args
+}
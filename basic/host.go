@@ -0,0 +1,52 @@
+package basic
+
+import "fmt"
+
+// a variable the embedding program owns. Scripts can read it but not bind it, with FOR EACH,
+// LAMBDA, PLOT or IMPORT ... AS. Either value is set, or get is called each time it's read.
+type hostVar_t struct {
+	value *Result_t
+	get   func() (*Result_t, error)
+}
+
+// sets a variable that programs can read but not rebind, so they can see host state without
+// changing it. Host variables take priority over ordinary ones, and Snapshot and Restore leave them alone.
+func (interp *Interpreter_t) SetReadOnlyVar(name string, value *Result_t) {
+	interp.host[name] = hostVar_t{value: value}
+}
+
+// sets a read-only variable whose value comes from get every time a program reads it, like a
+// dict of the player's current state. An error from get stops the program at the read.
+func (interp *Interpreter_t) SetComputedVar(name string, get func() (*Result_t, error)) {
+	interp.host[name] = hostVar_t{get: get}
+}
+
+// removes a read-only or computed variable.
+func (interp *Interpreter_t) UnsetHostVar(name string) {
+	delete(interp.host, name)
+}
+
+// reads a host variable, if there's one called name.
+func (interp *Interpreter_t) hostValue(name string, tok Token_t) (*Result_t, bool, error) {
+	hostVar, ok := interp.host[name]
+	if !ok {
+		return nil, false, nil
+	} else if hostVar.get == nil {
+		return hostVar.value, true, nil
+	}
+	value, err := hostVar.get()
+	if err != nil {
+		return nil, true, &RuntimeError_t{Code: ERR_BUILTIN, Details: fmt.Sprintf("%s: %s", name, err), Pos: tok.pos}
+	} else if value == nil {
+		return nil, true, &RuntimeError_t{Code: ERR_BUILTIN, Details: fmt.Sprintf("%s: the host gave no value", name), Pos: tok.pos}
+	}
+	return value, true, nil
+}
+
+// checks that a program may bind a variable called name, which it can't if the host owns it.
+func (interp *Interpreter_t) bindable(name string, tok Token_t) error {
+	if _, ok := interp.host[name]; ok {
+		return &RuntimeError_t{Code: ERR_READ_ONLY, Details: fmt.Sprintf("%s is read-only", name), Pos: tok.pos}
+	}
+	return interp.checkShadowing(name, tok)
+}
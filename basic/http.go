@@ -0,0 +1,117 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// limits of HTTPGET$ and HTTPPOST$ unless Options_t says otherwise.
+const (
+	DEFAULT_HTTP_TIMEOUT       = 10 * time.Second // longest a request may take, from sending it to reading the last of the response
+	DEFAULT_MAX_RESPONSE_BYTES = 1 << 20          // longest response body, in bytes
+)
+
+// evaluates HTTPGET$(url): the body of the response to a GET of url, as a string.
+// It needs the network permission.
+func (interp *Interpreter_t) httpGetCall(node *Node_t) (*Result_t, error) {
+	args, err := interp.httpArgs(node, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	return interp.httpRequest(node.tok, http.MethodGet, args[0], "", "")
+}
+
+// evaluates HTTPPOST$(url, body[, contentType]): the body of the response to POSTing body to
+// url, as a string. The content type is text/plain unless it's given. It needs the network
+// permission.
+func (interp *Interpreter_t) httpPostCall(node *Node_t) (*Result_t, error) {
+	args, err := interp.httpArgs(node, 2, 3)
+	if err != nil {
+		return nil, err
+	}
+	contentType := "text/plain; charset=utf-8"
+	if len(args) == 3 {
+		contentType = args[2]
+	}
+	return interp.httpRequest(node.tok, http.MethodPost, args[0], args[1], contentType)
+}
+
+// checks HTTPGET$ or HTTPPOST$ may be called and evaluates its arguments, which are all
+// strings, between lo and hi of them.
+func (interp *Interpreter_t) httpArgs(node *Node_t, lo int, hi int) ([]string, error) {
+	if len(node.args) < lo || len(node.args) > hi {
+		arity := fmt.Sprint(lo)
+		if hi > lo {
+			arity = fmt.Sprintf("%d to %d", lo, hi)
+		}
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes %s argument(s), got %d", node.tok.strVal, arity, len(node.args)), Pos: node.tok.pos}
+	} else if err := checkCapability(interp.Permissions().Network, strings.ToUpper(node.tok.strVal), "network", node.tok); err != nil {
+		return nil, err
+	}
+	args, err := interp.evaluateArgs(node)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, len(args))
+	for i, arg := range args {
+		if arg.ResultType != STRING_RESULT {
+			return nil, builtinError(node.tok, typeErrorf("argument %d is a %s, not a string", i+1, arg.ResultType))
+		}
+		ret[i] = arg.Sres
+	}
+	return ret, nil
+}
+
+// sends a request and reads the body of the response, within the interpreter's HTTPTimeout
+// and MaxResponseBytes, and the Run's own deadline. A response that isn't a 2xx is an error.
+func (interp *Interpreter_t) httpRequest(tok Token_t, method string, rawURL string, body string, contentType string) (*Result_t, error) {
+	if parsed, err := url.Parse(rawURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, builtinError(tok, fmt.Errorf("%s isn't an http or https URL", quoteString(rawURL)))
+	}
+	timeout := interp.opts.HTTPTimeout
+	if timeout <= 0 {
+		timeout = DEFAULT_HTTP_TIMEOUT
+	}
+	limit := interp.opts.MaxResponseBytes
+	if limit <= 0 {
+		limit = DEFAULT_MAX_RESPONSE_BYTES
+	}
+
+	ctx := interp.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if !interp.deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(ctx, interp.deadline)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, strings.NewReader(body))
+	if err != nil {
+		return nil, builtinError(tok, err)
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, builtinError(tok, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	if err != nil {
+		return nil, builtinError(tok, err)
+	} else if len(data) > limit {
+		return nil, builtinError(tok, fmt.Errorf("response is longer than the limit of %d bytes", limit))
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, builtinError(tok, fmt.Errorf("%s %s gave %s", method, rawURL, resp.Status))
+	}
+	return NewString(string(data)), nil
+}
@@ -0,0 +1,55 @@
+package basic
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			io.WriteString(w, r.Method+" "+r.Header.Get("Content-Type")+" "+string(body))
+		case "/big":
+			io.WriteString(w, strings.Repeat("x", 100))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	opts := Options_t{HTTPTimeout: 50 * time.Millisecond, MaxResponseBytes: 64}
+	res, err := NewInterpreter(opts).Run(`HTTPGET$("`+server.URL+`/echo")`, t.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Sres != "GET  " {
+		t.Errorf("GET: got %q", res.Sres)
+	}
+	res, err = NewInterpreter(opts).Run(`HTTPPOST$("`+server.URL+`/echo", "hi", "text/csv")`, t.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Sres != "POST text/csv hi" {
+		t.Errorf("POST: got %q", res.Sres)
+	}
+
+	for _, src := range []string{
+		`HTTPGET$("` + server.URL + `/big")`,     // longer than MaxResponseBytes
+		`HTTPGET$("` + server.URL + `/slow")`,    // longer than HTTPTimeout
+		`HTTPGET$("` + server.URL + `/missing")`, // a 404
+		`HTTPGET$("file:///etc/passwd")`,
+	} {
+		if got := runErrorCode(t, opts, src); got != ERR_BUILTIN {
+			t.Errorf("%q: got error code %q, want %q", src, got, ERR_BUILTIN)
+		}
+	}
+	if got := runErrorCode(t, Options_t{Permissions: DenyAll()}, `HTTPGET$("`+server.URL+`/echo")`); got != ERR_PERMISSION {
+		t.Errorf("without the network permission: got error code %q, want %q", got, ERR_PERMISSION)
+	}
+}
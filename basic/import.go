@@ -0,0 +1,120 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// builds and returns an Import node: IMPORT "path", optionally followed by AS name.
+// Without AS, the namespace is named after the file, so it has to make a valid name.
+func (parser *parser_t) importModule() (*Node_t, error) {
+	parser.advance()
+	ret := &Node_t{nodeType: IMPORT, tok: parser.currentToken}
+	parser.advance()
+	if isKeyword(parser.currentToken, "AS") {
+		parser.advance()
+		if parser.currentToken.tokenType != IDENTIFIER {
+			return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected a name after AS", Pos: parser.currentToken.pos}
+		}
+		ret.ops = []Token_t{parser.currentToken}
+		parser.advance()
+	} else if !isName(importName(ret)) {
+		return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: fmt.Sprintf("%s doesn't make a name, so IMPORT %s needs AS name", quoteString(importName(ret)), quoteString(ret.tok.strVal)), Pos: ret.tok.pos}
+	}
+	return ret, nil
+}
+
+// gets the name an IMPORT node binds the module to: the name after AS, or else the file's name
+// without its directory or extension, like utils for "lib/utils.bas".
+func importName(node *Node_t) string {
+	if len(node.ops) > 0 {
+		return node.ops[0].strVal
+	}
+	base := filepath.Base(node.tok.strVal)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// returns true if the string could be a variable's name.
+func isName(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if !isLetter(str[i]) && !(i > 0 && str[i] >= '0' && str[i] <= '9') {
+			return false
+		}
+	}
+	return str != ""
+}
+
+// evaluates an IMPORT node. The module runs in an interpreter of its own, with the same options,
+// so its variables don't mix with the importing program's. Its result, usually a dict of
+// functions like {"square": LAMBDA(x, x * x)}, is bound to the namespace name, where it's used
+// like utils["square"](3). A module is only run once, however many times it's imported.
+func (node *Node_t) evaluateImport(interp *Interpreter_t) (*Result_t, error) {
+	if interp.opts.DisableImports {
+		return nil, &RuntimeError_t{Code: ERR_IMPORT, Details: "IMPORT is disabled here", Pos: node.tok.pos}
+	} else if err := interp.bindable(importName(node), node.tok); err != nil {
+		return nil, err
+	}
+	path, err := interp.findModule(node)
+	if err != nil {
+		return nil, err
+	} else if err := interp.checkRead(path, node.tok); err != nil {
+		return nil, err
+	}
+	for i, importing := range interp.importing {
+		if importing == path {
+			cycle := append(append([]string{}, interp.importing[i:]...), path)
+			return nil, &RuntimeError_t{Code: ERR_IMPORT, Details: fmt.Sprintf("import cycle: %s", strings.Join(cycle, " imports ")), Pos: node.tok.pos}
+		}
+	}
+
+	res, ok := interp.modules[path]
+	if !ok {
+		src, err := interp.FileSystem().ReadFile(path)
+		if err != nil {
+			return nil, &RuntimeError_t{Code: ERR_IMPORT, Details: err.Error(), Pos: node.tok.pos}
+		}
+		module := NewInterpreter(interp.opts)
+		module.modules = interp.modules
+		module.importing = append(append([]string{}, interp.importing...), path)
+		module.importer = interp
+		ctx := interp.ctx // the module stops when this Run is cancelled
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		res, err = module.RunContext(ctx, string(src), path)
+		interp.steps, interp.allocated = module.steps, module.allocated
+		if err != nil {
+			return nil, err
+		}
+		interp.warnings = append(interp.warnings, res.Warnings...)
+		interp.modules[path] = res
+	}
+	interp.vars[importName(node)] = res
+	interp.define(importName(node), node.tok.pos)
+	return res, nil
+}
+
+// finds the file an IMPORT node names, giving its absolute path. A relative path is looked
+// for next to the importing file first, then in each of the ImportPaths in turn. Paths in a
+// FileSystem other than the OS's are only cleaned, since there's no working directory to join.
+func (interp *Interpreter_t) findModule(node *Node_t) (string, error) {
+	name := node.tok.strVal
+	candidates := []string{name}
+	if !filepath.IsAbs(name) {
+		candidates = []string{filepath.Join(filepath.Dir(node.tok.pos.filename), name)}
+		for _, dir := range interp.opts.ImportPaths {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+	for _, candidate := range candidates {
+		if info, err := interp.FileSystem().Stat(candidate); err != nil || info.IsDir() {
+			continue
+		} else if interp.opts.FileSystem != nil {
+			return filepath.Clean(candidate), nil
+		}
+		return filepath.Abs(candidate)
+	}
+	return "", &RuntimeError_t{Code: ERR_IMPORT, Details: fmt.Sprintf("module %s not found (looked in %s)", quoteString(name), strings.Join(candidates, ", ")), Pos: node.tok.pos}
+}
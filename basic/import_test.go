@@ -0,0 +1,56 @@
+package basic
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestImport(t *testing.T) {
+	files := MemoryFileSystem_t{"lib/utils.bas": `{"square": LAMBDA(x, x * x)}`}
+	res, err := NewInterpreter(Options_t{FileSystem: files}).Run(`IMPORT "lib/utils.bas"`+"\n"+`utils["square"](7)`, t.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Ires != 49 {
+		t.Errorf("got %s, want 49", res)
+	}
+}
+
+func TestImportSharesLimits(t *testing.T) {
+	files := MemoryFileSystem_t{
+		"loop.bas":  `FOLD(LAMBDA(a, b, a + b), 0, RANGE(0, 100000))`,
+		"big.bas":   `FOLD(LAMBDA(a, b, a + a), "x", RANGE(0, 40))`,
+		"small.bas": `FOLD(LAMBDA(a, b, a + b), 0, RANGE(0, 10))`,
+	}
+	for _, test := range []struct {
+		src  string
+		opts Options_t
+		want ErrorCode_t
+	}{
+		{`IMPORT "small.bas"`, Options_t{MaxSteps: 1000}, ""},
+		{`IMPORT "loop.bas"`, Options_t{MaxSteps: 1000}, ERR_STEP_LIMIT},
+		{`IMPORT "loop.bas"`, Options_t{Timeout: time.Millisecond}, ERR_STEP_LIMIT},
+		{`IMPORT "big.bas"`, Options_t{MaxAllocated: 1 << 20}, ERR_MEMORY_LIMIT},
+	} {
+		test.opts.FileSystem = files
+		if got := runErrorCode(t, test.opts, test.src); got != test.want {
+			t.Errorf("%q with %+v: got error code %q, want %q", test.src, test.opts, got, test.want)
+		}
+	}
+
+	// the importing program's steps count the module's
+	interp := NewInterpreter(Options_t{FileSystem: files, NoPrelude: true})
+	if _, err := interp.Run(`IMPORT "small.bas"`, t.Name()); err != nil {
+		t.Fatal(err)
+	} else if interp.steps < 10 {
+		t.Errorf("took %d steps, fewer than the module did", interp.steps)
+	}
+
+	// and it stops when the importing Run is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	interp = NewInterpreter(Options_t{FileSystem: files})
+	if _, err := interp.RunContext(ctx, `IMPORT "loop.bas"`, t.Name()); err == nil {
+		t.Errorf("a cancelled Run still imported the module")
+	}
+}
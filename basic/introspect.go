@@ -0,0 +1,123 @@
+package basic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// evaluates VARS(): a dict of every variable that's set and isn't a function, the program's
+// own and the host's, by name.
+func (interp *Interpreter_t) varsCall(node *Node_t) (*Result_t, error) {
+	if len(node.args) != 0 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 0 argument(s), got %d", node.tok.strVal, len(node.args)), Pos: node.tok.pos}
+	}
+	names := make([]string, 0, len(interp.vars)+len(interp.host))
+	for name := range interp.scope() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	keys := make([]string, 0, len(names))
+	values := make([]*Result_t, 0, len(names))
+	for _, name := range names {
+		value, _, err := interp.binding(name, node.tok)
+		if err != nil {
+			return nil, err
+		} else if value.ResultType != FUNCTION_RESULT {
+			keys, values = append(keys, name), append(values, value)
+		}
+	}
+	return NewDict(keys, values), nil
+}
+
+// evaluates FUNCS(): a list of the names of every function there is to call, from variables
+// holding one, like a LAMBDA or the constructor of a TYPE, to the builtins.
+func (interp *Interpreter_t) funcsCall(node *Node_t) (*Result_t, error) {
+	if len(node.args) != 0 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 0 argument(s), got %d", node.tok.strVal, len(node.args)), Pos: node.tok.pos}
+	}
+	seen := map[string]bool{}
+	for name := range interp.scope() {
+		value, _, err := interp.binding(name, node.tok)
+		if err != nil {
+			return nil, err
+		} else if value.ResultType == FUNCTION_RESULT {
+			seen[name] = true
+		}
+	}
+	for _, name := range Builtins() {
+		seen[name] = true
+	}
+	for name := range specialForms {
+		seen[name] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ret := make([]*Result_t, len(names))
+	for i, name := range names {
+		ret[i] = NewString(name)
+	}
+	return NewList(ret), nil
+}
+
+// evaluates DESCRIBE(name): a dict saying what the variable or builtin called name is. It has
+// its "name", its "kind" (variable, host variable, function or builtin) and its "type" as
+// TYPE() gives it. A variable has its "value" too, and a function its "arity" (-1 for any
+// number of arguments), its "params" and its "doc".
+func (interp *Interpreter_t) describeCall(node *Node_t) (*Result_t, error) {
+	if len(node.args) != 1 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 1 argument(s), got %d", node.tok.strVal, len(node.args)), Pos: node.tok.pos}
+	}
+	arg, err := node.args[0].evaluate(interp)
+	if err != nil {
+		return nil, err
+	} else if arg.ResultType != STRING_RESULT {
+		return nil, builtinError(node.tok, typeErrorf("argument 1 is a %s, not a string", arg.ResultType))
+	}
+	name := arg.Sres
+	value, kind, err := interp.binding(name, node.tok)
+	if err != nil {
+		return nil, err
+	} else if value == nil {
+		if value, ok := interp.builtinValue(name); ok {
+			return description(value.Fnres.Name, "builtin", value), nil
+		} else if _, ok := specialForms[strings.ToUpper(name)]; ok {
+			doc, _ := builtinDoc(name)
+			return description(strings.ToUpper(name), "builtin", NewFunction(&Function_t{Name: strings.ToUpper(name), Arity: -1, Doc: doc})), nil
+		}
+		return nil, undefinedVar(Token_t{strVal: name, pos: node.args[0].tok.pos}, interp.scope(), interp.locale)
+	}
+	return description(name, kind, value), nil
+}
+
+// gets the value of the variable called name, the host's or the program's, and what kind of
+// variable it is. The value is nil if there's no variable called that.
+func (interp *Interpreter_t) binding(name string, tok Token_t) (*Result_t, string, error) {
+	if value, ok, err := interp.hostValue(name, tok); err != nil {
+		return nil, "", err
+	} else if ok {
+		return value, "host variable", nil
+	} else if value, ok := interp.vars[name]; ok {
+		return value, "variable", nil
+	}
+	return nil, "", nil
+}
+
+// makes the dict DESCRIBE gives for a value bound to name. A function is a "function" rather
+// than a "variable", unless it's a builtin or the host's.
+func description(name string, kind string, value *Result_t) *Result_t {
+	keys := []string{"name", "kind", "type"}
+	if value.ResultType != FUNCTION_RESULT {
+		return NewDict(append(keys, "value"), []*Result_t{NewString(name), NewString(kind), NewString(typeName(value)), value})
+	} else if kind == "variable" {
+		kind = "function"
+	}
+	params := make([]*Result_t, len(value.Fnres.Params))
+	for i, param := range value.Fnres.Params {
+		params[i] = NewString(param.Name)
+	}
+	return NewDict(append(keys, "arity", "params", "doc"), []*Result_t{NewString(name), NewString(kind), NewString(typeName(value)), NewInt(int64(value.Fnres.Arity)), NewList(params), NewString(value.Fnres.Doc)})
+}
@@ -0,0 +1,106 @@
+package basic
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// interface for the files IMPORT reads modules from. The OS's files are used unless the
+// options give another one, like a MemoryFileSystem_t for tests and playgrounds.
+type FileSystem_t interface {
+	ReadFile(name string) ([]byte, error)  // gets the contents of a file
+	Stat(name string) (fs.FileInfo, error) // gets what a file is, or an error if there isn't one
+}
+
+// the OS's files, read through the os package.
+type osFileSystem_t struct{}
+
+func (osFileSystem_t) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFileSystem_t) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// a file system held in memory: the contents of each file, keyed by its path, like "lib/util.bas".
+// A directory is any path that's a prefix of a file's, so there's no making one on its own.
+type MemoryFileSystem_t map[string]string
+
+func (files MemoryFileSystem_t) ReadFile(name string) ([]byte, error) {
+	src, ok := files[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return []byte(src), nil
+}
+
+func (files MemoryFileSystem_t) Stat(name string) (fs.FileInfo, error) {
+	name = filepath.Clean(name)
+	if src, ok := files[name]; ok {
+		return memoryFileInfo_t{name: path.Base(filepath.ToSlash(name)), size: int64(len(src))}, nil
+	}
+	for file := range files {
+		if strings.HasPrefix(filepath.Clean(file), name+string(filepath.Separator)) {
+			return memoryFileInfo_t{name: path.Base(filepath.ToSlash(name)), dir: true}, nil
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// what Stat says about a file or directory of a MemoryFileSystem_t.
+type memoryFileInfo_t struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (info memoryFileInfo_t) Name() string       { return info.name }
+func (info memoryFileInfo_t) Size() int64        { return info.size }
+func (info memoryFileInfo_t) ModTime() time.Time { return time.Time{} }
+func (info memoryFileInfo_t) IsDir() bool        { return info.dir }
+func (info memoryFileInfo_t) Sys() interface{}   { return nil }
+
+func (info memoryFileInfo_t) Mode() fs.FileMode {
+	if info.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// gets where programs read from: the options' Stdin, or else os.Stdin.
+func (interp *Interpreter_t) Stdin() io.Reader {
+	if interp.opts.Stdin != nil {
+		return interp.opts.Stdin
+	}
+	return os.Stdin
+}
+
+// gets where programs write, like PLOT's charts: the options' Stdout, or else os.Stdout.
+func (interp *Interpreter_t) Stdout() io.Writer {
+	if interp.opts.Stdout != nil {
+		return interp.opts.Stdout
+	}
+	return os.Stdout
+}
+
+// gets where programs write diagnostics: the options' Stderr, or else os.Stderr.
+func (interp *Interpreter_t) Stderr() io.Writer {
+	if interp.opts.Stderr != nil {
+		return interp.opts.Stderr
+	}
+	return os.Stderr
+}
+
+// gets the files IMPORT reads: the options' FileSystem, or else the OS's.
+func (interp *Interpreter_t) FileSystem() FileSystem_t {
+	if interp.opts.FileSystem != nil {
+		return interp.opts.FileSystem
+	}
+	return osFileSystem_t{}
+}
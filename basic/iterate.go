@@ -0,0 +1,110 @@
+package basic
+
+import "fmt"
+
+// goes through the items of a collection one at a time. next returns false once there are none left.
+type iterator_t interface {
+	next() (*Result_t, bool)
+}
+
+// makes iterators, keyed by the type of value they go through. A new kind of collection
+// only has to add itself here for FOR EACH to work with it.
+var iterators map[resultType_t]func(res *Result_t) iterator_t
+
+func init() {
+	iterators = map[resultType_t]func(res *Result_t) iterator_t{
+		LIST_RESULT: func(res *Result_t) iterator_t { // the elements
+			return &sliceIterator_t{items: res.Lres}
+		},
+		DICT_RESULT: func(res *Result_t) iterator_t { // the keys, in order
+			keys := make([]*Result_t, res.Dres.Len())
+			for i, key := range res.Dres.keys {
+				keys[i] = NewString(key)
+			}
+			return &sliceIterator_t{items: keys}
+		},
+		STRING_RESULT: func(res *Result_t) iterator_t { // the characters, as one character strings
+			return &stringIterator_t{runes: []rune(res.Sres)}
+		},
+	}
+}
+
+// gets an iterator over the value, or a type error if it isn't a collection.
+func iterate(res *Result_t) (iterator_t, error) {
+	newIterator, ok := iterators[res.ResultType]
+	if !ok {
+		return nil, typeErrorf("can't iterate over a value of type %s", res.ResultType)
+	}
+	return newIterator(res), nil
+}
+
+// iterator over values that are already made.
+type sliceIterator_t struct {
+	items []*Result_t
+	idx   int
+}
+
+func (it *sliceIterator_t) next() (*Result_t, bool) {
+	if it.idx >= len(it.items) {
+		return nil, false
+	}
+	it.idx += 1
+	return it.items[it.idx-1], true
+}
+
+// iterator over the characters of a string, making each one as it's needed.
+type stringIterator_t struct {
+	runes []rune
+	idx   int
+}
+
+func (it *stringIterator_t) next() (*Result_t, bool) {
+	if it.idx >= len(it.runes) {
+		return nil, false
+	}
+	it.idx += 1
+	return NewString(string(it.runes[it.idx-1])), true
+}
+
+// evaluates a FOR_EACH node: the body once per item of the collection, with the loop variable
+// set to the item. The variable is put back the way it was afterwards, like a LAMBDA parameter.
+// The loop's value is the value of the last statement run, or 0 if the collection was empty.
+func (node *Node_t) evaluateForEach(interp *Interpreter_t) (*Result_t, error) {
+	collection, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	it, err := iterate(collection)
+	if err != nil {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("FOR EACH %s: %s", node.tok.strVal, err), Pos: node.left.tok.pos}
+	}
+
+	name := node.tok.strVal
+	if err := interp.bindable(name, node.tok); err != nil {
+		return nil, err
+	}
+	old, wasSet := interp.vars[name]
+	defer func() {
+		if wasSet {
+			interp.vars[name] = old
+		} else {
+			interp.undefine(name)
+		}
+	}()
+	interp.define(name, node.tok.pos)
+
+	ret := NewInt(0)
+	for item, ok := it.next(); ok; item, ok = it.next() {
+		interp.vars[name] = item
+		for _, stmt := range node.statements {
+			if err := interp.checkTimer(); err != nil {
+				return nil, err
+			}
+			ret, err = stmt.evaluate(interp)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ret, nil
+}
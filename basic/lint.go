@@ -0,0 +1,216 @@
+package basic
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// a lint rule, with its stable ID (like "L001") and its name (like "magic-number").
+// Either can be used to disable the rule in a LintConfig.
+type lintRule_t struct {
+	id    string
+	name  string
+	check func(node *Node_t, config LintConfig_t) []Diagnostic_t
+}
+
+// every rule the linter knows about, in ID order.
+var lintRules = []lintRule_t{
+	{id: "L001", name: "magic-number", check: lintMagicNumbers},
+	{id: "L002", name: "deep-nesting", check: lintDeepNesting},
+	{id: "L003", name: "self-operation", check: lintSelfOperations},
+	{id: "L004", name: "constant-condition", check: lintConstantConditions},
+	{id: "L005", name: "self-assignment", check: lintSelfAssignments},
+}
+
+// settings for the linter. Usually loaded from a JSON file, see LoadLintConfig.
+type LintConfig_t struct {
+	Disabled       []string  `json:"disabled"`        // IDs or names of rules that shouldn't run
+	AllowedNumbers []float64 `json:"allowed_numbers"` // literals that don't count as magic numbers
+	MaxDepth       int       `json:"max_depth"`       // deepest nesting of different operators allowed
+}
+
+// gets the config used when there is no config file.
+func DefaultLintConfig() LintConfig_t {
+	return LintConfig_t{AllowedNumbers: []float64{0, 1, 2}, MaxDepth: 4}
+}
+
+// reads a JSON lint config. Settings missing from the file keep their default values.
+func LoadLintConfig(path string) (LintConfig_t, error) {
+	config := DefaultLintConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return config, fmt.Errorf("bad lint config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// returns true if the rule is disabled by the config.
+func (config LintConfig_t) disabled(rule lintRule_t) bool {
+	for _, name := range config.Disabled {
+		if name == rule.id || name == rule.name {
+			return true
+		}
+	}
+	return false
+}
+
+// runs every enabled lint rule over the AST, returning what they flag in source order.
+// Findings are warnings whose Code is the rule ID.
+func Lint(node *Node_t, config LintConfig_t) []Diagnostic_t {
+	ret := make([]Diagnostic_t, 0)
+	for _, rule := range lintRules {
+		if config.disabled(rule) {
+			continue
+		}
+		ret = append(ret, rule.check(node, config)...)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Line != ret[j].Line {
+			return ret[i].Line < ret[j].Line
+		}
+		return ret[i].Col < ret[j].Col
+	})
+	return ret
+}
+
+// constructor for lint findings
+func newFinding(id string, node *Node_t, format string, args ...interface{}) Diagnostic_t {
+	pos := node.tok.pos
+	_, end := span(node)
+	return Diagnostic_t{Code: id, Severity: "warning", Message: fmt.Sprintf(format, args...), Filename: pos.filename, Line: pos.line, Col: pos.col, Offset: pos.index, End: end}
+}
+
+// L001: number literals that aren't in the allowed list should be given a name.
+func lintMagicNumbers(node *Node_t, config LintConfig_t) []Diagnostic_t {
+	ret := make([]Diagnostic_t, 0)
+	Walk(node, func(n *Node_t) bool {
+		if n.nodeType != FACTOR || n.tok.tokenType == STRING {
+			return true
+		}
+		value := n.Value().Fres // the float value is set for integers too
+		for _, allowed := range config.AllowedNumbers {
+			if value == allowed {
+				return true
+			}
+		}
+		ret = append(ret, newFinding("L001", n, "magic number %s, consider giving it a name", strconv.FormatFloat(value, 'g', -1, 64)))
+		return true
+	})
+	return ret
+}
+
+// L002: statements that nest too many different operators are hard to read.
+// A chain of the same kind of operation (like a+b+c+d) counts as one level.
+func lintDeepNesting(node *Node_t, config LintConfig_t) []Diagnostic_t {
+	ret := make([]Diagnostic_t, 0)
+	statements := []*Node_t{node}
+	if node.nodeType == STATEMENTS {
+		statements = node.statements
+	}
+	for _, stmt := range statements {
+		depth := nestingDepth(stmt)
+		if depth > config.MaxDepth {
+			ret = append(ret, newFinding("L002", stmt, "expression is nested %d levels deep (the limit is %d)", depth, config.MaxDepth))
+		}
+	}
+	return ret
+}
+
+// gets how many levels of different operations are nested in the expression.
+func nestingDepth(node *Node_t) int {
+	if node == nil || (node.nodeType != TERM && node.nodeType != EXPRESSION && node.nodeType != COMPARISON && node.nodeType != POWER && node.nodeType != UNARY_OP) {
+		return 0
+	}
+	ret := 0
+	for _, child := range []*Node_t{node.left, node.right} {
+		depth := nestingDepth(child)
+		if child != nil && child.nodeType != node.nodeType {
+			depth += 1
+		}
+		if depth > ret {
+			ret = depth
+		}
+	}
+	if ret == 0 { // innermost operation
+		ret = 1
+	}
+	return ret
+}
+
+// L003: operations whose two sides are the same variable, like x - x or x / x, are
+// constants in disguise and usually a typo.
+func lintSelfOperations(node *Node_t, config LintConfig_t) []Diagnostic_t {
+	ret := make([]Diagnostic_t, 0)
+	Walk(node, func(n *Node_t) bool {
+		if (n.tok.tokenType == SUB || n.tok.tokenType == DIV) && (n.nodeType == TERM || n.nodeType == EXPRESSION) &&
+			n.left.nodeType == VAR_ACCESS && n.right.nodeType == VAR_ACCESS && n.left.tok.strVal == n.right.tok.strVal {
+			ret = append(ret, newFinding("L003", n, "%s %s %s is always the same value", n.left.tok.strVal, tokenSymbol(n.tok.tokenType), n.right.tok.strVal))
+		}
+		return true
+	})
+	return ret
+}
+
+// L004: conditions that don't read anything, like IIF(1 < 2, a, b) or a CASE guarded by IF 1,
+// always go the same way, so one of the branches is dead.
+func lintConstantConditions(node *Node_t, config LintConfig_t) []Diagnostic_t {
+	ret := make([]Diagnostic_t, 0)
+	Walk(node, func(n *Node_t) bool {
+		conditions := make([]*Node_t, 0)
+		if n.nodeType == CALL && n.left == nil && strings.EqualFold(n.tok.strVal, "IIF") && len(n.args) == 3 {
+			conditions = append(conditions, n.args[0])
+		} else if n.nodeType == MATCH_EXPR {
+			for i := 1; i < len(n.args); i += 3 {
+				if n.args[i] != nil {
+					conditions = append(conditions, n.args[i])
+				}
+			}
+		}
+		for _, cond := range conditions {
+			if isConstant(cond) {
+				ret = append(ret, newFinding("L004", cond, "the condition %s is always the same", Format(cond)))
+			}
+		}
+		return true
+	})
+	return ret
+}
+
+// returns true if the expression is made only of literals and operators on them.
+func isConstant(node *Node_t) bool {
+	ret := true
+	Walk(node, func(n *Node_t) bool {
+		switch n.nodeType {
+		case FACTOR, TERM, EXPRESSION, POWER, UNARY_OP, COMPARISON, COMPARISON_CHAIN, LIST:
+		default:
+			ret = false
+		}
+		return ret
+	})
+	return ret
+}
+
+// L005: unpacking a variable into itself, like a, b = [a, 2], leaves it as it was.
+func lintSelfAssignments(node *Node_t, config LintConfig_t) []Diagnostic_t {
+	ret := make([]Diagnostic_t, 0)
+	Walk(node, func(n *Node_t) bool {
+		if n.nodeType != UNPACK || n.left.nodeType != LIST || len(n.left.args) != len(n.ops) {
+			return true
+		}
+		for i, name := range n.ops {
+			if value := n.left.args[i]; value.nodeType == VAR_ACCESS && value.tok.strVal == name.strVal {
+				ret = append(ret, newFinding("L005", value, "%s is unpacked into itself", name.strVal))
+			}
+		}
+		return true
+	})
+	return ret
+}
@@ -0,0 +1,41 @@
+package basic
+
+import "testing"
+
+// lints src with the default config and gets the IDs of the rules it broke, in order.
+func lintCodes(t *testing.T, src string) []string {
+	t.Helper()
+	node, err := Parse(src, t.Name())
+	if err != nil {
+		t.Fatalf("%q: %s", src, err)
+	}
+	ret := make([]string, 0)
+	for _, finding := range Lint(node, DefaultLintConfig()) {
+		ret = append(ret, finding.Code)
+	}
+	return ret
+}
+
+func TestLint(t *testing.T) {
+	for src, want := range map[string][]string{
+		`IIF(1 < 2, 0, 1)`:                           {"L004"},
+		`x, y = [1, 2]` + "\n" + `IIF(x < y, 0, 1)`:  {},
+		"MATCH 1\nCASE n IF 1 THEN 0\nEND MATCH":     {"L004"},
+		"MATCH 1\nCASE n IF n > 0 THEN 0\nEND MATCH": {},
+		`a, b = [1, 2]` + "\n" + `a, b = [a, 2]`:     {"L005"},
+		`a, b = [1, 2]` + "\n" + `a, b = [b, a]`:     {},
+		`a, b = [1, 2]` + "\n" + `a - a`:             {"L003"},
+	} {
+		got := lintCodes(t, src)
+		if len(got) != len(want) {
+			t.Errorf("%q: got %v, want %v", src, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%q: got %v, want %v", src, got, want)
+				break
+			}
+		}
+	}
+}
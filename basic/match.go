@@ -0,0 +1,415 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// returns true if tokens[i] starts a MATCH expression: MATCH, the value, then a new line or
+// ':' and a CASE. Anything else with MATCH in front is an expression, since MATCH can name a
+// variable.
+func isMatch(tokens []Token_t, i int) bool {
+	if !isKeyword(tokens[i], "MATCH") {
+		return false
+	}
+	j := i + 1
+	for j < len(tokens) && !isSeparator(tokens[j]) && tokens[j].tokenType != EOF {
+		j++
+	}
+	if j == i+1 {
+		return false
+	}
+	for j < len(tokens) && isSeparator(tokens[j]) {
+		j++
+	}
+	return j < len(tokens) && isKeyword(tokens[j], "CASE")
+}
+
+// builds and returns a Match node, like
+//
+//	MATCH shape
+//	    CASE Point(0, 0) THEN "the origin"
+//	    CASE Point(x, 0) IF x > 0 THEN "on the x axis"
+//	    CASE [first, rest...] THEN first
+//	    CASE _ THEN "something else"
+//	END MATCH
+//
+// Each case takes three args: the pattern, the guard after IF (nil if there isn't one) and the
+// value after THEN. The ops are the CASEs.
+func (parser *parser_t) match() (*Node_t, error) {
+	ret := &Node_t{nodeType: MATCH_EXPR, tok: parser.currentToken}
+	parser.advance()
+	subject, err := parser.comparison()
+	if err != nil {
+		return nil, err
+	}
+	ret.left = subject
+	parser.skipSeparators()
+	for !isEnd(parser.tokens, parser.idx, "MATCH") {
+		if parser.currentToken.tokenType == EOF {
+			return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "MATCH has no END MATCH", Pos: ret.tok.pos}
+		} else if !isKeyword(parser.currentToken, "CASE") {
+			return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected CASE or END MATCH", Pos: parser.currentToken.pos}
+		}
+		ret.ops = append(ret.ops, parser.currentToken)
+		parser.advance()
+		pattern, err := parser.pattern()
+		if err != nil {
+			return nil, err
+		}
+		var guard *Node_t
+		if isKeyword(parser.currentToken, "IF") {
+			parser.advance()
+			if guard, err = parser.comparison(); err != nil {
+				return nil, err
+			}
+		}
+		if !isKeyword(parser.currentToken, "THEN") {
+			return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected THEN after the pattern of a CASE", Pos: parser.currentToken.pos}
+		}
+		parser.advance()
+		value, err := parser.comparison()
+		if err != nil {
+			return nil, err
+		}
+		ret.args = append(ret.args, pattern, guard, value)
+		if !isSeparator(parser.currentToken) && !isEnd(parser.tokens, parser.idx, "MATCH") {
+			return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected a new line or ':' after a CASE", Pos: parser.currentToken.pos}
+		}
+		parser.skipSeparators()
+	}
+	parser.advance()
+	parser.advance()
+	return ret, nil
+}
+
+// builds and returns a pattern: a name, which matches anything and binds it (_ just matches),
+// a list of patterns, where the last can be a name with ... after it to take the rest, a
+// record's TYPE with patterns for its fields, like Point(x, 0) or Point(y = 0), or else a value
+// it has to be equal to, like 1, "a", NIL or Color.RED.
+func (parser *parser_t) pattern() (*Node_t, error) {
+	tok := parser.currentToken
+	next := Token_t{tokenType: EOF}
+	if parser.idx+1 < len(parser.tokens) {
+		next = parser.tokens[parser.idx+1]
+	}
+	switch {
+	case tok.tokenType == IDENTIFIER && next.tokenType == LPAREN:
+		return parser.recordPattern()
+	case tok.tokenType == IDENTIFIER && next.tokenType != DOT && !isNilName(tok.strVal):
+		parser.advance()
+		return &Node_t{nodeType: VAR_ACCESS, tok: tok}, nil
+	case tok.tokenType != LBRACKET:
+		return parser.expression()
+	}
+
+	ret := &Node_t{nodeType: LIST, tok: tok}
+	parser.advance()
+	for parser.currentToken.tokenType != RBRACKET {
+		if len(ret.args) > 0 && ret.args[len(ret.args)-1].nodeType == REST_PARAM {
+			return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "only the last pattern in a list can take the rest of it", Pos: parser.currentToken.pos}
+		}
+		elem, err := parser.pattern()
+		if err != nil {
+			return nil, err
+		}
+		if parser.currentToken.tokenType == ELLIPSIS {
+			if elem.nodeType != VAR_ACCESS {
+				return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "... can only follow a name in a pattern", Pos: parser.currentToken.pos}
+			}
+			elem = &Node_t{nodeType: REST_PARAM, tok: parser.currentToken, left: elem}
+			parser.advance()
+		}
+		ret.args = append(ret.args, elem)
+		if parser.currentToken.tokenType == COMMA {
+			parser.advance()
+		} else if parser.currentToken.tokenType != RBRACKET {
+			return nil, &ParseError_t{Code: ERR_EXPECTED_RBRACKET, Details: "expected ',' or ']'", Pos: parser.currentToken.pos}
+		}
+	}
+	parser.advance()
+	return ret, nil
+}
+
+// builds and returns the pattern for a record, a Call node with a pattern per field. Fields
+// can be given by name, like Point(y = 0), and the ones left out match anything.
+func (parser *parser_t) recordPattern() (*Node_t, error) {
+	ret := &Node_t{nodeType: CALL, tok: parser.currentToken}
+	parser.advance()
+	parser.advance()
+	for parser.currentToken.tokenType != RPAREN {
+		var arg *Node_t
+		if parser.currentToken.tokenType == IDENTIFIER && parser.idx+1 < len(parser.tokens) && parser.tokens[parser.idx+1].tokenType == EQ {
+			arg = &Node_t{nodeType: COMPARISON, left: &Node_t{nodeType: VAR_ACCESS, tok: parser.currentToken}, tok: parser.tokens[parser.idx+1]}
+			parser.advance()
+			parser.advance()
+			field, err := parser.pattern()
+			if err != nil {
+				return nil, err
+			}
+			arg.right = field
+		} else if len(ret.args) > 0 && ret.args[len(ret.args)-1].nodeType == COMPARISON {
+			return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "a field after a named one needs a name too", Pos: parser.currentToken.pos}
+		} else {
+			field, err := parser.pattern()
+			if err != nil {
+				return nil, err
+			}
+			arg = field
+		}
+		ret.args = append(ret.args, arg)
+		if parser.currentToken.tokenType == COMMA {
+			parser.advance()
+		} else if parser.currentToken.tokenType != RPAREN {
+			return nil, &ParseError_t{Code: ERR_EXPECTED_RPAREN, Details: "expected ',' or ')'", Pos: parser.currentToken.pos}
+		}
+	}
+	parser.advance()
+	return ret, nil
+}
+
+// evaluates a MATCH_EXPR node: the value of the first CASE whose pattern matches the value
+// and whose guard holds. The value is only evaluated once, and the names a pattern binds are
+// variables in its guard and value, put back the way they were afterwards. It's an error if
+// no CASE matches.
+func (node *Node_t) evaluateMatch(interp *Interpreter_t) (*Result_t, error) {
+	subject, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(node.args); i += 3 {
+		bound := []Token_t{}
+		values := map[string]*Result_t{}
+		ok, err := interp.matches(node.args[i], subject, &bound, values)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		res, matched, err := interp.matchCase(node.args[i+1], node.args[i+2], bound, values)
+		if err != nil || matched {
+			return res, err
+		}
+	}
+	return nil, &RuntimeError_t{Code: ERR_EVALUATION, Details: fmt.Sprintf("no CASE of the MATCH matches %s", explainValue(subject)), Pos: node.tok.pos}
+}
+
+// evaluates the guard and value of a CASE whose pattern matched, with the names it bound set.
+// matched is false if the guard doesn't hold.
+func (interp *Interpreter_t) matchCase(guard *Node_t, value *Node_t, bound []Token_t, values map[string]*Result_t) (res *Result_t, matched bool, err error) {
+	for _, name := range bound {
+		if err := interp.bindable(name.strVal, name); err != nil {
+			return nil, false, err
+		}
+	}
+	old := make([]*Result_t, len(bound))
+	for i, name := range bound {
+		old[i] = interp.vars[name.strVal]
+		interp.vars[name.strVal] = values[name.strVal]
+		interp.define(name.strVal, name.pos)
+	}
+	defer func() {
+		for i, name := range bound {
+			if old[i] == nil {
+				interp.undefine(name.strVal)
+			} else {
+				interp.vars[name.strVal] = old[i]
+			}
+		}
+	}()
+	if guard != nil {
+		cond, err := guard.evaluate(interp)
+		if err != nil {
+			return nil, false, err
+		}
+		holds, err := truthy(cond)
+		if err != nil {
+			return nil, false, builtinError(Token_t{strVal: "IF", pos: guard.tok.pos}, err)
+		} else if !holds {
+			return nil, false, nil
+		}
+	}
+	res, err = value.evaluate(interp)
+	return res, true, err
+}
+
+// returns true if a value matches a pattern, adding the names the pattern binds to bound and
+// their values to values. A name that comes up twice has to match equal values both times.
+func (interp *Interpreter_t) matches(pattern *Node_t, value *Result_t, bound *[]Token_t, values map[string]*Result_t) (bool, error) {
+	switch {
+	case pattern.nodeType == VAR_ACCESS && !isNilName(pattern.tok.strVal):
+		name := pattern.tok.strVal
+		if name == "_" {
+			return true, nil
+		} else if previous, ok := values[name]; ok {
+			return valuesWithin(previous, value, interp.tolerance()), nil
+		}
+		*bound = append(*bound, pattern.tok)
+		values[name] = value
+		return true, nil
+	case pattern.nodeType == REST_PARAM:
+		return interp.matches(pattern.left, value, bound, values)
+	case pattern.nodeType == LIST:
+		return interp.matchesList(pattern, value, bound, values)
+	case pattern.nodeType == CALL:
+		return interp.matchesRecord(pattern, value, bound, values)
+	}
+	expected, err := pattern.evaluate(interp)
+	if err != nil {
+		return false, err
+	}
+	return valuesWithin(expected, value, interp.tolerance()), nil
+}
+
+// matches a list against a list pattern, element by element.
+func (interp *Interpreter_t) matchesList(pattern *Node_t, value *Result_t, bound *[]Token_t, values map[string]*Result_t) (bool, error) {
+	if value.ResultType != LIST_RESULT {
+		return false, nil
+	}
+	elems := pattern.args
+	hasRest := len(elems) > 0 && elems[len(elems)-1].nodeType == REST_PARAM
+	if hasRest {
+		elems = elems[:len(elems)-1]
+	}
+	if len(value.Lres) < len(elems) || (!hasRest && len(value.Lres) != len(elems)) {
+		return false, nil
+	}
+	for i, elem := range elems {
+		if ok, err := interp.matches(elem, value.Lres[i], bound, values); !ok || err != nil {
+			return false, err
+		}
+	}
+	if hasRest {
+		rest := NewList(append([]*Result_t{}, value.Lres[len(elems):]...))
+		return interp.matches(pattern.args[len(elems)], rest, bound, values)
+	}
+	return true, nil
+}
+
+// matches a record against a record pattern: it has to be of the TYPE the pattern names, and
+// its fields have to match the patterns for them.
+func (interp *Interpreter_t) matchesRecord(pattern *Node_t, value *Result_t, bound *[]Token_t, values map[string]*Result_t) (bool, error) {
+	if value.ResultType != RECORD_RESULT || value.Rres.Type.Name != pattern.tok.strVal {
+		return false, nil
+	}
+	fields := value.Rres.Type.Fields
+	for i, arg := range pattern.args {
+		field, fieldPattern := i, arg
+		if arg.nodeType == COMPARISON {
+			field, fieldPattern = -1, arg.right
+			for j, param := range fields {
+				if param.Name == arg.left.tok.strVal {
+					field = j
+				}
+			}
+			if field < 0 {
+				return false, &RuntimeError_t{Code: ERR_INDEX, Details: fmt.Sprintf("%s has no field %s", pattern.tok.strVal, arg.left.tok.strVal), Pos: arg.left.tok.pos}
+			}
+		} else if len(pattern.args) != len(fields) {
+			return false, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("the pattern for %s has %d field(s), but it has %d", pattern.tok.strVal, len(pattern.args), len(fields)), Pos: pattern.tok.pos}
+		}
+		if ok, err := interp.matches(fieldPattern, value.Rres.values[field], bound, values); !ok || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// gets the names a pattern binds, in order.
+func patternNames(pattern *Node_t) []Token_t {
+	switch pattern.nodeType {
+	case VAR_ACCESS:
+		if pattern.tok.strVal == "_" || isNilName(pattern.tok.strVal) {
+			return nil
+		}
+		return []Token_t{pattern.tok}
+	case REST_PARAM:
+		return patternNames(pattern.left)
+	case COMPARISON: // a field given by name
+		return patternNames(pattern.right)
+	case LIST, CALL:
+		var ret []Token_t
+		for _, arg := range pattern.args {
+			ret = append(ret, patternNames(arg)...)
+		}
+		return ret
+	}
+	return nil
+}
+
+// reads the value patterns in a pattern, like the Color.RED in [Color.RED, x], the way
+// unbound does, since they're evaluated where the MATCH is.
+func unboundPattern(pattern *Node_t, scope map[string]Position_t, found func(Token_t, map[string]Position_t)) {
+	switch pattern.nodeType {
+	case VAR_ACCESS:
+	case REST_PARAM:
+	case COMPARISON:
+		unboundPattern(pattern.right, scope, found)
+	case LIST, CALL:
+		for _, arg := range pattern.args {
+			unboundPattern(arg, scope, found)
+		}
+	default:
+		unbound(pattern, scope, found)
+	}
+}
+
+// formats a MATCH_EXPR node, with its CASEs indented one per line.
+func formatMatch(node *Node_t) string {
+	lines := []string{"MATCH " + formatExpr(node.left)}
+	for i := 0; i < len(node.args); i += 3 {
+		line := "CASE " + formatPattern(node.args[i])
+		if node.args[i+1] != nil {
+			line += " IF " + formatExpr(node.args[i+1])
+		}
+		for j, valueLine := range strings.Split(formatExpr(node.args[i+2]), "\n") {
+			if j == 0 {
+				lines = append(lines, FORMAT_INDENT+line+" THEN "+valueLine)
+			} else {
+				lines = append(lines, FORMAT_INDENT+valueLine)
+			}
+		}
+	}
+	return strings.Join(append(lines, "END MATCH"), "\n")
+}
+
+// formats a pattern of a MATCH.
+func formatPattern(pattern *Node_t) string {
+	switch pattern.nodeType {
+	case REST_PARAM:
+		return formatPattern(pattern.left) + "..."
+	case COMPARISON:
+		return pattern.left.tok.strVal + " = " + formatPattern(pattern.right)
+	case LIST, CALL:
+		elems := make([]string, len(pattern.args))
+		for i, arg := range pattern.args {
+			elems[i] = formatPattern(arg)
+		}
+		if pattern.nodeType == LIST {
+			return "[" + strings.Join(elems, ", ") + "]"
+		}
+		return pattern.tok.strVal + "(" + strings.Join(elems, ", ") + ")"
+	}
+	return formatExpr(pattern)
+}
+
+// returns true if tokens[i] is one of the words of a MATCH expression, where the parser takes
+// it as one: MATCH, CASE, IF and THEN on the line of a CASE, and END MATCH.
+func isMatchKeyword(tokens []Token_t, i int) bool {
+	atStart := func(j int) bool { return j == 0 || isSeparator(tokens[j-1]) }
+	switch {
+	case isKeyword(tokens[i], "MATCH"):
+		return isMatch(tokens, i) || (i >= 1 && isEnd(tokens, i-1, "MATCH"))
+	case isKeyword(tokens[i], "END"):
+		return isEnd(tokens, i, "MATCH")
+	case isKeyword(tokens[i], "CASE"):
+		return atStart(i)
+	case isKeyword(tokens[i], "IF"), isKeyword(tokens[i], "THEN"):
+		for j := i - 1; j >= 0 && !isSeparator(tokens[j]); j-- {
+			if isKeyword(tokens[j], "CASE") && atStart(j) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package basic
+
+import "testing"
+
+const classifyShape = pointType + `classify, unused = [LAMBDA(shape, MATCH shape
+    CASE Point(0, 0) THEN "the origin"
+    CASE Point(x, 0) IF x > 0 THEN "on the x axis, " + HEX$(x)
+    CASE Point(y = 0) THEN "left of the origin"
+    CASE [] THEN "empty"
+    CASE [a, a] THEN "a pair"
+    CASE [first, rest...] IF LEN(rest) = 0 THEN "just " + first
+    CASE [first, rest...] THEN rest
+    CASE "hi" THEN "a greeting"
+    CASE NIL THEN "nothing"
+    CASE _ THEN "something else"
+END MATCH), 0]
+`
+
+func TestMatch(t *testing.T) {
+	for arg, want := range map[string]string{
+		`Point(0, 0)`:     `the origin`,
+		`Point(10, 0)`:    `on the x axis, A`,
+		`Point(-1, 0)`:    `left of the origin`,
+		`Point(1, 1)`:     `something else`,
+		`[]`:              `empty`,
+		`["x"]`:           `just x`,
+		`["x", "y", "z"]`: `["y", "z"]`,
+		`"hi"`:            `a greeting`,
+		`NIL`:             `nothing`,
+		`["x", "x"]`:      `a pair`,
+		`42`:              `something else`,
+	} {
+		src := classifyShape + "classify(" + arg + ")"
+		if got := runValue(t, src); got != want {
+			t.Errorf("classify(%s): got %s, want %s", arg, got, want)
+		}
+	}
+}
+
+func TestMatchBindings(t *testing.T) {
+	src := "x, y = [1, 2]\nz, w = [MATCH [10, 20]\n    CASE [x, y] THEN x + y\nEND MATCH, 0]\n[x, y, z]"
+	if got, want := runValue(t, src), `[1, 2, 30]`; got != want {
+		t.Errorf("got %s, want %s: a pattern's names are put back afterwards", got, want)
+	}
+	src = "MATCH [1, 2]\n    CASE [a, a] THEN \"same\"\n    CASE [a, b] THEN \"different\"\nEND MATCH"
+	if got, want := runValue(t, src), `different`; got != want {
+		t.Errorf("a name bound twice: got %s, want %s", got, want)
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	for src, want := range map[string]ErrorCode_t{
+		"MATCH 1\n    CASE 2 THEN 3\nEND MATCH":                                  ERR_EVALUATION,
+		pointType + "MATCH Point(1, 2)\n    CASE Point(1) THEN 3\nEND MATCH":     ERR_ARG_COUNT,
+		pointType + "MATCH Point(1, 2)\n    CASE Point(z = 1) THEN 3\nEND MATCH": ERR_INDEX,
+	} {
+		if got := runErrorCode(t, Options_t{}, src); got != want {
+			t.Errorf("%q: got error code %q, want %q", src, got, want)
+		}
+	}
+	for _, src := range []string{
+		"MATCH x\n    CASE [a..., b] THEN 1\nEND MATCH",
+		"MATCH x\n    CASE 1 THEN 1",
+		"MATCH x\n    CASE Point(x = 1, 2) THEN 1\nEND MATCH",
+	} {
+		if _, err := Parse(src, t.Name()); err == nil {
+			t.Errorf("%q: got no parse error", src)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package basic
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// a 2D matrix of numbers. Like lists, matrices are values: nothing changes one once it's made.
+type Matrix_t struct {
+	rows int
+	cols int
+	data []float64 // row by row
+}
+
+// makes a matrix Result out of rows of numbers. Every row has to be the same length, and
+// there has to be at least one number.
+func NewMatrix(rows [][]float64) (*Result_t, error) {
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return nil, dimensionErrorf("a matrix needs at least one row and one column")
+	}
+	mat := newMatrix(len(rows), len(rows[0]))
+	for i, row := range rows {
+		if len(row) != mat.cols {
+			return nil, dimensionErrorf("row %d has %d column(s) but row 1 has %d", i+1, len(row), mat.cols)
+		}
+		copy(mat.data[i*mat.cols:], row)
+	}
+	return &Result_t{ResultType: MATRIX_RESULT, Mres: mat}, nil
+}
+
+// constructor for a matrix of zeros.
+func newMatrix(rows int, cols int) *Matrix_t {
+	return &Matrix_t{rows: rows, cols: cols, data: make([]float64, rows*cols)}
+}
+
+// gets the number of rows.
+func (mat *Matrix_t) Rows() int {
+	return mat.rows
+}
+
+// gets the number of columns.
+func (mat *Matrix_t) Cols() int {
+	return mat.cols
+}
+
+// gets the number in row i and column j, counting from 0.
+func (mat *Matrix_t) At(i int, j int) float64 {
+	return mat.data[i*mat.cols+j]
+}
+
+func (mat *Matrix_t) set(i int, j int, value float64) {
+	mat.data[i*mat.cols+j] = value
+}
+
+// gets the rows as slices, copied.
+func (mat *Matrix_t) Slices() [][]float64 {
+	ret := make([][]float64, mat.rows)
+	for i := range ret {
+		ret[i] = append([]float64(nil), mat.data[i*mat.cols:(i+1)*mat.cols]...)
+	}
+	return ret
+}
+
+// writes the matrix the way it's made, like MATRIX([[1, 2], [3, 4]]).
+func (mat *Matrix_t) String() string {
+	rows := make([]string, mat.rows)
+	for i, row := range mat.Slices() {
+		elems := make([]string, len(row))
+		for j, elem := range row {
+			elems[j] = strconv.FormatFloat(elem, 'g', -1, 64)
+		}
+		rows[i] = "[" + strings.Join(elems, ", ") + "]"
+	}
+	return "MATRIX([" + strings.Join(rows, ", ") + "])"
+}
+
+// error a builtin returns when matrices don't have the sizes it needs, reported as ERR_DIMENSION
+// rather than ERR_BUILTIN.
+type dimensionError_t struct {
+	details string
+}
+
+func (err *dimensionError_t) Error() string {
+	return err.details
+}
+
+func dimensionErrorf(format string, args ...interface{}) error {
+	return &dimensionError_t{details: fmt.Sprintf(format, args...)}
+}
+
+// gets a matrix out of a matrix, or out of a list of lists of numbers.
+func toMatrix(res *Result_t) (*Matrix_t, error) {
+	if res.ResultType == MATRIX_RESULT {
+		return res.Mres, nil
+	} else if res.ResultType != LIST_RESULT {
+		return nil, typeErrorf("expected a matrix or a list of rows, not %s", res.ResultType)
+	}
+	rows := make([][]float64, len(res.Lres))
+	for i, row := range res.Lres {
+		if row.ResultType != LIST_RESULT {
+			return nil, typeErrorf("row %d is a %s, not a list", i+1, row.ResultType)
+		}
+		rows[i] = make([]float64, len(row.Lres))
+		for j, elem := range row.Lres {
+			if !elem.IsNumber() {
+				return nil, typeErrorf("element %d of row %d is a %s, not a number", j+1, i+1, elem.ResultType)
+			}
+			rows[i][j] = elem.Fres // the float value is set for integers too
+		}
+	}
+	mat, err := NewMatrix(rows)
+	if err != nil {
+		return nil, err
+	}
+	return mat.Mres, nil
+}
+
+// applies a binary operator when at least one side is a matrix. Matrices add and subtract
+// elementwise when they're the same size and * is matrix multiplication; a matrix can also
+// be multiplied by a number on either side, or divided by one.
+func matrixOp(left *Result_t, right *Result_t, op Token_t) (*Result_t, error) {
+	var ret *Matrix_t
+	var err error
+	switch {
+	case left.ResultType == MATRIX_RESULT && right.ResultType == MATRIX_RESULT && op.tokenType != DIV:
+		ret, err = combine(left.Mres, right.Mres, op.tokenType)
+	case left.ResultType == MATRIX_RESULT && right.IsNumber() && (op.tokenType == MUL || op.tokenType == DIV):
+		if op.tokenType == DIV && right.Fres == 0 {
+			return nil, &RuntimeError_t{Code: ERR_DIVISION_BY_ZERO, Details: "division by zero", Pos: op.pos}
+		}
+		ret = left.Mres.scale(right.Fres, op.tokenType)
+	case left.IsNumber() && right.ResultType == MATRIX_RESULT && op.tokenType == MUL:
+		ret = right.Mres.scale(left.Fres, MUL)
+	default:
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't apply %s to values of type %s and %s", tokenSymbol(op.tokenType), left.ResultType, right.ResultType), Pos: op.pos}
+	}
+	if err != nil {
+		return nil, &RuntimeError_t{Code: ERR_DIMENSION, Details: err.Error(), Pos: op.pos}
+	}
+	return &Result_t{ResultType: MATRIX_RESULT, Mres: ret}, nil
+}
+
+// adds, subtracts or multiplies two matrices.
+func combine(a *Matrix_t, b *Matrix_t, op TokenType_t) (*Matrix_t, error) {
+	if op == MUL {
+		if a.cols != b.rows {
+			return nil, dimensionErrorf("can't multiply a %dx%d matrix by a %dx%d matrix", a.rows, a.cols, b.rows, b.cols)
+		}
+		ret := newMatrix(a.rows, b.cols)
+		for i := 0; i < a.rows; i++ {
+			for j := 0; j < b.cols; j++ {
+				sum := 0.0
+				for k := 0; k < a.cols; k++ {
+					sum += a.At(i, k) * b.At(k, j)
+				}
+				ret.set(i, j, sum)
+			}
+		}
+		return ret, nil
+	}
+	if a.rows != b.rows || a.cols != b.cols {
+		return nil, dimensionErrorf("can't apply %s to a %dx%d matrix and a %dx%d matrix", tokenSymbol(op), a.rows, a.cols, b.rows, b.cols)
+	}
+	ret := newMatrix(a.rows, a.cols)
+	for i := range ret.data {
+		ret.data[i] = floatop(a.data[i], b.data[i], op)
+	}
+	return ret, nil
+}
+
+// multiplies or divides every element by a number.
+func (mat *Matrix_t) scale(factor float64, op TokenType_t) *Matrix_t {
+	ret := newMatrix(mat.rows, mat.cols)
+	for i, elem := range mat.data {
+		ret.data[i] = floatop(elem, factor, op)
+	}
+	return ret
+}
+
+// gets the matrix with its rows and columns swapped.
+func (mat *Matrix_t) transpose() *Matrix_t {
+	ret := newMatrix(mat.cols, mat.rows)
+	for i := 0; i < mat.rows; i++ {
+		for j := 0; j < mat.cols; j++ {
+			ret.set(j, i, mat.At(i, j))
+		}
+	}
+	return ret
+}
+
+// gets the determinant of a square matrix, by Gaussian elimination with partial pivoting.
+func (mat *Matrix_t) determinant() (float64, error) {
+	if mat.rows != mat.cols {
+		return 0, dimensionErrorf("only square matrices have a determinant, not %dx%d", mat.rows, mat.cols)
+	}
+	work := mat.scale(1, MUL)
+	det := 1.0
+	for col := 0; col < work.cols; col++ {
+		pivot := work.pivotRow(col)
+		if work.At(pivot, col) == 0 {
+			return 0, nil
+		}
+		if pivot != col {
+			work.swapRows(pivot, col)
+			det = -det
+		}
+		det *= work.At(col, col)
+		for row := col + 1; row < work.rows; row++ {
+			work.addRow(row, col, -work.At(row, col)/work.At(col, col))
+		}
+	}
+	return det, nil
+}
+
+// gets the inverse of a square matrix, by Gauss-Jordan elimination with partial pivoting.
+func (mat *Matrix_t) inverse() (*Matrix_t, error) {
+	if mat.rows != mat.cols {
+		return nil, dimensionErrorf("only square matrices have an inverse, not %dx%d", mat.rows, mat.cols)
+	}
+	n := mat.rows
+	work := newMatrix(n, 2*n) // the matrix with the identity next to it
+	for i := 0; i < n; i++ {
+		copy(work.data[i*2*n:], mat.data[i*n:(i+1)*n])
+		work.set(i, n+i, 1)
+	}
+	for col := 0; col < n; col++ {
+		pivot := work.pivotRow(col)
+		if math.Abs(work.At(pivot, col)) < 1e-12 {
+			return nil, fmt.Errorf("the matrix is singular, so it has no inverse")
+		}
+		work.swapRows(pivot, col)
+		work.scaleRow(col, 1/work.At(col, col))
+		for row := 0; row < n; row++ {
+			if row != col {
+				work.addRow(row, col, -work.At(row, col))
+			}
+		}
+	}
+	ret := newMatrix(n, n)
+	for i := 0; i < n; i++ {
+		copy(ret.data[i*n:(i+1)*n], work.data[i*2*n+n:(i+1)*2*n])
+	}
+	return ret, nil
+}
+
+// gets the row, from col down, with the biggest number in column col.
+func (mat *Matrix_t) pivotRow(col int) int {
+	ret := col
+	for row := col + 1; row < mat.rows; row++ {
+		if math.Abs(mat.At(row, col)) > math.Abs(mat.At(ret, col)) {
+			ret = row
+		}
+	}
+	return ret
+}
+
+func (mat *Matrix_t) swapRows(a int, b int) {
+	for j := 0; j < mat.cols; j++ {
+		mat.data[a*mat.cols+j], mat.data[b*mat.cols+j] = mat.At(b, j), mat.At(a, j)
+	}
+}
+
+func (mat *Matrix_t) scaleRow(row int, factor float64) {
+	for j := 0; j < mat.cols; j++ {
+		mat.data[row*mat.cols+j] *= factor
+	}
+}
+
+// adds factor times row from to row to.
+func (mat *Matrix_t) addRow(to int, from int, factor float64) {
+	for j := 0; j < mat.cols; j++ {
+		mat.data[to*mat.cols+j] += factor * mat.At(from, j)
+	}
+}
+
+// the linear algebra builtins. They all take a matrix or a list of rows.
+func init() {
+	RegisterBuiltin("MATRIX", 1, func(args []*Result_t) (*Result_t, error) { // MATRIX([[1, 2], [3, 4]])
+		mat, err := toMatrix(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &Result_t{ResultType: MATRIX_RESULT, Mres: mat}, nil
+	})
+	RegisterBuiltin("TRANSPOSE", 1, func(args []*Result_t) (*Result_t, error) {
+		mat, err := toMatrix(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &Result_t{ResultType: MATRIX_RESULT, Mres: mat.transpose()}, nil
+	})
+	RegisterBuiltin("DET", 1, func(args []*Result_t) (*Result_t, error) {
+		mat, err := toMatrix(args[0])
+		if err != nil {
+			return nil, err
+		}
+		det, err := mat.determinant()
+		if err != nil {
+			return nil, err
+		}
+		return NewFloat(det), nil
+	})
+	RegisterBuiltin("INVERSE", 1, func(args []*Result_t) (*Result_t, error) {
+		mat, err := toMatrix(args[0])
+		if err != nil {
+			return nil, err
+		}
+		inv, err := mat.inverse()
+		if err != nil {
+			return nil, err
+		}
+		return &Result_t{ResultType: MATRIX_RESULT, Mres: inv}, nil
+	})
+}
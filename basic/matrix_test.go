@@ -0,0 +1,60 @@
+package basic
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMatrix(t *testing.T) {
+	for src, want := range map[string]string{
+		`MATRIX([[1, 2], [3, 4]])`:                            `MATRIX([[1, 2], [3, 4]])`,
+		`MATRIX([[1, 2], [3, 4]]) + MATRIX([[1, 1], [1, 1]])`: `MATRIX([[2, 3], [4, 5]])`,
+		`MATRIX([[1, 2], [3, 4]]) - MATRIX([[1, 1], [1, 1]])`: `MATRIX([[0, 1], [2, 3]])`,
+		`MATRIX([[1, 2], [3, 4]]) * MATRIX([[5], [6]])`:       `MATRIX([[17], [39]])`,
+		`2 * MATRIX([[1, 2], [3, 4]])`:                        `MATRIX([[2, 4], [6, 8]])`,
+		`MATRIX([[1, 2], [3, 4]]) / 2`:                        `MATRIX([[0.5, 1], [1.5, 2]])`,
+		`TRANSPOSE([[1, 2, 3], [4, 5, 6]])`:                   `MATRIX([[1, 4], [2, 5], [3, 6]])`,
+		`DET([[1, 2], [3, 4]])`:                               `-2.0`,
+		`DET(MATRIX([[2, 0, 0], [0, 3, 0], [0, 0, 4]]))`:      `24.0`,
+		`INVERSE([[2, 0], [0, 4]])`:                           `MATRIX([[0.5, 0], [0, 0.25]])`,
+		`DET(INVERSE([[2, 0], [0, 4]]))`:                      `0.125`,
+	} {
+		if got := runValue(t, src); got != want {
+			t.Errorf("%q: got %s, want %s", src, got, want)
+		}
+	}
+	for src, want := range map[string]ErrorCode_t{
+		`MATRIX([[1, 2], [3]])`:                 ERR_DIMENSION,
+		`MATRIX([])`:                            ERR_DIMENSION,
+		`MATRIX([[1, "x"]])`:                    ERR_TYPE,
+		`MATRIX([[1, 2]]) + MATRIX([[1], [2]])`: ERR_DIMENSION,
+		`MATRIX([[1, 2]]) * MATRIX([[1, 2]])`:   ERR_DIMENSION,
+		`MATRIX([[1, 2]]) / 0`:                  ERR_DIVISION_BY_ZERO,
+		`MATRIX([[1, 2]]) + 1`:                  ERR_TYPE,
+		`DET([[1, 2, 3], [4, 5, 6]])`:           ERR_DIMENSION,
+		`INVERSE([[1, 2], [2, 4]])`:             ERR_BUILTIN,
+	} {
+		if got := runErrorCode(t, Options_t{}, src); got != want {
+			t.Errorf("%q: got error code %q, want %q", src, got, want)
+		}
+	}
+}
+
+func TestNewMatrix(t *testing.T) {
+	res, err := NewMatrix([][]float64{{1, 2, 3}, {4, 5, 6}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mat := res.Mres
+	if mat.Rows() != 2 || mat.Cols() != 3 || mat.At(1, 2) != 6 {
+		t.Errorf("got a %dx%d matrix with %g at (1, 2)", mat.Rows(), mat.Cols(), mat.At(1, 2))
+	}
+	rows := mat.Slices()
+	rows[0][0] = math.Pi
+	if mat.At(0, 0) != 1 {
+		t.Errorf("changing what Slices gave back changed the matrix")
+	}
+	if _, err := NewMatrix([][]float64{{1}, {2, 3}}); err == nil {
+		t.Errorf("ragged rows: got no error")
+	}
+}
@@ -0,0 +1,132 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// builds the rest of a comparison whose left side is an IN or BETWEEN, like x IN [1, 2, 3] or
+// x BETWEEN 1 AND 10, once the left side has been parsed. They don't chain with the other
+// comparisons, so ok is false if the current token isn't one of them.
+func (parser *parser_t) membership(left *Node_t) (ret *Node_t, ok bool, err error) {
+	operator := parser.currentToken
+	if isKeyword(operator, "IN") {
+		parser.advance()
+		right, err := parser.coalesce()
+		if err != nil {
+			return nil, true, err
+		}
+		return &Node_t{nodeType: IN_OP, left: left, tok: operator, right: right}, true, nil
+	} else if !isKeyword(operator, "BETWEEN") {
+		return nil, false, nil
+	}
+
+	parser.advance()
+	low, err := parser.coalesce()
+	if err != nil {
+		return nil, true, err
+	}
+	if !isKeyword(parser.currentToken, "AND") {
+		return nil, true, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected AND after BETWEEN", Pos: parser.currentToken.pos}
+	}
+	and := parser.currentToken
+	parser.advance()
+	high, err := parser.coalesce()
+	if err != nil {
+		return nil, true, err
+	}
+	return &Node_t{nodeType: BETWEEN_OP, left: left, tok: operator, args: []*Node_t{low, high}, ops: []Token_t{and}}, true, nil
+}
+
+// evaluates an IN_OP node to 1 if left is in right and 0 if it isn't: one of the elements of a
+// list, equal the way = has it, a piece of a string, or one of the keys of a dict. Strings and
+// keys ignore case if the IgnoreCase option is on, like = does.
+func (node *Node_t) evaluateIn(interp *Interpreter_t) (*Result_t, error) {
+	item, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := node.right.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case collection.ResultType == LIST_RESULT:
+		tol := interp.tolerance()
+		for _, elem := range collection.Lres {
+			if valuesWithin(item, elem, tol) {
+				return boolResult(true), nil
+			}
+		}
+		return boolResult(false), nil
+	case collection.ResultType == STRING_RESULT && item.ResultType == STRING_RESULT:
+		if interp.opts.IgnoreCase {
+			return boolResult(strings.Contains(strings.ToLower(collection.Sres), strings.ToLower(item.Sres))), nil
+		}
+		return boolResult(strings.Contains(collection.Sres, item.Sres)), nil
+	case collection.ResultType == DICT_RESULT && item.ResultType == STRING_RESULT:
+		_, ok := collection.Dres.Get(item.Sres)
+		for _, key := range collection.Dres.keys {
+			ok = ok || (interp.opts.IgnoreCase && compareText(key, item.Sres, true) == 0)
+		}
+		return boolResult(ok), nil
+	}
+	return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't apply %s to values of type %s and %s", "IN", item.ResultType, collection.ResultType), Pos: node.tok.pos}
+}
+
+// evaluates a BETWEEN_OP node to 1 if left is from args[0] to args[1], both included, and 0 if
+// it isn't. Like <=, it works for numbers and strings.
+func (node *Node_t) evaluateBetween(interp *Interpreter_t) (*Result_t, error) {
+	values := make([]*Result_t, 3)
+	for i, operand := range []*Node_t{node.left, node.args[0], node.args[1]} {
+		value, err := operand.evaluate(interp)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	item, low, high := values[0], values[1], values[2]
+	if err := interp.checkEnums(low, item, node.tok); err != nil {
+		return nil, err
+	} else if err := interp.checkEnums(item, high, node.ops[0]); err != nil {
+		return nil, err
+	}
+	tol := Tolerance_t{IgnoreCase: interp.opts.IgnoreCase}
+	aboveLow, err := compare(low, item, LE, tol)
+	if err != nil {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't apply %s to values of type %s and %s", "BETWEEN", item.ResultType, low.ResultType), Pos: node.tok.pos}
+	}
+	belowHigh, err := compare(item, high, LE, tol)
+	if err != nil {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't apply %s to values of type %s and %s", "BETWEEN", item.ResultType, high.ResultType), Pos: node.ops[0].pos}
+	}
+	return boolResult(aboveLow && belowHigh), nil
+}
+
+// returns true if tokens[i] is an IN or BETWEEN used as an operator, or the AND of a BETWEEN,
+// where the parser takes it as one: straight after an operand.
+func isOperatorKeyword(tokens []Token_t, i int) bool {
+	afterOperand := func(j int) bool {
+		if j == 0 {
+			return false
+		}
+		switch tokens[j-1].tokenType {
+		case INT, FLOAT, STRING, IDENTIFIER, PARAM, RPAREN, RBRACKET, RBRACE:
+			return true
+		}
+		return false
+	}
+	switch {
+	case isKeyword(tokens[i], "IN"), isKeyword(tokens[i], "BETWEEN"):
+		return afterOperand(i)
+	case isKeyword(tokens[i], "AND"):
+		for j := i - 1; j >= 0 && !isSeparator(tokens[j]); j-- {
+			if isKeyword(tokens[j], "AND") && afterOperand(j) {
+				return false // that one closed the BETWEEN
+			} else if isKeyword(tokens[j], "BETWEEN") && afterOperand(j) {
+				return afterOperand(i)
+			}
+		}
+	}
+	return false
+}
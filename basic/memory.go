@@ -0,0 +1,93 @@
+package basic
+
+import "fmt"
+
+// size of an interpreter's virtual memory, in bytes: addresses run from 0 to MEMORY_SIZE - 1.
+const MEMORY_SIZE = 65536
+
+// gets the interpreter's virtual memory, the bytes PEEK reads and POKE writes. Embedders can
+// read and change it directly, before or between Runs. It keeps its contents from one Run to the next.
+func (interp *Interpreter_t) Memory() []byte {
+	if interp.memory == nil { // most programs never touch it, so it's only made when needed
+		interp.memory = make([]byte, MEMORY_SIZE)
+	}
+	return interp.memory
+}
+
+// returns true if the tokens at the start of a statement make a POKE rather than an expression
+// using a variable called POKE: the word has to be followed by something that starts an address.
+func isPoke(tokens []Token_t, i int) bool {
+	if !isKeyword(tokens[i], "POKE") || i+1 >= len(tokens) {
+		return false
+	}
+	switch tokens[i+1].tokenType {
+	case INT, FLOAT, IDENTIFIER, LPAREN:
+		return true
+	}
+	return false
+}
+
+// builds and returns a Poke node: POKE address, value.
+func (parser *parser_t) poke() (*Node_t, error) {
+	ret := &Node_t{nodeType: POKE, tok: parser.currentToken}
+	parser.advance()
+	addr, err := parser.comparison()
+	if err != nil {
+		return nil, err
+	}
+	if parser.currentToken.tokenType != COMMA {
+		return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected ',' between POKE's address and value", Pos: parser.currentToken.pos}
+	}
+	parser.advance()
+	value, err := parser.comparison()
+	if err != nil {
+		return nil, err
+	}
+	ret.args = []*Node_t{addr, value}
+	return ret, nil
+}
+
+// evaluates an address for PEEK or POKE, which has to be an int inside memory.
+func address(res *Result_t, tok Token_t) (int, error) {
+	if res.ResultType != INTEGER {
+		return 0, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("%s: address is a %s, not an int", tok.strVal, res.ResultType), Pos: tok.pos}
+	} else if res.Ires < 0 || res.Ires >= MEMORY_SIZE {
+		return 0, &RuntimeError_t{Code: ERR_MEMORY, Details: fmt.Sprintf("%s: address %d is outside memory (0 to %d)", tok.strVal, res.Ires, MEMORY_SIZE-1), Pos: tok.pos}
+	}
+	return int(res.Ires), nil
+}
+
+// evaluates a POKE node, writing a byte to memory. Its value is 0.
+func (node *Node_t) evaluatePoke(interp *Interpreter_t) (*Result_t, error) {
+	args, err := interp.evaluateArgs(node)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := address(args[0], node.tok)
+	if err != nil {
+		return nil, err
+	}
+	if args[1].ResultType != INTEGER {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("%s: value is a %s, not an int", node.tok.strVal, args[1].ResultType), Pos: node.tok.pos}
+	} else if args[1].Ires < 0 || args[1].Ires > 255 {
+		return nil, &RuntimeError_t{Code: ERR_MEMORY, Details: fmt.Sprintf("%s: value %d doesn't fit in a byte (0 to 255)", node.tok.strVal, args[1].Ires), Pos: node.tok.pos}
+	}
+	interp.Memory()[addr] = byte(args[1].Ires)
+	return NewInt(0), nil
+}
+
+// evaluates PEEK(address): the byte at that address, as an int.
+func (interp *Interpreter_t) peekCall(node *Node_t) (*Result_t, error) {
+	if len(node.args) != 1 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 1 argument(s), got %d", node.tok.strVal, len(node.args)), Pos: node.tok.pos}
+	}
+	res, err := node.args[0].evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := address(res, node.tok)
+	if err != nil {
+		return nil, err
+	}
+	return NewInt(int64(interp.Memory()[addr])), nil
+}
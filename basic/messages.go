@@ -0,0 +1,398 @@
+package basic
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// translations of error and warning messages, keyed by locale and then by the English format
+// string the message is made from. Translations keep the verbs of the English, in the same order
+// unless they say otherwise with indexes like %[2]s. Messages without a translation stay in
+// English, and error codes are never translated, so tooling can rely on them in any language.
+var catalogs = map[string]map[string]string{
+	"de": {
+		"%s at line %d, col %d in file %s [%s]":                           "%s in Zeile %d, Spalte %d in Datei %s [%s]",
+		"%s: %s":                                                          "%s: %s",
+		"%s (not allowed in strict mode)":                                 "%s (im strikten Modus nicht erlaubt)",
+		"division by zero":                                                "Division durch null",
+		"variable %s is not defined":                                      "Variable %s ist nicht definiert",
+		"placeholder ?%s has no value":                                    "Platzhalter ?%s hat keinen Wert",
+		"variable %s is not defined; did you mean %s?":                    "Variable %s ist nicht definiert; meinten Sie %s?",
+		"%s (defined at line %d, col %d)":                                 "%s (definiert in Zeile %d, Spalte %d)",
+		"function %s is not defined":                                      "Funktion %s ist nicht definiert",
+		"%s takes %d argument(s), got %d":                                 "%s erwartet %d Argument(e), erhielt %d",
+		"%s is missing argument %s":                                       "%s fehlt das Argument %s",
+		"... can only follow a parameter of a LAMBDA":                     "... kann nur auf einen Parameter eines LAMBDA folgen",
+		"%s gets argument %s twice":                                       "%s erhält das Argument %s zweimal",
+		"%s: argument %d comes after a named one, so it needs a name too": "%s: Argument %d folgt auf ein benanntes und braucht daher auch einen Namen",
+		"can't use ?. on a value of type %s":                              "?. kann nicht auf einen Wert vom Typ %s angewendet werden",
+		"can't unpack a value of type %s, only a list":                    "ein Wert vom Typ %s kann nicht entpackt werden, nur eine Liste",
+		"can't unpack a list of %d value(s) into %d variables":            "eine Liste mit %d Wert(en) kann nicht in %d Variablen entpackt werden",
+		"%s is unpacked into more than once":                              "%s wird mehr als einmal entpackt",
+		"calls went more than the limit of %d deep":                       "Aufrufe waren tiefer verschachtelt als die Grenze von %d",
+		"%s compares floats exactly, which rounding can throw off; write ~= to compare them within a tolerance": "%s vergleicht Gleitkommazahlen exakt, was durch Rundung verfälscht werden kann; schreiben Sie ~=, um sie mit einer Toleranz zu vergleichen",
+		"the variable %s hides the builtin %s":                                            "die Variable %s verdeckt die eingebaute Funktion %s",
+		"%s is bound but never read; start its name with _ if that's on purpose":          "%s wird gebunden, aber nie gelesen; beginnen Sie den Namen mit _, wenn das Absicht ist",
+		"database path %s can't have a ? or # in it":                                      "Datenbankpfad %s darf kein ? oder # enthalten",
+		"%s can't run %s, it would reach files outside the database":                      "%s kann %s nicht ausführen, es würde auf Dateien außerhalb der Datenbank zugreifen",
+		"a %s of %d elements is longer than the limit of %d":                              "ein %s mit %d Elementen ist länger als die erlaubten %d",
+		"a string of %d bytes is longer than the limit of %d":                             "ein String mit %d Bytes ist länger als die erlaubten %d",
+		"program made more than the limit of %d bytes of strings and lists":               "Programm hat mehr als die erlaubten %d Bytes an Strings und Listen erzeugt",
+		"took longer than its limit of %s":                                                "hat länger als die erlaubten %s gedauert",
+		"%s gave more rows than the limit of %d":                                          "%s ergab mehr Zeilen als die erlaubten %d",
+		"%d isn't an open database":                                                       "%d ist keine offene Datenbank",
+		"databases can only be opened from the OS's files":                                "Datenbanken können nur aus Dateien des Betriebssystems geöffnet werden",
+		"there's no %s driver registered, the program running go-basic has to import one": "es ist kein %s-Treiber registriert, das Programm, das go-basic ausführt, muss einen importieren",
+		"%s isn't an http or https URL":                                                   "%s ist keine http- oder https-URL",
+		"%s %s gave %s":                                                                   "%s %s ergab %s",
+		"response is longer than the limit of %d bytes":                                   "Antwort ist länger als die erlaubten %d Bytes",
+		"reading %s isn't permitted":                                                      "Lesen von %s ist nicht erlaubt",
+		"writing %s isn't permitted":                                                      "Schreiben von %s ist nicht erlaubt",
+		"%s needs the %s permission":                                                      "%s braucht die Berechtigung %s",
+		"number is longer than the limit of %d bytes":                                     "Zahl ist länger als die erlaubten %d Bytes",
+		"name is longer than the limit of %d bytes":                                       "Name ist länger als die erlaubten %d Bytes",
+		"string literal is longer than the limit of %d bytes":                             "Zeichenkette ist länger als die erlaubten %d Bytes",
+		"%s is a %s, not a %s":                                                            "%s ist ein %s, kein %s",
+		"expected a type after AS, like INT or the name of a TYPE":                        "nach AS wird ein Typ erwartet, wie INT oder der Name eines TYPE",
+		"%s needs a name or a function, not a %s":                                         "%s braucht einen Namen oder eine Funktion, kein %s",
+		"there's no function called %s to help with":                                      "es gibt keine Funktion namens %s, zu der es Hilfe gibt",
+		"no CASE of the MATCH matches %s":                                                 "kein CASE des MATCH passt auf %s",
+		"expected THEN after the pattern of a CASE":                                       "THEN nach dem Muster eines CASE erwartet",
+		"the pattern for %s has %d field(s), but it has %d":                               "das Muster für %s hat %d Feld(er), der Typ aber %d",
+		"ENUM %s: %s has to be an int, not a %s":                                          "ENUM %s: %s muss eine ganze Zahl sein, kein Wert vom Typ %s",
+		"%s compares %s with %s; write INT() around a member to compare it as a number":   "%s vergleicht %s mit %s; schreiben Sie INT() um ein Element, um es als Zahl zu vergleichen",
+		"%s %s has no END %s":                                                             "%s %s hat kein END %s",
+		"%s %s has %s more than once":                                                     "%s %s hat %s mehr als einmal",
+		"TYPE %s: %s has to be a function, not a %s":                                      "TYPE %s: %s muss eine Funktion sein, kein Wert vom Typ %s",
+		"%s has no field %s":                                                              "%s hat kein Feld %s",
+		"can't use . on a value of type %s":                                               ". kann nicht auf einen Wert vom Typ %s angewendet werden",
+		"expected a field after '.'":                                                      "Feld nach '.' erwartet",
+		"can't apply %s to values of type %s and %s":                                      "%s kann nicht auf Werte vom Typ %s und %s angewendet werden",
+		"expected ')'":                                   "')' erwartet",
+		"expected operator":                              "Operator erwartet",
+		"expected factor":                                "Faktor erwartet",
+		"string literal is never closed":                 "Zeichenkette wird nie geschlossen",
+		"illegal character '%c'":                         "ungültiges Zeichen '%c'",
+		"index %d is out of range for a %s of length %d": "Index %d liegt außerhalb des Bereichs (%s der Länge %d)",
+		"key %s is not in the dict":                      "Schlüssel %s ist nicht im Dict",
+		"program took more than the limit of %d steps":   "Programm brauchte mehr als die erlaubten %d Schritte",
+		"program ran longer than the limit of %s":        "Programm lief länger als die erlaubten %s",
+		"program ran past its deadline":                  "Programm lief über seine Frist hinaus",
+		"program was cancelled":                          "Programm wurde abgebrochen",
+		"module %s not found (looked in %s)":             "Modul %s nicht gefunden (gesucht in %s)",
+		"IMPORT is disabled here":                        "IMPORT ist hier deaktiviert",
+		"%s is read-only":                                "%s ist schreibgeschützt",
+		"FOR EACH %s has no NEXT":                        "FOR EACH %s hat kein NEXT",
+		"square root of negative number %g":              "Quadratwurzel der negativen Zahl %g",
+		"logarithm of non-positive number %g":            "Logarithmus der nicht positiven Zahl %g",
+		"left operand %s of %s is the int %d, implicitly converted to float; write FLOAT(%s) to convert it explicitly":  "linker Operand %s von %s ist der Int %d, implizit in Float umgewandelt; schreiben Sie FLOAT(%s), um ihn explizit umzuwandeln",
+		"right operand %s of %s is the int %d, implicitly converted to float; write FLOAT(%s) to convert it explicitly": "rechter Operand %s von %s ist der Int %d, implizit in Float umgewandelt; schreiben Sie FLOAT(%s), um ihn explizit umzuwandeln",
+		"integer division %d / %d truncates to %d": "Ganzzahldivision %d / %d wird auf %d abgeschnitten",
+	},
+	"fr": {
+		"%s at line %d, col %d in file %s [%s]":                           "%s à la ligne %d, colonne %d du fichier %s [%s]",
+		"%s: %s":                                                          "%s : %s",
+		"%s (not allowed in strict mode)":                                 "%s (interdit en mode strict)",
+		"division by zero":                                                "division par zéro",
+		"variable %s is not defined":                                      "la variable %s n'est pas définie",
+		"placeholder ?%s has no value":                                    "le paramètre ?%s n'a pas de valeur",
+		"variable %s is not defined; did you mean %s?":                    "la variable %s n'est pas définie ; vouliez-vous dire %s ?",
+		"%s (defined at line %d, col %d)":                                 "%s (définie à la ligne %d, colonne %d)",
+		"function %s is not defined":                                      "la fonction %s n'est pas définie",
+		"%s takes %d argument(s), got %d":                                 "%s prend %d argument(s), %d reçu(s)",
+		"%s is missing argument %s":                                       "il manque l'argument %[2]s à %[1]s",
+		"... can only follow a parameter of a LAMBDA":                     "... ne peut suivre qu'un paramètre d'un LAMBDA",
+		"%s gets argument %s twice":                                       "%s reçoit l'argument %s deux fois",
+		"%s: argument %d comes after a named one, so it needs a name too": "%s : l'argument %d suit un argument nommé, il doit donc être nommé aussi",
+		"can't use ?. on a value of type %s":                              "impossible d'utiliser ?. sur une valeur de type %s",
+		"can't unpack a value of type %s, only a list":                    "impossible de décomposer une valeur de type %s, seulement une liste",
+		"can't unpack a list of %d value(s) into %d variables":            "impossible de décomposer une liste de %d valeur(s) en %d variables",
+		"%s is unpacked into more than once":                              "%s reçoit plus d'une valeur décomposée",
+		"calls went more than the limit of %d deep":                       "les appels sont imbriqués au-delà de la limite de %d",
+		"%s compares floats exactly, which rounding can throw off; write ~= to compare them within a tolerance": "%s compare des flottants exactement, ce que les arrondis peuvent fausser ; écrivez ~= pour les comparer avec une tolérance",
+		"the variable %s hides the builtin %s":                                            "la variable %s masque la fonction intégrée %s",
+		"%s is bound but never read; start its name with _ if that's on purpose":          "%s est liée mais jamais lue ; commencez son nom par _ si c'est voulu",
+		"database path %s can't have a ? or # in it":                                      "le chemin de base de données %s ne peut pas contenir ? ou #",
+		"%s can't run %s, it would reach files outside the database":                      "%s ne peut pas exécuter %s, cela atteindrait des fichiers hors de la base de données",
+		"a %s of %d elements is longer than the limit of %d":                              "un %s de %d éléments dépasse la limite de %d",
+		"a string of %d bytes is longer than the limit of %d":                             "une chaîne de %d octets dépasse la limite de %d",
+		"program made more than the limit of %d bytes of strings and lists":               "le programme a créé plus que la limite de %d octets de chaînes et de listes",
+		"took longer than its limit of %s":                                                "a pris plus longtemps que sa limite de %s",
+		"%s gave more rows than the limit of %d":                                          "%s a donné plus de lignes que la limite de %d",
+		"%d isn't an open database":                                                       "%d n'est pas une base de données ouverte",
+		"databases can only be opened from the OS's files":                                "les bases de données ne peuvent être ouvertes que depuis les fichiers du système",
+		"there's no %s driver registered, the program running go-basic has to import one": "aucun pilote %s n'est enregistré, le programme qui exécute go-basic doit en importer un",
+		"%s isn't an http or https URL":                                                   "%s n'est pas une URL http ou https",
+		"%s %s gave %s":                                                                   "%s %s a donné %s",
+		"response is longer than the limit of %d bytes":                                   "la réponse dépasse la limite de %d octets",
+		"reading %s isn't permitted":                                                      "la lecture de %s n'est pas autorisée",
+		"writing %s isn't permitted":                                                      "l'écriture de %s n'est pas autorisée",
+		"%s needs the %s permission":                                                      "%s nécessite la permission %s",
+		"number is longer than the limit of %d bytes":                                     "le nombre dépasse la limite de %d octets",
+		"name is longer than the limit of %d bytes":                                       "le nom dépasse la limite de %d octets",
+		"string literal is longer than the limit of %d bytes":                             "la chaîne dépasse la limite de %d octets",
+		"%s is a %s, not a %s":                                                            "%s est un %s, pas un %s",
+		"expected a type after AS, like INT or the name of a TYPE":                        "un type est attendu après AS, comme INT ou le nom d'un TYPE",
+		"%s needs a name or a function, not a %s":                                         "%s attend un nom ou une fonction, pas un %s",
+		"there's no function called %s to help with":                                      "il n'y a pas de fonction nommée %s pour laquelle afficher l'aide",
+		"no CASE of the MATCH matches %s":                                                 "aucun CASE du MATCH ne correspond à %s",
+		"expected THEN after the pattern of a CASE":                                       "THEN attendu après le motif d'un CASE",
+		"the pattern for %s has %d field(s), but it has %d":                               "le motif de %s a %d champ(s), mais le type en a %d",
+		"ENUM %s: %s has to be an int, not a %s":                                          "ENUM %s : %s doit être un entier, pas une valeur de type %s",
+		"%s compares %s with %s; write INT() around a member to compare it as a number":   "%s compare %s avec %s ; écrivez INT() autour d'un membre pour le comparer comme un nombre",
+		"%s %s has no END %s":                                                             "%s %s n'a pas de END %s",
+		"%s %s has %s more than once":                                                     "%s %s a %s plus d'une fois",
+		"TYPE %s: %s has to be a function, not a %s":                                      "TYPE %s : %s doit être une fonction, pas une valeur de type %s",
+		"%s has no field %s":                                                              "%s n'a pas de champ %s",
+		"can't use . on a value of type %s":                                               "impossible d'utiliser . sur une valeur de type %s",
+		"expected a field after '.'":                                                      "champ attendu après '.'",
+		"can't apply %s to values of type %s and %s":                                      "impossible d'appliquer %s à des valeurs de type %s et %s",
+		"expected ')'":                                   "')' attendu",
+		"expected operator":                              "opérateur attendu",
+		"expected factor":                                "facteur attendu",
+		"string literal is never closed":                 "la chaîne n'est jamais fermée",
+		"illegal character '%c'":                         "caractère illégal '%c'",
+		"index %d is out of range for a %s of length %d": "l'indice %d est hors limites pour un %s de longueur %d",
+		"key %s is not in the dict":                      "la clé %s n'est pas dans le dict",
+		"program took more than the limit of %d steps":   "le programme a dépassé la limite de %d étapes",
+		"program ran longer than the limit of %s":        "le programme a dépassé la limite de durée de %s",
+		"program ran past its deadline":                  "le programme a dépassé son échéance",
+		"program was cancelled":                          "le programme a été annulé",
+		"module %s not found (looked in %s)":             "module %s introuvable (cherché dans %s)",
+		"IMPORT is disabled here":                        "IMPORT est désactivé ici",
+		"%s is read-only":                                "%s est en lecture seule",
+		"FOR EACH %s has no NEXT":                        "FOR EACH %s n'a pas de NEXT",
+		"square root of negative number %g":              "racine carrée du nombre négatif %g",
+		"logarithm of non-positive number %g":            "logarithme du nombre non positif %g",
+		"left operand %s of %s is the int %d, implicitly converted to float; write FLOAT(%s) to convert it explicitly":  "l'opérande gauche %s de %s est l'entier %d, converti implicitement en flottant ; écrivez FLOAT(%s) pour le convertir explicitement",
+		"right operand %s of %s is the int %d, implicitly converted to float; write FLOAT(%s) to convert it explicitly": "l'opérande droit %s de %s est l'entier %d, converti implicitement en flottant ; écrivez FLOAT(%s) pour le convertir explicitement",
+		"integer division %d / %d truncates to %d": "la division entière %d / %d est tronquée à %d",
+	},
+	"es": {
+		"%s at line %d, col %d in file %s [%s]":                           "%s en la línea %d, columna %d del archivo %s [%s]",
+		"%s: %s":                                                          "%s: %s",
+		"%s (not allowed in strict mode)":                                 "%s (no permitido en modo estricto)",
+		"division by zero":                                                "división por cero",
+		"variable %s is not defined":                                      "la variable %s no está definida",
+		"placeholder ?%s has no value":                                    "el marcador ?%s no tiene valor",
+		"variable %s is not defined; did you mean %s?":                    "la variable %s no está definida; ¿quiso decir %s?",
+		"%s (defined at line %d, col %d)":                                 "%s (definida en la línea %d, columna %d)",
+		"function %s is not defined":                                      "la función %s no está definida",
+		"%s takes %d argument(s), got %d":                                 "%s espera %d argumento(s), recibió %d",
+		"%s is missing argument %s":                                       "a %s le falta el argumento %s",
+		"... can only follow a parameter of a LAMBDA":                     "... solo puede ir después de un parámetro de un LAMBDA",
+		"%s gets argument %s twice":                                       "%s recibe el argumento %s dos veces",
+		"%s: argument %d comes after a named one, so it needs a name too": "%s: el argumento %d va después de uno con nombre, así que también necesita nombre",
+		"can't use ?. on a value of type %s":                              "no se puede usar ?. en un valor de tipo %s",
+		"can't unpack a value of type %s, only a list":                    "no se puede desempaquetar un valor de tipo %s, solo una lista",
+		"can't unpack a list of %d value(s) into %d variables":            "no se puede desempaquetar una lista de %d valor(es) en %d variables",
+		"%s is unpacked into more than once":                              "%s se desempaqueta más de una vez",
+		"calls went more than the limit of %d deep":                       "las llamadas se anidaron más allá del límite de %d",
+		"%s compares floats exactly, which rounding can throw off; write ~= to compare them within a tolerance": "%s compara flotantes exactamente, lo que el redondeo puede alterar; escriba ~= para compararlos con una tolerancia",
+		"the variable %s hides the builtin %s":                                            "la variable %s oculta la función integrada %s",
+		"%s is bound but never read; start its name with _ if that's on purpose":          "%s se vincula pero nunca se lee; empiece su nombre con _ si es a propósito",
+		"database path %s can't have a ? or # in it":                                      "la ruta de base de datos %s no puede contener ? ni #",
+		"%s can't run %s, it would reach files outside the database":                      "%s no puede ejecutar %s, accedería a archivos fuera de la base de datos",
+		"a %s of %d elements is longer than the limit of %d":                              "un %s de %d elementos supera el límite de %d",
+		"a string of %d bytes is longer than the limit of %d":                             "una cadena de %d bytes supera el límite de %d",
+		"program made more than the limit of %d bytes of strings and lists":               "el programa creó más que el límite de %d bytes de cadenas y listas",
+		"took longer than its limit of %s":                                                "tardó más que su límite de %s",
+		"%s gave more rows than the limit of %d":                                          "%s devolvió más filas que el límite de %d",
+		"%d isn't an open database":                                                       "%d no es una base de datos abierta",
+		"databases can only be opened from the OS's files":                                "las bases de datos solo se pueden abrir desde los archivos del sistema",
+		"there's no %s driver registered, the program running go-basic has to import one": "no hay ningún controlador %s registrado, el programa que ejecuta go-basic tiene que importar uno",
+		"%s isn't an http or https URL":                                                   "%s no es una URL http o https",
+		"%s %s gave %s":                                                                   "%s %s devolvió %s",
+		"response is longer than the limit of %d bytes":                                   "la respuesta supera el límite de %d bytes",
+		"reading %s isn't permitted":                                                      "no se permite leer %s",
+		"writing %s isn't permitted":                                                      "no se permite escribir %s",
+		"%s needs the %s permission":                                                      "%s necesita el permiso %s",
+		"number is longer than the limit of %d bytes":                                     "el número supera el límite de %d bytes",
+		"name is longer than the limit of %d bytes":                                       "el nombre supera el límite de %d bytes",
+		"string literal is longer than the limit of %d bytes":                             "la cadena supera el límite de %d bytes",
+		"%s is a %s, not a %s":                                                            "%s es un %s, no un %s",
+		"expected a type after AS, like INT or the name of a TYPE":                        "se esperaba un tipo después de AS, como INT o el nombre de un TYPE",
+		"%s needs a name or a function, not a %s":                                         "%s necesita un nombre o una función, no un %s",
+		"there's no function called %s to help with":                                      "no hay ninguna función llamada %s de la que mostrar ayuda",
+		"no CASE of the MATCH matches %s":                                                 "ningún CASE del MATCH coincide con %s",
+		"expected THEN after the pattern of a CASE":                                       "se esperaba THEN después del patrón de un CASE",
+		"the pattern for %s has %d field(s), but it has %d":                               "el patrón de %s tiene %d campo(s), pero el tipo tiene %d",
+		"ENUM %s: %s has to be an int, not a %s":                                          "ENUM %s: %s tiene que ser un entero, no un valor de tipo %s",
+		"%s compares %s with %s; write INT() around a member to compare it as a number":   "%s compara %s con %s; escriba INT() alrededor de un miembro para compararlo como número",
+		"%s %s has no END %s":                                                             "%s %s no tiene END %s",
+		"%s %s has %s more than once":                                                     "%s %s tiene %s más de una vez",
+		"TYPE %s: %s has to be a function, not a %s":                                      "TYPE %s: %s tiene que ser una función, no un valor de tipo %s",
+		"%s has no field %s":                                                              "%s no tiene el campo %s",
+		"can't use . on a value of type %s":                                               "no se puede usar . en un valor de tipo %s",
+		"expected a field after '.'":                                                      "se esperaba un campo después de '.'",
+		"can't apply %s to values of type %s and %s":                                      "no se puede aplicar %s a valores de tipo %s y %s",
+		"expected ')'":                                   "se esperaba ')'",
+		"expected operator":                              "se esperaba un operador",
+		"expected factor":                                "se esperaba un factor",
+		"string literal is never closed":                 "la cadena nunca se cierra",
+		"illegal character '%c'":                         "carácter ilegal '%c'",
+		"index %d is out of range for a %s of length %d": "el índice %d está fuera de rango para un %s de longitud %d",
+		"key %s is not in the dict":                      "la clave %s no está en el dict",
+		"program took more than the limit of %d steps":   "el programa superó el límite de %d pasos",
+		"program ran longer than the limit of %s":        "el programa superó el límite de tiempo de %s",
+		"program ran past its deadline":                  "el programa superó su plazo",
+		"program was cancelled":                          "el programa fue cancelado",
+		"module %s not found (looked in %s)":             "no se encontró el módulo %s (se buscó en %s)",
+		"IMPORT is disabled here":                        "IMPORT está desactivado aquí",
+		"%s is read-only":                                "%s es de solo lectura",
+		"FOR EACH %s has no NEXT":                        "FOR EACH %s no tiene NEXT",
+		"square root of negative number %g":              "raíz cuadrada del número negativo %g",
+		"logarithm of non-positive number %g":            "logaritmo del número no positivo %g",
+		"left operand %s of %s is the int %d, implicitly converted to float; write FLOAT(%s) to convert it explicitly":  "el operando izquierdo %s de %s es el entero %d, convertido implícitamente a float; escriba FLOAT(%s) para convertirlo explícitamente",
+		"right operand %s of %s is the int %d, implicitly converted to float; write FLOAT(%s) to convert it explicitly": "el operando derecho %s de %s es el entero %d, convertido implícitamente a float; escriba FLOAT(%s) para convertirlo explícitamente",
+		"integer division %d / %d truncates to %d": "la división entera %d / %d se trunca a %d",
+	},
+}
+
+// a format string from the catalogs, turned into a regexp that matches the messages made from it.
+type pattern_t struct {
+	format string
+	re     *regexp.Regexp
+}
+
+// every English format in the catalogs, longest first so the most specific one matches.
+var patterns []pattern_t
+
+// what each verb used in the catalogs matches in a formatted message.
+var verbPatterns = map[string]string{"%s": "(.+?)", "%d": "(-?[0-9]+)", "%g": "(\\S+)", "%c": "(.)"}
+
+var verb = regexp.MustCompile(`%(\[[0-9]+\])?[sdgc]`)
+
+func init() {
+	formats := make(map[string]bool)
+	for _, catalog := range catalogs {
+		for format := range catalog {
+			formats[format] = true
+		}
+	}
+	for format := range formats {
+		parts := verb.Split(format, -1)
+		verbs := verb.FindAllString(format, -1)
+		expr := "^" + regexp.QuoteMeta(parts[0])
+		for i, v := range verbs {
+			expr += verbPatterns[v] + regexp.QuoteMeta(parts[i+1])
+		}
+		patterns = append(patterns, pattern_t{format: format, re: regexp.MustCompile(expr + "$")})
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i].format) != len(patterns[j].format) {
+			return len(patterns[i].format) > len(patterns[j].format)
+		}
+		return patterns[i].format < patterns[j].format
+	})
+}
+
+// gets the locales there are translations for, like "de". English is always available as well.
+func Locales() []string {
+	ret := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		ret = append(ret, locale)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// turns a locale like "de_DE.UTF-8" or "fr-CA" into the language the catalogs are keyed by, like "de".
+// "" picks one from the environment: BASIC_LANG, then the usual LC_ALL, LC_MESSAGES and LANG.
+func normalizeLocale(locale string) string {
+	if locale == "" {
+		for _, name := range []string{"BASIC_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+			if locale = os.Getenv(name); locale != "" {
+				break
+			}
+		}
+	}
+	if i := strings.IndexAny(locale, "_-.@"); i >= 0 {
+		locale = locale[:i]
+	}
+	return strings.ToLower(locale)
+}
+
+// gets the translation of a format string, or the format itself if there isn't one.
+func message(locale string, format string) string {
+	if translated, ok := catalogs[locale][format]; ok {
+		return translated
+	}
+	return format
+}
+
+// translates an already formatted message by finding the format it was made from. The values
+// that went into it are kept as they were, apart from messages nested inside, like the reason
+// after "SQR: ", which are translated too.
+func translate(locale string, text string) string {
+	catalog, ok := catalogs[locale]
+	if !ok {
+		return text
+	} else if translated, ok := catalog[text]; ok {
+		return translated
+	}
+	for _, pattern := range patterns {
+		translated, ok := catalog[pattern.format]
+		if !ok {
+			continue
+		}
+		match := pattern.re.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		args := make([]interface{}, len(match)-1)
+		for i, value := range match[1:] {
+			args[i] = translate(locale, value)
+		}
+		return fmt.Sprintf(verb.ReplaceAllString(translated, "%${1}s"), args...) // the values are all strings now
+	}
+	return text
+}
+
+// writes an error or warning with where it happened, like "division by zero at line 0, col 2 in file stdin [E101]".
+func describe(locale string, details string, pos Position_t, tag string) string {
+	return fmt.Sprintf(message(locale, "%s at line %d, col %d in file %s [%s]"), details, pos.line, pos.col, pos.filename, tag)
+}
+
+// translates the messages of an error returned by the interpreter into a locale's language,
+// like "de" or "fr_FR.UTF-8" ("" picks one from the environment, like Options_t.Locale).
+// Errors keep their codes and positions. Errors of other types, and messages the locale has
+// no translation for, are left in English.
+func Localize(err error, locale string) error {
+	locale = normalizeLocale(locale)
+	if _, ok := catalogs[locale]; !ok {
+		return err
+	}
+	switch e := err.(type) {
+	case ErrorList_t:
+		ret := make(ErrorList_t, len(e))
+		for i, inner := range e {
+			ret[i] = Localize(inner, locale)
+		}
+		return ret
+	case *LexError_t:
+		return &LexError_t{Code: e.Code, Details: translate(locale, e.Details), Pos: e.Pos, locale: locale}
+	case *ParseError_t:
+		return &ParseError_t{Code: e.Code, Details: translate(locale, e.Details), Pos: e.Pos, locale: locale}
+	case *RuntimeError_t:
+		return &RuntimeError_t{Code: e.Code, Details: translate(locale, e.Details), Pos: e.Pos, locale: locale}
+	}
+	return err
+}
+
+// translates the messages of warnings into a locale's language, the same way as Localize.
+func localizeWarnings(warnings []Warning_t, locale string) []Warning_t {
+	if _, ok := catalogs[locale]; !ok {
+		return warnings
+	}
+	ret := make([]Warning_t, len(warnings))
+	for i, warning := range warnings {
+		ret[i] = warning
+		ret[i].Details = translate(locale, warning.Details)
+		ret[i].locale = locale
+	}
+	return ret
+}
@@ -0,0 +1,93 @@
+package basic
+
+import (
+	"expvar"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// names of the metrics the interpreter reports.
+const (
+	METRIC_EVALUATIONS = "evaluations"   // counter: programs run, whether they worked or not
+	METRIC_ERRORS      = "errors"        // counter: errors, labelled with their code (like "E101")
+	METRIC_PARSE_TIME  = "parse_latency" // histogram: time spent lexing and parsing
+	METRIC_EVAL_TIME   = "eval_latency"  // histogram: time spent evaluating
+)
+
+// pluggable sink for the interpreter's metrics, so an embedder can feed them into whatever
+// monitoring they use. Set it in Options_t. Implementations must be safe for concurrent use
+// if the interpreters using them are.
+type Metrics_t interface {
+	// adds one to a counter. label tells apart counts of the same metric (like error codes), or is "".
+	Inc(name string, label string)
+	// records one observation of a latency.
+	Observe(name string, d time.Duration)
+}
+
+// records how long has passed since start, if metrics are on.
+func (interp *Interpreter_t) observe(name string, start time.Time) {
+	if interp.opts.Metrics != nil {
+		interp.opts.Metrics.Observe(name, time.Since(start))
+	}
+}
+
+// counts an evaluation and, if it failed, its errors by code.
+func (interp *Interpreter_t) countEvaluation(err error) {
+	if interp.opts.Metrics == nil {
+		return
+	}
+	interp.opts.Metrics.Inc(METRIC_EVALUATIONS, "")
+	if err != nil {
+		for _, diag := range Diagnostics(err) {
+			interp.opts.Metrics.Inc(METRIC_ERRORS, diag.Code)
+		}
+	}
+}
+
+// upper bounds of the buckets used by the expvar histograms.
+var expvarBuckets = []time.Duration{100 * time.Microsecond, time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond, time.Second}
+
+// Metrics implementation publishing to expvar (and so to /debug/vars when net/http is serving it).
+// Everything goes in one expvar.Map: counters as "name" or "name.label", and histograms as
+// "name.count", "name.sum_ns" and cumulative "name.le_<bound>" buckets.
+type ExpvarMetrics_t struct {
+	vars *expvar.Map
+	mu   sync.Mutex
+}
+
+// publishes a new expvar.Map with the given name and returns the Metrics writing to it.
+// Like expvar.Publish, it panics if the name is already in use.
+func NewExpvarMetrics(name string) *ExpvarMetrics_t {
+	return &ExpvarMetrics_t{vars: expvar.NewMap(name)}
+}
+
+func (metrics *ExpvarMetrics_t) Inc(name string, label string) {
+	if label != "" {
+		name += "." + label
+	}
+	metrics.vars.Add(name, 1)
+}
+
+func (metrics *ExpvarMetrics_t) Observe(name string, d time.Duration) {
+	metrics.mu.Lock() // keep the count, sum and buckets consistent with each other
+	defer metrics.mu.Unlock()
+	metrics.vars.Add(name+".count", 1)
+	metrics.vars.Add(name+".sum_ns", int64(d))
+	for _, bound := range expvarBuckets {
+		if d <= bound {
+			metrics.vars.Add(name+".le_"+bound.String(), 1)
+		}
+	}
+	metrics.vars.Add(name+".le_inf", 1)
+}
+
+// gets a counter's current value, mostly useful for tests.
+func (metrics *ExpvarMetrics_t) Counter(name string) int64 {
+	v, ok := metrics.vars.Get(name).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(v.String(), 10, 64)
+	return n
+}
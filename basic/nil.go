@@ -0,0 +1,72 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// makes a NIL Result, the value that stands for nothing, like an entry a dict hasn't got.
+func NewNil() *Result_t {
+	return &Result_t{ResultType: NIL_RESULT}
+}
+
+// returns true if name is NIL, which reads as the NIL value unless there's a variable called that.
+func isNilName(name string) bool {
+	return strings.EqualFold(name, "NIL")
+}
+
+// builds and returns a Coalesce node, or just the expression if there's no ??. It binds looser
+// than arithmetic and tighter than comparisons, so a ?? 0 + 1 is a ?? (0 + 1), and a ?? 0 = 1
+// compares the result of the ?? with 1.
+func (parser *parser_t) coalesce() (*Node_t, error) {
+	left, err := parser.expression()
+	if err != nil {
+		return nil, err
+	}
+	for parser.currentToken.tokenType == COALESCE {
+		operator := parser.currentToken
+		parser.advance()
+		right, err := parser.expression()
+		if err != nil {
+			return nil, err
+		}
+		left = &Node_t{nodeType: COALESCE_OP, left: left, tok: operator, right: right}
+	}
+	return left, nil
+}
+
+// evaluates a COALESCE_OP node: the left side, unless it's NIL, and then the right side.
+// The right side is only evaluated if it's needed. Errors on the left aren't caught.
+func (node *Node_t) evaluateCoalesce(interp *Interpreter_t) (*Result_t, error) {
+	left, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	} else if left.ResultType != NIL_RESULT {
+		return left, nil
+	}
+	return node.right.evaluate(interp)
+}
+
+// evaluates a SAFE_ACCESS node: the entry of a dict or field of a record, or NIL if it hasn't got it or is
+// NIL itself, so a?.b?.c is NIL rather than an error if anything along the way is missing.
+func (node *Node_t) evaluateSafeAccess(interp *Interpreter_t) (*Result_t, error) {
+	target, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	switch target.ResultType {
+	case NIL_RESULT:
+		return target, nil
+	case DICT_RESULT:
+		if value, ok := target.Dres.Get(node.ops[0].strVal); ok {
+			return value, nil
+		}
+		return NewNil(), nil
+	case RECORD_RESULT:
+		if value, ok := target.Rres.Get(node.ops[0].strVal); ok {
+			return value, nil
+		}
+		return NewNil(), nil
+	}
+	return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't use ?. on a value of type %s", target.ResultType), Pos: node.tok.pos}
+}
@@ -0,0 +1,180 @@
+package basic
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// enumerated type for how Display writes numbers.
+type Notation_t int
+
+const (
+	NOTATION_PLAIN       Notation_t = iota // like ValueString: 4700 and 0.0033
+	NOTATION_ENGINEERING                   // with an exponent that's a multiple of 3: 4.7e3 and 3.3e-3
+	NOTATION_SI                            // with an SI prefix instead of the exponent: 4.7k and 3.3m
+)
+
+// gets the name of this notation, as ParseNotation takes it.
+func (notation Notation_t) String() string {
+	return [3]string{"plain", "engineering", "si"}[int(notation)]
+}
+
+// gets the notation with the given name: "plain", "engineering" or "si".
+func ParseNotation(name string) (Notation_t, error) {
+	switch strings.ToLower(name) {
+	case "plain":
+		return NOTATION_PLAIN, nil
+	case "engineering":
+		return NOTATION_ENGINEERING, nil
+	case "si":
+		return NOTATION_SI, nil
+	}
+	return 0, fmt.Errorf("unknown notation %q, expected plain, engineering or si", name)
+}
+
+// where plain notation switches floats to scientific notation, unless the options say otherwise:
+// past 10^15 floats can't hold every digit before the point anyway, and below 10^-4 six decimals
+// would show fewer than three significant digits.
+const (
+	DEFAULT_SCIENTIFIC_ABOVE = 1e15
+	DEFAULT_SCIENTIFIC_BELOW = 1e-4
+)
+
+// the SI prefixes from 10^-24 to 10^24, one per power of 1000.
+var siPrefixes = []string{"y", "z", "a", "f", "p", "n", "µ", "m", "", "k", "M", "G", "T", "P", "E", "Z", "Y"}
+
+// gets the value of a result the way this interpreter writes it, which is ValueString
+// with the numbers in the interpreter's Notation, switching to scientific notation where
+// its options say, with its Decimals if it has any, and their thousands separated if it's
+// plain and Grouping is on. Ints are written in the interpreter's Radix instead if it isn't
+// decimal, and never grouped then.
+func (interp *Interpreter_t) Display(res *Result_t) string {
+	notation := interp.opts.Notation
+	if notation == NOTATION_PLAIN {
+		above, below := interp.opts.ScientificAbove, interp.opts.ScientificBelow
+		if above == 0 {
+			above = DEFAULT_SCIENTIFIC_ABOVE
+		}
+		if below == 0 {
+			below = DEFAULT_SCIENTIFIC_BELOW
+		}
+		decimals := interp.opts.Decimals
+		if decimals <= 0 {
+			decimals = -1
+		}
+		return res.formatValue(func(num *Result_t) string {
+			if num.ResultType == INTEGER && interp.opts.Radix != RADIX_DECIMAL {
+				return formatInt(num.Ires, interp.opts.Radix)
+			}
+			text := strconv.FormatInt(num.Ires, 10)
+			if num.ResultType == FLOATING {
+				text = formatFloat(num.Fres, above, below, decimals)
+			}
+			if interp.opts.Grouping {
+				return groupThousands(text, ",")
+			}
+			return text
+		})
+	}
+	return res.formatValue(func(num *Result_t) string {
+		if num.ResultType == INTEGER && interp.opts.Radix != RADIX_DECIMAL {
+			return formatInt(num.Ires, interp.opts.Radix)
+		}
+		return engineering(num.Fres, notation == NOTATION_SI) // the float value is set for integers too
+	})
+}
+
+// writes a float with the given number of decimals, or in scientific notation if it's at least
+// above or smaller than below, so it doesn't come out as a long run of digits or as 0.000000.
+// With decimals -1 it has as few digits as read back as the same float, and a point even if
+// it's whole, so 2.0 doesn't read back as the int 2.
+func formatFloat(x float64, above float64, below float64, decimals int) string {
+	if size := math.Abs(x); x != 0 && !math.IsInf(x, 0) && (size >= above || size < below) {
+		return strconv.FormatFloat(x, 'e', decimals, 64)
+	}
+	ret := strconv.FormatFloat(x, 'f', decimals, 64)
+	if decimals < 0 && !math.IsInf(x, 0) && !math.IsNaN(x) && !strings.Contains(ret, ".") {
+		ret += ".0"
+	}
+	return ret
+}
+
+// puts sep between every three digits before the decimal point of a written number,
+// so "-1234567.5" becomes "-1,234,567.5". Only the run of digits at the start is grouped, so
+// an exponent, as in "1.5e+20", is left as it is, and so are "+Inf" and "NaN".
+func groupThousands(num string, sep string) string {
+	sign, digits := "", num
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	end := 0
+	for end < len(digits) && digits[end] >= '0' && digits[end] <= '9' {
+		end++
+	}
+	digits, fraction := digits[:end], digits[end:]
+	var out strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out.WriteString(sep)
+		}
+		out.WriteRune(digit)
+	}
+	return sign + out.String() + fraction
+}
+
+// FORMAT$(x[, decimals[, separator]]): x with its thousands separated, whatever the interpreter's
+// Grouping option says. decimals fixes the digits after the point; without it, ints have none and
+// floats have as many as they need. The separator is "," unless it's given.
+func init() {
+	RegisterBuiltin("FORMAT$", -1, func(args []*Result_t) (*Result_t, error) {
+		if len(args) < 1 || len(args) > 3 {
+			return nil, fmt.Errorf("takes 1 to 3 arguments, got %d", len(args))
+		} else if !args[0].IsNumber() {
+			return nil, typeErrorf("argument 1 is a %s, not a number", args[0].ResultType)
+		}
+		text := strconv.FormatFloat(args[0].Fres, 'f', -1, 64)
+		if args[0].ResultType == INTEGER {
+			text = strconv.FormatInt(args[0].Ires, 10)
+		}
+		if len(args) >= 2 {
+			if args[1].ResultType != INTEGER {
+				return nil, typeErrorf("the number of decimals has to be an int, not %s", args[1].ResultType)
+			} else if args[1].Ires < 0 || args[1].Ires > 20 {
+				return nil, fmt.Errorf("can't show %d decimals, it has to be from 0 to 20", args[1].Ires)
+			}
+			rounded := round(args[0], int(args[1].Ires), ROUND_HALF_UP) // in decimal, so 2.675 shows as 2.68
+			text = strconv.FormatFloat(rounded.Fres, 'f', int(args[1].Ires), 64)
+		}
+		sep := ","
+		if len(args) == 3 {
+			if args[2].ResultType != STRING_RESULT {
+				return nil, typeErrorf("the separator has to be a string, not %s", args[2].ResultType)
+			}
+			sep = args[2].Sres
+		}
+		return NewString(groupThousands(text, sep)), nil
+	})
+}
+
+// writes a number with up to 6 significant digits and an exponent that's a multiple of 3,
+// or the matching SI prefix if si is set and there is one.
+func engineering(x float64, si bool) string {
+	if x == 0 || math.IsNaN(x) || math.IsInf(x, 0) {
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	exp := int(math.Floor(math.Log10(math.Abs(x))/3)) * 3
+	mantissa, _ := strconv.ParseFloat(strconv.FormatFloat(x/math.Pow(10, float64(exp)), 'g', 6, 64), 64)
+	if math.Abs(mantissa) >= 1000 { // rounding 999.9999 up
+		mantissa /= 1000
+		exp += 3
+	}
+	digits := strconv.FormatFloat(mantissa, 'f', -1, 64)
+	if idx := exp/3 + 8; si && idx >= 0 && idx < len(siPrefixes) {
+		return digits + siPrefixes[idx]
+	} else if exp == 0 {
+		return digits
+	}
+	return digits + "e" + strconv.Itoa(exp)
+}
@@ -0,0 +1,21 @@
+package basic
+
+import "testing"
+
+func TestGroupThousands(t *testing.T) {
+	for num, want := range map[string]string{
+		"1234567":     "1,234,567",
+		"-1234567.5":  "-1,234,567.5",
+		"123":         "123",
+		"1.5e+20":     "1.5e+20",
+		"12345e+20":   "12,345e+20",
+		"-1.2345e-07": "-1.2345e-07",
+		"+Inf":        "+Inf",
+		"-Inf":        "-Inf",
+		"NaN":         "NaN",
+	} {
+		if got := groupThousands(num, ","); got != want {
+			t.Errorf("groupThousands(%q): got %q, want %q", num, got, want)
+		}
+	}
+}
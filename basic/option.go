@@ -0,0 +1,115 @@
+package basic
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// enumerated type for the unit trig functions work in.
+type AngleMode_t int
+
+const (
+	ANGLE_RADIANS AngleMode_t = iota
+	ANGLE_DEGREES
+)
+
+// a setting that an OPTION statement can change, like OPTION ANGLE DEGREES.
+type setting_t struct {
+	values []string                                  // what it can be set to, in upper case
+	set    func(interp *Interpreter_t, value string) // value is one of values
+}
+
+// the settings OPTION can change, keyed by upper case name. They stay changed for the rest
+// of the program, and for later programs run by the same interpreter.
+var settings map[string]setting_t
+
+func init() {
+	settings = map[string]setting_t{
+		"ANGLE": {values: []string{"DEGREES", "RADIANS"}, set: func(interp *Interpreter_t, value string) {
+			interp.opts.Angle = ANGLE_RADIANS
+			if value == "DEGREES" {
+				interp.opts.Angle = ANGLE_DEGREES
+			}
+		}},
+		"NOTATION": {values: []string{"PLAIN", "ENGINEERING", "SI"}, set: func(interp *Interpreter_t, value string) {
+			interp.opts.Notation, _ = ParseNotation(value)
+		}},
+		"BASE": {values: []string{"DECIMAL", "HEX", "BINARY", "OCTAL"}, set: func(interp *Interpreter_t, value string) {
+			interp.opts.Radix, _ = ParseRadix(value)
+		}},
+		"GROUPING": {values: []string{"ON", "OFF"}, set: func(interp *Interpreter_t, value string) {
+			interp.opts.Grouping = value == "ON"
+		}},
+		"COMPARE": {values: []string{"BINARY", "TEXT"}, set: func(interp *Interpreter_t, value string) {
+			interp.opts.IgnoreCase = value == "TEXT"
+		}},
+		"EQUALITY": {values: []string{"APPROX", "EXACT"}, set: func(interp *Interpreter_t, value string) {
+			interp.opts.Epsilon = 0
+			if value == "APPROX" {
+				interp.opts.Epsilon = DEFAULT_EPSILON
+			}
+		}},
+	}
+}
+
+// builds and returns an Option node: OPTION setting value. The setting and value are checked
+// here, so a misspelt one is a syntax error rather than something found halfway through a run.
+func (parser *parser_t) option() (*Node_t, error) {
+	ret := &Node_t{nodeType: OPTION, tok: parser.currentToken}
+	parser.advance()
+	name := parser.currentToken
+	setting, ok := settings[strings.ToUpper(name.strVal)]
+	if name.tokenType != IDENTIFIER || !ok {
+		return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected the name of an option after OPTION, like ANGLE", Pos: name.pos}
+	}
+	parser.advance()
+	value := parser.currentToken
+	for _, allowed := range setting.values {
+		if isKeyword(value, allowed) {
+			parser.advance()
+			ret.ops = []Token_t{name, value}
+			return ret, nil
+		}
+	}
+	return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: fmt.Sprintf("expected %s after OPTION %s", strings.Join(setting.values, " or "), strings.ToUpper(name.strVal)), Pos: value.pos}
+}
+
+// evaluates an OPTION node, changing the setting. Its value is 0.
+func (node *Node_t) evaluateOption(interp *Interpreter_t) (*Result_t, error) {
+	settings[strings.ToUpper(node.ops[0].strVal)].set(interp, strings.ToUpper(node.ops[1].strVal))
+	return NewInt(0), nil
+}
+
+// how the builtins that deal in angles use them.
+const (
+	ANGLE_ARGUMENT = iota // the argument is an angle, like SIN's
+	ANGLE_RESULT          // the result is an angle, like ATN's
+)
+
+// the builtins that work in radians, which have to be converted when the interpreter works in degrees.
+var angleBuiltins = map[string]int{"SIN": ANGLE_ARGUMENT, "COS": ANGLE_ARGUMENT, "TAN": ANGLE_ARGUMENT, "ATN": ANGLE_RESULT}
+
+// wraps a builtin so it works in the interpreter's angle unit, if it deals in angles.
+func (interp *Interpreter_t) angled(name string, fn BuiltinFunc_t) BuiltinFunc_t {
+	use, ok := angleBuiltins[name]
+	if !ok || interp.opts.Angle != ANGLE_DEGREES {
+		return fn
+	}
+	return func(args []*Result_t) (*Result_t, error) {
+		if use == ANGLE_ARGUMENT && len(args) == 1 && args[0].IsNumber() {
+			args = []*Result_t{NewFloat(args[0].Fres * math.Pi / 180)} // the float value is set for integers too
+		}
+		res, err := fn(args)
+		if err == nil && use == ANGLE_RESULT && res.IsNumber() {
+			res = NewFloat(res.Fres * 180 / math.Pi)
+		}
+		return res, err
+	}
+}
+
+// the angle conversions, which work the same whatever OPTION ANGLE says.
+func init() {
+	RegisterBuiltin("DEG", 1, floatBuiltin(func(x float64) float64 { return x * 180 / math.Pi })) // radians to degrees
+	RegisterBuiltin("RAD", 1, floatBuiltin(func(x float64) float64 { return x * math.Pi / 180 })) // degrees to radians
+}
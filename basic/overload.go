@@ -0,0 +1,65 @@
+package basic
+
+import "fmt"
+
+// the operators a dict can define for itself, by keeping a function of two arguments under the
+// operator, like {"x": 1, "y": 2, "+": add_points}. <> is the opposite of what "=" says,
+// unless the dict defines "<>" too.
+var overloadable = map[TokenType_t]bool{ADD: true, SUB: true, MUL: true, DIV: true, EQ: true, NE: true}
+
+// gets the function an operand defines for an operator, if it's a dict that has one or a record
+// whose TYPE does.
+func operatorMethod(operand *Result_t, op TokenType_t) (*Function_t, bool) {
+	if operand.ResultType == RECORD_RESULT {
+		method, ok := operand.Rres.Type.methods[tokenSymbol(op)]
+		return method, ok
+	} else if operand.ResultType != DICT_RESULT {
+		return nil, false
+	}
+	if method, ok := operand.Dres.Get(tokenSymbol(op)); ok && method.ResultType == FUNCTION_RESULT {
+		return method.Fnres, true
+	}
+	return nil, false
+}
+
+// applies an operator the left operand defines, or failing that the right one, calling its
+// function with both operands in the order they're written. ok is false if neither defines it,
+// and the operator works the way it always does.
+func overloaded(left *Result_t, right *Result_t, op Token_t) (res *Result_t, ok bool, err error) {
+	if !overloadable[op.tokenType] || (!definesOperators(left) && !definesOperators(right)) {
+		return nil, false, nil
+	}
+	method, ok := operatorMethod(left, op.tokenType)
+	if !ok {
+		method, ok = operatorMethod(right, op.tokenType)
+	}
+	if !ok && op.tokenType == NE {
+		return overloadedNot(left, right, op)
+	} else if !ok {
+		return nil, false, nil
+	}
+
+	res, err = method.Call([]*Result_t{left, right})
+	if err != nil {
+		return nil, true, builtinError(Token_t{strVal: tokenSymbol(op.tokenType), pos: op.pos}, err)
+	}
+	return res, true, nil
+}
+
+// returns true if a value is a dict or record, which can define operators.
+func definesOperators(operand *Result_t) bool {
+	return operand.ResultType == DICT_RESULT || operand.ResultType == RECORD_RESULT
+}
+
+// applies <> to dicts and records that only define =, as the opposite of it.
+func overloadedNot(left *Result_t, right *Result_t, op Token_t) (*Result_t, bool, error) {
+	res, ok, err := overloaded(left, right, Token_t{tokenType: EQ, pos: op.pos})
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	holds, err := truthy(res)
+	if err != nil {
+		return nil, true, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("=: %s", err), Pos: op.pos}
+	}
+	return boolResult(!holds), true, nil
+}
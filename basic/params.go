@@ -0,0 +1,176 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// a named parameter of a function, with the value it takes when a call leaves it out.
+type Param_t struct {
+	Name    string
+	Default *Result_t // nil if every call has to give it
+	Type    string    // the type its argument has to be, from an annotation like x AS INT. "" if it can be anything
+	Rest    bool      // whether it collects the arguments past the others into a list, like nums in LAMBDA(nums..., SUM(nums)). Only the last one can
+}
+
+// adds a builtin like RegisterBuiltin, but with named parameters, so callers can leave out the
+// ones with defaults and pass any of them by name, like SORT(xs, descending = 1). fn always
+// gets one argument per parameter, with the defaults filled in.
+func RegisterBuiltinParams(name string, params []Param_t, fn BuiltinFunc_t) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+	builtins[strings.ToUpper(name)] = builtin_t{arity: len(params), fn: fn, params: params}
+}
+
+// gets the named parameters of a builtin, nil if it hasn't got any or there's no such builtin.
+func builtinParams(name string) []Param_t {
+	builtinsMu.RLock()
+	defer builtinsMu.RUnlock()
+	return builtins[strings.ToUpper(name)].params
+}
+
+// gets the parameter an argument like scale = 2 names, -1 if it isn't one. Arguments are
+// expressions, so it's only a named argument if the name is one of the parameters; otherwise
+// it's an ordinary comparison.
+func namedParam(arg *Node_t, params []Param_t) int {
+	if arg.nodeType != COMPARISON || arg.tok.tokenType != EQ || arg.left.nodeType != VAR_ACCESS {
+		return -1
+	}
+	for i, param := range params {
+		if param.Name == arg.left.tok.strVal {
+			return i
+		}
+	}
+	return -1
+}
+
+// evaluates the arguments of a call to a function with named parameters, putting them in the
+// order of the parameters: the ones given by position first, then the named ones, then the
+// defaults of any left out. A rest parameter gets the ones by position past the others, as a
+// list. Without params, it's just evaluateArgs.
+func (interp *Interpreter_t) callArgs(node *Node_t, params []Param_t) ([]*Result_t, error) {
+	if params == nil {
+		return interp.evaluateArgs(node)
+	}
+	ret := make([]*Result_t, len(params))
+	var rest []*Result_t
+	named := false
+	for i, arg := range node.args {
+		if j := namedParam(arg, params); j >= 0 {
+			if ret[j] != nil {
+				return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s gets argument %s twice", node.tok.strVal, params[j].Name), Pos: arg.left.tok.pos}
+			}
+			value, err := arg.right.evaluate(interp)
+			if err != nil {
+				return nil, err
+			}
+			ret[j], named = value, true
+			continue
+		} else if named {
+			return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s: argument %d comes after a named one, so it needs a name too", node.tok.strVal, i+1), Pos: node.tok.pos}
+		} else if i >= len(params)-1 && params[len(params)-1].Rest {
+			value, err := arg.evaluate(interp)
+			if err != nil {
+				return nil, err
+			}
+			rest = append(rest, value)
+			continue
+		} else if i >= len(params) {
+			return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes %d argument(s), got %d", node.tok.strVal, len(params), len(node.args)), Pos: node.tok.pos}
+		}
+		value, err := arg.evaluate(interp)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = value
+	}
+	if last := len(params) - 1; params[last].Rest && ret[last] == nil {
+		ret[last] = NewList(append([]*Result_t{}, rest...))
+	}
+	for i, param := range params {
+		if ret[i] == nil && param.Default == nil {
+			return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s is missing argument %s", node.tok.strVal, param.Name), Pos: node.tok.pos}
+		} else if ret[i] == nil {
+			ret[i] = param.Default
+		}
+	}
+	return ret, nil
+}
+
+// fills in the defaults of the parameters left off the end of args, for calls by position,
+// like the ones MAP makes, and gathers the arguments for a rest parameter into a list.
+func withDefaults(args []*Result_t, params []Param_t) []*Result_t {
+	if len(params) > 0 && params[len(params)-1].Rest {
+		fixed := len(params) - 1
+		if len(args) >= fixed {
+			return append(append([]*Result_t{}, args[:fixed]...), NewList(append([]*Result_t{}, args[fixed:]...)))
+		}
+		filled := withDefaults(args, params[:fixed])
+		if len(filled) < fixed {
+			return args // let the function report what's missing
+		}
+		return append(filled, NewList([]*Result_t{}))
+	}
+	if len(args) >= len(params) || params[len(args)].Default == nil {
+		return args
+	}
+	ret := append([]*Result_t{}, args...)
+	for _, param := range params[len(args):] {
+		if param.Default == nil {
+			return args // let the arity check report it
+		}
+		ret = append(ret, param.Default)
+	}
+	return ret
+}
+
+// builds a Rest Param node from a LAMBDA's parameter and the ... after it. Nothing else can
+// have a ... after it.
+func (parser *parser_t) restParam(call *Node_t, param *Node_t) (*Node_t, error) {
+	if !strings.EqualFold(call.tok.strVal, "LAMBDA") || param.nodeType != VAR_ACCESS {
+		return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "... can only follow a parameter of a LAMBDA", Pos: parser.currentToken.pos}
+	}
+	ret := &Node_t{nodeType: REST_PARAM, tok: parser.currentToken, left: param}
+	parser.advance()
+	return ret, nil
+}
+
+// gets the parameters of a LAMBDA from all but the last of its arguments: names, or names with
+// defaults like scale = 1, which are evaluated when the LAMBDA is. Once a parameter has a
+// default, every one after it needs one too, so calls by position can leave them off the end.
+// The last can be a rest parameter, like nums.... A docstring before them isn't one.
+func (interp *Interpreter_t) lambdaParams(node *Node_t) ([]Param_t, []Token_t, error) {
+	args := lambdaArgs(node)
+	params := make([]Param_t, len(args)-1)
+	names := make([]Token_t, len(params))
+	for i, arg := range args[:len(params)] {
+		if arg.nodeType == REST_PARAM && i < len(params)-1 {
+			return nil, nil, &RuntimeError_t{Code: ERR_BUILTIN, Details: fmt.Sprintf("%s: only the last parameter can collect the rest of the arguments", node.tok.strVal), Pos: arg.tok.pos}
+		} else if arg.nodeType == REST_PARAM {
+			params[i], names[i] = Param_t{Name: arg.left.tok.strVal, Rest: true}, arg.left.tok
+			continue
+		}
+		name, typ := unannotated(arg) // a name, or a name with its type like x AS INT
+		if name.nodeType == VAR_ACCESS {
+			if i > 0 && params[i-1].Default != nil {
+				return nil, nil, &RuntimeError_t{Code: ERR_BUILTIN, Details: fmt.Sprintf("%s: parameter %d needs a default, like the one before it", node.tok.strVal, i+1), Pos: arg.tok.pos}
+			}
+			params[i], names[i] = Param_t{Name: name.tok.strVal, Type: typ}, name.tok
+			continue
+		} else if arg.nodeType == COMPARISON && arg.tok.tokenType == EQ {
+			name, typ = unannotated(arg.left)
+		}
+		if name.nodeType != VAR_ACCESS {
+			return nil, nil, &RuntimeError_t{Code: ERR_BUILTIN, Details: fmt.Sprintf("%s: parameter %d has to be a variable name", node.tok.strVal, i+1), Pos: arg.tok.pos}
+		}
+		value, err := arg.right.evaluate(interp)
+		if err != nil {
+			return nil, nil, err
+		}
+		if value, err = annotated(value, typ, fmt.Sprintf("the default of %s", name.tok.strVal)); err != nil {
+			return nil, nil, builtinError(node.tok, err)
+		}
+		params[i], names[i] = Param_t{Name: name.tok.strVal, Default: value, Type: typ}, name.tok
+	}
+	return params, names, nil
+}
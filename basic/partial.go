@@ -0,0 +1,189 @@
+package basic
+
+import "strings"
+
+// partially evaluates src with the variables env knows, which is read like EvalWith's, giving
+// back what's left to work out once the rest are known. Parts that only read known variables
+// become the values they evaluate to, sums and products are simplified like Simplify does, and
+// an IIF whose condition is known becomes the branch it takes, so
+//
+//	price * (1 + tax) + IIF(tier = 1, 5, 0)
+//
+// with tax 0.25 and tier 1 is 1.25 * price + 5. Parts that fail when they're evaluated, like
+// the 1 / 0 in IIF(tier = 1, 1 / 0, 2) with tier unknown, are left as they are, to fail only if
+// they're reached. Format gives the source of what's left. Uses the default options.
+func Partial(src string, env interface{}) (*Node_t, error) {
+	return NewInterpreter(Options_t{}).Partial(src, env)
+}
+
+// like Partial, with this interpreter's variables and options. Values from env take priority
+// over variables already set on the interpreter, but don't replace them.
+func (interp *Interpreter_t) Partial(src string, env interface{}) (*Node_t, error) {
+	node, err := parse(src, "partial", interp.opts)
+	if err != nil {
+		return nil, Localize(err, interp.locale)
+	}
+
+	scope := NewInterpreter(interp.opts)
+	for name, value := range interp.vars {
+		scope.vars[name] = value
+	}
+	if !interp.opts.NoPrelude {
+		if err := scope.loadPrelude(); err != nil {
+			return nil, Localize(err, interp.locale)
+		}
+	}
+	for name := range variables(node) {
+		value, ok, err := lookupField(env, name)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			scope.vars[name] = value
+		}
+	}
+	ret, err := scope.partial(node)
+	if err != nil {
+		return nil, Localize(err, interp.locale)
+	}
+	return ret, nil
+}
+
+// partially evaluates a node with the interpreter's variables, leaving the ones it hasn't got
+// as they are.
+func (interp *Interpreter_t) partial(node *Node_t) (*Node_t, error) {
+	if node == nil {
+		return nil, nil
+	} else if isLambda(node) {
+		return interp.partialLambda(node)
+	} else if !interp.hasUnbound(node) {
+		res, err := node.evaluate(interp)
+		if err != nil {
+			return node, nil // it might never be evaluated, like a branch of an IIF, so it's for later
+		} else if literal, ok := literalNode(res); ok {
+			return literal, nil
+		}
+		return node, nil
+	}
+
+	switch node.nodeType {
+	case FOR_EACH, UNPACK, MATCH_EXPR, TYPE_DEF, ENUM_DEF: // they bind names of their own, so they're left as they are
+		return node, nil
+	case CALL:
+		if branch, ok, err := interp.takenBranch(node); err == nil && ok { // a condition that fails is left for later too
+			return interp.partial(branch)
+		}
+	}
+	if simplified, err := interp.simplify(node); err == nil {
+		return simplified, nil
+	}
+	return interp.partialParts(node)
+}
+
+// gives a copy of a node with its parts partially evaluated.
+func (interp *Interpreter_t) partialParts(node *Node_t) (*Node_t, error) {
+	ret := *node
+	var err error
+	if ret.left, err = interp.partial(node.left); err != nil {
+		return nil, err
+	} else if ret.right, err = interp.partial(node.right); err != nil {
+		return nil, err
+	}
+	ret.statements = make([]*Node_t, len(node.statements))
+	for i, stmt := range node.statements {
+		if ret.statements[i], err = interp.partial(stmt); err != nil {
+			return nil, err
+		}
+	}
+	ret.args = make([]*Node_t, len(node.args))
+	for i, arg := range node.args {
+		if ret.args[i], err = interp.partial(arg); err != nil {
+			return nil, err
+		}
+	}
+	return &ret, nil
+}
+
+// partially evaluates the body and defaults of a LAMBDA, without its parameters, which aren't
+// known until it's called even if a variable has the same name.
+func (interp *Interpreter_t) partialLambda(node *Node_t) (*Node_t, error) {
+	args := lambdaArgs(node)
+	hidden := map[string]*Result_t{}
+	for _, param := range args[:len(args)-1] {
+		if param.nodeType == COMPARISON {
+			param = param.left
+		} else if param.nodeType == REST_PARAM {
+			param = param.left
+		}
+		name, _ := unannotated(param)
+		if value, ok := interp.vars[name.tok.strVal]; ok {
+			hidden[name.tok.strVal] = value
+			delete(interp.vars, name.tok.strVal)
+		}
+	}
+	defer func() {
+		for name, value := range hidden {
+			interp.vars[name] = value
+		}
+	}()
+
+	ret := *node
+	ret.args = append([]*Node_t{}, node.args...)
+	for i, arg := range node.args {
+		var err error
+		if param, _ := unannotated(arg); param.nodeType == VAR_ACCESS || arg.nodeType == REST_PARAM || (hasDocstring(node) && i == 0) {
+			continue
+		} else if arg.nodeType == COMPARISON && i < len(node.args)-1 {
+			def := *arg
+			if def.right, err = interp.partial(arg.right); err != nil {
+				return nil, err
+			}
+			ret.args[i] = &def
+		} else if ret.args[i], err = interp.partial(arg); err != nil {
+			return nil, err
+		}
+	}
+	return &ret, nil
+}
+
+// gets the branch an IIF takes, if its condition is known. ok is false for any other node.
+func (interp *Interpreter_t) takenBranch(node *Node_t) (*Node_t, bool, error) {
+	if node.nodeType != CALL || node.left != nil || !strings.EqualFold(node.tok.strVal, "IIF") || len(node.args) != 3 || interp.hasUnbound(node.args[0]) {
+		return nil, false, nil
+	}
+	cond, err := node.args[0].evaluate(interp)
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := truthy(cond)
+	if err != nil {
+		return nil, false, builtinError(node.tok, err)
+	} else if ok {
+		return node.args[1], true, nil
+	}
+	return node.args[2], true, nil
+}
+
+// builds the literal a value is written as: a number, a string or a list of them. ok is false
+// for values that haven't got one, like functions.
+func literalNode(res *Result_t) (*Node_t, bool) {
+	switch res.ResultType {
+	case INTEGER, FLOATING:
+		if res.Fres < 0 { // -2 rather than a literal that reads back as something else, like -2 ^ 2
+			return &Node_t{nodeType: UNARY_OP, tok: Token_t{tokenType: SUB}, left: numberNode(numberOp(NewInt(-1), res, MUL))}, true
+		}
+		return numberNode(res), true
+	case STRING_RESULT:
+		return &Node_t{nodeType: FACTOR, tok: Token_t{tokenType: STRING, strVal: res.Sres}}, true
+	case LIST_RESULT:
+		ret := &Node_t{nodeType: LIST, tok: Token_t{tokenType: LBRACKET}}
+		for _, elem := range res.Lres {
+			literal, ok := literalNode(elem)
+			if !ok {
+				return nil, false
+			}
+			ret.args = append(ret.args, literal)
+		}
+		return ret, true
+	}
+	return nil, false
+}
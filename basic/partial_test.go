@@ -0,0 +1,47 @@
+package basic
+
+import "testing"
+
+func TestPartial(t *testing.T) {
+	env := map[string]interface{}{"tax": 0.25, "tier": 1, "zero": 0}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`price * (1 + tax) + IIF(tier = 1, 5, 0)`, `1.25 * price + 5`},
+		{`IIF(tier = 2, price, 0)`, `0`},
+		{`IIF(b, 1 / 0, 2)`, `IIF(b, 1 / 0, 2)`},
+		{`IIF(b, 1 / zero, tax)`, `IIF(b, 1 / zero, 0.25)`},
+		{`IIF(1 / zero, price, 2)`, `IIF(1 / zero, price, 2)`},
+		{`LAMBDA(tax, tax * tier)`, `LAMBDA(tax, tax)`},
+		{`[tax, tier, price]`, `[0.25, 1, price]`},
+	}
+	for _, test := range tests {
+		node, err := Partial(test.src, env)
+		if err != nil {
+			t.Errorf("%q: %s", test.src, err)
+		} else if got := Format(node); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestPartialWithResults(t *testing.T) {
+	dict := NewDict([]string{"tax", "tier"}, []*Result_t{NewFloat(0.25), NewInt(1)})
+	envs := []interface{}{
+		dict,
+		map[string]*Result_t{"tax": NewFloat(0.25), "tier": NewInt(1)},
+		struct{ Tax, Tier *Result_t }{NewFloat(0.25), NewInt(1)},
+	}
+	for _, env := range envs {
+		node, err := Partial(`price * (1 + tax) + IIF(tier = 1, 5, 0)`, env)
+		if err != nil {
+			t.Errorf("%T: %s", env, err)
+		} else if got, want := Format(node), `1.25 * price + 5`; got != want {
+			t.Errorf("%T: got %q, want %q", env, got, want)
+		}
+	}
+	if _, err := Partial(`price * tax`, NewInt(1)); err == nil {
+		t.Error("reading variables from an int didn't fail")
+	}
+}
@@ -0,0 +1,128 @@
+package basic
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// what programs may reach outside the interpreter. The zero value allows nothing, so an
+// embedder grants exactly the access its scripts need, starting from DenyAll. Builtins a host
+// adds itself can check the same permissions with Interpreter_t.Permissions.
+type Permissions_t struct {
+	ReadRoots  []string // directories whose files programs may read, like with IMPORT, and everything under them
+	WriteRoots []string // directories whose files programs may write, and everything under them
+	Network    bool     // whether programs may make network requests
+	Exec       bool     // whether programs may run other programs
+	Env        bool     // whether programs may read environment variables, like with ENVIRON$
+	Clock      bool     // whether programs may go by the time of day, like with ON TIMER
+}
+
+// gets permissions that allow nothing at all, to grant access on top of.
+func DenyAll() *Permissions_t {
+	return &Permissions_t{}
+}
+
+// gets the permissions programs run with. Without any in the options, everything is allowed,
+// with ReadRoots and WriteRoots being the root of the file system.
+func (interp *Interpreter_t) Permissions() Permissions_t {
+	if interp.opts.Permissions != nil {
+		return *interp.opts.Permissions
+	}
+	root := string(filepath.Separator)
+	if interp.opts.FileSystem != nil {
+		root = "."
+	}
+	return Permissions_t{ReadRoots: []string{root}, WriteRoots: []string{root}, Network: true, Exec: true, Env: true, Clock: true}
+}
+
+// returns a RuntimeError at tok unless programs may read the file at path.
+func (interp *Interpreter_t) checkRead(path string, tok Token_t) error {
+	if interp.opts.Permissions == nil || interp.underRoot(path, interp.opts.Permissions.ReadRoots) {
+		return nil
+	}
+	return &RuntimeError_t{Code: ERR_PERMISSION, Details: fmt.Sprintf("reading %s isn't permitted", path), Pos: tok.pos}
+}
+
+// returns a RuntimeError at tok unless programs may write the file at path.
+func (interp *Interpreter_t) checkWrite(path string, tok Token_t) error {
+	if interp.opts.Permissions == nil || interp.underRoot(path, interp.opts.Permissions.WriteRoots) {
+		return nil
+	}
+	return &RuntimeError_t{Code: ERR_PERMISSION, Details: fmt.Sprintf("writing %s isn't permitted", path), Pos: tok.pos}
+}
+
+// returns a RuntimeError at tok unless programs are granted a capability, which allowed says.
+// what says what needed it, like "ON TIMER", and capability names the field, like "clock".
+func checkCapability(allowed bool, what string, capability string, tok Token_t) error {
+	if allowed {
+		return nil
+	}
+	return &RuntimeError_t{Code: ERR_PERMISSION, Details: fmt.Sprintf("%s needs the %s permission", what, capability), Pos: tok.pos}
+}
+
+// returns true if path is one of roots, or inside one. Paths are made absolute first, like
+// IMPORT's, and have their symlinks followed, unless the files are in a FileSystem other than
+// the OS's.
+func (interp *Interpreter_t) underRoot(path string, roots []string) bool {
+	path = interp.realPath(path)
+	for _, root := range roots {
+		rel, err := filepath.Rel(interp.realPath(root), path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// gets the path a file is known by when checking permissions: the absolute path for the OS's
+// files, or the cleaned path for any other FileSystem.
+func (interp *Interpreter_t) resolvePath(path string) string {
+	if interp.opts.FileSystem != nil {
+		return filepath.Clean(path)
+	} else if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// gets the path a file really is at when checking permissions: resolvePath's, with symlinks
+// followed for the OS's files, so a link inside a root to somewhere outside it isn't under the
+// root. What's at the end of the path and doesn't exist yet, like a file about to be written,
+// is kept as it is.
+func (interp *Interpreter_t) realPath(path string) string {
+	path = interp.resolvePath(path)
+	if interp.opts.FileSystem != nil {
+		return path
+	}
+	rest := ""
+	for {
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			return filepath.Join(real, rest)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(path, rest)
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+}
+
+// evaluates ENVIRON$(name): the value of the environment variable called name, or "" if it
+// isn't set. It needs the Env permission.
+func (interp *Interpreter_t) environCall(node *Node_t) (*Result_t, error) {
+	if len(node.args) != 1 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 1 argument(s), got %d", node.tok.strVal, len(node.args)), Pos: node.tok.pos}
+	}
+	arg, err := node.args[0].evaluate(interp)
+	if err != nil {
+		return nil, err
+	} else if arg.ResultType != STRING_RESULT {
+		return nil, builtinError(node.tok, typeErrorf("argument 1 is a %s, not a string", arg.ResultType))
+	} else if err := checkCapability(interp.Permissions().Env, strings.ToUpper(node.tok.strVal), "env", node.tok); err != nil {
+		return nil, err
+	}
+	return NewString(os.Getenv(arg.Sres)), nil
+}
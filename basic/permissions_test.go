@@ -0,0 +1,90 @@
+package basic
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	files := MemoryFileSystem_t{"lib/m.bas": `1`, "secret/s.bas": `2`}
+	readLib := &Permissions_t{ReadRoots: []string{"lib"}}
+	for _, test := range []struct {
+		src         string
+		permissions *Permissions_t
+		want        ErrorCode_t
+	}{
+		{`ENVIRON$("HOME")`, nil, ""},
+		{`ENVIRON$("HOME")`, DenyAll(), ERR_PERMISSION},
+		{`ENVIRON$("HOME")`, &Permissions_t{Env: true}, ""},
+		{`HTTPGET$("http://127.0.0.1:1/")`, DenyAll(), ERR_PERMISSION},
+		{`HTTPPOST$("http://127.0.0.1:1/", "x")`, &Permissions_t{Env: true, Clock: true}, ERR_PERMISSION},
+		{`f, g = [LAMBDA(0), 0]` + "\n" + `ON TIMER(1) GOSUB f`, DenyAll(), ERR_PERMISSION},
+		{`IMPORT "lib/m.bas"`, nil, ""},
+		{`IMPORT "lib/m.bas"`, DenyAll(), ERR_PERMISSION},
+		{`IMPORT "lib/m.bas"`, readLib, ""},
+		{`IMPORT "secret/s.bas"`, readLib, ERR_PERMISSION},
+		{`IMPORT "lib/../secret/s.bas" AS s`, readLib, ERR_PERMISSION},
+	} {
+		opts := Options_t{FileSystem: files, Permissions: test.permissions}
+		if got := runErrorCode(t, opts, test.src); got != test.want {
+			t.Errorf("%q with %+v: got error code %q, want %q", test.src, test.permissions, got, test.want)
+		}
+	}
+}
+
+func TestDenyAllWrites(t *testing.T) {
+	interp := NewInterpreter(Options_t{Permissions: DenyAll()})
+	if err := interp.checkWrite("out.txt", Token_t{}); err == nil {
+		t.Errorf("writing with DenyAll: got no error")
+	}
+	interp = NewInterpreter(Options_t{Permissions: &Permissions_t{WriteRoots: []string{"out"}}})
+	if err := interp.checkWrite("out/a.txt", Token_t{}); err != nil {
+		t.Errorf("writing under a write root: %s", err)
+	} else if err := interp.checkWrite("outside.txt", Token_t{}); err == nil {
+		t.Errorf("writing outside the write roots: got no error")
+	}
+}
+
+func TestPermissionsFollowSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	root, outside := filepath.Join(dir, "root"), filepath.Join(dir, "outside")
+	for _, d := range []string{root, outside} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for path, src := range map[string]string{filepath.Join(root, "m.bas"): `1`, filepath.Join(outside, "s.bas"): `2`} {
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skip("can't make symlinks here:", err)
+	} else if err := os.Symlink(root, filepath.Join(dir, "alias")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		src   string
+		roots []string
+		want  ErrorCode_t
+	}{
+		{`IMPORT "` + filepath.Join(root, "m.bas") + `"`, []string{root}, ""},
+		{`IMPORT "` + filepath.Join(root, "link", "s.bas") + `"`, []string{root}, ERR_PERMISSION},
+		{`IMPORT "` + filepath.Join(root, "link", "s.bas") + `"`, []string{outside}, ""},
+		{`IMPORT "` + filepath.Join(root, "m.bas") + `"`, []string{filepath.Join(dir, "alias")}, ""},
+	} {
+		opts := Options_t{Permissions: &Permissions_t{ReadRoots: test.roots}}
+		if got := runErrorCode(t, opts, test.src); got != test.want {
+			t.Errorf("%q with roots %v: got error code %q, want %q", test.src, test.roots, got, test.want)
+		}
+	}
+
+	interp := NewInterpreter(Options_t{Permissions: &Permissions_t{WriteRoots: []string{root}}})
+	if err := interp.checkWrite(filepath.Join(root, "link", "new.txt"), Token_t{}); err == nil {
+		t.Errorf("writing a new file through a link out of the root: got no error")
+	} else if err := interp.checkWrite(filepath.Join(root, "new", "deeper.txt"), Token_t{}); err != nil {
+		t.Errorf("writing a new file in a new directory under the root: %s", err)
+	}
+}
@@ -0,0 +1,228 @@
+package basic
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// size of the chart the PLOT builtin prints.
+const (
+	PLOT_WIDTH  = 64
+	PLOT_HEIGHT = 20
+)
+
+// an expression sampled over a range of one of its variables. Ys[i] is the value at Xs[i],
+// or NaN where the expression couldn't be evaluated there (like LOG of a negative number).
+type Plot_t struct {
+	Xs []float64
+	Ys []float64
+}
+
+// evaluates expr at samples evenly spaced points from lo to hi, with variable set to each in
+// turn. Other variables come from the interpreter, and the variable is put back afterwards.
+func (interp *Interpreter_t) Plot(expr string, variable string, lo float64, hi float64, samples int) (*Plot_t, error) {
+	node, err := parse(expr, "plot", interp.opts)
+	if err != nil {
+		return nil, err
+	} else if _, ok := interp.host[variable]; ok {
+		return nil, fmt.Errorf("%s is read-only", variable)
+	}
+	interp.resetLimits(time.Now())
+	return interp.sample(node, variable, lo, hi, samples)
+}
+
+// does the work of Plot on a parsed expression. Errors at a point leave a gap in the plot,
+// except running out of steps, which stops the whole thing.
+func (interp *Interpreter_t) sample(node *Node_t, variable string, lo float64, hi float64, samples int) (*Plot_t, error) {
+	if samples < 2 {
+		return nil, fmt.Errorf("a plot needs at least 2 samples, got %d", samples)
+	} else if !(lo < hi) {
+		return nil, fmt.Errorf("the range of a plot has to go upwards, got %g..%g", lo, hi)
+	}
+
+	old, wasSet := interp.vars[variable]
+	defer func() {
+		if wasSet {
+			interp.vars[variable] = old
+		} else {
+			interp.undefine(variable)
+		}
+	}()
+
+	ret := &Plot_t{Xs: make([]float64, samples), Ys: make([]float64, samples)}
+	for i := range ret.Xs {
+		x := lo + (hi-lo)*float64(i)/float64(samples-1)
+		interp.vars[variable] = NewFloat(x)
+		res, err := node.evaluate(interp)
+		var runtimeErr *RuntimeError_t
+		if errors.As(err, &runtimeErr) && runtimeErr.Code == ERR_STEP_LIMIT {
+			return nil, err
+		}
+		ret.Xs[i], ret.Ys[i] = x, math.NaN()
+		if err == nil && res.IsNumber() {
+			ret.Ys[i] = res.Fres
+		}
+	}
+	return ret, nil
+}
+
+// evaluates PLOT(expr, variable, lo, hi): samples expr like Plot and prints the chart.
+// It's not a normal builtin, since expr has to be evaluated again for every point.
+func (interp *Interpreter_t) plotCall(node *Node_t) (*Result_t, error) {
+	if len(node.args) != 4 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 4 argument(s), got %d", node.tok.strVal, len(node.args)), Pos: node.tok.pos}
+	}
+	variable := node.args[1]
+	if variable.nodeType != VAR_ACCESS {
+		return nil, &RuntimeError_t{Code: ERR_BUILTIN, Details: fmt.Sprintf("%s: the second argument has to be a variable name", node.tok.strVal), Pos: node.tok.pos}
+	} else if err := interp.bindable(variable.tok.strVal, variable.tok); err != nil {
+		return nil, err
+	}
+	lo, err := node.args[2].evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := node.args[3].evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	if !lo.IsNumber() || !hi.IsNumber() {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("%s: the ends of the range have to be numbers", node.tok.strVal), Pos: node.tok.pos}
+	}
+
+	plot, err := interp.sample(node.args[0], variable.tok.strVal, lo.Fres, hi.Fres, PLOT_WIDTH)
+	if err != nil {
+		var runtimeErr *RuntimeError_t
+		if errors.As(err, &runtimeErr) {
+			return nil, err
+		}
+		return nil, &RuntimeError_t{Code: ERR_BUILTIN, Details: fmt.Sprintf("%s: %s", node.tok.strVal, err), Pos: node.tok.pos}
+	}
+	io.WriteString(interp.Stdout(), plot.ASCII(PLOT_HEIGHT))
+	return NewInt(0), nil
+}
+
+// gets the range of the plotted values, ignoring gaps, widened if it's empty.
+func (plot *Plot_t) yRange() (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, y := range plot.Ys {
+		if !math.IsNaN(y) && !math.IsInf(y, 0) {
+			lo, hi = math.Min(lo, y), math.Max(hi, y)
+		}
+	}
+	if lo > hi { // nothing to plot
+		return -1, 1
+	} else if lo == hi {
+		return lo - 1, hi + 1
+	}
+	return lo, hi
+}
+
+// draws the plot as text, one column per sample and height rows tall, with the axes
+// where they're in range and the y range labelled on the left.
+func (plot *Plot_t) ASCII(height int) string {
+	width := len(plot.Xs)
+	ylo, yhi := plot.yRange()
+	row := func(y float64) int { // row 0 is the top
+		return int(math.Round((yhi - y) / (yhi - ylo) * float64(height-1)))
+	}
+
+	grid := make([][]byte, height)
+	for r := range grid {
+		grid[r] = []byte(strings.Repeat(" ", width))
+	}
+	if ylo <= 0 && 0 <= yhi {
+		for c := range grid[row(0)] {
+			grid[row(0)][c] = '-'
+		}
+	}
+	if xlo, xhi := plot.Xs[0], plot.Xs[width-1]; xlo <= 0 && 0 <= xhi {
+		col := int(math.Round(-xlo / (xhi - xlo) * float64(width-1)))
+		for r := range grid {
+			if grid[r][col] == '-' {
+				grid[r][col] = '+'
+			} else {
+				grid[r][col] = '|'
+			}
+		}
+	}
+	for c, y := range plot.Ys {
+		if !math.IsNaN(y) && !math.IsInf(y, 0) {
+			grid[row(y)][c] = '*'
+		}
+	}
+
+	var out strings.Builder
+	for r, line := range grid {
+		label := ""
+		if r == 0 {
+			label = fmt.Sprintf("%.4g", yhi)
+		} else if r == height-1 {
+			label = fmt.Sprintf("%.4g", ylo)
+		}
+		fmt.Fprintf(&out, "%10s |%s\n", label, line)
+	}
+	xlo, xhi := fmt.Sprintf("%.4g", plot.Xs[0]), fmt.Sprintf("%.4g", plot.Xs[width-1])
+	gap := width - len(xlo) - len(xhi)
+	if gap < 1 {
+		gap = 1
+	}
+	fmt.Fprintf(&out, "%10s  %s%s%s\n", "", xlo, strings.Repeat(" ", gap), xhi)
+	return out.String()
+}
+
+// draws the plot as a PNG image, one pixel column per sample and height pixels tall.
+func (plot *Plot_t) PNG(w io.Writer, height int) error {
+	width := len(plot.Xs)
+	ylo, yhi := plot.yRange()
+	row := func(y float64) int {
+		return int(math.Round((yhi - y) / (yhi - ylo) * float64(height-1)))
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	white, grey, black := color.RGBA{255, 255, 255, 255}, color.RGBA{180, 180, 180, 255}, color.RGBA{0, 0, 0, 255}
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, white)
+		}
+	}
+	if ylo <= 0 && 0 <= yhi {
+		for x := 0; x < width; x++ {
+			img.Set(x, row(0), grey)
+		}
+	}
+	if xlo, xhi := plot.Xs[0], plot.Xs[width-1]; xlo <= 0 && 0 <= xhi {
+		col := int(math.Round(-xlo / (xhi - xlo) * float64(width-1)))
+		for y := 0; y < height; y++ {
+			img.Set(col, y, grey)
+		}
+	}
+	// join each point to the one before it with a vertical run, so steep parts stay connected
+	prev := -1
+	for x, y := range plot.Ys {
+		if math.IsNaN(y) || math.IsInf(y, 0) {
+			prev = -1
+			continue
+		}
+		r := row(y)
+		from, to := r, r
+		if prev >= 0 {
+			from, to = prev, r
+			if from > to {
+				from, to = to, from
+			}
+		}
+		for ry := from; ry <= to; ry++ {
+			img.Set(x, ry, black)
+		}
+		prev = r
+	}
+	return png.Encode(w, img)
+}
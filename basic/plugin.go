@@ -0,0 +1,17 @@
+package basic
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// loads a Go plugin (built with `go build -buildmode=plugin`) holding a builtin library.
+// The plugin's init functions run when it's opened, so a library just calls RegisterBuiltin
+// from init. The plugin must be built against the same version of go-basic as the program loading it.
+func LoadPlugin(path string) error {
+	_, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("loading plugin %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package basic
+
+import (
+	"fmt"
+	"math"
+)
+
+// evaluates a POWER node like x^2. An int to a non-negative int power is an int, anything else is a float.
+func (node *Node_t) evaluatePower(interp *Interpreter_t) (*Result_t, error) {
+	base, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	exponent, err := node.right.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	if !base.IsNumber() || !exponent.IsNumber() {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't apply ^ to values of type %s and %s", base.ResultType, exponent.ResultType), Pos: node.tok.pos}
+	}
+	if base.Fres == 0 && exponent.Fres < 0 { // the float value is set for integers too
+		return nil, &RuntimeError_t{Code: ERR_DIVISION_BY_ZERO, Details: "division by zero", Pos: node.tok.pos}
+	}
+
+	if err := interp.checkPromotion(node, base, exponent); err != nil {
+		return nil, err
+	}
+
+	if base.ResultType == INTEGER && exponent.ResultType == INTEGER && exponent.Ires >= 0 {
+		ret, ok := intPow(base.Ires, exponent.Ires)
+		if !ok {
+			return nil, &RuntimeError_t{Code: ERR_EVALUATION, Details: fmt.Sprintf("%d^%d doesn't fit in an integer", base.Ires, exponent.Ires), Pos: node.tok.pos}
+		}
+		return NewInt(ret), nil
+	}
+	ret := math.Pow(base.Fres, exponent.Fres)
+	if math.IsNaN(ret) {
+		return nil, &RuntimeError_t{Code: ERR_EVALUATION, Details: fmt.Sprintf("can't raise negative number %g to the fractional power %g", base.Fres, exponent.Fres), Pos: node.tok.pos}
+	}
+	return NewFloat(ret), nil
+}
+
+// raises an int to a non-negative int power by repeated squaring. Returns false if the result overflows.
+func intPow(base int64, exponent int64) (int64, bool) {
+	ret := int64(1)
+	for exponent > 0 {
+		if exponent%2 == 1 {
+			if !mulFits(ret, base) {
+				return 0, false
+			}
+			ret *= base
+		}
+		exponent /= 2
+		if exponent > 0 {
+			if !mulFits(base, base) {
+				return 0, false
+			}
+			base *= base
+		}
+	}
+	return ret, true
+}
+
+// returns true if a * b doesn't overflow an int64.
+func mulFits(a int64, b int64) bool {
+	if a == 0 || b == 0 {
+		return true
+	}
+	product := a * b
+	return product/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64)
+}
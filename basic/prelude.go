@@ -0,0 +1,62 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// the helpers in the standard prelude, written in BASIC: each is a name and the LAMBDA it's bound to.
+// Parameters start with _ so they don't hide the caller's variables from a function passed in.
+var preludeFunctions = [][2]string{
+	{"SUM", "LAMBDA(_list, FOLD(LAMBDA(_a, _b, _a + _b), 0, _list))"},
+	{"PRODUCT", "LAMBDA(_list, FOLD(LAMBDA(_a, _b, _a * _b), 1, _list))"},
+	{"MAX", "LAMBDA(_list, REDUCE(LAMBDA(_a, _b, IIF(_a < _b, _b, _a)), _list))"},
+	{"MIN", "LAMBDA(_list, REDUCE(LAMBDA(_a, _b, IIF(_b < _a, _b, _a)), _list))"},
+	{"CLAMP", "LAMBDA(_x, _lo, _hi, IIF(_x < _lo, _lo, IIF(_x > _hi, _hi, _x)))"},
+	{"REVERSE", "LAMBDA(_list, FOLD(LAMBDA(_a, _b, [_b] + _a), [], _list))"},
+	{"COUNT", "LAMBDA(_fn, _list, LEN(FILTER(_fn, _list)))"},
+	{"ANY", "LAMBDA(_fn, _list, LEN(FILTER(_fn, _list)) > 0)"},
+	{"ALL", "LAMBDA(_fn, _list, LEN(FILTER(_fn, _list)) = LEN(_list))"},
+	{"FACT", "LAMBDA(_n, IIF(_n <= 1, 1, _n * FACT(_n - 1)))"},
+	{"HYPOT", "LAMBDA(_a, _b, SQR(_a * _a + _b * _b))"},
+}
+
+// source of the prelude every interpreter loads unless its options say otherwise: a dict of
+// small helpers like SUM, MAX, CLAMP and REVERSE that don't need to be Go builtins.
+var StandardPrelude = func() string {
+	entries := make([]string, len(preludeFunctions))
+	for i, fn := range preludeFunctions {
+		entries[i] = quoteString(fn[0]) + ": " + fn[1]
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}()
+
+// runs the prelude, binding each of its functions as a variable. Variables set before the
+// first Run are left alone, so a program's own SUM wins over the prelude's.
+func (interp *Interpreter_t) loadPrelude() error {
+	src := interp.opts.Prelude
+	if src == "" {
+		src = StandardPrelude
+	}
+	node, err := parse(src, "prelude", Options_t{})
+	if err != nil {
+		return err
+	}
+	tracer := interp.opts.Tracer // the prelude isn't part of the program being traced
+	interp.opts.Tracer = nil
+	defer func() { interp.opts.Tracer = tracer }()
+	interp.resetLimits(time.Now())
+	res, err := node.evaluate(interp)
+	if err != nil {
+		return err
+	} else if res.ResultType != DICT_RESULT {
+		return &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("the prelude is a %s, not a dict of functions", res.ResultType), Pos: node.tok.pos}
+	}
+	for _, name := range res.Dres.keys {
+		if _, ok := interp.vars[name]; !ok {
+			interp.vars[name] = res.Dres.values[name]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package basic
+
+import "testing"
+
+func TestPreludeEverywhere(t *testing.T) {
+	res, err := EvalWith("SUM(xs)", map[string]interface{}{"xs": []int{1, 2, 3}})
+	if err != nil {
+		t.Errorf("EvalWith: %s", err)
+	} else if res.Ires != 6 {
+		t.Errorf("EvalWith: got %s, want 6", res.ValueString())
+	}
+
+	out, err := ResolveJSON([]byte(`{"total": "=SUM(xs)"}`), map[string]interface{}{"xs": []int{1, 2}})
+	if err != nil {
+		t.Errorf("ResolveJSON: %s", err)
+	} else if string(out) != `{"total":3}` {
+		t.Errorf("ResolveJSON: got %s", out)
+	}
+
+	sheet := NewSheet()
+	sheet.SetValue("A1", NewInt(2))
+	if err := sheet.SetFormula("B1", "SUM([A1, 1])"); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := sheet.Get("B1"); err != nil {
+		t.Errorf("sheet: %s", err)
+	} else if res.Ires != 3 {
+		t.Errorf("sheet: got %s, want 3", res.ValueString())
+	}
+
+	if same, err := Equivalent("SUM([x, y])", "x + y"); err != nil || !same {
+		t.Errorf("Equivalent: got %v, %v, want equivalent", same, err)
+	}
+	if same, err := Equivalent("MAX([x, 1])", "MAX([x, 2])"); err != nil || same {
+		t.Errorf("Equivalent: got %v, %v, want not equivalent", same, err)
+	}
+}
+
+func TestEvalWithKeepsProgramsOwnFunctions(t *testing.T) {
+	interp := NewInterpreter(Options_t{})
+	if _, err := interp.Run(`SUM, x = [LAMBDA(l, 42), 0]`, t.Name()); err != nil {
+		t.Fatal(err)
+	}
+	res, err := interp.EvalWith("SUM([1, 2])", nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Ires != 42 {
+		t.Errorf("got %s, want the program's own SUM", res.ValueString())
+	}
+}
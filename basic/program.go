@@ -0,0 +1,91 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// a program with placeholders written ?name, like `price * ?quantity`, parsed once and given
+// values for them with Bind. Values go into the program as values rather than as source, so
+// user input can't change what the program does the way pasting it into the text could.
+type Program_t struct {
+	node   *Node_t
+	src    string
+	fn     string
+	params []string             // the names of the placeholders, sorted
+	values map[string]*Result_t // what Bind gave them, nil until it's called
+}
+
+// parses a program that may have placeholders in it.
+func ParseProgram(src string, fn string) (*Program_t, error) {
+	node, err := Parse(src, fn)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	params := make([]string, 0)
+	Walk(node, func(n *Node_t) bool {
+		if n.nodeType == PARAM_ACCESS && !seen[n.tok.strVal] {
+			seen[n.tok.strVal] = true
+			params = append(params, n.tok.strVal)
+		}
+		return true
+	})
+	sort.Strings(params)
+	return &Program_t{node: node, src: src, fn: fn, params: params}, nil
+}
+
+// gets the names of the program's placeholders, without the ?, sorted.
+func (prog *Program_t) Params() []string {
+	return append([]string{}, prog.params...)
+}
+
+// gets a copy of the program with its placeholders given values, ready for RunProgram. Every
+// placeholder needs a value, and every value needs a placeholder, so a misspelt name is caught here.
+func (prog *Program_t) Bind(values map[string]*Result_t) (*Program_t, error) {
+	missing := make([]string, 0)
+	for _, name := range prog.params {
+		if value, ok := values[name]; !ok || value == nil {
+			missing = append(missing, "?"+name)
+		}
+	}
+	if len(missing) == 1 {
+		return nil, fmt.Errorf("placeholder %s has no value", missing[0])
+	} else if len(missing) > 1 {
+		return nil, fmt.Errorf("placeholders %s have no values", strings.Join(missing, ", "))
+	}
+
+	bound := make(map[string]*Result_t, len(values))
+	for name, value := range values {
+		i := sort.SearchStrings(prog.params, name)
+		if i == len(prog.params) || prog.params[i] != name {
+			return nil, fmt.Errorf("there's no placeholder ?%s in the program", name)
+		}
+		bound[name] = value
+	}
+	ret := *prog
+	ret.values = bound
+	return &ret, nil
+}
+
+// runs a program whose placeholders have been given values with Bind, like RunContext runs source.
+func (interp *Interpreter_t) RunProgram(ctx context.Context, prog *Program_t) (*Result_t, error) {
+	if prog.values == nil && len(prog.params) > 0 {
+		return nil, fmt.Errorf("the program's placeholders haven't been bound")
+	}
+	start := time.Now()
+	res, err := interp.runNode(ctx, prog.node, prog.values)
+	interp.audit(ctx, prog.src, prog.fn, start, res, err)
+	return res, err
+}
+
+// evaluates a PARAM_ACCESS node to the value its placeholder was bound to.
+func (node *Node_t) evaluateParam(interp *Interpreter_t) (*Result_t, error) {
+	if value, ok := interp.params[node.tok.strVal]; ok {
+		return value, nil
+	}
+	return nil, &RuntimeError_t{Code: ERR_UNDEFINED_VAR, Details: fmt.Sprintf("placeholder ?%s has no value", node.tok.strVal), Pos: node.tok.pos}
+}
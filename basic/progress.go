@@ -0,0 +1,37 @@
+package basic
+
+import (
+	"time"
+)
+
+// how many steps apart Options_t.Progress is called unless ProgressEvery says otherwise:
+// often enough for a progress bar to move smoothly, rarely enough not to slow the Run down.
+const DEFAULT_PROGRESS_EVERY = 10000
+
+// how far a Run has got, as told to Options_t.Progress.
+type Progress_t struct {
+	Steps   int           // nodes evaluated so far
+	Elapsed time.Duration // time since the Run started
+	Pos     Position_t    // where the node being evaluated is in the source
+}
+
+// function a host gives in Options_t.Progress to hear how a long Run is going, like to move a
+// progress bar. It's called on the goroutine running the program, so it should be quick, and
+// returning false stops the Run with an ERR_STEP_LIMIT RuntimeError, like a cancel button would.
+type ProgressFunc_t func(progress Progress_t) bool
+
+// tells the options' Progress how far this Run has got, if it's time to. Returns a RuntimeError
+// at node if Progress says to stop.
+func (interp *Interpreter_t) reportProgress(node *Node_t) error {
+	every := interp.opts.ProgressEvery
+	if every <= 0 {
+		every = DEFAULT_PROGRESS_EVERY
+	}
+	if interp.steps%every != 0 {
+		return nil
+	}
+	if !interp.opts.Progress(Progress_t{Steps: interp.steps, Elapsed: time.Since(interp.started), Pos: node.tok.pos}) {
+		return &RuntimeError_t{Code: ERR_STEP_LIMIT, Details: "program was cancelled", Pos: node.tok.pos}
+	}
+	return nil
+}
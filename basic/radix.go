@@ -0,0 +1,47 @@
+package basic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// enumerated type for the base Display writes ints in.
+type Radix_t int
+
+const (
+	RADIX_DECIMAL Radix_t = iota // like ValueString: 255
+	RADIX_HEX                    // 0xff
+	RADIX_BINARY                 // 0b11111111
+	RADIX_OCTAL                  // 0o377
+)
+
+// gets the name of this radix, as ParseRadix takes it.
+func (radix Radix_t) String() string {
+	return [4]string{"decimal", "hex", "binary", "octal"}[int(radix)]
+}
+
+// gets the radix with the given name: "decimal", "hex", "binary" or "octal".
+func ParseRadix(name string) (Radix_t, error) {
+	switch strings.ToLower(name) {
+	case "decimal":
+		return RADIX_DECIMAL, nil
+	case "hex":
+		return RADIX_HEX, nil
+	case "binary":
+		return RADIX_BINARY, nil
+	case "octal":
+		return RADIX_OCTAL, nil
+	}
+	return 0, fmt.Errorf("unknown radix %q, expected decimal, hex, binary or octal", name)
+}
+
+// writes an int in the given radix, with a prefix saying which unless it's decimal, like -0x1f.
+func formatInt(x int64, radix Radix_t) string {
+	prefix := [4]string{"", "0x", "0b", "0o"}[int(radix)]
+	base := [4]int{10, 16, 2, 8}[int(radix)]
+	if x < 0 {
+		return "-" + prefix + strconv.FormatUint(uint64(-x), base) // uint64 so the smallest int64 negates
+	}
+	return prefix + strconv.FormatInt(x, base)
+}
@@ -0,0 +1,274 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// a type of record declared with TYPE ... END TYPE, like
+//
+//	TYPE Point
+//	    x
+//	    y = 0
+//	END TYPE
+//
+// The declaration binds its name to a constructor, so Point(1, 2) or Point(x = 1) makes a record.
+type RecordType_t struct {
+	Name    string
+	Fields  []Param_t              // the fields in the order they're declared, with their defaults
+	methods map[string]*Function_t // the operators the type defines, like "+", keyed by symbol
+	source  *Node_t                // the TYPE it was declared by, so a Snapshot can save it. nil for types made in Go
+}
+
+// a record: a value of a RecordType_t, with one value per field. Like lists, records are
+// values, so nothing changes one once it's made.
+type Record_t struct {
+	Type   *RecordType_t
+	values []*Result_t
+}
+
+// makes a record Result of the given type, with values for its fields in order.
+func NewRecord(typ *RecordType_t, values []*Result_t) *Result_t {
+	return &Result_t{ResultType: RECORD_RESULT, Rres: &Record_t{Type: typ, values: values}}
+}
+
+// gets the value of a field of the record, and whether it has that field.
+func (record *Record_t) Get(field string) (*Result_t, bool) {
+	for i, param := range record.Type.Fields {
+		if param.Name == field {
+			return record.values[i], true
+		}
+	}
+	return nil, false
+}
+
+// gets the names of the fields of the record's type, in order.
+func (typ *RecordType_t) fieldNames() []string {
+	ret := make([]string, len(typ.Fields))
+	for i, param := range typ.Fields {
+		ret[i] = param.Name
+	}
+	return ret
+}
+
+// returns true if two record types have the same name and fields, as a TYPE declared again does.
+func (typ *RecordType_t) sameAs(other *RecordType_t) bool {
+	if typ == other {
+		return true
+	} else if typ.Name != other.Name || len(typ.Fields) != len(other.Fields) {
+		return false
+	}
+	for i, param := range typ.Fields {
+		if param.Name != other.Fields[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+// returns true if tokens[i] starts a declaration with the given keyword, like TYPE or ENUM:
+// the keyword, then a name, then the end of the line. Anything else with the keyword in front
+// is an expression, since it can name a variable.
+func isDeclaration(tokens []Token_t, i int, keyword string) bool {
+	return isKeyword(tokens[i], keyword) && i+2 < len(tokens) && tokens[i+1].tokenType == IDENTIFIER && (isSeparator(tokens[i+2]) || tokens[i+2].tokenType == EOF)
+}
+
+// returns true if tokens[i] is the END of an END keyword, like END TYPE.
+func isEnd(tokens []Token_t, i int, keyword string) bool {
+	return isKeyword(tokens[i], "END") && i+1 < len(tokens) && isKeyword(tokens[i+1], keyword)
+}
+
+// builds and returns a declaration node of the given type: the keyword and a name, then its
+// members up to END and the keyword, one per line or separated by commas. memberName gets the
+// name of a member, or false if it isn't one, and expected says what one looks like.
+func (parser *parser_t) declaration(nodeType NodeType_t, keyword string, memberName func(*Node_t) (string, bool), expected string) (*Node_t, error) {
+	parser.advance()
+	ret := &Node_t{nodeType: nodeType, tok: parser.currentToken}
+	parser.advance()
+	parser.skipSeparators()
+	seen := map[string]bool{}
+	for !isEnd(parser.tokens, parser.idx, keyword) {
+		if parser.currentToken.tokenType == EOF {
+			return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: fmt.Sprintf("%s %s has no END %s", keyword, ret.tok.strVal, keyword), Pos: ret.tok.pos}
+		}
+		start := parser.currentToken
+		member, err := parser.comparison()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := memberName(member)
+		if !ok {
+			return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: fmt.Sprintf("expected %s in %s %s", expected, keyword, ret.tok.strVal), Pos: start.pos}
+		} else if seen[name] {
+			return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: fmt.Sprintf("%s %s has %s more than once", keyword, ret.tok.strVal, name), Pos: start.pos}
+		}
+		seen[name] = true
+		ret.args = append(ret.args, member)
+		if parser.currentToken.tokenType == COMMA {
+			parser.advance()
+			continue
+		} else if !isSeparator(parser.currentToken) && !isEnd(parser.tokens, parser.idx, keyword) {
+			return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: fmt.Sprintf("expected a new line, ':' or ',' after a member of %s %s", keyword, ret.tok.strVal), Pos: parser.currentToken.pos}
+		}
+		parser.skipSeparators()
+	}
+	parser.advance()
+	parser.advance()
+	return ret, nil
+}
+
+// builds and returns a Type Def node. A member is a field, a field with a default like y = 0,
+// or an operator the type defines, like "+" = LAMBDA(a, b, Point(a.x + b.x, a.y + b.y)).
+func (parser *parser_t) typeDef() (*Node_t, error) {
+	return parser.declaration(TYPE_DEF, "TYPE", memberName, "a field, like x or x = 0, or an operator, like \"+\" = LAMBDA(a, b, ...),")
+}
+
+// gets the name of a member of a TYPE: the field, or the operator's symbol. A field can be
+// annotated with its type, like x AS FLOAT.
+func memberName(member *Node_t) (string, bool) {
+	field, _ := unannotated(member)
+	switch {
+	case field.nodeType == VAR_ACCESS:
+		return field.tok.strVal, true
+	case member.nodeType != COMPARISON || member.tok.tokenType != EQ:
+		return "", false
+	}
+	field, _ = unannotated(member.left)
+	switch {
+	case field.nodeType == VAR_ACCESS:
+		return field.tok.strVal, true
+	case member.left.nodeType == FACTOR && member.left.tok.tokenType == STRING:
+		for op := range overloadable {
+			if tokenSymbol(op) == member.left.tok.strVal {
+				return member.left.tok.strVal, true
+			}
+		}
+	}
+	return "", false
+}
+
+// evaluates a TYPE_DEF node, binding its name to the constructor of the type for the rest of
+// the program, like an IMPORT. Its value is the constructor.
+func (node *Node_t) evaluateTypeDef(interp *Interpreter_t) (*Result_t, error) {
+	if err := interp.bindable(node.tok.strVal, node.tok); err != nil {
+		return nil, err
+	}
+	ret, err := interp.constructor(node)
+	if err != nil {
+		return nil, err
+	}
+	interp.vars[node.tok.strVal] = ret
+	interp.define(node.tok.strVal, node.tok.pos)
+	return ret, nil
+}
+
+// makes the type a TYPE_DEF node declares, and gives back its constructor. Defaults and
+// operators are evaluated here, once. Records can only be made with values of the types their
+// fields are annotated with.
+func (interp *Interpreter_t) constructor(node *Node_t) (*Result_t, error) {
+	typ := &RecordType_t{Name: node.tok.strVal, methods: map[string]*Function_t{}, source: node}
+	for _, member := range node.args {
+		if field, fieldType := unannotated(member); field.nodeType == VAR_ACCESS {
+			typ.Fields = append(typ.Fields, Param_t{Name: field.tok.strVal, Type: fieldType})
+			continue
+		}
+		value, err := member.right.evaluate(interp)
+		if err != nil {
+			return nil, err
+		}
+		if field, fieldType := unannotated(member.left); field.nodeType == VAR_ACCESS {
+			if value, err = annotated(value, fieldType, fmt.Sprintf("the default of %s", field.tok.strVal)); err != nil {
+				return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("TYPE %s: %s", typ.Name, err), Pos: member.tok.pos}
+			}
+			typ.Fields = append(typ.Fields, Param_t{Name: field.tok.strVal, Default: value, Type: fieldType})
+		} else if value.ResultType != FUNCTION_RESULT {
+			return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("TYPE %s: %s has to be a function, not a %s", typ.Name, quoteString(member.left.tok.strVal), value.ResultType), Pos: member.tok.pos}
+		} else {
+			typ.methods[member.left.tok.strVal] = value.Fnres
+		}
+	}
+
+	return NewFunction(&Function_t{Name: typ.Name, Arity: len(typ.Fields), Params: typ.Fields, Returns: typ.Name, source: node, record: typ, call: func(args []*Result_t) (*Result_t, error) {
+		values, err := annotatedArgs(typ.Fields, args)
+		if err != nil {
+			return nil, err
+		}
+		return NewRecord(typ, values), nil
+	}}), nil
+}
+
+// remakes a record type from a Snapshot: from the source of its TYPE, so it gets its defaults
+// and operators back, or failing that from the names of its fields.
+func (interp *Interpreter_t) restoreRecordType(src string, fields []string) (*RecordType_t, error) {
+	if !strings.HasPrefix(src, "TYPE ") {
+		typ := &RecordType_t{Name: src}
+		for _, name := range fields {
+			typ.Fields = append(typ.Fields, Param_t{Name: name})
+		}
+		return typ, nil
+	}
+	ctor, err := interp.restoreConstructor(src)
+	if err != nil {
+		return nil, err
+	}
+	return ctor.Fnres.record, nil
+}
+
+// remakes the constructor of a record type from the source of its TYPE, without binding it.
+func (interp *Interpreter_t) restoreConstructor(src string) (*Result_t, error) {
+	node, err := Parse(src, "snapshot")
+	if err != nil {
+		return nil, err
+	} else if node.nodeType == STATEMENTS && len(node.statements) == 1 {
+		node = node.statements[0]
+	}
+	if node.nodeType != TYPE_DEF {
+		return nil, fmt.Errorf("%s isn't a TYPE", src)
+	}
+	return interp.constructor(node)
+}
+
+// evaluates a FIELD_ACCESS node: the field of a record, or the entry of a dict, like p.x.
+// Unlike ?., it's an error if there's no such field.
+func (node *Node_t) evaluateField(interp *Interpreter_t) (*Result_t, error) {
+	target, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	name := node.ops[0].strVal
+	switch target.ResultType {
+	case RECORD_RESULT:
+		if value, ok := target.Rres.Get(name); ok {
+			return value, nil
+		}
+		return nil, &RuntimeError_t{Code: ERR_INDEX, Details: fmt.Sprintf("%s has no field %s", target.Rres.Type.Name, name), Pos: node.ops[0].pos}
+	case DICT_RESULT:
+		if value, ok := target.Dres.Get(name); ok {
+			return value, nil
+		}
+		return nil, &RuntimeError_t{Code: ERR_INDEX, Details: fmt.Sprintf("key %s is not in the dict", quoteString(name)), Pos: node.ops[0].pos}
+	}
+	return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't use . on a value of type %s", target.ResultType), Pos: node.tok.pos}
+}
+
+// writes a record the way its constructor would make it, like Point(x = 1, y = 2).
+func (record *Record_t) format(number func(num *Result_t) string) string {
+	fields := make([]string, len(record.values))
+	for i, value := range record.values {
+		if value.ResultType == STRING_RESULT {
+			fields[i] = record.Type.Fields[i].Name + " = " + quoteString(value.Sres)
+		} else {
+			fields[i] = record.Type.Fields[i].Name + " = " + value.formatValue(number)
+		}
+	}
+	return record.Type.Name + "(" + strings.Join(fields, ", ") + ")"
+}
+
+// formats a TYPE_DEF or ENUM_DEF node, with its members indented one per line.
+func formatDeclaration(node *Node_t, keyword string) string {
+	lines := []string{keyword + " " + node.tok.strVal}
+	for _, member := range node.args {
+		lines = append(lines, FORMAT_INDENT+formatExpr(member))
+	}
+	return strings.Join(append(lines, "END "+keyword), "\n")
+}
@@ -0,0 +1,70 @@
+package basic
+
+import "testing"
+
+const pointType = `TYPE Point
+    x
+    y = 0
+    "+" = LAMBDA(a, b, Point(a.x + b.x, a.y + b.y))
+END TYPE
+`
+
+func TestRecords(t *testing.T) {
+	for src, want := range map[string]string{
+		`Point(1, 2)`:                   `Point(x = 1, y = 2)`,
+		`Point(1)`:                      `Point(x = 1, y = 0)`,
+		`Point(y = 5, x = 1)`:           `Point(x = 1, y = 5)`,
+		`Point(1, 2).x + Point(3, 4).y`: `5`,
+		`Point(1, 2) + Point(10, 20)`:   `Point(x = 11, y = 22)`,
+		`Point(1, 2) = Point(1, 2)`:     `1`,
+		`Point(1, 2) = Point(1, 3)`:     `0`,
+		`TYPE(Point(1, 2))`:             `Point`,
+	} {
+		if got := runValue(t, pointType+src); got != want {
+			t.Errorf("%q: got %s, want %s", src, got, want)
+		}
+	}
+	for src, want := range map[string]ErrorCode_t{
+		`Point(1, 2).z`:       ERR_INDEX,
+		`Point()`:             ERR_ARG_COUNT,
+		`Point(1, 2, 3)`:      ERR_ARG_COUNT,
+		`Point(1) - Point(2)`: ERR_TYPE,
+		"n, m = [42, 0]\nn.x": ERR_TYPE,
+	} {
+		if got := runErrorCode(t, Options_t{}, pointType+src); got != want {
+			t.Errorf("%q: got error code %q, want %q", src, got, want)
+		}
+	}
+}
+
+func TestRecordTypeAnnotations(t *testing.T) {
+	src := "TYPE Temp\n    celsius AS FLOAT\nEND TYPE\n"
+	if got := runValue(t, src+`Temp(21.5).celsius`); got != `21.5` {
+		t.Errorf("got %s, want 21.5", got)
+	}
+	if got := runErrorCode(t, Options_t{}, src+`Temp("warm")`); got != ERR_TYPE {
+		t.Errorf("a string for a FLOAT field: got error code %q, want %q", got, ERR_TYPE)
+	}
+}
+
+func TestRecordDeclarationErrors(t *testing.T) {
+	for _, src := range []string{
+		"TYPE Point\n    x\n    x\nEND TYPE",
+		"TYPE Point\n    x",
+		"TYPE Point\n    1 + 2\nEND TYPE",
+	} {
+		if _, err := Parse(src, t.Name()); err == nil {
+			t.Errorf("%q: got no error", src)
+		}
+	}
+}
+
+func TestNewRecord(t *testing.T) {
+	typ := &RecordType_t{Name: "Pair", Fields: []Param_t{{Name: "a"}, {Name: "b"}}}
+	res := NewRecord(typ, []*Result_t{NewInt(1), NewString("two")})
+	if b, ok := res.Rres.Get("b"); !ok || b.Sres != "two" {
+		t.Errorf("field b: got %v, %v", b, ok)
+	} else if _, ok := res.Rres.Get("c"); ok {
+		t.Errorf("field c: got one")
+	}
+}
@@ -0,0 +1,191 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// evaluates src with its variables read from env, which can be a struct (or pointer to one),
+// a map with string keys, or a dict or record Result. Variables resolve to exported struct
+// fields, matched by their `basic:"name"` tag, their exact name or their name ignoring case,
+// in that order. Fields and map values can be Go values or Results.
+// Uses the default options.
+func EvalWith(src string, env interface{}) (*Result_t, error) {
+	return NewInterpreter(Options_t{}).EvalWith(src, env)
+}
+
+// like EvalWith, using this interpreter's options, limits like Timeout and MaxSteps included,
+// and its locale for errors. Values from env take priority over variables already set on the
+// interpreter, but don't replace them.
+func (interp *Interpreter_t) EvalWith(src string, env interface{}) (*Result_t, error) {
+	start := time.Now()
+	res, err := interp.evalWith(src, env)
+	interp.audit(context.Background(), src, "eval", start, res, err)
+	return res, err
+}
+
+// does the work of EvalWith.
+func (interp *Interpreter_t) evalWith(src string, env interface{}) (*Result_t, error) {
+	start := time.Now()
+	node, err := parse(src, "eval", interp.opts)
+	interp.observe(METRIC_PARSE_TIME, start)
+	if err != nil {
+		interp.countEvaluation(err)
+		return nil, Localize(err, interp.locale)
+	}
+
+	scope := NewInterpreter(interp.opts)
+	scope.preluded = interp.preluded // then its variables have the prelude's functions already
+	for name, value := range interp.vars {
+		scope.vars[name] = value
+	}
+	for name, hostVar := range interp.host {
+		scope.host[name] = hostVar
+	}
+	for name := range variables(node) {
+		value, ok, err := lookupField(env, name)
+		if err != nil {
+			interp.countEvaluation(err)
+			return nil, err
+		} else if ok {
+			scope.vars[name] = value
+		}
+	}
+
+	return scope.runNode(context.Background(), node, nil)
+}
+
+// finds the variable name in env (a struct, a pointer to one, a map with string keys or a
+// dict or record Result). ok is false if env has nothing by that name.
+func lookupField(env interface{}, name string) (value *Result_t, ok bool, err error) {
+	if res, isResult := env.(*Result_t); isResult && res != nil {
+		return resultField(res, name)
+	}
+	v := reflect.ValueOf(env)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		v = v.Elem()
+	}
+
+	var field reflect.Value
+	switch v.Kind() {
+	case reflect.Struct:
+		field = structField(v, name)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false, fmt.Errorf("can't read variables from a %s, map keys must be strings", v.Type())
+		}
+		field = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+	case reflect.Invalid:
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("can't read variables from a %s, only structs and maps", v.Type())
+	}
+	if !field.IsValid() {
+		return nil, false, nil
+	}
+
+	value, err = toResult(field)
+	if err != nil {
+		return nil, false, fmt.Errorf("variable %s: %w", name, err)
+	}
+	return value, true, nil
+}
+
+// finds the variable name in a dict or record. ok is false if it hasn't got that key or field.
+func resultField(res *Result_t, name string) (value *Result_t, ok bool, err error) {
+	switch res.ResultType {
+	case DICT_RESULT:
+		value, ok = res.Dres.Get(name)
+	case RECORD_RESULT:
+		value, ok = res.Rres.Get(name)
+	default:
+		return nil, false, fmt.Errorf("can't read variables from a %s, only dicts and records", res.ResultType)
+	}
+	return value, ok, nil
+}
+
+// finds the exported field of the struct for a variable name, or an invalid Value if there isn't one.
+func structField(v reflect.Value, name string) reflect.Value {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ { // tags win
+		if t.Field(i).PkgPath == "" && t.Field(i).Tag.Get("basic") == name {
+			return v.Field(i)
+		}
+	}
+	if f, ok := t.FieldByName(name); ok && f.PkgPath == "" {
+		return v.FieldByIndex(f.Index)
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" && strings.EqualFold(t.Field(i).Name, name) {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// converts a Go number into a Result. Booleans become 1 and 0, and Results are used as they are.
+func toResult(v reflect.Value) (*Result_t, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, fmt.Errorf("value is nil")
+		} else if v.CanInterface() && v.Type() == reflect.TypeOf(&Result_t{}) {
+			return v.Interface().(*Result_t), nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewInt(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > 1<<63-1 {
+			return nil, fmt.Errorf("%d overflows 64 bits", v.Uint())
+		}
+		return NewInt(int64(v.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return NewFloat(v.Float()), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return NewInt(1), nil
+		}
+		return NewInt(0), nil
+	case reflect.String:
+		return NewString(v.String()), nil
+	case reflect.Slice, reflect.Array:
+		elems := make([]*Result_t, v.Len())
+		for i := range elems {
+			elem, err := toResult(v.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			elems[i] = elem
+		}
+		return NewList(elems), nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("can't use a %s as a dict, keys must be strings", v.Type())
+		}
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys) // map order is random, so pick one that isn't
+		values := make([]*Result_t, len(keys))
+		for i, key := range keys {
+			value, err := toResult(v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())))
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", key, err)
+			}
+			values[i] = value
+		}
+		return NewDict(keys, values), nil
+	default:
+		return nil, fmt.Errorf("can't use a %s as a value", v.Type())
+	}
+}
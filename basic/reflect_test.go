@@ -0,0 +1,51 @@
+package basic
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvalWithTimeout(t *testing.T) {
+	interp := NewInterpreter(Options_t{Timeout: 50 * time.Millisecond, Locale: "de"})
+	start := time.Now()
+	_, err := interp.EvalWith("SUM(RANGE(0, 1000000))", nil)
+	var runtimeErr *RuntimeError_t
+	if !errors.As(err, &runtimeErr) || runtimeErr.Code != ERR_STEP_LIMIT {
+		t.Fatalf("got %v, want an %s error", err, ERR_STEP_LIMIT)
+	} else if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("stopped after %s", elapsed)
+	} else if !strings.Contains(err.Error(), "Programm") {
+		t.Errorf("got %q, want it in German", err)
+	}
+}
+
+func TestEvalWithProgress(t *testing.T) {
+	var elapsed []time.Duration
+	interp := NewInterpreter(Options_t{ProgressEvery: 100, Progress: func(progress Progress_t) bool {
+		elapsed = append(elapsed, progress.Elapsed)
+		return true
+	}})
+	if _, err := interp.EvalWith("SUM(RANGE(0, 1000))", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(elapsed) == 0 {
+		t.Fatal("Progress was never called")
+	}
+	for _, e := range elapsed {
+		if e < 0 || e > time.Minute {
+			t.Errorf("Progress was told %s had passed", e)
+		}
+	}
+}
+
+func TestEvalWithResults(t *testing.T) {
+	env := NewDict([]string{"xs", "name"}, []*Result_t{NewList([]*Result_t{NewInt(1), NewInt(2)}), NewString("go")})
+	res, err := EvalWith(`LEN(xs) + LEN(name)`, env)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.ResultType != INTEGER || res.Ires != 4 {
+		t.Errorf("got %s, want 4", res)
+	}
+}
@@ -0,0 +1,182 @@
+package basic
+
+import (
+	"errors"
+	"time"
+)
+
+// error a paused program unwinds with when its Execution is stopped.
+var ErrStopped = errors.New("execution stopped")
+
+// a program that runs a slice at a time: each Resume runs it until it reaches a YIELD, uses up
+// its step Budget or ends, then hands control back, like a coroutine. Game scripts can run a
+// slice per frame without blocking the frame loop.
+//
+// The program runs on a goroutine of its own, but only while Resume is waiting for it, so it
+// never runs at the same time as the caller. A program that's abandoned before it ends should
+// be stopped with Stop, or its goroutine is never freed.
+type Execution_t struct {
+	Budget int // most steps each Resume runs before pausing. 0 means no limit
+
+	interp  *Interpreter_t
+	node    *Node_t
+	resume  chan bool     // true to carry on, false to stop
+	paused  chan struct{} // the program has paused or ended
+	started bool
+	done    bool
+	stop    bool      // whether Stop is unwinding the program
+	used    int       // steps run since the last Resume
+	steps   int       // the program's step count while it's paused, as Run resets the interpreter's
+	yielded *Result_t // what the program last yielded, nil if it paused for its budget
+	result  *Result_t
+	err     error
+}
+
+// returns true if tokens[i] starts a YIELD statement rather than an expression using a
+// variable called YIELD: the word on its own, or followed by something that starts a value,
+// like NOT or a sign. So YIELD -1 yields -1, rather than taking 1 from a variable.
+func isYield(tokens []Token_t, i int) bool {
+	if !isKeyword(tokens[i], "YIELD") || i+1 >= len(tokens) {
+		return false
+	}
+	switch tokens[i+1].tokenType {
+	case INT, FLOAT, STRING, IDENTIFIER, PARAM, ADD, SUB, LPAREN, LBRACKET, LBRACE, NEWLINE, COLON, EOF:
+		return true
+	}
+	return false
+}
+
+// builds and returns a Yield node: YIELD, with an optional value.
+func (parser *parser_t) yield() (*Node_t, error) {
+	ret := &Node_t{nodeType: YIELD, tok: parser.currentToken}
+	parser.advance()
+	if isSeparator(parser.currentToken) || parser.currentToken.tokenType == EOF {
+		return ret, nil
+	}
+	value, err := parser.comparison()
+	if err != nil {
+		return nil, err
+	}
+	ret.left = value
+	return ret, nil
+}
+
+// evaluates a YIELD node, pausing the Execution running it. Its value is the value yielded, or
+// 0 if there isn't one. In a plain Run, which can't pause, YIELD just carries on.
+func (node *Node_t) evaluateYield(interp *Interpreter_t) (*Result_t, error) {
+	ret := NewInt(0)
+	if node.left != nil {
+		var err error
+		if ret, err = node.left.evaluate(interp); err != nil {
+			return nil, err
+		}
+	}
+	if interp.exec != nil {
+		if err := interp.exec.pause(ret); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// parses a program and returns an Execution to run it with, without running any of it yet.
+func (interp *Interpreter_t) Start(txt string, fn string) (*Execution_t, error) {
+	node, err := parse(txt, fn, interp.opts)
+	if err != nil {
+		return nil, Localize(err, interp.locale)
+	}
+	return &Execution_t{interp: interp, node: node, resume: make(chan bool), paused: make(chan struct{})}, nil
+}
+
+// runs the program until it yields, uses up its Budget or ends. It returns what YIELD was given
+// (nil if the program paused for its budget), or once the program is Done, its result or error.
+// The interpreter's Timeout applies to each Resume rather than the whole program.
+func (exec *Execution_t) Resume() (*Result_t, error) {
+	if exec.done {
+		return exec.result, exec.err
+	}
+	exec.used = 0
+	if !exec.started {
+		exec.started = true
+		go exec.run()
+	} else {
+		exec.resume <- true
+	}
+	<-exec.paused
+	if exec.done {
+		return exec.result, exec.err
+	}
+	return exec.yielded, nil
+}
+
+// returns true once the program has ended or been stopped.
+func (exec *Execution_t) Done() bool {
+	return exec.done
+}
+
+// abandons a paused program, unwinding it with ErrStopped. Its result is then ErrStopped too.
+func (exec *Execution_t) Stop() {
+	if exec.started && !exec.done {
+		exec.resume <- false
+		<-exec.paused
+	}
+	exec.done = true
+	exec.result, exec.err = nil, ErrStopped
+}
+
+// evaluates the program, on its own goroutine.
+func (exec *Execution_t) run() {
+	interp := exec.interp
+	if !interp.preluded && !interp.opts.NoPrelude {
+		interp.preluded = true
+		exec.err = interp.loadPrelude()
+	}
+	if exec.err == nil {
+		interp.warnings = nil
+		interp.checkUnused(exec.node)
+		interp.resetLimits(time.Now())
+		interp.exec = exec
+		exec.result, exec.err = exec.node.evaluate(interp)
+		interp.exec = nil
+		if exec.err == nil {
+			exec.result.Warnings = interp.warnings
+		}
+	}
+	exec.err = Localize(exec.err, interp.locale)
+	exec.done = true
+	exec.paused <- struct{}{}
+}
+
+// hands control back to Resume until it's called again, or fails with ErrStopped if Stop is called instead.
+func (exec *Execution_t) pause(value *Result_t) error {
+	if exec.stop {
+		return ErrStopped
+	}
+	interp := exec.interp
+	exec.yielded, exec.steps = value, interp.steps
+	interp.exec = nil // a Run while this is paused mustn't pause it
+	exec.paused <- struct{}{}
+	if !<-exec.resume {
+		exec.stop = true
+		interp.exec = exec // so every step fails from here on
+		return ErrStopped
+	}
+	interp.exec = exec
+	interp.resetLimits(time.Now())
+	interp.steps = exec.steps
+	return nil
+}
+
+// counts a step against the budget, pausing once it's used up. A program being stopped fails
+// at every step, so it unwinds even through builtins that carry on after errors.
+func (exec *Execution_t) tick() error {
+	if exec.stop {
+		return ErrStopped
+	}
+	exec.used++
+	if exec.Budget > 0 && exec.used >= exec.Budget {
+		exec.used = 0
+		return exec.pause(nil)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package basic
+
+import "testing"
+
+func TestYieldSigned(t *testing.T) {
+	for src, want := range map[string]int64{`YIELD -1`: -1, `YIELD +2`: 2, `YIELD (3)`: 3} {
+		exec, err := NewInterpreter(Options_t{}).Start(src, t.Name())
+		if err != nil {
+			t.Fatalf("%q: %s", src, err)
+		}
+		got, err := exec.Resume()
+		if err != nil {
+			t.Fatalf("%q: %s", src, err)
+		}
+		if exec.Done() || got == nil || got.Ires != want {
+			t.Errorf("%q: got %v, want it to yield %d", src, got, want)
+		}
+		exec.Stop()
+	}
+}
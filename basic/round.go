@@ -0,0 +1,123 @@
+package basic
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// enumerated type for how ROUND breaks ties, when a number is exactly halfway.
+type RoundingMode_t int
+
+const (
+	ROUND_HALF_UP   RoundingMode_t = iota // away from zero: 2.5 is 3 and -2.5 is -3, like on paper
+	ROUND_HALF_EVEN                       // to the even neighbour: 2.5 is 2 and 3.5 is 4, also called banker's rounding
+)
+
+// gets the name of this mode, as ParseRoundingMode takes it.
+func (mode RoundingMode_t) String() string {
+	return [2]string{"half-up", "half-even"}[int(mode)]
+}
+
+// gets the rounding mode with the given name, "half-up" or "half-even".
+func ParseRoundingMode(name string) (RoundingMode_t, error) {
+	switch strings.ToLower(name) {
+	case "half-up":
+		return ROUND_HALF_UP, nil
+	case "half-even":
+		return ROUND_HALF_EVEN, nil
+	}
+	return 0, fmt.Errorf("unknown rounding mode %q, expected half-up or half-even", name)
+}
+
+// rounds the decimal digits of a non-negative number, given as digits with the decimal point
+// after the first point of them, to ndigits after the point (before it, if ndigits is negative).
+// The result is the rounded digits as an integer, which is the rounded number times 10^ndigits.
+func roundDigits(digits string, point int, ndigits int, mode RoundingMode_t) string {
+	cut := point + ndigits
+	if cut >= len(digits) { // nothing to round off
+		return digits + strings.Repeat("0", cut-len(digits))
+	} else if cut < 0 { // rounding off more than there is
+		return "0"
+	}
+	kept, rest := []byte(digits[:cut]), digits[cut:]
+	up := rest[0] > '5' || (rest[0] == '5' && strings.TrimRight(rest[1:], "0") != "")
+	if rest[0] == '5' && !up { // exactly halfway
+		up = mode == ROUND_HALF_UP || (len(kept) > 0 && (kept[len(kept)-1]-'0')%2 == 1)
+	}
+	if up {
+		i := len(kept) - 1
+		for ; i >= 0 && kept[i] == '9'; i-- {
+			kept[i] = '0'
+		}
+		if i < 0 {
+			kept = append([]byte{'1'}, kept...)
+		} else {
+			kept[i] += 1
+		}
+	}
+	if len(kept) == 0 {
+		return "0"
+	}
+	return string(kept)
+}
+
+// rounds x to ndigits decimal places. Floats are rounded in decimal, as they're written, so
+// 2.675 rounds to 2.68 even though the nearest float to 2.675 is a little less than it.
+// Ints stay ints, and so does anything rounded to 0 digits or fewer if it fits.
+func round(x *Result_t, ndigits int, mode RoundingMode_t) *Result_t {
+	if x.ResultType == INTEGER {
+		if ndigits >= 0 {
+			return x
+		}
+		digits := strconv.FormatUint(uint64(abs(x.Ires)), 10)
+		rounded, _ := strconv.ParseInt(roundDigits(digits, len(digits), ndigits, mode)+strings.Repeat("0", -ndigits), 10, 64)
+		if x.Ires < 0 {
+			rounded = -rounded
+		}
+		return NewInt(rounded)
+	}
+	if math.IsNaN(x.Fres) || math.IsInf(x.Fres, 0) {
+		return x
+	}
+	text := strconv.FormatFloat(math.Abs(x.Fres), 'f', -1, 64)
+	point := strings.Index(text, ".")
+	if point < 0 {
+		point = len(text)
+	}
+	rounded, _ := strconv.ParseFloat(roundDigits(strings.Replace(text, ".", "", 1), point, ndigits, mode)+"e"+strconv.Itoa(-ndigits), 64)
+	if x.Fres < 0 {
+		rounded = -rounded
+	}
+	if ndigits <= 0 && math.Abs(rounded) < math.MaxInt64 {
+		return NewInt(int64(rounded))
+	}
+	return NewFloat(rounded)
+}
+
+// evaluates ROUND(x[, ndigits]): x rounded to ndigits decimal places, 0 if they're left out.
+// Ties are broken by the interpreter's Rounding option. It's not a normal builtin because
+// it needs that option.
+func (interp *Interpreter_t) roundCall(node *Node_t) (*Result_t, error) {
+	args, err := interp.evaluateArgs(node)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 1 && len(args) != 2 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("%s takes 1 or 2 argument(s), got %d", node.tok.strVal, len(args)), Pos: node.tok.pos}
+	}
+	if !args[0].IsNumber() {
+		return nil, builtinError(node.tok, typeErrorf("argument 1 is a %s, not a number", args[0].ResultType))
+	}
+	ndigits := int64(0)
+	if len(args) == 2 {
+		if args[1].ResultType != INTEGER {
+			return nil, builtinError(node.tok, typeErrorf("the number of digits has to be an int, not %s", args[1].ResultType))
+		} else if args[1].Ires < -18 || args[1].Ires > 18 {
+			return nil, builtinError(node.tok, fmt.Errorf("can't round to %d digits, it has to be between -18 and 18", args[1].Ires))
+		}
+		ndigits = args[1].Ires
+	}
+	return round(args[0], int(ndigits), interp.opts.Rounding), nil
+}
@@ -0,0 +1,109 @@
+package basic
+
+// enumerated type for the highlight class of a piece of source.
+type TokenClass_t int
+
+const (
+	CLASS_NUMBER TokenClass_t = iota
+	CLASS_OPERATOR
+	CLASS_IDENTIFIER
+	CLASS_FUNCTION
+	CLASS_STRING
+	CLASS_KEYWORD
+)
+
+// gets the name of this class. The names match the LSP semantic token types.
+func (class TokenClass_t) String() string {
+	return [6]string{"number", "operator", "variable", "function", "string", "keyword"}[int(class)]
+}
+
+// a classified piece of source. Start and End are byte offsets into the source (End is exclusive),
+// Line and Col are where Start is.
+type SemanticToken_t struct {
+	Class TokenClass_t
+	Start int
+	End   int
+	Line  int
+	Col   int
+}
+
+// returns true if a token can end what a factor annotates, like x or f(x), so an AS after it
+// is an annotation.
+func endsOperand(tok Token_t) bool {
+	switch tok.tokenType {
+	case IDENTIFIER, INT, FLOAT, STRING, PARAM, RPAREN, RBRACKET, RBRACE:
+		return true
+	}
+	return false
+}
+
+// returns true if tokens[i] is one of the words of a FOR EACH loop, an OPTION statement, an IMPORT, a TYPE or ENUM, a POKE, an ON TIMER, a YIELD, a command like PSET or an annotation's AS, where the parser takes it as one.
+func isStatementKeyword(tokens []Token_t, i int) bool {
+	atStart := func(j int) bool { return j == 0 || isSeparator(tokens[j-1]) }
+	switch {
+	case isKeyword(tokens[i], "FOR"), isKeyword(tokens[i], "NEXT"), isKeyword(tokens[i], "OPTION"):
+		return atStart(i)
+	case isKeyword(tokens[i], "TYPE"), isKeyword(tokens[i], "ENUM"):
+		return (atStart(i) && isDeclaration(tokens, i, tokens[i].strVal)) || (i >= 1 && isEnd(tokens, i-1, tokens[i].strVal) && atStart(i-1))
+	case isKeyword(tokens[i], "END"):
+		return atStart(i) && i+1 < len(tokens) && (isEnd(tokens, i, "TYPE") || isEnd(tokens, i, "ENUM"))
+	case isKeyword(tokens[i], "POKE"):
+		return atStart(i) && isPoke(tokens, i)
+	case isCommand(tokens, i):
+		return atStart(i)
+	case isKeyword(tokens[i], "YIELD"):
+		return atStart(i) && isYield(tokens, i)
+	case isKeyword(tokens[i], "ON"):
+		return atStart(i) && isOnTimer(tokens, i)
+	case isKeyword(tokens[i], "TIMER"):
+		return i >= 1 && atStart(i-1) && isOnTimer(tokens, i-1)
+	case isKeyword(tokens[i], "GOSUB"):
+		return isGosub(tokens, i)
+	case isKeyword(tokens[i], "IMPORT"):
+		return atStart(i) && i+1 < len(tokens) && tokens[i+1].tokenType == STRING
+	case isKeyword(tokens[i], "AS"): // IMPORT "path" AS name, or an annotation like x AS INT
+		if i >= 2 && tokens[i-1].tokenType == STRING && isKeyword(tokens[i-2], "IMPORT") && atStart(i-2) {
+			return true
+		}
+		return i >= 1 && i+1 < len(tokens) && tokens[i+1].tokenType == IDENTIFIER && endsOperand(tokens[i-1])
+	case isKeyword(tokens[i], "EACH"):
+		return i >= 1 && isKeyword(tokens[i-1], "FOR") && atStart(i-1)
+	case isKeyword(tokens[i], "IN"):
+		return i >= 3 && isKeyword(tokens[i-2], "EACH") && isKeyword(tokens[i-3], "FOR") && atStart(i-3)
+	}
+	return (i >= 1 && isKeyword(tokens[i-1], "OPTION") && atStart(i-1)) || (i >= 2 && isKeyword(tokens[i-2], "OPTION") && atStart(i-2))
+}
+
+// maps the source to highlight classes, for editors and syntax highlighters.
+// Punctuation (parentheses, brackets, colons) and whitespace aren't classified.
+// If the source has an illegal character, the tokens before it are still returned along with the error,
+// so half-typed code can be highlighted.
+func SemanticTokens(src string) ([]SemanticToken_t, error) {
+	tokens, err := newLexer(src, "").makeTokens()
+
+	ret := make([]SemanticToken_t, 0, len(tokens))
+	for i, tok := range tokens {
+		var class TokenClass_t
+		switch tok.tokenType {
+		case INT, FLOAT:
+			class = CLASS_NUMBER
+		case STRING:
+			class = CLASS_STRING
+		case ADD, SUB, MUL, DIV, POW, LT, GT, LE, GE, EQ, NE, APPROX, COALESCE, SAFE_DOT, ELLIPSIS, DOT:
+			class = CLASS_OPERATOR
+		case PARAM:
+			class = CLASS_IDENTIFIER
+		case IDENTIFIER:
+			class = CLASS_IDENTIFIER
+			if isStatementKeyword(tokens, i) || isOperatorKeyword(tokens, i) || isMatchKeyword(tokens, i) {
+				class = CLASS_KEYWORD
+			} else if i+1 < len(tokens) && tokens[i+1].tokenType == LPAREN {
+				class = CLASS_FUNCTION
+			}
+		default:
+			continue
+		}
+		ret = append(ret, SemanticToken_t{Class: class, Start: tok.pos.index, End: tok.end, Line: tok.pos.line, Col: tok.pos.col})
+	}
+	return ret, err
+}
@@ -0,0 +1,223 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// a spreadsheet: a grid of cells that hold either a plain value or a formula referencing
+// other cells, like "A1 + B2 * 3". The sheet keeps track of which cells depend on which,
+// refuses formulas that would make a cycle, and when a cell changes only recalculates the
+// cells that depend on it.
+type Sheet_t struct {
+	cells      map[string]*cell_t
+	dependents map[string]map[string]bool // cell -> cells whose formulas reference it
+	interp     *Interpreter_t             // evaluates every formula, with the options the sheet was made with
+}
+
+// a single cell of a Sheet.
+type cell_t struct {
+	formula *Node_t           // nil for plain values
+	deps    map[string]string // cells the formula references -> the name used for it in the formula
+	value   *Result_t
+	err     error
+}
+
+// constructor for Sheet objects. Formulas are evaluated with the default options.
+func NewSheet() *Sheet_t {
+	return NewInterpreter(Options_t{}).NewSheet()
+}
+
+// makes a Sheet whose formulas are parsed and evaluated with this interpreter's options, its
+// limits, permissions and timeout included. The sheet has an interpreter of its own for them,
+// so setting cells doesn't touch this one's variables.
+func (interp *Interpreter_t) NewSheet() *Sheet_t {
+	return &Sheet_t{cells: make(map[string]*cell_t), dependents: make(map[string]map[string]bool), interp: NewInterpreter(interp.opts)}
+}
+
+// returns true if the name is a cell reference: letters followed by digits, like A1 or AB12.
+func isCellRef(name string) bool {
+	i := 0
+	for i < len(name) && ((name[i] >= 'A' && name[i] <= 'Z') || (name[i] >= 'a' && name[i] <= 'z')) {
+		i++
+	}
+	if i == 0 || i == len(name) {
+		return false
+	}
+	for ; i < len(name); i++ {
+		if name[i] < '0' || name[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizes a cell reference (refs are case-insensitive), returning an error if it isn't one.
+func cellKey(ref string) (string, error) {
+	if !isCellRef(ref) {
+		return "", fmt.Errorf("%q is not a cell reference", ref)
+	}
+	return strings.ToUpper(ref), nil
+}
+
+// sets a cell to a plain value and recalculates the cells depending on it.
+func (sheet *Sheet_t) SetValue(ref string, value *Result_t) error {
+	key, err := cellKey(ref)
+	if err != nil {
+		return err
+	}
+	sheet.setDeps(key, nil)
+	sheet.cells[key] = &cell_t{value: value}
+	sheet.recalculate(key)
+	return nil
+}
+
+// sets a cell to a formula and recalculates it and the cells depending on it.
+// Formulas that don't parse, reference something other than cells, or would make
+// a cell depend on itself are rejected and leave the sheet unchanged.
+func (sheet *Sheet_t) SetFormula(ref string, formula string) error {
+	key, err := cellKey(ref)
+	if err != nil {
+		return err
+	}
+	node, err := parse(formula, key, sheet.interp.opts)
+	if err != nil {
+		return Localize(err, sheet.interp.locale)
+	}
+
+	deps := make(map[string]string)
+	for name := range variables(node) {
+		depKey, err := cellKey(name)
+		if err != nil {
+			return fmt.Errorf("formula for %s: %w", key, err)
+		}
+		deps[depKey] = name
+	}
+	for depKey := range deps {
+		if depKey == key || sheet.dependsOn(depKey, key) {
+			return fmt.Errorf("formula for %s would make a cycle through %s", key, depKey)
+		}
+	}
+
+	sheet.setDeps(key, deps)
+	sheet.cells[key] = &cell_t{formula: node, deps: deps}
+	sheet.recalculate(key)
+	return nil
+}
+
+// gets the value of a cell. Empty cells are 0, like in any other spreadsheet.
+// If the cell's formula couldn't be evaluated, the error is returned.
+func (sheet *Sheet_t) Get(ref string) (*Result_t, error) {
+	key, err := cellKey(ref)
+	if err != nil {
+		return nil, err
+	}
+	cell, ok := sheet.cells[key]
+	if !ok {
+		return NewInt(0), nil
+	}
+	return cell.value, cell.err
+}
+
+// gets the (normalized) references of every cell that isn't empty, sorted.
+func (sheet *Sheet_t) Cells() []string {
+	ret := make([]string, 0, len(sheet.cells))
+	for key := range sheet.cells {
+		ret = append(ret, key)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// returns true if the formula of the cell `from` references `to`, directly or through other cells.
+func (sheet *Sheet_t) dependsOn(from, to string) bool {
+	cell, ok := sheet.cells[from]
+	if !ok {
+		return false
+	}
+	for dep := range cell.deps {
+		if dep == to || sheet.dependsOn(dep, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// replaces the recorded dependencies of a cell.
+func (sheet *Sheet_t) setDeps(key string, deps map[string]string) {
+	if old, ok := sheet.cells[key]; ok {
+		for dep := range old.deps {
+			delete(sheet.dependents[dep], key)
+		}
+	}
+	for dep := range deps {
+		if sheet.dependents[dep] == nil {
+			sheet.dependents[dep] = make(map[string]bool)
+		}
+		sheet.dependents[dep][key] = true
+	}
+}
+
+// recalculates the changed cell and every cell that (transitively) depends on it, with each
+// cell recalculated after the cells it references.
+func (sheet *Sheet_t) recalculate(changed string) {
+	dirty := map[string]bool{changed: true}
+	queue := []string{changed}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for dependent := range sheet.dependents[key] {
+			if !dirty[dependent] {
+				dirty[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	done := make(map[string]bool)
+	var visit func(key string)
+	visit = func(key string) {
+		if done[key] {
+			return
+		}
+		done[key] = true
+		cell := sheet.cells[key]
+		for dep := range cell.deps {
+			if dirty[dep] {
+				visit(dep)
+			}
+		}
+		sheet.evaluateCell(cell)
+	}
+	for key := range dirty {
+		if _, ok := sheet.cells[key]; ok {
+			visit(key)
+		}
+	}
+}
+
+// evaluates the formula of a cell (if it has one) using the current values of the cells it references,
+// the way Run would, so formulas can use the prelude's functions too. The prelude is loaded once,
+// by the first formula, and each formula gets a fresh count of steps and time.
+func (sheet *Sheet_t) evaluateCell(cell *cell_t) {
+	if cell.formula == nil {
+		return
+	}
+	interp := sheet.interp
+	defer func() {
+		for _, name := range cell.deps {
+			delete(interp.vars, name)
+		}
+	}()
+	for depKey, name := range cell.deps {
+		value, err := sheet.Get(depKey)
+		if err != nil {
+			cell.value, cell.err = nil, fmt.Errorf("%s has an error: %w", depKey, err)
+			return
+		}
+		interp.SetVar(name, value)
+	}
+	cell.value, cell.err = interp.runNode(context.Background(), cell.formula, nil)
+}
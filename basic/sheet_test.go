@@ -0,0 +1,114 @@
+package basic
+
+import (
+	"errors"
+	"testing"
+)
+
+// gets the int in a cell, failing the test if it hasn't got one.
+func cellInt(t *testing.T, sheet *Sheet_t, ref string) int64 {
+	t.Helper()
+	res, err := sheet.Get(ref)
+	if err != nil {
+		t.Fatalf("%s: %s", ref, err)
+	} else if res.ResultType != INTEGER {
+		t.Fatalf("%s: got %s, want an int", ref, res.ResultType)
+	}
+	return res.Ires
+}
+
+func TestSheet(t *testing.T) {
+	sheet := NewSheet()
+	sheet.SetValue("A1", NewInt(2))
+	sheet.SetValue("b2", NewInt(5))
+	if err := sheet.SetFormula("C1", "a1 + B2 * 3"); err != nil {
+		t.Fatal(err)
+	} else if err := sheet.SetFormula("C2", "C1 * 2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := cellInt(t, sheet, "C1"); got != 17 {
+		t.Errorf("C1: got %d, want 17", got)
+	} else if got := cellInt(t, sheet, "c2"); got != 34 {
+		t.Errorf("C2: got %d, want 34", got)
+	}
+
+	sheet.SetValue("A1", NewInt(10))
+	if got := cellInt(t, sheet, "C2"); got != 50 {
+		t.Errorf("C2 after changing A1: got %d, want 50", got)
+	}
+	if got := cellInt(t, sheet, "Z9"); got != 0 {
+		t.Errorf("empty cell: got %d, want 0", got)
+	}
+	if got, want := sheet.Cells(), []string{"A1", "B2", "C1", "C2"}; len(got) != len(want) {
+		t.Errorf("Cells: got %v, want %v", got, want)
+	}
+}
+
+func TestSheetRejects(t *testing.T) {
+	sheet := NewSheet()
+	if err := sheet.SetFormula("A1", "B1 + 1"); err != nil {
+		t.Fatal(err)
+	} else if err := sheet.SetFormula("B1", "C1 * 2"); err != nil {
+		t.Fatal(err)
+	}
+	for ref, formula := range map[string]string{
+		"C1": "A1 + 1", // a cycle through B1
+		"D1": "D1",
+		"E1": "x + 1",
+		"F1": "1 +",
+	} {
+		if err := sheet.SetFormula(ref, formula); err == nil {
+			t.Errorf("%s = %s: got no error", ref, formula)
+		}
+	}
+	if err := sheet.SetValue("1A", NewInt(1)); err == nil {
+		t.Errorf("setting 1A: got no error")
+	}
+	if got := cellInt(t, sheet, "A1"); got != 1 {
+		t.Errorf("A1: got %d, want 1", got)
+	}
+}
+
+func TestSheetErrors(t *testing.T) {
+	sheet := NewSheet()
+	if err := sheet.SetFormula("A1", "1 / B1"); err != nil {
+		t.Fatal(err)
+	} else if err := sheet.SetFormula("A2", "A1 + 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sheet.Get("A2"); err == nil {
+		t.Errorf("A2 of an error: got no error")
+	}
+	sheet.SetValue("B1", NewInt(1))
+	if got := cellInt(t, sheet, "A2"); got != 2 {
+		t.Errorf("A2 once B1 is set: got %d, want 2", got)
+	}
+}
+
+func TestSheetOptions(t *testing.T) {
+	interp := NewInterpreter(Options_t{MaxSteps: 1000, Permissions: DenyAll(), MaxSourceBytes: 100})
+	sheet := interp.NewSheet()
+	sheet.SetValue("A1", NewInt(1000000))
+	if err := sheet.SetFormula("B1", "SUM(RANGE(0, A1))"); err != nil {
+		t.Fatal(err)
+	} else if err := sheet.SetFormula("B2", `LEN(ENVIRON$("HOME")) + A1`); err != nil {
+		t.Fatal(err)
+	} else if err := sheet.SetFormula("B3", "SUM([A1, 1])"); err != nil {
+		t.Fatal(err)
+	}
+	for ref, want := range map[string]ErrorCode_t{"B1": ERR_STEP_LIMIT, "B2": ERR_PERMISSION} {
+		var runtimeErr *RuntimeError_t
+		if _, err := sheet.Get(ref); !errors.As(err, &runtimeErr) || runtimeErr.Code != want {
+			t.Errorf("%s: got %v, want an %s error", ref, err, want)
+		}
+	}
+	if got := cellInt(t, sheet, "B3"); got != 1000001 {
+		t.Errorf("B3: got %d, want 1000001", got)
+	}
+	if err := sheet.SetFormula("C1", "A1"+string(make([]byte, 100))); err == nil {
+		t.Errorf("a formula longer than MaxSourceBytes: got no error")
+	}
+	if _, ok := sheet.interp.GetVar("A1"); ok {
+		t.Errorf("cells are left behind as variables")
+	}
+}
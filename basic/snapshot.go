@@ -0,0 +1,234 @@
+package basic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// a copy of an interpreter's variables, functions included, made by Snapshot and put back by
+// Restore. It's plain data with json tags, so it can be saved and restored later, like for an
+// undo, or sent to another process. Settings changed with OPTION aren't part of it.
+type Snapshot_t struct {
+	Vars map[string]SnapshotValue_t `json:"vars"`
+}
+
+// a value in a Snapshot. Which fields are set depends on Type.
+type SnapshotValue_t struct {
+	Type   string            `json:"type"`             // the value's type, like "int" or "function"
+	Int    int64             `json:"int,omitempty"`    // an int
+	Enum   string            `json:"enum,omitempty"`   // the ENUM an int is a member of, if it's one
+	Text   string            `json:"text,omitempty"`   // a string, a float (written exactly, so NaN and Inf survive), or the source of a LAMBDA, builtin, TYPE or expression
+	Keys   []string          `json:"keys,omitempty"`   // a dict's keys or a record's fields, in order
+	Values []SnapshotValue_t `json:"values,omitempty"` // a list's elements, a dict's or record's values in the order of its keys, or a matrix's rows
+}
+
+// copies the interpreter's variables into a Snapshot. Functions are saved as their source, so
+// they have to be LAMBDAs or builtins: a function made in Go with NewFunction can't be saved.
+func (interp *Interpreter_t) Snapshot() (*Snapshot_t, error) {
+	ret := &Snapshot_t{Vars: make(map[string]SnapshotValue_t, len(interp.vars))}
+	for name, value := range interp.vars {
+		saved, err := interp.snapshotValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", name, err)
+		}
+		ret.Vars[name] = saved
+	}
+	return ret, nil
+}
+
+// replaces the interpreter's variables with the ones in a Snapshot, remaking functions from
+// their source. If anything in it can't be restored, the variables are left as they were.
+func (interp *Interpreter_t) Restore(snap *Snapshot_t) error {
+	vars := make(map[string]*Result_t, len(snap.Vars))
+	interp.resetLimits(time.Now()) // remaking a LAMBDA counts as a step
+	for name, saved := range snap.Vars {
+		value, err := interp.restoreValue(saved)
+		if err != nil {
+			return fmt.Errorf("variable %s: %w", name, err)
+		}
+		vars[name] = value
+	}
+	interp.vars = vars
+	interp.definedAt = make(map[string]Position_t) // where they were bound is lost
+	interp.preluded = false                        // a snapshot from before the first Run has no prelude, so the next Run fills it in
+	return nil
+}
+
+// converts a value for a Snapshot.
+func (interp *Interpreter_t) snapshotValue(res *Result_t) (SnapshotValue_t, error) {
+	ret := SnapshotValue_t{Type: res.ResultType.String()}
+	switch res.ResultType {
+	case INTEGER:
+		ret.Int, ret.Enum = res.Ires, res.Enum
+	case FLOATING:
+		ret.Text = strconv.FormatFloat(res.Fres, 'g', -1, 64)
+	case STRING_RESULT:
+		ret.Text = res.Sres
+	case LIST_RESULT:
+		values, err := interp.snapshotValues(res.Lres)
+		if err != nil {
+			return ret, err
+		}
+		ret.Values = values
+	case DICT_RESULT:
+		ret.Keys = append([]string{}, res.Dres.keys...)
+		elems := make([]*Result_t, len(ret.Keys))
+		for i, key := range ret.Keys {
+			elems[i] = res.Dres.values[key]
+		}
+		values, err := interp.snapshotValues(elems)
+		if err != nil {
+			return ret, err
+		}
+		ret.Values = values
+	case MATRIX_RESULT:
+		for _, row := range res.Mres.Slices() {
+			saved := SnapshotValue_t{Type: LIST_RESULT.String()}
+			for _, elem := range row {
+				saved.Values = append(saved.Values, SnapshotValue_t{Type: FLOATING.String(), Text: strconv.FormatFloat(elem, 'g', -1, 64)})
+			}
+			ret.Values = append(ret.Values, saved)
+		}
+	case RECORD_RESULT:
+		ret.Text = res.Rres.Type.Name
+		if res.Rres.Type.source != nil {
+			ret.Text = Format(res.Rres.Type.source)
+		}
+		ret.Keys = res.Rres.Type.fieldNames()
+		values, err := interp.snapshotValues(res.Rres.values)
+		if err != nil {
+			return ret, err
+		}
+		ret.Values = values
+	case FUNCTION_RESULT:
+		if res.Fnres.source != nil {
+			ret.Text = Format(res.Fnres.source)
+		} else if _, ok := interp.builtinValue(res.Fnres.Name); ok {
+			ret.Text = res.Fnres.Name
+		} else {
+			return ret, fmt.Errorf("function %s isn't a LAMBDA or a builtin, so it can't be saved", res.Fnres.Name)
+		}
+	case SYMBOLIC_RESULT:
+		ret.Text = Format(res.Xres)
+	}
+	return ret, nil
+}
+
+// converts the elements of a list for a Snapshot.
+func (interp *Interpreter_t) snapshotValues(elems []*Result_t) ([]SnapshotValue_t, error) {
+	ret := make([]SnapshotValue_t, len(elems))
+	for i, elem := range elems {
+		saved, err := interp.snapshotValue(elem)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = saved
+	}
+	return ret, nil
+}
+
+// remakes a value from a Snapshot.
+func (interp *Interpreter_t) restoreValue(saved SnapshotValue_t) (*Result_t, error) {
+	switch saved.Type {
+	case INTEGER.String():
+		ret := NewInt(saved.Int)
+		ret.Enum = saved.Enum
+		return ret, nil
+	case FLOATING.String():
+		f, err := strconv.ParseFloat(saved.Text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad float %q", saved.Text)
+		}
+		return NewFloat(f), nil
+	case STRING_RESULT.String():
+		return NewString(saved.Text), nil
+	case LIST_RESULT.String():
+		elems, err := interp.restoreValues(saved.Values)
+		if err != nil {
+			return nil, err
+		}
+		return NewList(elems), nil
+	case DICT_RESULT.String():
+		if len(saved.Keys) != len(saved.Values) {
+			return nil, fmt.Errorf("dict has %d keys but %d values", len(saved.Keys), len(saved.Values))
+		}
+		values, err := interp.restoreValues(saved.Values)
+		if err != nil {
+			return nil, err
+		}
+		return NewDict(saved.Keys, values), nil
+	case MATRIX_RESULT.String():
+		rows := make([][]float64, len(saved.Values))
+		for i, row := range saved.Values {
+			elems, err := interp.restoreValues(row.Values)
+			if err != nil {
+				return nil, err
+			}
+			for _, elem := range elems {
+				rows[i] = append(rows[i], elem.Fres)
+			}
+		}
+		return NewMatrix(rows)
+	case RECORD_RESULT.String():
+		typ, err := interp.restoreRecordType(saved.Text, saved.Keys)
+		if err != nil {
+			return nil, err
+		} else if len(typ.Fields) != len(saved.Values) {
+			return nil, fmt.Errorf("record %s has %d fields but %d values", typ.Name, len(typ.Fields), len(saved.Values))
+		}
+		values, err := interp.restoreValues(saved.Values)
+		if err != nil {
+			return nil, err
+		}
+		return NewRecord(typ, values), nil
+	case FUNCTION_RESULT.String():
+		if strings.HasPrefix(saved.Text, "TYPE ") {
+			return interp.restoreConstructor(saved.Text)
+		} else if !strings.HasPrefix(strings.ToUpper(saved.Text), "LAMBDA") {
+			if value, ok := interp.builtinValue(saved.Text); ok {
+				return value, nil
+			}
+			return nil, fmt.Errorf("there's no builtin called %s here", saved.Text)
+		}
+		return interp.restoreLambda(saved.Text)
+	case NIL_RESULT.String():
+		return NewNil(), nil
+	case SYMBOLIC_RESULT.String():
+		node, err := Parse(saved.Text, "snapshot")
+		if err != nil {
+			return nil, err
+		}
+		return NewSymbolic(node), nil
+	}
+	return nil, fmt.Errorf("unknown type %q", saved.Type)
+}
+
+// remakes the elements of a list from a Snapshot.
+func (interp *Interpreter_t) restoreValues(saved []SnapshotValue_t) ([]*Result_t, error) {
+	ret := make([]*Result_t, len(saved))
+	for i, elem := range saved {
+		value, err := interp.restoreValue(elem)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = value
+	}
+	return ret, nil
+}
+
+// evaluates the source of a LAMBDA from a Snapshot, checking it makes a function.
+func (interp *Interpreter_t) restoreLambda(src string) (*Result_t, error) {
+	node, err := Parse(src, "snapshot")
+	if err != nil {
+		return nil, err
+	}
+	res, err := node.evaluate(interp)
+	if err != nil {
+		return nil, err
+	} else if res.ResultType != FUNCTION_RESULT {
+		return nil, fmt.Errorf("%s is a %s, not a function", src, res.ResultType)
+	}
+	return res, nil
+}
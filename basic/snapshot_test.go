@@ -0,0 +1,94 @@
+package basic
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	src := `TYPE Point
+    x
+    y = 0
+END TYPE
+ENUM Color
+    RED
+    GREEN = 5
+END ENUM
+n, f = [42, 1.5]
+s, xs = ["héllo", [1, [2.5, "x"]]]
+d, p = [{"b": 1, "a": [2]}, Point(3, 4)]
+m, c = [MATRIX([[1, 2], [3, 4]]), Color.GREEN]
+double, sorter = [LAMBDA(v, v * 2), SORT]`
+	interp := NewInterpreter(Options_t{})
+	if _, err := interp.Run(src, t.Name()); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := interp.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Snapshot_t
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewInterpreter(Options_t{})
+	if err := restored.Restore(&loaded); err != nil {
+		t.Fatal(err)
+	}
+	for _, expr := range []string{`n`, `f`, `s`, `xs`, `d`, `p`, `p.y`, `m`, `c`, `Color.RED`, `double(21)`, `sorter([3, 1, 2])`, `Point(1).x`} {
+		want, err := interp.Run(expr, t.Name())
+		if err != nil {
+			t.Fatalf("%s before: %s", expr, err)
+		}
+		got, err := restored.Run(expr, t.Name())
+		if err != nil {
+			t.Errorf("%s after restoring: %s", expr, err)
+		} else if !got.Equal(want) {
+			t.Errorf("%s after restoring: got %s, want %s", expr, got.ValueString(), want.ValueString())
+		}
+	}
+}
+
+func TestSnapshotUndo(t *testing.T) {
+	interp := NewInterpreter(Options_t{})
+	if _, err := interp.Run(`x, y = [1, 2]`, t.Name()); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := interp.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := interp.Run(`x, z = [10, 3]`, t.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if err := interp.Restore(snap); err != nil {
+		t.Fatal(err)
+	}
+	if x, _ := interp.GetVar("x"); x == nil || x.Ires != 1 {
+		t.Errorf("x after undoing: got %v, want 1", x)
+	} else if _, ok := interp.GetVar("z"); ok {
+		t.Errorf("z is still set after undoing")
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	interp := NewInterpreter(Options_t{})
+	interp.SetVar("f", NewFunction(&Function_t{Name: "f", Arity: 0}))
+	if _, err := interp.Snapshot(); err == nil {
+		t.Errorf("saving a Go function: got no error")
+	}
+
+	interp = NewInterpreter(Options_t{})
+	interp.SetVar("x", NewInt(1))
+	bad := &Snapshot_t{Vars: map[string]SnapshotValue_t{"y": {Type: "int", Int: 2}, "z": {Type: "nonsense"}}}
+	if err := interp.Restore(bad); err == nil {
+		t.Errorf("restoring a value of an unknown type: got no error")
+	} else if x, ok := interp.GetVar("x"); !ok || x.Ires != 1 {
+		t.Errorf("a failed Restore changed the variables")
+	}
+}
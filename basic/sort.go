@@ -0,0 +1,158 @@
+package basic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// orders two values: negative if a comes first, positive if b does, 0 if they're equal.
+// Numbers compare with numbers and strings with strings (see compareText);
+// anything else can't be compared.
+func compareValues(a *Result_t, b *Result_t, ignoreCase bool) (int, error) {
+	if a.IsNumber() && b.IsNumber() {
+		if a.ResultType == INTEGER && b.ResultType == INTEGER { // exact, even past 2^53
+			if a.Ires < b.Ires {
+				return -1, nil
+			} else if a.Ires > b.Ires {
+				return 1, nil
+			}
+			return 0, nil
+		}
+		if a.Fres < b.Fres {
+			return -1, nil
+		} else if a.Fres > b.Fres {
+			return 1, nil
+		}
+		return 0, nil
+	} else if a.ResultType == STRING_RESULT && b.ResultType == STRING_RESULT {
+		return compareText(a.Sres, b.Sres, ignoreCase), nil
+	}
+	return 0, typeErrorf("can't compare %s with %s", a.ResultType, b.ResultType)
+}
+
+// orders two strings by code point, the way a dictionary would if it put "B" before "a".
+// With ignoreCase they're compared as if they were lower case, so "a" < "B" and "A" = "a".
+func compareText(a string, b string, ignoreCase bool) int {
+	if ignoreCase {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}
+	return strings.Compare(a, b) // UTF-8 orders by code point byte for byte
+}
+
+// sorts a copy of elems by the matching keys, keeping equal elements in their original order.
+func sortByKeys(elems []*Result_t, keys []*Result_t, descending bool, ignoreCase bool) (*Result_t, error) {
+	order := make([]int, len(elems))
+	for i := range order {
+		order[i] = i
+	}
+	var err error
+	sort.SliceStable(order, func(i, j int) bool {
+		cmp, cmpErr := compareValues(keys[order[i]], keys[order[j]], ignoreCase)
+		if cmpErr != nil && err == nil {
+			err = cmpErr
+		}
+		if descending {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Result_t, len(elems))
+	for i, j := range order {
+		ret[i] = elems[j]
+	}
+	return NewList(ret), nil
+}
+
+// gets whether the optional last argument of SORT or SORTBY asks for descending order.
+func descendingArg(args []*Result_t, min int) (bool, error) {
+	if len(args) < min || len(args) > min+1 {
+		return false, fmt.Errorf("takes %d or %d arguments, got %d", min, min+1, len(args))
+	} else if len(args) == min {
+		return false, nil
+	}
+	return truthy(args[min])
+}
+
+// the builtins that compare strings, keyed by name, made to ignore case or not. The
+// interpreter swaps in the ones that ignore it when the IgnoreCase option is on.
+var caseBuiltins = map[string]func(ignoreCase bool) BuiltinFunc_t{"SORT": sortBuiltin, "SORTBY": sortByBuiltin, "MINMAX": minMaxBuiltin}
+
+// sorting builtins. Both return a new list, leaving the one they're given alone.
+func init() {
+	RegisterBuiltinParams("SORT", []Param_t{{Name: "list"}, {Name: "descending", Default: NewInt(0)}}, sortBuiltin(false))
+	RegisterBuiltinParams("SORTBY", []Param_t{{Name: "list"}, {Name: "key"}, {Name: "descending", Default: NewInt(0)}}, sortByBuiltin(false))
+	RegisterBuiltin("MINMAX", 1, minMaxBuiltin(false))
+}
+
+// wraps a builtin so it compares strings the way the interpreter's IgnoreCase option says.
+func (interp *Interpreter_t) collated(name string, fn BuiltinFunc_t) BuiltinFunc_t {
+	if build, ok := caseBuiltins[name]; ok && interp.opts.IgnoreCase {
+		return build(true)
+	}
+	return fn
+}
+
+// makes SORT(list[, descending]).
+func sortBuiltin(ignoreCase bool) BuiltinFunc_t {
+	return func(args []*Result_t) (*Result_t, error) {
+		descending, err := descendingArg(args, 1)
+		if err != nil {
+			return nil, err
+		} else if args[0].ResultType != LIST_RESULT {
+			return nil, typeErrorf("can only sort a list, not %s", args[0].ResultType)
+		}
+		return sortByKeys(args[0].Lres, args[0].Lres, descending, ignoreCase)
+	}
+}
+
+// makes SORTBY(list, keyfn[, descending]).
+func sortByBuiltin(ignoreCase bool) BuiltinFunc_t {
+	return func(args []*Result_t) (*Result_t, error) {
+		descending, err := descendingArg(args, 2)
+		if err != nil {
+			return nil, err
+		} else if args[0].ResultType != LIST_RESULT {
+			return nil, typeErrorf("can only sort a list, not %s", args[0].ResultType)
+		} else if args[1].ResultType != FUNCTION_RESULT {
+			return nil, typeErrorf("the key has to be a function, not %s", args[1].ResultType)
+		}
+		keys := make([]*Result_t, len(args[0].Lres))
+		for i, elem := range args[0].Lres {
+			keys[i], err = args[1].Fnres.Call([]*Result_t{elem})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return sortByKeys(args[0].Lres, keys, descending, ignoreCase)
+	}
+}
+
+// makes MINMAX(list): [smallest, biggest], to unpack like lo, hi = MINMAX(xs). The first of
+// equal elements wins either way.
+func minMaxBuiltin(ignoreCase bool) BuiltinFunc_t {
+	return func(args []*Result_t) (*Result_t, error) {
+		if args[0].ResultType != LIST_RESULT {
+			return nil, typeErrorf("can only find the smallest and biggest of a list, not %s", args[0].ResultType)
+		} else if len(args[0].Lres) == 0 {
+			return nil, fmt.Errorf("an empty list has no smallest or biggest element")
+		}
+		lo, hi := args[0].Lres[0], args[0].Lres[0]
+		for _, elem := range args[0].Lres[1:] {
+			if cmp, err := compareValues(elem, lo, ignoreCase); err != nil {
+				return nil, err
+			} else if cmp < 0 {
+				lo = elem
+			}
+			if cmp, err := compareValues(elem, hi, ignoreCase); err != nil {
+				return nil, err
+			} else if cmp > 0 {
+				hi = elem
+			}
+		}
+		return NewList([]*Result_t{lo, hi}), nil
+	}
+}
@@ -0,0 +1,40 @@
+package basic
+
+import "testing"
+
+func TestSort(t *testing.T) {
+	for src, want := range map[string]string{
+		`SORT([3, 1.5, 2])`:     `[1.5, 2, 3]`,
+		`SORT([3, 1, 2], 1)`:    `[3, 2, 1]`,
+		`SORT(["b", "B", "a"])`: `["B", "a", "b"]`,
+		`SORT([])`:              `[]`,
+		`SORTBY(["ccc", "a", "bb"], LAMBDA(s, LEN(s)))`:             `["a", "bb", "ccc"]`,
+		`SORTBY(["bb", "a", "cc"], LAMBDA(s, LEN(s)), 1)`:           `["bb", "cc", "a"]`,
+		`SORTBY([[2, "x"], [1, "y"], [2, "z"]], LAMBDA(p, p[0]))`:   `[[1, "y"], [2, "x"], [2, "z"]]`,
+		`MINMAX([3, -1, 7, 2])`:                                     `[-1, 7]`,
+		`xs, ys = [[3, 1, 2], 0]` + "\n" + `SORT(xs)` + "\n" + `xs`: `[3, 1, 2]`,
+	} {
+		if got := runValue(t, src); got != want {
+			t.Errorf("%q: got %s, want %s", src, got, want)
+		}
+	}
+	for src, want := range map[string]ErrorCode_t{
+		`SORT([1, "a"])`:                 ERR_TYPE,
+		`SORT(5)`:                        ERR_TYPE,
+		`SORTBY([1, 2], LAMBDA(x, [x]))`: ERR_TYPE,
+		`MINMAX([])`:                     ERR_BUILTIN,
+	} {
+		if got := runErrorCode(t, Options_t{}, src); got != want {
+			t.Errorf("%q: got error code %q, want %q", src, got, want)
+		}
+	}
+}
+
+func TestSortIgnoreCase(t *testing.T) {
+	res, err := NewInterpreter(Options_t{IgnoreCase: true}).Run(`SORT(["b", "B", "a", "A"])`, t.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := res.ValueString(), `["a", "A", "b", "B"]`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package basic
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// the tone BEEP plays, for backends that can play tones.
+const (
+	BEEP_FREQUENCY = 800                    // in Hz
+	BEEP_DURATION  = 250 * time.Millisecond // how long it lasts
+)
+
+// interface for what plays SOUND and BEEP. A backend can ring the terminal bell, play real tones
+// through an audio library, or just record what it's asked to play, for tests.
+type Audio_t interface {
+	Sound(frequency float64, duration time.Duration) error // plays a tone of frequency Hz
+	Beep() error                                           // plays the standard beep
+}
+
+// a backend that rings the terminal bell by writing a BEL character, for BEEP and every SOUND alike.
+// It doesn't wait for the sound's duration.
+type bellAudio_t struct {
+	out io.Writer
+}
+
+// constructor for an audio backend that rings the terminal bell on out.
+func NewBellAudio(out io.Writer) Audio_t {
+	return &bellAudio_t{out: out}
+}
+
+func (audio *bellAudio_t) Sound(frequency float64, duration time.Duration) error {
+	return audio.Beep()
+}
+
+func (audio *bellAudio_t) Beep() error {
+	_, err := io.WriteString(audio.out, "\a")
+	return err
+}
+
+// a note played through a RecordingAudio_t.
+type Note_t struct {
+	Frequency float64 // in Hz
+	Duration  time.Duration
+}
+
+// a silent backend that keeps a list of everything it was asked to play, in order.
+// BEEP is recorded as a note of BEEP_FREQUENCY and BEEP_DURATION.
+type RecordingAudio_t struct {
+	Notes []Note_t
+}
+
+func (audio *RecordingAudio_t) Sound(frequency float64, duration time.Duration) error {
+	audio.Notes = append(audio.Notes, Note_t{Frequency: frequency, Duration: duration})
+	return nil
+}
+
+func (audio *RecordingAudio_t) Beep() error {
+	return audio.Sound(BEEP_FREQUENCY, BEEP_DURATION)
+}
+
+// gets the backend SOUND and BEEP use: the one in the options, or else the terminal bell on Stdout.
+func (interp *Interpreter_t) audio() Audio_t {
+	if interp.opts.Audio != nil {
+		return interp.opts.Audio
+	}
+	return NewBellAudio(interp.Stdout())
+}
+
+// SOUND frequency, duration plays a tone of frequency Hz for duration milliseconds. BEEP plays the standard beep.
+func init() {
+	commands["SOUND"] = command_t{arities: []int{2}, run: func(interp *Interpreter_t, args []float64) error {
+		if args[0] <= 0 {
+			return fmt.Errorf("frequency %g isn't positive", args[0])
+		} else if args[1] < 0 {
+			return fmt.Errorf("duration %g is negative", args[1])
+		}
+		return interp.audio().Sound(args[0], time.Duration(args[1]*float64(time.Millisecond)))
+	}}
+	commands["BEEP"] = command_t{arities: []int{0}, run: func(interp *Interpreter_t, args []float64) error {
+		return interp.audio().Beep()
+	}}
+}
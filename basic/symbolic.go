@@ -0,0 +1,412 @@
+package basic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// a term of a polynomial: a number times a product of symbols, each to a power.
+// A symbol is an unbound variable, or a part of the expression that can't be expanded (like SIN(x)),
+// named by how it's written.
+type term_t struct {
+	coef    *Result_t      // an int or a float
+	factors map[string]int // symbol to power
+}
+
+// gets the names of the term's symbols, sorted.
+func (term *term_t) symbols() []string {
+	names := make([]string, 0, len(term.factors))
+	for name := range term.factors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gets the key of the term's product of symbols, which is the same for like terms: x^2*y for x*y*x.
+// The symbols are split by newlines, which can't appear in the expressions they're named after.
+func (term *term_t) key() string {
+	names := term.symbols()
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s^%d", name, term.factors[name])
+	}
+	return strings.Join(parts, "\n")
+}
+
+// gets the total power of the term's symbols.
+func (term *term_t) degree() int {
+	ret := 0
+	for _, power := range term.factors {
+		ret += power
+	}
+	return ret
+}
+
+// a polynomial in the symbols, as its terms keyed by term_t.key. Terms with a zero coefficient are left out.
+type poly_t struct {
+	terms   map[string]*term_t
+	symbols map[string]*Node_t // the node each symbol stands for
+}
+
+// constructor for a polynomial that is just a number.
+func constPoly(num *Result_t) *poly_t {
+	ret := &poly_t{terms: make(map[string]*term_t), symbols: make(map[string]*Node_t)}
+	ret.add(&term_t{coef: num, factors: map[string]int{}})
+	return ret
+}
+
+// constructor for a polynomial that is just a symbol.
+func symbolPoly(name string, node *Node_t) *poly_t {
+	ret := &poly_t{terms: make(map[string]*term_t), symbols: map[string]*Node_t{name: node}}
+	ret.add(&term_t{coef: NewInt(1), factors: map[string]int{name: 1}})
+	return ret
+}
+
+// adds a term to the polynomial, combining it with a like term if there is one.
+func (poly *poly_t) add(term *term_t) {
+	key := term.key()
+	if like, ok := poly.terms[key]; ok {
+		term = &term_t{coef: numberOp(like.coef, term.coef, ADD), factors: term.factors}
+	}
+	if term.coef.Fres == 0 { // the float value is set for integers too
+		delete(poly.terms, key)
+	} else {
+		poly.terms[key] = term
+	}
+}
+
+// gets the number the polynomial is, if it has no symbols.
+func (poly *poly_t) constant() (*Result_t, bool) {
+	if len(poly.terms) == 0 {
+		return NewInt(0), true
+	} else if term, ok := poly.terms[""]; ok && len(poly.terms) == 1 {
+		return term.coef, true
+	}
+	return nil, false
+}
+
+// combines two polynomials with +, - or *.
+func combinePolys(left *poly_t, right *poly_t, op TokenType_t) *poly_t {
+	ret := constPoly(NewInt(0))
+	for _, symbols := range []map[string]*Node_t{left.symbols, right.symbols} {
+		for name, node := range symbols {
+			ret.symbols[name] = node
+		}
+	}
+	if op == MUL {
+		for _, a := range left.terms {
+			for _, b := range right.terms {
+				factors := make(map[string]int, len(a.factors)+len(b.factors))
+				for name, power := range a.factors {
+					factors[name] += power
+				}
+				for name, power := range b.factors {
+					factors[name] += power
+				}
+				ret.add(&term_t{coef: numberOp(a.coef, b.coef, MUL), factors: factors})
+			}
+		}
+		return ret
+	}
+	for _, term := range left.terms {
+		ret.add(term)
+	}
+	for _, term := range right.terms {
+		if op == SUB {
+			term = &term_t{coef: numberOp(NewInt(-1), term.coef, MUL), factors: term.factors}
+		}
+		ret.add(term)
+	}
+	return ret
+}
+
+// returns true if the polynomial is sure to be a float whatever its symbols are: one of its
+// coefficients is, and an int added to or multiplied by a float becomes one.
+func (poly *poly_t) isFloat() bool {
+	for _, term := range poly.terms {
+		if term.coef.ResultType == FLOATING {
+			return true
+		}
+	}
+	return false
+}
+
+// divides every coefficient by a number that isn't 0. / on two ints truncates, so ints only
+// divide if every coefficient does exactly, like (2*x + 4) / 2 = x + 2. Otherwise the division
+// is only kept if the polynomial or the divisor is sure to be a float, so (2*x + 1) / 2.0 is
+// x + 0.5 but x / 2 stays as it is: for x = 3, it's 1, not 1.5. Returns false then.
+func (poly *poly_t) divide(divisor *Result_t) (*poly_t, bool) {
+	exact := divisor.ResultType == INTEGER
+	for _, term := range poly.terms {
+		exact = exact && term.coef.ResultType == INTEGER && term.coef.Ires%divisor.Ires == 0
+	}
+	if !exact && divisor.ResultType != FLOATING && !poly.isFloat() {
+		return nil, false
+	} else if !exact {
+		divisor = NewFloat(divisor.Fres)
+	}
+	ret := constPoly(NewInt(0))
+	ret.symbols = poly.symbols
+	for _, term := range poly.terms {
+		ret.add(&term_t{coef: numberOp(term.coef, divisor, DIV), factors: term.factors})
+	}
+	return ret, true
+}
+
+// divides by a polynomial of a single term, like 3 * x, if every term has at least its symbols:
+// (6 * x^2 + 3 * x) / (3 * x) is 2 * x + 1. Returns false if the division doesn't come out even,
+// or its coefficients don't and ints would truncate them, as in divide.
+func (poly *poly_t) divideTerm(divisor *poly_t) (*poly_t, bool) {
+	if len(divisor.terms) != 1 {
+		return nil, false
+	}
+	var by *term_t
+	for _, term := range divisor.terms {
+		by = term
+	}
+	reduced := constPoly(NewInt(0))
+	reduced.symbols = poly.symbols
+	for _, term := range poly.terms {
+		factors := make(map[string]int, len(term.factors))
+		for name, power := range term.factors {
+			factors[name] = power
+		}
+		for name, power := range by.factors {
+			if factors[name] < power {
+				return nil, false
+			} else if factors[name] == power {
+				delete(factors, name)
+			} else {
+				factors[name] -= power
+			}
+		}
+		reduced.add(&term_t{coef: term.coef, factors: factors})
+	}
+	return reduced.divide(by.coef)
+}
+
+// highest power of a sum that polynomial expands, like (x + 1)^8. Higher ones are kept as they are.
+const maxExpandedPower = 8
+
+// error for an expression that can't be worked on symbolically, like one joining strings.
+var errNotSymbolic = errors.New("expression can't be simplified symbolically")
+
+// returns true if a variable is neither set in the interpreter nor the name of a builtin.
+func (interp *Interpreter_t) unbound(name string) bool {
+	if _, ok := interp.vars[name]; ok {
+		return false
+	}
+	_, ok := interp.builtinValue(name)
+	return !ok
+}
+
+// returns true if the expression reads a variable that isn't bound.
+func (interp *Interpreter_t) hasUnbound(node *Node_t) bool {
+	ret := false
+	Walk(node, func(n *Node_t) bool {
+		if n.nodeType == VAR_ACCESS && interp.unbound(n.tok.strVal) {
+			ret = true
+		}
+		return !ret
+	})
+	return ret
+}
+
+// turns an expression into a polynomial in its unbound variables. Parts without unbound
+// variables are evaluated, and parts with them that aren't sums, differences, products,
+// negations or divisions by a number become symbols of their own.
+func (interp *Interpreter_t) polynomial(node *Node_t) (*poly_t, error) {
+	if !interp.hasUnbound(node) {
+		res, err := node.evaluate(interp)
+		if err != nil {
+			return nil, err
+		} else if !res.IsNumber() {
+			return nil, errNotSymbolic
+		}
+		return constPoly(res), nil
+	}
+
+	switch node.nodeType {
+	case VAR_ACCESS:
+		return symbolPoly(node.tok.strVal, node), nil
+	case UNARY_OP:
+		operand, err := interp.polynomial(node.left)
+		if err != nil {
+			return nil, err
+		} else if node.tok.tokenType == SUB {
+			return combinePolys(constPoly(NewInt(0)), operand, SUB), nil
+		}
+	case TERM, EXPRESSION:
+		left, err := interp.polynomial(node.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := interp.polynomial(node.right)
+		if err != nil {
+			return nil, err
+		}
+		if node.tok.tokenType != DIV {
+			return combinePolys(left, right, node.tok.tokenType), nil
+		} else if divisor, ok := right.constant(); ok && divisor.Fres != 0 {
+			if quotient, ok := left.divide(divisor); ok {
+				return quotient, nil
+			}
+		} else if quotient, ok := left.divideTerm(right); ok {
+			return quotient, nil
+		}
+	case POWER:
+		base, err := interp.polynomial(node.left)
+		if err != nil {
+			return nil, err
+		}
+		exponent, err := interp.polynomial(node.right)
+		if err != nil {
+			return nil, err
+		}
+		if power, ok := exponent.constant(); ok && power.ResultType == INTEGER && power.Ires >= 0 && (power.Ires <= maxExpandedPower || len(base.terms) == 1) {
+			ret := constPoly(NewInt(1))
+			for i := int64(0); i < power.Ires; i++ {
+				ret = combinePolys(ret, base, MUL)
+			}
+			return ret, nil
+		}
+	case CALL: // an IIF whose condition is known is the branch it takes
+		if branch, ok, err := interp.takenBranch(node); err != nil {
+			return nil, err
+		} else if ok {
+			return interp.polynomial(branch)
+		}
+	case LIST, DICT, FACTOR, MATCH_EXPR:
+		return nil, errNotSymbolic
+	}
+	symbol, err := interp.simplifyParts(node)
+	if err != nil {
+		return nil, err
+	}
+	return symbolPoly(Format(symbol), symbol), nil
+}
+
+// simplifies the operands or arguments of a node that can't be expanded itself, like the
+// argument of SIN(x + x), giving a copy of the node with them replaced.
+func (interp *Interpreter_t) simplifyParts(node *Node_t) (*Node_t, error) {
+	ret := *node
+	var err error
+	if node.left != nil {
+		if ret.left, err = interp.simplify(node.left); err != nil {
+			return nil, err
+		}
+	}
+	if node.right != nil {
+		if ret.right, err = interp.simplify(node.right); err != nil {
+			return nil, err
+		}
+	}
+	if node.nodeType == CALL {
+		ret.args = make([]*Node_t, len(node.args))
+		for i, arg := range node.args {
+			if ret.args[i], err = interp.simplify(arg); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &ret, nil
+}
+
+// simplifies an expression with unbound variables into a sum of terms, like 2 * x + 6 for
+// 2 * (x + 3). Terms are ordered by degree, highest first, and then by their symbols.
+func (interp *Interpreter_t) simplify(node *Node_t) (*Node_t, error) {
+	poly, err := interp.polynomial(node)
+	if err != nil {
+		return nil, err
+	}
+	terms := make([]*term_t, 0, len(poly.terms))
+	for _, term := range poly.terms {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].degree() != terms[j].degree() {
+			return terms[i].degree() > terms[j].degree()
+		}
+		return terms[i].key() < terms[j].key()
+	})
+	if len(terms) == 0 {
+		return numberNode(NewInt(0)), nil
+	}
+
+	ret := poly.termNode(terms[0], terms[0].coef)
+	for _, term := range terms[1:] {
+		if term.coef.Fres < 0 { // x - 2 rather than x + -2
+			node := poly.termNode(term, numberOp(NewInt(-1), term.coef, MUL))
+			ret = &Node_t{nodeType: EXPRESSION, tok: Token_t{tokenType: SUB}, left: ret, right: node}
+		} else {
+			node := poly.termNode(term, term.coef)
+			ret = &Node_t{nodeType: EXPRESSION, tok: Token_t{tokenType: ADD}, left: ret, right: node}
+		}
+	}
+	return ret, nil
+}
+
+// builds the node for a term with the given coefficient: the coefficient times each symbol
+// to its power. A coefficient of 1 is left out, and one of -1 negates the first symbol, unless
+// it's a float, which is kept so the term is still one: 1.0 * x rather than x.
+func (poly *poly_t) termNode(term *term_t, coef *Result_t) *Node_t {
+	var ret *Node_t
+	if len(term.factors) == 0 || (coef.Fres != 1 && coef.Fres != -1) || coef.ResultType == FLOATING {
+		ret = numberNode(coef)
+	}
+	for _, name := range term.symbols() {
+		symbol := poly.symbols[name]
+		if power := term.factors[name]; power > 1 {
+			symbol = &Node_t{nodeType: POWER, tok: Token_t{tokenType: POW}, left: symbol, right: numberNode(NewInt(int64(power)))}
+		}
+		if ret == nil && coef.Fres == -1 {
+			ret = &Node_t{nodeType: UNARY_OP, tok: Token_t{tokenType: SUB}, left: symbol}
+		} else if ret == nil {
+			ret = symbol
+		} else {
+			ret = &Node_t{nodeType: TERM, tok: Token_t{tokenType: MUL}, left: ret, right: symbol}
+		}
+	}
+	return ret
+}
+
+// builds a literal node for a number.
+func numberNode(num *Result_t) *Node_t {
+	if num.ResultType == INTEGER {
+		return &Node_t{nodeType: FACTOR, tok: Token_t{tokenType: INT, intVal: num.Ires, floatVal: num.Fres}}
+	}
+	return &Node_t{nodeType: FACTOR, tok: Token_t{tokenType: FLOAT, floatVal: num.Fres}}
+}
+
+// simplifies an expression with unbound variables, like Run does in Symbolic mode:
+// 2 * (x + 3) becomes 2 * x + 6. Parts that can't be expanded, like SIN(x), are kept
+// as they are, with their own arguments simplified.
+func Simplify(node *Node_t) (*Node_t, error) {
+	return NewInterpreter(Options_t{}).simplify(node)
+}
+
+// makes a symbolic Result holding a simplified expression.
+func NewSymbolic(node *Node_t) *Result_t {
+	return &Result_t{ResultType: SYMBOLIC_RESULT, Xres: node}
+}
+
+// gets the symbolic result of a program that read an unbound variable, or the error it got
+// if the program can't be simplified.
+func (interp *Interpreter_t) symbolicResult(node *Node_t, evalErr error) (*Result_t, error) {
+	var runtimeErr *RuntimeError_t
+	if !errors.As(evalErr, &runtimeErr) || runtimeErr.Code != ERR_UNDEFINED_VAR {
+		return nil, evalErr
+	}
+	switch node.nodeType {
+	case STATEMENTS, FOR_EACH, OPTION, IMPORT, POKE, COMMAND, ON_TIMER, YIELD, UNPACK, TYPE_DEF, ENUM_DEF:
+		return nil, evalErr
+	}
+	simplified, err := interp.simplify(node)
+	if err != nil {
+		return nil, evalErr
+	}
+	return NewSymbolic(simplified), nil
+}
@@ -0,0 +1,71 @@
+package basic
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	for src, want := range map[string]string{
+		"2*(x+3)":           "2 * x + 6",
+		"x + x - 3":         "2 * x - 3",
+		"(x+1)^2":           "x^2 + 2 * x + 1",
+		"SIN(x + x)":        "SIN(2 * x)",
+		"(2*x+4)/2":         "x + 2",
+		"(6*x^2+3*x)/(3*x)": "2 * x + 1",
+		"x/2.0*2":           "1.0 * x",
+		"0.5*x/2":           "0.25 * x",
+		// / on ints truncates, so these can't be cancelled: for x = 3, x / 2 * 2 is 2
+		"x/2*2":           "2 * (x / 2)",
+		"(2*x+1)/2":       "(2 * x + 1) / 2",
+		"(6*x^2+x)/(3*x)": "(6 * x^2 + x) / (3 * x)",
+	} {
+		node, err := Parse(src, t.Name())
+		if err != nil {
+			t.Fatalf("%q: %s", src, err)
+		}
+		simplified, err := Simplify(node)
+		if err != nil {
+			t.Errorf("%q: %s", src, err)
+		} else if got := Format(simplified); got != want {
+			t.Errorf("Simplify(%q): got %q, want %q", src, got, want)
+		}
+	}
+}
+
+func TestSimplifyKeepsValues(t *testing.T) {
+	for _, src := range []string{"x/2*2", "(2*x+1)/2", "(2*x+4)/2", "(6*x^2+x)/(3*x)", "x/2.0*2"} {
+		node, err := Parse(src, t.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		simplified, err := Simplify(node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, x := range []*Result_t{NewInt(3), NewInt(-7), NewFloat(2.5)} {
+			original, simple := NewInterpreter(Options_t{}), NewInterpreter(Options_t{})
+			original.SetVar("x", x)
+			simple.SetVar("x", x)
+			want, err1 := original.runNode(context.Background(), node, nil)
+			got, err2 := simple.runNode(context.Background(), simplified, nil)
+			if err1 != nil || err2 != nil {
+				t.Fatalf("%q with x = %s: %v, %v", src, x, err1, err2)
+			} else if !got.Equal(want) || got.ResultType != want.ResultType {
+				t.Errorf("%q with x = %s: simplified to %s, which is %s, not %s", src, x, Format(simplified), got, want)
+			}
+		}
+	}
+}
+
+func TestSymbolicRun(t *testing.T) {
+	res, err := NewInterpreter(Options_t{Symbolic: true}).Run("2*(x+3)", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if res.ResultType != SYMBOLIC_RESULT || Format(res.Xres) != "2 * x + 6" {
+		t.Errorf("got %s %s, want the expression 2 * x + 6", res.ResultType, res)
+	}
+	if _, err := NewInterpreter(Options_t{}).Run("2*(x+3)", t.Name()); err == nil {
+		t.Errorf("without Symbolic, an unbound variable didn't fail")
+	}
+}
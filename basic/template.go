@@ -0,0 +1,45 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// replaces every `{{ expr }}` marker in the document with the value of the expression,
+// evaluated with the given variables. Useful for mail-merge style templating.
+func ExpandTemplate(doc string, vars map[string]*Result_t) (string, error) {
+	interp := NewInterpreter(Options_t{})
+	for name, value := range vars {
+		interp.SetVar(name, value)
+	}
+	return interp.ExpandTemplate(doc)
+}
+
+// replaces every `{{ expr }}` marker in the document with the value of the expression,
+// evaluated by this interpreter (so with its options and variables).
+// Errors say which marker failed and which line of the document it's on.
+func (interp *Interpreter_t) ExpandTemplate(doc string) (string, error) {
+	var ret strings.Builder
+	rest := doc
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			ret.WriteString(rest)
+			return ret.String(), nil
+		}
+		line := strings.Count(doc[:len(doc)-len(rest)+start], "\n")
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return "", fmt.Errorf("template marker on line %d is never closed with }}", line)
+		}
+
+		expr := rest[start+2 : start+end]
+		res, err := interp.Run(expr, "template")
+		if err != nil {
+			return "", fmt.Errorf("template marker {{%s}} on line %d: %w", expr, line, err)
+		}
+		ret.WriteString(rest[:start])
+		ret.WriteString(res.ValueString())
+		rest = rest[start+end+2:]
+	}
+}
@@ -0,0 +1,260 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// the limits a tenant's programs run with. Zero fields mean no limit.
+type Quota_t struct {
+	MaxSteps       int           // most steps a single Run may take
+	MaxTotalSteps  int64         // most steps all of the tenant's Runs may take between them
+	Timeout        time.Duration // longest a single Run may take
+	MaxSourceBytes int           // longest program the tenant may run
+	MaxListLength  int           // longest list a program may make. 0 means DEFAULT_MAX_LIST_LENGTH, like in Options_t
+	MaxMemory      int           // most bytes the tenant's variables and PEEK/POKE memory may take up after a Run, roughly, and that a Run may make strings and lists of as it goes
+}
+
+// owns an interpreter for each of many tenants, like the customers of a service that runs
+// their formulas, each with its own variables and quota. Tenants are made the first time
+// they're used and evicted, variables and all, once they've been idle for longer than
+// IdleTimeout. It's safe for concurrent use: runs for different tenants happen at the same
+// time, and runs for the same tenant one after another.
+type Manager_t struct {
+	IdleTimeout time.Duration // how long a tenant may go unused before it's evicted. 0 means never. Set it before the Manager is used
+
+	opts      Options_t // what new tenants' interpreters start with, before their quota
+	quota     Quota_t   // the quota of tenants that haven't been given one of their own
+	mu        sync.Mutex
+	tenants   map[string]*tenant_t
+	quotas    map[string]Quota_t
+	lastSweep time.Time
+}
+
+// a tenant of a Manager.
+type tenant_t struct {
+	mu       sync.Mutex // held while the tenant's interpreter is in use
+	interp   *Interpreter_t
+	steps    int64     // steps its Runs have taken so far
+	lastUsed time.Time // zero while it's in use, so it isn't evicted then. Guarded by the manager's mu
+	evicted  bool      // guarded by the manager's mu too
+}
+
+// constructor for Manager objects. Every tenant's interpreter is made with opts, and runs
+// with quota unless SetQuota gives it another.
+func NewManager(opts Options_t, quota Quota_t) *Manager_t {
+	return &Manager_t{opts: opts, quota: quota, tenants: make(map[string]*tenant_t), quotas: make(map[string]Quota_t), lastSweep: time.Now()}
+}
+
+// gives a tenant its own quota, which applies from its next Run. The steps it's already
+// taken still count towards MaxTotalSteps.
+func (manager *Manager_t) SetQuota(name string, quota Quota_t) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.quotas[name] = quota
+}
+
+// runs a program for a tenant, within its quota, making the tenant if it's new. The tenant's
+// name is added to ctx's audit tags as "tenant". A Run that makes more than MaxMemory bytes of
+// strings and lists fails as soon as it does, like with the MaxAllocated option, and one that
+// leaves the variables and memory taking up more than MaxMemory fails once it's done. Either way
+// the variables and memory are put back the way they were before it.
+func (manager *Manager_t) Run(ctx context.Context, name string, src string) (*Result_t, error) {
+	tenant, quota := manager.acquire(name)
+	defer manager.release(tenant)
+	interp := tenant.interp
+
+	if quota.MaxTotalSteps > 0 && tenant.steps >= quota.MaxTotalSteps {
+		return nil, fmt.Errorf("tenant %s has used up its quota of %d steps", name, quota.MaxTotalSteps)
+	}
+	interp.opts.MaxSteps = quota.MaxSteps
+	if left := quota.MaxTotalSteps - tenant.steps; quota.MaxTotalSteps > 0 && (quota.MaxSteps == 0 || left < int64(quota.MaxSteps)) {
+		interp.opts.MaxSteps = int(left)
+	}
+	interp.opts.Timeout = quota.Timeout
+	interp.opts.MaxSourceBytes = quota.MaxSourceBytes
+	interp.opts.MaxListLength = quota.MaxListLength
+	interp.opts.MaxAllocated = quota.MaxMemory
+
+	// a Run that fails, however far it got, leaves the tenant as it was before it
+	vars := make(map[string]*Result_t, len(interp.vars)) // values never change once they're made, so a shallow copy will do
+	for key, value := range interp.vars {
+		vars[key] = value
+	}
+	definedAt := make(map[string]Position_t, len(interp.definedAt))
+	for key, pos := range interp.definedAt {
+		definedAt[key] = pos
+	}
+	var memory []byte // POKE changes memory in place, so it's copied
+	if interp.memory != nil {
+		memory = append([]byte(nil), interp.memory...)
+	}
+	rollback := func() {
+		interp.vars, interp.definedAt, interp.memory = vars, definedAt, memory
+	}
+	interp.steps = 0 // a Run that doesn't parse doesn't take any
+	res, err := interp.RunContext(WithAuditTags(ctx, map[string]string{"tenant": name}), src, name)
+	tenant.steps += int64(interp.steps)
+	if err != nil {
+		rollback()
+		return nil, err
+	}
+	if size := interp.size(); quota.MaxMemory > 0 && size > quota.MaxMemory {
+		rollback()
+		return nil, fmt.Errorf("tenant %s's variables would take up about %d bytes, more than its quota of %d", name, size, quota.MaxMemory)
+	}
+	return res, nil
+}
+
+// calls fn with a tenant's interpreter, making the tenant if it's new, so its variables can be
+// set or read with nothing else using it at the same time. The interpreter mustn't be kept after fn returns.
+func (manager *Manager_t) With(name string, fn func(interp *Interpreter_t) error) error {
+	tenant, _ := manager.acquire(name)
+	defer manager.release(tenant)
+	return fn(tenant.interp)
+}
+
+// gets how many steps a tenant's Runs have taken between them, 0 if there's no such tenant.
+func (manager *Manager_t) Steps(name string) int64 {
+	manager.mu.Lock()
+	tenant, ok := manager.tenants[name]
+	manager.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	tenant.mu.Lock()
+	defer tenant.mu.Unlock()
+	return tenant.steps
+}
+
+// gets the names of the tenants, sorted.
+func (manager *Manager_t) Tenants() []string {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	ret := make([]string, 0, len(manager.tenants))
+	for name := range manager.tenants {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// removes a tenant, with its variables and the count of steps it's taken, closing its
+// databases. If it's in use, the Run using it finishes first, and the next one makes a new tenant.
+func (manager *Manager_t) Evict(name string) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	if tenant, ok := manager.tenants[name]; ok {
+		delete(manager.tenants, name)
+		tenant.evicted = true
+		if !tenant.lastUsed.IsZero() { // otherwise the Run using it closes it when it's done
+			tenant.interp.Close()
+		}
+	}
+}
+
+// removes the tenants that have been idle for longer than IdleTimeout, returning how many
+// there were. Runs do this every so often anyway.
+func (manager *Manager_t) EvictIdle() int {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	return manager.evictIdle(time.Now())
+}
+
+// does the work of EvictIdle, with the manager locked.
+func (manager *Manager_t) evictIdle(now time.Time) int {
+	manager.lastSweep = now
+	if manager.IdleTimeout <= 0 {
+		return 0
+	}
+	ret := 0
+	for name, tenant := range manager.tenants {
+		if !tenant.lastUsed.IsZero() && now.Sub(tenant.lastUsed) > manager.IdleTimeout {
+			delete(manager.tenants, name)
+			tenant.evicted = true
+			tenant.interp.Close()
+			ret++
+		}
+	}
+	return ret
+}
+
+// gets a tenant, making it if it's new, and locks it for the caller, with its quota.
+func (manager *Manager_t) acquire(name string) (*tenant_t, Quota_t) {
+	for {
+		manager.mu.Lock()
+		now := time.Now()
+		if manager.IdleTimeout > 0 && now.Sub(manager.lastSweep) > manager.IdleTimeout/2 {
+			manager.evictIdle(now)
+		}
+		tenant, ok := manager.tenants[name]
+		if !ok {
+			tenant = &tenant_t{interp: NewInterpreter(manager.opts), lastUsed: now}
+			manager.tenants[name] = tenant
+		}
+		quota, ok := manager.quotas[name]
+		if !ok {
+			quota = manager.quota
+		}
+		manager.mu.Unlock()
+
+		tenant.mu.Lock()
+		manager.mu.Lock()
+		evicted := tenant.evicted
+		tenant.lastUsed = time.Time{}
+		manager.mu.Unlock()
+		if !evicted { // otherwise it went while we waited for it, so start again with a new one
+			return tenant, quota
+		}
+		tenant.mu.Unlock()
+	}
+}
+
+// unlocks a tenant the caller is done with, closing its interpreter if it was evicted meanwhile.
+func (manager *Manager_t) release(tenant *tenant_t) {
+	manager.mu.Lock()
+	tenant.lastUsed = time.Now()
+	if tenant.evicted {
+		tenant.interp.Close()
+	}
+	manager.mu.Unlock()
+	tenant.mu.Unlock()
+}
+
+// gets roughly how many bytes the interpreter's variables and memory take up.
+func (interp *Interpreter_t) size() int {
+	ret := len(interp.memory)
+	for name, value := range interp.vars {
+		ret += len(name) + sizeOf(value)
+	}
+	return ret
+}
+
+// gets roughly how many bytes a value takes up.
+func sizeOf(res *Result_t) int {
+	ret := 16 // the type and the number
+	switch res.ResultType {
+	case STRING_RESULT:
+		ret += len(res.Sres)
+	case LIST_RESULT:
+		for _, elem := range res.Lres {
+			ret += 8 + sizeOf(elem)
+		}
+	case DICT_RESULT:
+		for key, value := range res.Dres.values {
+			ret += 2*len(key) + sizeOf(value) // keys are kept twice, in the map and in order
+		}
+	case MATRIX_RESULT:
+		ret += 8 * len(res.Mres.data)
+	case RECORD_RESULT:
+		for _, value := range res.Rres.values {
+			ret += 8 + sizeOf(value)
+		}
+	case FUNCTION_RESULT, SYMBOLIC_RESULT:
+		ret += 64
+	}
+	return ret
+}
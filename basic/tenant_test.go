@@ -0,0 +1,61 @@
+package basic
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTenantMemoryIsEnforcedDuringTheRun(t *testing.T) {
+	manager := NewManager(Options_t{}, Quota_t{MaxMemory: 1 << 20})
+	start := time.Now()
+	_, err := manager.Run(context.Background(), "a", `FOLD(LAMBDA(a, b, a + a), "x", RANGE(0, 40))`)
+	var runtimeErr *RuntimeError_t
+	if !errors.As(err, &runtimeErr) || runtimeErr.Code != ERR_MEMORY_LIMIT {
+		t.Fatalf("got %v, want an %s error", err, ERR_MEMORY_LIMIT)
+	} else if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %s to fail", elapsed)
+	}
+}
+
+func TestTenantRollsBackVariablesAndMemory(t *testing.T) {
+	manager := NewManager(Options_t{}, Quota_t{MaxMemory: 1 << 17})
+	ctx := context.Background()
+	if _, err := manager.Run(ctx, "a", "x, y = [1, 2]\nPOKE 10, 7"); err != nil {
+		t.Fatal(err)
+	}
+	// leaves a variable far bigger than the quota, so the Run is undone once it's finished
+	if _, err := manager.Run(ctx, "a", "x, y = [RANGE(0, 5000), 3]\nPOKE 10, 9"); err == nil {
+		t.Fatal("a Run over the quota didn't fail")
+	}
+	res, err := manager.Run(ctx, "a", "[LEN([x]), y, PEEK(10)]")
+	if err != nil {
+		t.Fatal(err)
+	} else if got := res.ValueString(); got != "[1, 2, 7]" {
+		t.Errorf("got %s after the Run was undone, want [1, 2, 7]", got)
+	}
+}
+
+func TestTenantRollsBackFailedRuns(t *testing.T) {
+	manager := NewManager(Options_t{}, Quota_t{MaxSteps: 50})
+	ctx := context.Background()
+	if _, err := manager.Run(ctx, "a", "x, y = [1, 2]"); err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []string{
+		"x, y = [10, 20]\n1 / 0",                           // a runtime error
+		"x, y = [10, 20]\nz, w = [SUM(RANGE(0, 1000)), 0]", // out of steps
+		"x, y = [10, 20]\nFOO(1)",                          // undefined
+	} {
+		if _, err := manager.Run(ctx, "a", src); err == nil {
+			t.Fatalf("%q didn't fail", src)
+		}
+		res, err := manager.Run(ctx, "a", "[x, y]")
+		if err != nil {
+			t.Fatal(err)
+		} else if got := res.ValueString(); got != "[1, 2]" {
+			t.Errorf("after %q failed: got %s, want [1, 2]", src, got)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// error a builtin gives when a call to it runs longer than its time limit.
+type timeoutError_t struct {
+	limit time.Duration
+}
+
+func (err *timeoutError_t) Error() string {
+	return fmt.Sprintf("took longer than its limit of %s", err.limit)
+}
+
+// adds a builtin like RegisterBuiltin, but with a limit on how long each call may take, for
+// builtins that wait on something slow, like a host callback over the network. A call that
+// runs longer fails with an ERR_BUILTIN_TIMEOUT RuntimeError, which the host can tell apart
+// from the builtin failing by its Code. Go can't stop fn, so it's left running in the
+// background and whatever it gives back in the end is thrown away.
+func RegisterBuiltinTimeout(name string, arity int, timeout time.Duration, fn BuiltinFunc_t) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+	builtins[strings.ToUpper(name)] = builtin_t{arity: arity, fn: fn, timeout: timeout}
+}
+
+// gives a registered builtin a limit on how long each call may take, like
+// RegisterBuiltinTimeout does, or takes it away with 0. It's an error if there's no such builtin.
+func SetBuiltinTimeout(name string, timeout time.Duration) error {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+	builtin, ok := builtins[strings.ToUpper(name)]
+	if !ok {
+		return fmt.Errorf("there's no builtin called %s", name)
+	}
+	builtin.timeout = timeout
+	builtins[strings.ToUpper(name)] = builtin
+	return nil
+}
+
+// error a timed builtin gives when the Run it was called in has to stop while it's waiting,
+// because the Run's context is done or it's past its Timeout. builtinError turns it into the
+// ERR_STEP_LIMIT RuntimeError step would have stopped the Run with.
+type stoppedError_t struct {
+	details string
+}
+
+func (err *stoppedError_t) Error() string {
+	return err.details
+}
+
+// wraps a builtin so each call fails once it's taken longer than timeout, or as soon as the
+// Run it's called in is cancelled or runs out of time. A timeout of 0 leaves it as it is.
+func (interp *Interpreter_t) timed(timeout time.Duration, fn BuiltinFunc_t) BuiltinFunc_t {
+	if timeout <= 0 {
+		return fn
+	}
+	return func(args []*Result_t) (*Result_t, error) {
+		type outcome_t struct {
+			res *Result_t
+			err error
+		}
+		done := make(chan outcome_t, 1) // buffered, so a call that's given up on can still finish
+		go func() {
+			res, err := fn(args)
+			done <- outcome_t{res, err}
+		}()
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		ctx := interp.ctx // the Run calling it, which needn't be the one that made a function value of it
+		var cancelled <-chan struct{}
+		if ctx != nil {
+			cancelled = ctx.Done()
+		}
+		var overtime <-chan time.Time
+		if !interp.deadline.IsZero() {
+			deadline := time.NewTimer(time.Until(interp.deadline))
+			defer deadline.Stop()
+			overtime = deadline.C
+		}
+		select {
+		case outcome := <-done:
+			return outcome.res, outcome.err
+		case <-timer.C:
+			return nil, &timeoutError_t{limit: timeout}
+		case <-cancelled:
+			return nil, &stoppedError_t{details: cancelledDetails(ctx.Err())}
+		case <-overtime:
+			return nil, &stoppedError_t{details: fmt.Sprintf("program ran longer than the limit of %s", interp.opts.Timeout)}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package basic
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	// sleeps for its argument's milliseconds, but may only take 50
+	RegisterBuiltinTimeout("TESTSLOW", 1, 50*time.Millisecond, func(args []*Result_t) (*Result_t, error) {
+		time.Sleep(time.Duration(args[0].Ires) * time.Millisecond)
+		return NewInt(1), nil
+	})
+}
+
+func TestBuiltinTimeout(t *testing.T) {
+	if got := runErrorCode(t, Options_t{}, `TESTSLOW(1)`); got != "" {
+		t.Errorf("a quick call failed with %q", got)
+	}
+	if got := runErrorCode(t, Options_t{}, `TESTSLOW(500)`); got != ERR_BUILTIN_TIMEOUT {
+		t.Errorf("a slow call: got error code %q, want %q", got, ERR_BUILTIN_TIMEOUT)
+	}
+	if got := runErrorCode(t, Options_t{}, `f, g = [TESTSLOW, 0]`+"\n"+`f(500)`); got != ERR_BUILTIN_TIMEOUT {
+		t.Errorf("a slow call of a function value: got error code %q, want %q", got, ERR_BUILTIN_TIMEOUT)
+	}
+}
+
+func TestSetBuiltinTimeout(t *testing.T) {
+	RegisterBuiltin("TESTSLOWER", 1, func(args []*Result_t) (*Result_t, error) {
+		time.Sleep(time.Duration(args[0].Ires) * time.Millisecond)
+		return NewInt(1), nil
+	})
+	if err := SetBuiltinTimeout("testslower", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if got := runErrorCode(t, Options_t{}, `TESTSLOWER(200)`); got != ERR_BUILTIN_TIMEOUT {
+		t.Errorf("got error code %q, want %q", got, ERR_BUILTIN_TIMEOUT)
+	}
+	if err := SetBuiltinTimeout("NOSUCHBUILTIN", time.Second); err == nil {
+		t.Error("setting the timeout of a builtin that doesn't exist didn't fail")
+	}
+}
+
+func TestTimedBuiltinStopsWithTheRun(t *testing.T) {
+	interp := NewInterpreter(Options_t{Locale: "de"})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := interp.RunContext(ctx, `TESTSLOW(40)`, t.Name())
+	runtimeErr, ok := err.(*RuntimeError_t)
+	if !ok || runtimeErr.Code != ERR_STEP_LIMIT {
+		t.Fatalf("got %v, want an %s error", err, ERR_STEP_LIMIT)
+	} else if !strings.Contains(err.Error(), "Frist") {
+		t.Errorf("got %q, want it in German", err)
+	}
+
+	if got := runErrorCode(t, Options_t{Timeout: 10 * time.Millisecond}, `TESTSLOW(40)`); got != ERR_STEP_LIMIT {
+		t.Errorf("running past Timeout: got error code %q, want %q", got, ERR_STEP_LIMIT)
+	}
+}
+
+func TestTimedFunctionValueOutlivesItsRun(t *testing.T) {
+	interp := NewInterpreter(Options_t{})
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := interp.RunContext(ctx, `f, g = [TESTSLOW, 1]`, t.Name()); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := interp.Run(`f(5)`, t.Name()); err != nil {
+		t.Errorf("calling it in a later Run: %s", err)
+	}
+}
@@ -0,0 +1,105 @@
+package basic
+
+import (
+	"fmt"
+	"time"
+)
+
+// the subroutine ON TIMER set up, and when it's next due.
+type timer_t struct {
+	interval time.Duration
+	next     time.Time
+	handler  *Function_t
+	tok      Token_t // the ON TIMER statement, for errors from the handler
+}
+
+// returns true if the tokens at the start of a statement make an ON TIMER statement.
+func isOnTimer(tokens []Token_t, i int) bool {
+	return isKeyword(tokens[i], "ON") && i+1 < len(tokens) && isKeyword(tokens[i+1], "TIMER")
+}
+
+// returns true if tokens[i] is the GOSUB of an ON TIMER statement, right after the ')' closing its interval.
+func isGosub(tokens []Token_t, i int) bool {
+	if i < 1 || tokens[i-1].tokenType != RPAREN {
+		return false
+	}
+	start := i
+	for start > 0 && !isSeparator(tokens[start-1]) {
+		start--
+	}
+	return isOnTimer(tokens, start)
+}
+
+// builds and returns an On Timer node: ON TIMER(seconds) GOSUB handler.
+func (parser *parser_t) onTimer() (*Node_t, error) {
+	ret := &Node_t{nodeType: ON_TIMER, tok: parser.currentToken}
+	parser.advance()
+	parser.advance()
+	if parser.currentToken.tokenType != LPAREN {
+		return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected '(' after ON TIMER", Pos: parser.currentToken.pos}
+	}
+	parser.advance()
+	seconds, err := parser.comparison()
+	if err != nil {
+		return nil, err
+	}
+	if parser.currentToken.tokenType != RPAREN {
+		return nil, &ParseError_t{Code: ERR_EXPECTED_RPAREN, Details: "expected ')' after ON TIMER's interval", Pos: parser.currentToken.pos}
+	}
+	parser.advance()
+	if !isKeyword(parser.currentToken, "GOSUB") {
+		return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: "expected GOSUB after ON TIMER(...)", Pos: parser.currentToken.pos}
+	}
+	parser.advance()
+	handler, err := parser.comparison()
+	if err != nil {
+		return nil, err
+	}
+	ret.args = []*Node_t{seconds, handler}
+	return ret, nil
+}
+
+// evaluates an ON TIMER node, replacing any timer set before. The handler has to be a function
+// taking no arguments, like a variable bound to LAMBDA(...). Its value is 0.
+func (node *Node_t) evaluateOnTimer(interp *Interpreter_t) (*Result_t, error) {
+	if err := checkCapability(interp.Permissions().Clock, "ON TIMER", "clock", node.tok); err != nil {
+		return nil, err
+	}
+	args, err := interp.evaluateArgs(node)
+	if err != nil {
+		return nil, err
+	}
+	if !args[0].IsNumber() {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("ON TIMER: interval is a %s, not a number", args[0].ResultType), Pos: node.tok.pos}
+	} else if args[0].Fres <= 0 { // the float value is set for integers too
+		return nil, &RuntimeError_t{Code: ERR_BUILTIN, Details: fmt.Sprintf("ON TIMER: interval %g isn't positive", args[0].Fres), Pos: node.tok.pos}
+	} else if args[1].ResultType != FUNCTION_RESULT {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("ON TIMER: handler is a %s, not a function", args[1].ResultType), Pos: node.tok.pos}
+	} else if args[1].Fnres.Arity > 0 {
+		return nil, &RuntimeError_t{Code: ERR_ARG_COUNT, Details: fmt.Sprintf("ON TIMER: handler takes %d argument(s), it has to take none", args[1].Fnres.Arity), Pos: node.tok.pos}
+	}
+	interval := time.Duration(args[0].Fres * float64(time.Second))
+	interp.timer = &timer_t{interval: interval, next: time.Now().Add(interval), handler: args[1].Fnres, tok: node.tok}
+	return NewInt(0), nil
+}
+
+// runs the timer's handler if it's due. It's called between statements, so the handler never
+// interrupts one half way through, and it's evaluated on the Run's own goroutine, where the Run's
+// step and time limits cover it like any other code. A handler that's fallen behind runs once,
+// not once for every interval it missed.
+func (interp *Interpreter_t) checkTimer() error {
+	timer := interp.timer
+	if timer == nil {
+		return nil
+	}
+	now := time.Now()
+	if now.Before(timer.next) {
+		return nil
+	}
+	timer.next = timer.next.Add(timer.interval)
+	if timer.next.Before(now) {
+		timer.next = now.Add(timer.interval)
+	}
+	_, err := callValue(timer.tok, timer.handler, nil)
+	return err
+}
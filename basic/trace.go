@@ -0,0 +1,174 @@
+package basic
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// hook for watching evaluation node by node, for debuggers, visualizers and explainers.
+// Set it in Options_t. Enter is called before a node's children are evaluated and Exit
+// after the node is done, so the calls nest like the tree: a binary node's Exit comes
+// after both of its operands' Exits.
+type Tracer_t interface {
+	Enter(node *Node_t)
+	// res is nil if the node failed with err.
+	Exit(node *Node_t, res *Result_t, err error)
+}
+
+// one evaluated node in a recorded trace. Nodes are listed in the order they were
+// entered, so a parent always comes before its children. Offsets are in bytes from the
+// start of the source, and times are nanoseconds from the start of the evaluation.
+type TraceNode_t struct {
+	ID       int           `json:"id"`
+	Parent   int           `json:"parent"` // -1 for the root
+	Kind     string        `json:"kind"`
+	Token    string        `json:"token"` // the literal, name or operator the node was built from
+	Start    int           `json:"start"`
+	End      int           `json:"end"`
+	Line     int           `json:"line"`
+	Col      int           `json:"col"`
+	Operands []interface{} `json:"operands"` // values of the node's operands or arguments, null where they failed
+	Result   interface{}   `json:"result"`   // null if the node failed
+	Error    *Diagnostic_t `json:"error,omitempty"`
+	EnterNs  int64         `json:"enter_ns"`
+	ExitNs   int64         `json:"exit_ns"`
+}
+
+// evaluates src and records every node evaluated along the way. If the evaluation
+// fails, the trace still holds the nodes evaluated up to the error.
+func Trace(src string, fn string) ([]TraceNode_t, error) {
+	return NewInterpreter(Options_t{}).Trace(src, fn)
+}
+
+// like Trace, using this interpreter's options and variables. A Tracer set in the
+// options still sees every node.
+func (interp *Interpreter_t) Trace(src string, fn string) ([]TraceNode_t, error) {
+	var trace []TraceNode_t
+	recorder := newTraceRecorder(func(nodes []TraceNode_t) { trace = nodes })
+	recorder.next = interp.opts.Tracer
+	interp.opts.Tracer = recorder
+	defer func() { interp.opts.Tracer = recorder.next }()
+
+	_, err := interp.Run(src, fn)
+	return trace, err
+}
+
+// gets a Tracer that writes the trace of every evaluation to w as one line of JSON,
+// {"nodes": [...]}, for visualizers to read. Write errors are ignored.
+func NewJSONTracer(w io.Writer) Tracer_t {
+	encoder := json.NewEncoder(w)
+	return newTraceRecorder(func(nodes []TraceNode_t) {
+		encoder.Encode(struct {
+			Nodes []TraceNode_t `json:"nodes"`
+		}{nodes})
+	})
+}
+
+// Tracer that builds TraceNode_ts, handing them to done each time a root node finishes.
+type traceRecorder_t struct {
+	next  Tracer_t // the tracer that was set before, if any
+	done  func([]TraceNode_t)
+	start time.Time
+	nodes []TraceNode_t
+	ids   map[*Node_t]int
+	stack []int // ids of the nodes entered but not exited yet
+}
+
+func newTraceRecorder(done func([]TraceNode_t)) *traceRecorder_t {
+	return &traceRecorder_t{done: done}
+}
+
+func (recorder *traceRecorder_t) Enter(node *Node_t) {
+	if recorder.next != nil {
+		recorder.next.Enter(node)
+	}
+	parent := -1
+	if len(recorder.stack) == 0 {
+		recorder.start = time.Now()
+		recorder.nodes = nil
+		recorder.ids = make(map[*Node_t]int)
+	} else {
+		parent = recorder.stack[len(recorder.stack)-1]
+	}
+
+	start, end := span(node)
+	id := len(recorder.nodes)
+	recorder.nodes = append(recorder.nodes, TraceNode_t{
+		ID:       id,
+		Parent:   parent,
+		Kind:     node.nodeType.String(),
+		Token:    traceToken(node),
+		Start:    start.index,
+		End:      end,
+		Line:     start.line,
+		Col:      start.col,
+		Operands: []interface{}{},
+		EnterNs:  time.Since(recorder.start).Nanoseconds(),
+	})
+	recorder.ids[node] = id
+	recorder.stack = append(recorder.stack, id)
+}
+
+func (recorder *traceRecorder_t) Exit(node *Node_t, res *Result_t, err error) {
+	if recorder.next != nil {
+		recorder.next.Exit(node, res, err)
+	}
+	traced := &recorder.nodes[recorder.ids[node]]
+	traced.ExitNs = time.Since(recorder.start).Nanoseconds()
+	if err != nil {
+		diag := Diagnostics(err)[0]
+		traced.Error = &diag
+	} else {
+		traced.Result = res.Interface()
+	}
+	if node.nodeType != STATEMENTS {
+		for _, operand := range append([]*Node_t{node.left, node.right}, node.args...) {
+			if id, ok := recorder.ids[operand]; ok {
+				traced.Operands = append(traced.Operands, recorder.nodes[id].Result)
+			}
+		}
+	}
+
+	recorder.stack = recorder.stack[:len(recorder.stack)-1]
+	if len(recorder.stack) == 0 {
+		recorder.done(recorder.nodes)
+	}
+}
+
+// gets the text a node was built from, for labelling it.
+func traceToken(node *Node_t) string {
+	switch node.nodeType {
+	case FACTOR:
+		return explainValue(node.Value())
+	case VAR_ACCESS, CALL:
+		return node.tok.strVal
+	case PARAM_ACCESS:
+		return "?" + node.tok.strVal
+	case STATEMENTS:
+		return ""
+	default:
+		return tokenSymbol(node.tok.tokenType)
+	}
+}
+
+// gets where the source of a node starts, and the offset just past where it ends.
+// Brackets around the node aren't counted, since the tree doesn't keep them.
+func span(node *Node_t) (Position_t, int) {
+	var start Position_t
+	end, first := 0, true
+	Walk(node, func(n *Node_t) bool {
+		if n.nodeType == STATEMENTS {
+			return true
+		}
+		if first || n.tok.pos.index < start.index {
+			start = n.tok.pos
+		}
+		if first || n.tok.end > end {
+			end = n.tok.end
+		}
+		first = false
+		return true
+	})
+	return start, end
+}
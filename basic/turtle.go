@@ -0,0 +1,61 @@
+package basic
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// where the turtle is and which way it faces. It starts in the middle of the canvas, facing up,
+// with its pen down.
+type turtle_t struct {
+	x, y    float64 // kept unrounded, so lots of short moves don't drift
+	heading float64 // degrees clockwise from straight up
+	penUp   bool
+}
+
+// moves the turtle forward (or back, if distance is negative), drawing a line if its pen is down.
+func (interp *Interpreter_t) forward(distance float64) error {
+	turtle := &interp.turtle
+	radians := turtle.heading * math.Pi / 180
+	x := turtle.x + distance*math.Sin(radians)
+	y := turtle.y - distance*math.Cos(radians) // y grows downwards on the canvas
+	if math.Abs(x) > MAX_COORDINATE || math.Abs(y) > MAX_COORDINATE {
+		return fmt.Errorf("the turtle would end up further from 0 than %d", MAX_COORDINATE)
+	}
+	if !turtle.penUp {
+		interp.line(int(math.Round(turtle.x)), int(math.Round(turtle.y)), int(math.Round(x)), int(math.Round(y)))
+	}
+	turtle.x, turtle.y = x, y
+	return nil
+}
+
+// gets what the program has drawn as an SVG document, or "" if it hasn't drawn anything.
+// Shapes are written in the order they were drawn, so it looks the same as the Canvas.
+func (interp *Interpreter_t) SVG() string {
+	if interp.canvas == nil {
+		return ""
+	}
+	size := interp.canvas.Bounds().Size()
+	var ret strings.Builder
+	fmt.Fprintf(&ret, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", size.X, size.Y, size.X, size.Y)
+	fmt.Fprintf(&ret, "<rect width=\"%d\" height=\"%d\" fill=\"#000000\"/>\n", size.X, size.Y)
+	for _, shape := range interp.shapes {
+		rgb := fmt.Sprintf("#%02x%02x%02x", shape.color.R, shape.color.G, shape.color.B)
+		switch shape.kind {
+		case "rect":
+			fmt.Fprintf(&ret, "<rect x=\"%d\" y=\"%d\" width=\"1\" height=\"1\" fill=\"%s\"/>\n", shape.coords[0], shape.coords[1], rgb)
+		case "line": // through the middle of the end pixels
+			fmt.Fprintf(&ret, "<line x1=\"%g\" y1=\"%g\" x2=\"%g\" y2=\"%g\" stroke=\"%s\" stroke-linecap=\"square\"/>\n", middle(shape.coords[0]), middle(shape.coords[1]), middle(shape.coords[2]), middle(shape.coords[3]), rgb)
+		case "circle":
+			fmt.Fprintf(&ret, "<circle cx=\"%g\" cy=\"%g\" r=\"%d\" stroke=\"%s\" fill=\"none\"/>\n", middle(shape.coords[0]), middle(shape.coords[1]), shape.coords[2], rgb)
+		}
+	}
+	ret.WriteString("</svg>\n")
+	return ret.String()
+}
+
+// gets the SVG coordinate of the middle of a pixel.
+func middle(coord int) float64 {
+	return float64(coord) + 0.5
+}
@@ -0,0 +1,396 @@
+package basic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// what the builtins give back, for the type checker, keyed by upper case name. Builtins that
+// aren't here give back something it can't tell before they run, like ABS, which gives back
+// whatever kind of number it's given.
+var builtinReturns = map[string]string{
+	"INT": "int", "LEN": "int", "PARSEINT": "int", "SETBIT": "int", "CLEARBIT": "int", "TESTBIT": "int", "POPCOUNT": "int", "ROTL": "int", "ROTR": "int", "DBOPEN": "int", "DBCLOSE": "int",
+	"FLOAT": "float", "SQR": "float", "LOG": "float", "EXP": "float", "SIN": "float", "COS": "float", "TAN": "float", "ATN": "float", "RND": "float", "DEG": "float", "RAD": "float", "NORM": "float", "DET": "float",
+	"HEX$": "string", "OCT$": "string", "BIN$": "string", "FORMAT$": "string", "TYPE": "string", "HELP": "string", "ENVIRON$": "string", "HTTPGET$": "string", "HTTPPOST$": "string",
+	"MAP": "list", "FILTER": "list", "SORT": "list", "SORTBY": "list", "MINMAX": "list", "RANGE": "list", "CROSS": "list", "FUNCS": "list", "DBQUERY": "list",
+	"VARS": "dict", "DESCRIBE": "dict",
+	"MATRIX": "matrix", "TRANSPOSE": "matrix", "INVERSE": "matrix",
+	"LAMBDA": "function",
+}
+
+// what the type checker knows about a function it can see being made: the types of its
+// parameters and of what it gives back, and for the constructor of a TYPE, of its fields.
+type signature_t struct {
+	params  []Param_t
+	returns string            // "" if it isn't annotated
+	fields  map[string]string // the types of the fields of the records a constructor makes, nil for other functions
+}
+
+// the type checker, as it walks a program with the types it knows the variables have.
+type typeChecker_t struct {
+	declared    map[string]bool         // the names of the TYPEs and ENUMs the program declares, which annotations can name
+	records     map[string]*signature_t // the constructors of the TYPEs the program declares, by name
+	diagnostics []Diagnostic_t
+}
+
+// the types the checker knows variables have, "" for a variable it can't tell the type of, and
+// the functions it knows they hold.
+type typeScope_t struct {
+	types map[string]string
+	funcs map[string]*signature_t
+}
+
+// checks the annotations in a program against the types of what they annotate, as far as they
+// can be worked out without running it: literals, annotated parameters and fields, what
+// annotated functions and the builtins give back, and arithmetic on those. Anything it can't
+// tell the type of passes, so only annotated code is checked. Findings are errors: T001 for a
+// value of the wrong type and T002 for an annotation naming a type there isn't.
+func CheckTypes(node *Node_t) []Diagnostic_t {
+	checker := &typeChecker_t{declared: map[string]bool{}, records: map[string]*signature_t{}, diagnostics: make([]Diagnostic_t, 0)}
+	Walk(node, func(n *Node_t) bool {
+		if n.nodeType == TYPE_DEF || n.nodeType == ENUM_DEF {
+			checker.declared[n.tok.strVal] = true
+		}
+		return true
+	})
+	checker.infer(node, &typeScope_t{types: map[string]string{}, funcs: map[string]*signature_t{}})
+	sort.SliceStable(checker.diagnostics, func(i, j int) bool {
+		if checker.diagnostics[i].Line != checker.diagnostics[j].Line {
+			return checker.diagnostics[i].Line < checker.diagnostics[j].Line
+		}
+		return checker.diagnostics[i].Col < checker.diagnostics[j].Col
+	})
+	return checker.diagnostics
+}
+
+// reports a finding about a node.
+func (checker *typeChecker_t) report(code string, node *Node_t, format string, args ...interface{}) {
+	finding := newFinding(code, node, format, args...)
+	finding.Severity = "error"
+	checker.diagnostics = append(checker.diagnostics, finding)
+}
+
+// gets the type an annotation names, reporting it if there's no such type.
+func (checker *typeChecker_t) annotation(node *Node_t) string {
+	typ := annotationType(node.ops[0].strVal)
+	if _, ok := annotationTypes[strings.ToUpper(node.ops[0].strVal)]; !ok && !checker.declared[typ] {
+		checker.report("T002", node, "there's no type called %s", node.ops[0].strVal)
+		return ""
+	}
+	return typ
+}
+
+// returns true if a value of type typ can be given where want is: if either is unknown, the
+// types match, or it's a number where a float is wanted. "number" might be an int or a float.
+func assignable(typ string, want string) bool {
+	switch {
+	case typ == "" || want == "" || want == "any" || typ == want:
+		return true
+	case want == "number" || want == "float":
+		return typ == "int" || typ == "float" || typ == "number"
+	case want == "int":
+		return typ == "number"
+	}
+	return false
+}
+
+// returns true if the program declares an ENUM called name.
+func (checker *typeChecker_t) isEnum(name string) bool {
+	return checker.declared[name] && checker.records[name] == nil
+}
+
+// checks that a value of type typ can be given where want is, reporting it if it can't. A
+// member of an ENUM is an int too.
+func (checker *typeChecker_t) expect(node *Node_t, typ string, want string, what string) {
+	if checker.isEnum(typ) && isNumberType(want) {
+		return
+	} else if !assignable(typ, want) {
+		checker.report("T001", node, "%s is %s, not %s", what, withArticle(typ), withArticle(want))
+	}
+}
+
+// puts "a" or "an" before the name of a type, like "an int" or "a float".
+func withArticle(typ string) string {
+	if typ != "" && strings.ContainsRune("aeiouAEIOU", rune(typ[0])) {
+		return "an " + typ
+	}
+	return "a " + typ
+}
+
+// makes a copy of a scope for a LAMBDA or loop body, without what the checker knew about names
+// that body binds.
+func (scope *typeScope_t) within(names ...string) *typeScope_t {
+	ret := &typeScope_t{types: make(map[string]string, len(scope.types)), funcs: make(map[string]*signature_t, len(scope.funcs))}
+	for name, typ := range scope.types {
+		ret.types[name] = typ
+	}
+	for name, sig := range scope.funcs {
+		ret.funcs[name] = sig
+	}
+	for _, name := range names {
+		ret.bind(name, "", nil)
+	}
+	return ret
+}
+
+// records the type of a variable, and the function it holds if the checker knows it.
+func (scope *typeScope_t) bind(name string, typ string, sig *signature_t) {
+	scope.types[name] = typ
+	delete(scope.funcs, name)
+	if sig != nil {
+		scope.funcs[name] = sig
+	}
+}
+
+// gets the type of a node's value, as far as the checker can tell ("" if it can't), checking
+// the annotations in it on the way.
+func (checker *typeChecker_t) infer(node *Node_t, scope *typeScope_t) string {
+	typ, _ := checker.inferFunc(node, scope)
+	return typ
+}
+
+// gets the type of a node's value like infer, and the signature of the function it is if the
+// checker can tell.
+func (checker *typeChecker_t) inferFunc(node *Node_t, scope *typeScope_t) (string, *signature_t) {
+	if node == nil {
+		return "", nil
+	}
+	switch node.nodeType {
+	case FACTOR:
+		return map[TokenType_t]string{INT: "int", FLOAT: "float", STRING: "string"}[node.tok.tokenType], nil
+	case VAR_ACCESS:
+		return scope.types[node.tok.strVal], scope.funcs[node.tok.strVal]
+	case ANNOTATION:
+		typ := checker.annotation(node)
+		if isLambda(node.left) {
+			sig := checker.lambda(node.left, scope, typ)
+			return "function", sig
+		}
+		checker.expect(node, checker.infer(node.left, scope), typ, formatExpr(node.left))
+		return typ, nil
+	case CALL:
+		return checker.call(node, scope)
+	case EXPRESSION, TERM:
+		return arithmeticType(checker.infer(node.left, scope), checker.infer(node.right, scope), node.tok.tokenType), nil
+	case POWER:
+		if left, right := checker.infer(node.left, scope), checker.infer(node.right, scope); isNumberType(left) && isNumberType(right) {
+			return "number", nil
+		}
+		return "", nil
+	case UNARY_OP:
+		if typ := checker.infer(node.left, scope); isNumberType(typ) {
+			return typ, nil
+		}
+		return "", nil
+	case COMPARISON, COMPARISON_CHAIN, IN_OP, BETWEEN_OP:
+		checker.walk(node, scope)
+		return "int", nil
+	case LIST:
+		checker.walk(node, scope)
+		return "list", nil
+	case DICT:
+		checker.walk(node, scope)
+		return "dict", nil
+	case FIELD_ACCESS:
+		typ := checker.infer(node.left, scope)
+		if node.left.nodeType == VAR_ACCESS && checker.isEnum(node.left.tok.strVal) {
+			return node.left.tok.strVal, nil // a member of an ENUM
+		} else if record, ok := checker.records[typ]; ok {
+			return record.fields[node.ops[0].strVal], nil
+		}
+		return "", nil
+	case TYPE_DEF:
+		checker.typeDef(node, scope)
+		return "function", checker.records[node.tok.strVal]
+	case ENUM_DEF:
+		checker.walk(node, scope)
+		scope.bind(node.tok.strVal, "dict", nil)
+		return "dict", nil
+	case UNPACK:
+		checker.unpack(node, scope)
+		return "", nil
+	case FOR_EACH:
+		checker.infer(node.left, scope)
+		inner := scope.within(node.tok.strVal)
+		for _, stmt := range node.statements {
+			checker.infer(stmt, inner)
+		}
+		return "", nil
+	case MATCH_EXPR:
+		checker.infer(node.left, scope)
+		for i := 0; i < len(node.args); i += 3 {
+			names := patternNames(node.args[i])
+			inner := scope.within()
+			for _, name := range names {
+				inner.bind(name.strVal, "", nil)
+			}
+			checker.infer(node.args[i+1], inner)
+			checker.infer(node.args[i+2], inner)
+		}
+		return "", nil
+	case STATEMENTS:
+		typ := ""
+		for _, stmt := range node.statements {
+			typ = checker.infer(stmt, scope)
+		}
+		return typ, nil
+	case IMPORT:
+		scope.bind(importName(node), "dict", nil)
+		return "", nil
+	}
+	checker.walk(node, scope)
+	return "", nil
+}
+
+// checks the children of a node whose own type doesn't depend on theirs.
+func (checker *typeChecker_t) walk(node *Node_t, scope *typeScope_t) {
+	checker.infer(node.left, scope)
+	checker.infer(node.right, scope)
+	for _, stmt := range node.statements {
+		checker.infer(stmt, scope)
+	}
+	for _, arg := range node.args {
+		checker.infer(arg, scope)
+	}
+}
+
+// returns true if a type is a number of some kind.
+func isNumberType(typ string) bool {
+	return typ == "int" || typ == "float" || typ == "number"
+}
+
+// gets the type of left op right for +, -, * and /, "" if the checker can't tell. Ints give an
+// int, as / truncates, and a float with either gives a float.
+func arithmeticType(left string, right string, op TokenType_t) string {
+	switch {
+	case left == "int" && right == "int":
+		return "int"
+	case (left == "float" && isNumberType(right)) || (right == "float" && isNumberType(left)):
+		return "float"
+	case isNumberType(left) && isNumberType(right):
+		return "number"
+	case op == ADD && left == right && (left == "string" || left == "list"):
+		return left
+	}
+	return ""
+}
+
+// gets the type of a call: what the function gives back, if the checker knows the function,
+// checking the arguments against its parameters.
+func (checker *typeChecker_t) call(node *Node_t, scope *typeScope_t) (string, *signature_t) {
+	if isLambda(node) {
+		return "function", checker.lambda(node, scope, "")
+	}
+	sig := scope.funcs[node.tok.strVal]
+	if node.left != nil {
+		checker.infer(node.left, scope)
+		sig = nil
+	} else if _, ok := scope.types[node.tok.strVal]; !ok && sig == nil { // not a variable, so a builtin
+		checker.walk(node, scope)
+		typ := builtinReturns[strings.ToUpper(node.tok.strVal)]
+		if strings.EqualFold(node.tok.strVal, "IIF") && len(node.args) == 3 {
+			if then, otherwise := checker.infer(node.args[1], scope), checker.infer(node.args[2], scope); then == otherwise {
+				typ = then
+			}
+		}
+		return typ, nil
+	}
+	if sig == nil {
+		checker.walk(node, scope)
+		return "", nil
+	}
+	for i, arg := range node.args {
+		param := -1
+		if named := namedParam(arg, sig.params); named >= 0 {
+			param, arg = named, arg.right
+		} else if i < len(sig.params) && !sig.params[i].Rest {
+			param = i
+		}
+		typ := checker.infer(arg, scope)
+		if param >= 0 {
+			checker.expect(arg, typ, sig.params[param].Type, fmt.Sprintf("argument %d (%s) of %s", param+1, sig.params[param].Name, node.tok.strVal))
+		}
+	}
+	return sig.returns, nil
+}
+
+// checks a LAMBDA: the defaults of its parameters, and its body with the types its parameters
+// are annotated with, against returns. Gives back its signature.
+func (checker *typeChecker_t) lambda(node *Node_t, scope *typeScope_t, returns string) *signature_t {
+	args := lambdaArgs(node)
+	if len(args) == 0 {
+		return nil
+	}
+	sig := &signature_t{returns: returns}
+	inner := scope.within()
+	for _, arg := range args[:len(args)-1] {
+		param := checker.param(arg, scope)
+		sig.params = append(sig.params, param)
+		if param.Rest {
+			inner.bind(param.Name, "list", nil)
+		} else {
+			inner.bind(param.Name, param.Type, nil)
+		}
+	}
+	body := args[len(args)-1]
+	checker.expect(body, checker.infer(body, inner), returns, "what the LAMBDA gives back")
+	return sig
+}
+
+// gets a parameter of a LAMBDA or field of a TYPE as the checker knows it, checking its
+// default against its annotation.
+func (checker *typeChecker_t) param(arg *Node_t, scope *typeScope_t) Param_t {
+	if arg.nodeType == REST_PARAM {
+		return Param_t{Name: arg.left.tok.strVal, Rest: true}
+	}
+	name, def := arg, (*Node_t)(nil)
+	if arg.nodeType == COMPARISON {
+		name, def = arg.left, arg.right
+	}
+	typ := ""
+	if name.nodeType == ANNOTATION {
+		typ = checker.annotation(name)
+		name = name.left
+	}
+	if def != nil {
+		checker.expect(def, checker.infer(def, scope), typ, "the default of "+name.tok.strVal)
+	}
+	return Param_t{Name: name.tok.strVal, Type: typ}
+}
+
+// checks a TYPE and records its constructor, which makes records of it from its fields.
+func (checker *typeChecker_t) typeDef(node *Node_t, scope *typeScope_t) {
+	sig := &signature_t{returns: node.tok.strVal, fields: map[string]string{}}
+	for _, member := range node.args {
+		if member.nodeType == COMPARISON && member.left.nodeType == FACTOR { // an operator
+			checker.infer(member.right, scope)
+			continue
+		}
+		field := checker.param(member, scope)
+		sig.params = append(sig.params, field)
+		sig.fields[field.Name] = field.Type
+	}
+	checker.records[node.tok.strVal] = sig
+	scope.bind(node.tok.strVal, "function", sig)
+}
+
+// binds the names of an unpacking, to the types of the elements of a list written out with
+// as many of them, or to types the checker can't tell.
+func (checker *typeChecker_t) unpack(node *Node_t, scope *typeScope_t) {
+	if node.left.nodeType != LIST || len(node.left.args) != len(node.ops) {
+		checker.infer(node.left, scope)
+		for _, name := range node.ops {
+			scope.bind(name.strVal, "", nil)
+		}
+		return
+	}
+	types := make([]string, len(node.ops))
+	sigs := make([]*signature_t, len(node.ops))
+	for i, elem := range node.left.args {
+		types[i], sigs[i] = checker.inferFunc(elem, scope)
+	}
+	for i, name := range node.ops {
+		scope.bind(name.strVal, types[i], sigs[i])
+	}
+}
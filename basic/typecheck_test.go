@@ -0,0 +1,20 @@
+package basic
+
+import "testing"
+
+func TestCheckTypesMessages(t *testing.T) {
+	for src, want := range map[string]string{
+		`1.5 AS INT`:    "1.5 is a float, not an int",
+		`"a" AS FLOAT`:  `"a" is a string, not a float`,
+		`[1] AS STRING`: "[1] is a list, not a string",
+	} {
+		node, err := Parse(src, t.Name())
+		if err != nil {
+			t.Fatalf("%q: %s", src, err)
+		}
+		diagnostics := CheckTypes(node)
+		if len(diagnostics) != 1 || diagnostics[0].Message != want {
+			t.Errorf("%q: got %v, want the one finding %q", src, diagnostics, want)
+		}
+	}
+}
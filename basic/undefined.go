@@ -0,0 +1,253 @@
+package basic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// most names an undefined-variable error suggests instead.
+const MAX_SUGGESTIONS = 3
+
+// records where a variable was bound, unless it's bound already: a variable that's still
+// set keeps the position it was first given.
+func (interp *Interpreter_t) define(name string, pos Position_t) {
+	if _, ok := interp.definedAt[name]; !ok {
+		interp.definedAt[name] = pos
+	}
+}
+
+// removes a variable when the FOR EACH loop, LAMBDA or PLOT that bound it is finished with it.
+func (interp *Interpreter_t) undefine(name string) {
+	delete(interp.vars, name)
+	delete(interp.definedAt, name)
+}
+
+// gets the variables that are set, with where each was bound. Host variables and the ones set
+// with SetVar or by the prelude have the zero Position_t, as they weren't bound anywhere in the program.
+func (interp *Interpreter_t) scope() map[string]Position_t {
+	ret := make(map[string]Position_t, len(interp.vars)+len(interp.host))
+	for name := range interp.vars {
+		ret[name] = interp.definedAt[name]
+	}
+	for name := range interp.host {
+		ret[name] = Position_t{}
+	}
+	return ret
+}
+
+// makes the error for reading a variable that isn't set, suggesting the names in scope
+// that look most like it. The hint is put together in the locale's language, as the list
+// of suggestions is too loose for Localize to pick apart afterwards.
+func undefinedVar(tok Token_t, scope map[string]Position_t, locale string) error {
+	details := fmt.Sprintf("variable %s is not defined", tok.strVal)
+	if suggestions := suggest(tok.strVal, scope, locale); len(suggestions) > 0 {
+		details = fmt.Sprintf(message(locale, "variable %s is not defined; did you mean %s?"), tok.strVal, strings.Join(suggestions, ", "))
+	}
+	return &RuntimeError_t{Code: ERR_UNDEFINED_VAR, Details: details, Pos: tok.pos}
+}
+
+// gets the names in scope close enough to name to be a typo of it, closest first, each with where it was defined if that's known.
+func suggest(name string, scope map[string]Position_t, locale string) []string {
+	type candidate_t struct {
+		name     string
+		distance int
+	}
+	limit := len(name) / 3
+	if limit < 1 {
+		limit = 1
+	}
+	candidates := make([]candidate_t, 0)
+	for other := range scope {
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(other)); distance <= limit {
+			candidates = append(candidates, candidate_t{other, distance})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	if len(candidates) > MAX_SUGGESTIONS {
+		candidates = candidates[:MAX_SUGGESTIONS]
+	}
+	ret := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		ret[i] = candidate.name
+		if pos := scope[candidate.name]; pos.filename != "" {
+			ret[i] = fmt.Sprintf(message(locale, "%s (defined at line %d, col %d)"), candidate.name, pos.line, pos.col)
+		}
+	}
+	return ret
+}
+
+// gets the edit distance between two strings: how many single characters have to be inserted,
+// deleted or changed, or pairs of neighbours swapped, to turn one into the other. Swaps count as
+// one edit because they're such a common typo, like itme for item.
+func editDistance(a string, b string) int {
+	dist := make([][]int, len(a)+1)
+	for i := range dist {
+		dist[i] = make([]int, len(b)+1)
+		dist[i][0] = i
+	}
+	for j := range dist[0] {
+		dist[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			dist[i][j] = minInt(minInt(dist[i-1][j]+1, dist[i][j-1]+1), dist[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				dist[i][j] = minInt(dist[i][j], dist[i-2][j-2]+1)
+			}
+		}
+	}
+	return dist[len(a)][len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// checks a program before it runs for variables that are read without ever being bound, the way
+// strict mode does. A variable counts as bound if it's set already, or if an IMPORT or unpacking before it,
+// a FOR EACH loop around it or a LAMBDA or PLOT it's inside binds it. Every such read is reported.
+func (interp *Interpreter_t) checkUndefined(node *Node_t) error {
+	errs := ErrorList_t{}
+	unbound(node, interp.scope(), func(tok Token_t, scope map[string]Position_t) {
+		if _, ok := interp.builtinValue(tok.strVal); !ok && !isNilName(tok.strVal) {
+			errs = append(errs, undefinedVar(tok, scope, interp.locale))
+		}
+	})
+	if len(errs) == 1 {
+		return errs[0]
+	} else if len(errs) > 1 {
+		return errs
+	}
+	return nil
+}
+
+// calls found for every variable read in the AST that isn't in scope or bound by the
+// program around it, with the variables that are bound there. Builtins are reported too.
+func unbound(node *Node_t, scope map[string]Position_t, found func(Token_t, map[string]Position_t)) {
+	if node == nil {
+		return
+	}
+	switch node.nodeType {
+	case VAR_ACCESS:
+		if _, ok := scope[node.tok.strVal]; !ok {
+			found(node.tok, scope)
+		}
+		return
+	case IMPORT: // binds its name for the statements after it
+		scope[importName(node)] = node.tok.pos
+		return
+	case MATCH_EXPR: // each CASE binds the names in its pattern for its guard and value
+		unbound(node.left, scope, found)
+		for i := 0; i < len(node.args); i += 3 {
+			unboundPattern(node.args[i], scope, found)
+			inner := within(scope, patternNames(node.args[i])...)
+			unbound(node.args[i+1], inner, found)
+			unbound(node.args[i+2], inner, found)
+		}
+		return
+	case ENUM_DEF: // binds its name for the statements after it
+		for _, member := range node.args {
+			if member.nodeType == COMPARISON {
+				unbound(member.right, scope, found)
+			}
+		}
+		scope[node.tok.strVal] = node.tok.pos
+		return
+	case TYPE_DEF: // binds its name for the statements after it, and for the operators it defines
+		scope[node.tok.strVal] = node.tok.pos
+		for _, member := range node.args {
+			if member.nodeType == COMPARISON {
+				unbound(member.right, scope, found)
+			}
+		}
+		return
+	case UNPACK: // binds its names for the statements after it, once its value has been read
+		unbound(node.left, scope, found)
+		for _, name := range node.ops {
+			scope[name.strVal] = name.pos
+		}
+		return
+	case FOR_EACH:
+		unbound(node.left, scope, found)
+		inner := within(scope, node.tok)
+		for _, stmt := range node.statements {
+			unbound(stmt, inner, found)
+		}
+		return
+	case CALL:
+		if node.left == nil && strings.EqualFold(node.tok.strVal, "LAMBDA") && len(node.args) > 0 {
+			args := lambdaArgs(node)
+			params := make([]Token_t, 0, len(args)-1)
+			for _, param := range args[:len(args)-1] {
+				if param.nodeType == COMPARISON { // a default, read where the LAMBDA is
+					unbound(param.right, scope, found)
+					param, _ = unannotated(param.left)
+				} else if param.nodeType == REST_PARAM {
+					param = param.left
+				} else {
+					param, _ = unannotated(param)
+				}
+				params = append(params, param.tok)
+			}
+			unbound(node.args[len(node.args)-1], within(scope, params...), found)
+			return
+		} else if node.left == nil && strings.EqualFold(node.tok.strVal, "PLOT") && len(node.args) == 4 && node.args[1].nodeType == VAR_ACCESS {
+			unbound(node.args[0], within(scope, node.args[1].tok), found)
+			unbound(node.args[2], scope, found)
+			unbound(node.args[3], scope, found)
+			return
+		}
+		for _, arg := range node.args { // an unbound name = value is taken to be a named argument
+			if isNamedArg(arg, scope) {
+				unbound(arg.right, scope, found)
+			} else {
+				unbound(arg, scope, found)
+			}
+		}
+		unbound(node.left, scope, found)
+		return
+	}
+	unbound(node.left, scope, found)
+	unbound(node.right, scope, found)
+	for _, arg := range node.args {
+		unbound(arg, scope, found)
+	}
+	for _, stmt := range node.statements {
+		unbound(stmt, scope, found)
+	}
+}
+
+// gets a copy of scope with more variables bound, at the tokens that bind them.
+func within(scope map[string]Position_t, names ...Token_t) map[string]Position_t {
+	ret := make(map[string]Position_t, len(scope)+len(names))
+	for name, pos := range scope {
+		ret[name] = pos
+	}
+	for _, name := range names {
+		ret[name.strVal] = name.pos
+	}
+	return ret
+}
+
+// returns true if a call's argument looks like a named one, name = value, with a name that
+// isn't a variable in scope, so it can only be the name of a parameter.
+func isNamedArg(arg *Node_t, scope map[string]Position_t) bool {
+	if arg.nodeType != COMPARISON || arg.tok.tokenType != EQ || arg.left.nodeType != VAR_ACCESS {
+		return false
+	}
+	_, ok := scope[arg.left.tok.strVal]
+	return !ok
+}
@@ -0,0 +1,79 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// returns true if tokens[i] starts an unpacking statement like lo, hi = MINMAX(xs): two or
+// more variable names separated by commas, then =. A single name before = is a comparison.
+func isUnpack(tokens []Token_t, i int) bool {
+	for j := i; j+1 < len(tokens) && tokens[j].tokenType == IDENTIFIER; j += 2 {
+		if tokens[j+1].tokenType == EQ {
+			return j > i
+		} else if tokens[j+1].tokenType != COMMA {
+			return false
+		}
+	}
+	return false
+}
+
+// builds and returns an Unpack node: names, then =, then the list they take the elements of.
+// Lists stand in for tuples, so a LAMBDA hands back several values by making a list of them.
+func (parser *parser_t) unpack() (*Node_t, error) {
+	names := make([]Token_t, 0, 2)
+	for parser.currentToken.tokenType != EQ {
+		for _, name := range names {
+			if name.strVal == parser.currentToken.strVal {
+				return nil, &ParseError_t{Code: ERR_UNEXPECTED_TOKEN, Details: fmt.Sprintf("%s is unpacked into more than once", parser.currentToken.strVal), Pos: parser.currentToken.pos}
+			}
+		}
+		names = append(names, parser.currentToken)
+		parser.advance()
+		if parser.currentToken.tokenType == COMMA {
+			parser.advance()
+		}
+	}
+	ret := &Node_t{nodeType: UNPACK, tok: parser.currentToken, ops: names}
+	parser.advance()
+	value, err := parser.comparison()
+	if err != nil {
+		return nil, err
+	}
+	ret.left = value
+	return ret, nil
+}
+
+// evaluates an UNPACK node, binding each variable to the matching element of the list, for
+// the rest of the program like an IMPORT. The list has to have exactly one element per
+// variable. Its value is the list.
+func (node *Node_t) evaluateUnpack(interp *Interpreter_t) (*Result_t, error) {
+	value, err := node.left.evaluate(interp)
+	if err != nil {
+		return nil, err
+	}
+	if value.ResultType != LIST_RESULT {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't unpack a value of type %s, only a list", value.ResultType), Pos: node.tok.pos}
+	} else if len(value.Lres) != len(node.ops) {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't unpack a list of %d value(s) into %d variables", len(value.Lres), len(node.ops)), Pos: node.tok.pos}
+	}
+	for _, name := range node.ops {
+		if err := interp.bindable(name.strVal, name); err != nil {
+			return nil, err
+		}
+	}
+	for i, name := range node.ops {
+		interp.vars[name.strVal] = value.Lres[i]
+		interp.define(name.strVal, name.pos)
+	}
+	return value, nil
+}
+
+// gets the variables an UNPACK node binds, as they're written, joined by commas.
+func unpackNames(node *Node_t) string {
+	names := make([]string, len(node.ops))
+	for i, name := range node.ops {
+		names[i] = name.strVal
+	}
+	return strings.Join(names, ", ")
+}
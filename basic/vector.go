@@ -0,0 +1,122 @@
+package basic
+
+import (
+	"fmt"
+	"math"
+)
+
+// gets the numbers of a list used as a vector, and whether they're all ints.
+func toVector(res *Result_t) ([]*Result_t, bool, error) {
+	if res.ResultType != LIST_RESULT {
+		return nil, false, typeErrorf("expected a list of numbers, not %s", res.ResultType)
+	}
+	allInts := true
+	for i, elem := range res.Lres {
+		if !elem.IsNumber() {
+			return nil, false, typeErrorf("element %d is a %s, not a number", i+1, elem.ResultType)
+		}
+		allInts = allInts && elem.ResultType == INTEGER
+	}
+	return res.Lres, allInts, nil
+}
+
+// applies a binary operator to two numbers: ints stay ints, anything with a float in it is a float.
+// Dividing by zero has to be checked first.
+func numberOp(left *Result_t, right *Result_t, op TokenType_t) *Result_t {
+	if left.ResultType == INTEGER && right.ResultType == INTEGER {
+		return NewInt(intop(left.Ires, right.Ires, op))
+	}
+	return NewFloat(floatop(left.Fres, right.Fres, op)) // the float value is set for integers too
+}
+
+// returns true if the operation is * or / between a list and a number, for vectorOp.
+func isVectorOp(left *Result_t, right *Result_t, op Token_t) bool {
+	if op.tokenType != MUL && op.tokenType != DIV {
+		return false
+	}
+	return (left.ResultType == LIST_RESULT && right.IsNumber()) || (left.IsNumber() && right.ResultType == LIST_RESULT)
+}
+
+// applies * or / between a list of numbers and a number, element by element: 2 * [1, 2] is [2, 4]
+// and [1, 2] / 2 is [0.5, 1] if either side has a float. A number can only be on the left of *.
+func vectorOp(left *Result_t, right *Result_t, op Token_t) (*Result_t, error) {
+	vector, scalar, scalarOnLeft := left, right, false
+	if left.IsNumber() {
+		vector, scalar, scalarOnLeft = right, left, true
+	}
+	if op.tokenType == DIV && scalarOnLeft {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't apply / to values of type %s and %s", left.ResultType, right.ResultType), Pos: op.pos}
+	}
+	elems, _, err := toVector(vector)
+	if err != nil {
+		return nil, &RuntimeError_t{Code: ERR_TYPE, Details: fmt.Sprintf("can't apply %s to a list and a number: %s", tokenSymbol(op.tokenType), err), Pos: op.pos}
+	}
+	if op.tokenType == DIV && scalar.Fres == 0 {
+		return nil, &RuntimeError_t{Code: ERR_DIVISION_BY_ZERO, Details: "division by zero", Pos: op.pos}
+	}
+	ret := make([]*Result_t, len(elems))
+	for i, elem := range elems {
+		if scalarOnLeft {
+			ret[i] = numberOp(scalar, elem, op.tokenType)
+		} else {
+			ret[i] = numberOp(elem, scalar, op.tokenType)
+		}
+	}
+	return NewList(ret), nil
+}
+
+// gets two vectors of the same length, for the builtins that combine them.
+func vectorPair(a *Result_t, b *Result_t) ([]*Result_t, []*Result_t, bool, error) {
+	left, leftInts, err := toVector(a)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	right, rightInts, err := toVector(b)
+	if err != nil {
+		return nil, nil, false, err
+	} else if len(left) != len(right) {
+		return nil, nil, false, dimensionErrorf("the vectors have different lengths, %d and %d", len(left), len(right))
+	}
+	return left, right, leftInts && rightInts, nil
+}
+
+// the vector builtins. Vectors are lists of numbers; the results are ints if every number is.
+func init() {
+	RegisterBuiltin("DOT", 2, func(args []*Result_t) (*Result_t, error) { // DOT(a, b): the sum of the products of the elements
+		a, b, allInts, err := vectorPair(args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		ret := NewInt(0)
+		if !allInts {
+			ret = NewFloat(0)
+		}
+		for i := range a {
+			ret = numberOp(ret, numberOp(a[i], b[i], MUL), ADD)
+		}
+		return ret, nil
+	})
+	RegisterBuiltin("CROSS", 2, func(args []*Result_t) (*Result_t, error) { // CROSS(a, b): the cross product of two 3D vectors
+		a, b, _, err := vectorPair(args[0], args[1])
+		if err != nil {
+			return nil, err
+		} else if len(a) != 3 {
+			return nil, dimensionErrorf("the cross product needs 3D vectors, not %dD", len(a))
+		}
+		component := func(i, j int) *Result_t { // a[i]*b[j] - a[j]*b[i]
+			return numberOp(numberOp(a[i], b[j], MUL), numberOp(a[j], b[i], MUL), SUB)
+		}
+		return NewList([]*Result_t{component(1, 2), component(2, 0), component(0, 1)}), nil
+	})
+	RegisterBuiltin("NORM", 1, func(args []*Result_t) (*Result_t, error) { // NORM(a): the length of the vector
+		a, _, err := toVector(args[0])
+		if err != nil {
+			return nil, err
+		}
+		sum := 0.0
+		for _, elem := range a {
+			sum += elem.Fres * elem.Fres
+		}
+		return NewFloat(math.Sqrt(sum)), nil
+	})
+}
@@ -0,0 +1,186 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// enumerated type for the kinds of warning the interpreter can raise.
+type WarningType_t int
+
+const (
+	IMPLICIT_CONVERSION WarningType_t = iota // an integer operand was converted to a float to match the other operand
+	INTEGER_DIVISION                         // dividing two integers threw away a remainder
+	ENUM_COMPARISON                          // a member of an enum was compared with something that isn't a member of the same enum
+	FLOAT_EQUALITY                           // = or <> compared a float exactly, with no Epsilon to allow for rounding
+	SHADOWED_BUILTIN                         // a variable was bound with the name of a builtin, hiding it
+	UNUSED_VARIABLE                          // a variable was bound and never read
+)
+
+// gets the name of this warning type, like "implicit-conversion"
+func (warningType WarningType_t) String() string {
+	names := [6]string{"implicit-conversion", "integer-division", "enum-comparison", "float-equality", "shadowed-builtin", "unused-variable"}
+	if warningType < 0 || int(warningType) >= len(names) {
+		return fmt.Sprintf("WarningType_t(%d)", int(warningType))
+	}
+	return names[warningType]
+}
+
+// gets the stable code of this warning type, like "W001". Codes never change meaning, so
+// tooling and docs can refer to them.
+func (warningType WarningType_t) Code() string {
+	return fmt.Sprintf("W%03d", int(warningType)+1)
+}
+
+// a non-fatal diagnostic. Unlike errors, warnings don't stop evaluation; they are
+// handed back alongside the result.
+type Warning_t struct {
+	WarningType WarningType_t
+	Details     string
+	Pos         Position_t
+	locale      string // the language its Details are in, "" for English
+}
+
+// returns true if strict mode turns this type of warning into an error.
+// Integer division is implicit narrowing (the remainder is silently lost), so it isn't allowed,
+// and neither is mixing ints and floats: where money is involved, a float creeping in has to be
+// asked for with FLOAT() or INT(). Comparisons have to stay within an enum, too.
+func (warningType WarningType_t) strictError() bool {
+	switch warningType {
+	case INTEGER_DIVISION, IMPLICIT_CONVERSION, ENUM_COMPARISON:
+		return true
+	default:
+		return false
+	}
+}
+
+// returns a String representation of this warning, like "integer division 7 / 2 truncates to 3 at line 0, col 1 in file stdin [integer-division]"
+func (warning Warning_t) String() string {
+	return describe(warning.locale, warning.Details, warning.Pos, warning.WarningType.String())
+}
+
+// converts this warning into a machine-readable diagnostic.
+func (warning Warning_t) Diagnostic() Diagnostic_t {
+	return Diagnostic_t{Code: warning.WarningType.Code(), Severity: "warning", Message: warning.Details,
+		Filename: warning.Pos.filename, Line: warning.Pos.line, Col: warning.Pos.col, Offset: warning.Pos.index}
+}
+
+// returns true if the interpreter's options allow warnings of this type to be reported.
+func (interp *Interpreter_t) warningEnabled(warningType WarningType_t) bool {
+	for _, disabled := range interp.opts.DisabledWarnings {
+		if disabled == warningType {
+			return false
+		}
+	}
+	return true
+}
+
+// records a warning of the given type at pos, unless that type is disabled.
+// In strict mode, warnings that strict mode forbids are returned as an error instead.
+func (interp *Interpreter_t) warn(warningType WarningType_t, pos Position_t, format string, args ...interface{}) error {
+	warning := Warning_t{WarningType: warningType, Details: fmt.Sprintf(format, args...), Pos: pos}
+	if interp.opts.Strict && warningType.strictError() {
+		return &RuntimeError_t{Code: ERR_STRICT, Details: warning.Details + " (not allowed in strict mode)", Pos: pos}
+	}
+	if interp.warningEnabled(warningType) {
+		interp.warnings = append(interp.warnings, warning)
+	}
+	return nil
+}
+
+// warns about an int operand of a binary operation being converted to a float because the
+// other one is a float, at the int operand so it's clear which one to wrap in FLOAT().
+func (interp *Interpreter_t) checkPromotion(node *Node_t, left *Result_t, right *Result_t) error {
+	if left.ResultType == INTEGER && right.ResultType == FLOATING {
+		pos, _ := span(node.left)
+		return interp.warn(IMPLICIT_CONVERSION, pos, "left operand %s of %s is the int %d, implicitly converted to float; write FLOAT(%s) to convert it explicitly", Format(node.left), tokenSymbol(node.tok.tokenType), left.Ires, Format(node.left))
+	} else if left.ResultType == FLOATING && right.ResultType == INTEGER {
+		pos, _ := span(node.right)
+		return interp.warn(IMPLICIT_CONVERSION, pos, "right operand %s of %s is the int %d, implicitly converted to float; write FLOAT(%s) to convert it explicitly", Format(node.right), tokenSymbol(node.tok.tokenType), right.Ires, Format(node.right))
+	}
+	return nil
+}
+
+// warns about = or <> comparing a float exactly, as 0.1 + 0.2 = 0.3 doesn't hold once the
+// sum has been rounded. With an Epsilon set, = already allows for that.
+func (interp *Interpreter_t) checkFloatEquality(left *Result_t, right *Result_t, op Token_t) error {
+	if (op.tokenType != EQ && op.tokenType != NE) || interp.opts.Epsilon != 0 || (left.ResultType != FLOATING && right.ResultType != FLOATING) {
+		return nil
+	}
+	return interp.warn(FLOAT_EQUALITY, op.pos, "%s compares floats exactly, which rounding can throw off; write ~= to compare them within a tolerance", tokenSymbol(op.tokenType))
+}
+
+// warns about binding a variable with the name of a builtin, which hides the builtin for as
+// long as the variable is set.
+func (interp *Interpreter_t) checkShadowing(name string, tok Token_t) error {
+	builtinsMu.RLock()
+	_, ok := builtins[strings.ToUpper(name)]
+	builtinsMu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return interp.warn(SHADOWED_BUILTIN, tok.pos, "the variable %s hides the builtin %s", name, strings.ToUpper(name))
+}
+
+// warns about the variables a program binds and never reads: FOR EACH loop variables and
+// LAMBDA parameters that aren't used in their body, and names it unpacks into that aren't used
+// anywhere. An UNPACK that's the last statement is left alone, as a later Run can still read
+// what it binds. Names starting with _ are taken to be unused on purpose. Strict mode doesn't
+// mind unused variables, so this never fails.
+func (interp *Interpreter_t) checkUnused(node *Node_t) {
+	last := node
+	if node.nodeType == STATEMENTS && len(node.statements) > 0 {
+		last = node.statements[len(node.statements)-1]
+	}
+	unused := make([]Token_t, 0)
+	Walk(node, func(n *Node_t) bool {
+		switch {
+		case n.nodeType == UNPACK && n != last:
+			for _, name := range n.ops {
+				if !reads(node, name.strVal) {
+					unused = append(unused, name)
+				}
+			}
+		case n.nodeType == FOR_EACH:
+			if !readsAny(n.statements, n.tok.strVal) {
+				unused = append(unused, n.tok)
+			}
+		case n.nodeType == CALL && n.left == nil && strings.EqualFold(n.tok.strVal, "LAMBDA") && len(n.args) > 0:
+			args := lambdaArgs(n)
+			for _, param := range args[:len(args)-1] {
+				if param.nodeType == COMPARISON || param.nodeType == REST_PARAM {
+					param = param.left
+				}
+				if param, _ = unannotated(param); param.nodeType == VAR_ACCESS && !reads(args[len(args)-1], param.tok.strVal) {
+					unused = append(unused, param.tok)
+				}
+			}
+		}
+		return true
+	})
+	for _, name := range unused {
+		if !strings.HasPrefix(name.strVal, "_") {
+			interp.warn(UNUSED_VARIABLE, name.pos, "%s is bound but never read; start its name with _ if that's on purpose", name.strVal)
+		}
+	}
+}
+
+// returns true if the AST reads the variable called name anywhere, or calls it.
+func reads(node *Node_t, name string) bool {
+	found := false
+	Walk(node, func(n *Node_t) bool {
+		found = found || ((n.nodeType == VAR_ACCESS || (n.nodeType == CALL && n.left == nil)) && n.tok.strVal == name)
+		return !found
+	})
+	return found
+}
+
+// returns true if any of the statements reads the variable called name.
+func readsAny(statements []*Node_t, name string) bool {
+	for _, stmt := range statements {
+		if reads(stmt, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package basic
+
+import "testing"
+
+// runs src and gets the types of the warnings it raised, in order.
+func warningTypes(t *testing.T, opts Options_t, src string) []WarningType_t {
+	t.Helper()
+	res, err := NewInterpreter(opts).Run(src, t.Name())
+	if err != nil {
+		t.Fatalf("%q: %s", src, err)
+	}
+	ret := make([]WarningType_t, len(res.Warnings))
+	for i, warning := range res.Warnings {
+		ret[i] = warning.WarningType
+	}
+	return ret
+}
+
+func TestWarnings(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		opts Options_t
+		want []WarningType_t
+	}{
+		{`0.1 + 0.2 = 0.3`, Options_t{}, []WarningType_t{FLOAT_EQUALITY}},
+		{`0.5 <> 1`, Options_t{}, []WarningType_t{FLOAT_EQUALITY}},
+		{`0.1 + 0.2 = 0.3`, Options_t{Epsilon: 1e-9}, nil},
+		{`0.1 + 0.2 ~= 0.3`, Options_t{}, nil},
+		{`1 = 1`, Options_t{}, nil},
+		{`len, n = [1, 2]` + "\n" + `len + n`, Options_t{}, []WarningType_t{SHADOWED_BUILTIN}},
+		{`a, b = [1, 2]` + "\n" + `a`, Options_t{}, []WarningType_t{UNUSED_VARIABLE}},
+		{`_a, b = [1, 2]` + "\n" + `b`, Options_t{}, nil},
+		{`a, b = [1, 2]`, Options_t{}, nil}, // a later Run may read them
+		{`f, g = [LAMBDA(x, y, x), 1]` + "\n" + `f(1, g)`, Options_t{}, []WarningType_t{UNUSED_VARIABLE}},
+		{`f, g = [LAMBDA(x, _y, x), 1]` + "\n" + `f(1, g)`, Options_t{}, nil},
+		{"FOR EACH x IN [1, 2]\n1\nNEXT x", Options_t{}, []WarningType_t{UNUSED_VARIABLE}},
+		{"FOR EACH x IN [1, 2]\nx\nNEXT x", Options_t{}, nil},
+		{`0.1 + 0.2 = 0.3`, Options_t{DisabledWarnings: []WarningType_t{FLOAT_EQUALITY}}, nil},
+	} {
+		got := warningTypes(t, test.opts, test.src)
+		if len(got) != len(test.want) {
+			t.Errorf("%q: got warnings %v, want %v", test.src, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%q: got warnings %v, want %v", test.src, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestWarningTypeString(t *testing.T) {
+	if got := UNUSED_VARIABLE.String(); got != "unused-variable" {
+		t.Errorf("UNUSED_VARIABLE: got %q", got)
+	}
+	if got := WarningType_t(99).String(); got != "WarningType_t(99)" {
+		t.Errorf("an unknown warning type: got %q", got)
+	}
+}
@@ -0,0 +1,117 @@
+// Package basictest has helpers for testing code that embeds go-basic, so formulas
+// can be checked from a regular go test.
+package basictest
+
+import (
+	"fmt"
+	"go-basic/basic"
+	"os"
+	"strings"
+	"testing"
+)
+
+// when true, AssertASTGolden writes the golden file instead of comparing against it.
+// Set it from a test, or run the tests with BASICTEST_UPDATE=1 in the environment.
+var UpdateGolden = os.Getenv("BASICTEST_UPDATE") != ""
+
+// runs src and returns its result, failing the test right away if it doesn't evaluate.
+func MustEval(t testing.TB, src string) *basic.Result_t {
+	t.Helper()
+	res, err := basic.Run(src, t.Name())
+	if err != nil {
+		t.Fatalf("evaluating %q: %s", src, err)
+	}
+	return res
+}
+
+// runs src and checks that its result is want. An int or int64 want expects an integer result,
+// a float64 want expects a float result and a string want a string. A []interface{} want expects
+// a list, with each element compared the same way, so []interface{}{1, "a", 2.5} matches [1, "a", 2.5].
+// Any other value can be given as a *basic.Result_t, which has to be Equal to the result.
+func AssertResult(t testing.TB, src string, want interface{}) {
+	t.Helper()
+	res := MustEval(t, src)
+	problem, err := mismatch(res, want)
+	if err != nil {
+		t.Fatalf("AssertResult: %s", err)
+	} else if problem != "" {
+		t.Errorf("%q: %s", src, problem)
+	}
+}
+
+// describes how res differs from want, or returns "" if it doesn't. The error is for a want
+// of a type AssertResult can't compare against.
+func mismatch(res *basic.Result_t, want interface{}) (string, error) {
+	switch want := want.(type) {
+	case int:
+		return intMismatch(res, int64(want)), nil
+	case int64:
+		return intMismatch(res, want), nil
+	case float64:
+		if res.ResultType != basic.FLOATING {
+			return fmt.Sprintf("got %s %s, want float %v", res.ResultType, res, want), nil
+		} else if res.Fres != want {
+			return fmt.Sprintf("got %v, want %v", res.Fres, want), nil
+		}
+	case string:
+		if res.ResultType != basic.STRING_RESULT {
+			return fmt.Sprintf("got %s %s, want string %q", res.ResultType, res, want), nil
+		} else if res.Sres != want {
+			return fmt.Sprintf("got %q, want %q", res.Sres, want), nil
+		}
+	case []interface{}:
+		if res.ResultType != basic.LIST_RESULT {
+			return fmt.Sprintf("got %s %s, want a list", res.ResultType, res), nil
+		} else if len(res.Lres) != len(want) {
+			return fmt.Sprintf("got %s, a list of %d, want a list of %d", res, len(res.Lres), len(want)), nil
+		}
+		for i, elem := range res.Lres {
+			if problem, err := mismatch(elem, want[i]); err != nil || problem != "" {
+				return fmt.Sprintf("element %d: %s", i, problem), err
+			}
+		}
+	case *basic.Result_t:
+		if !res.Equal(want) {
+			return fmt.Sprintf("got %s, want %s", res, want), nil
+		}
+	default:
+		return "", fmt.Errorf("can't compare against a %T", want)
+	}
+	return "", nil
+}
+
+// describes how res differs from the integer want, or returns "" if it doesn't.
+func intMismatch(res *basic.Result_t, want int64) string {
+	if res.ResultType != basic.INTEGER {
+		return fmt.Sprintf("got %s %s, want integer %d", res.ResultType, res, want)
+	} else if res.Ires != want {
+		return fmt.Sprintf("got %d, want %d", res.Ires, want)
+	}
+	return ""
+}
+
+// parses src and compares the String form of its AST with the contents of the golden file.
+// If UpdateGolden is set, the file is (re)written instead.
+func AssertASTGolden(t testing.TB, src string, goldenPath string) {
+	t.Helper()
+	node, err := basic.Parse(src, t.Name())
+	if err != nil {
+		t.Fatalf("parsing %q: %s", src, err)
+	}
+	got := node.String() + "\n"
+
+	if UpdateGolden {
+		if err := os.WriteFile(goldenPath, []byte(got), 0644); err != nil {
+			t.Fatalf("updating golden file: %s", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("reading golden file: %s (run with BASICTEST_UPDATE=1 to create it)", err)
+	}
+	if got != strings.ReplaceAll(string(want), "\r\n", "\n") {
+		t.Errorf("AST of %q doesn't match %s\ngot:  %s\nwant: %s", src, goldenPath, strings.TrimSpace(got), strings.TrimSpace(string(want)))
+	}
+}
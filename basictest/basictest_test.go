@@ -0,0 +1,41 @@
+package basictest
+
+import (
+	"go-basic/basic"
+	"testing"
+)
+
+func TestAssertResult(t *testing.T) {
+	AssertResult(t, `1 + 2`, 3)
+	AssertResult(t, `1 + 2`, int64(3))
+	AssertResult(t, `1.5 * 2`, 3.0)
+	AssertResult(t, `"a" + "b"`, "ab")
+	AssertResult(t, `[1, "a", [2.5]]`, []interface{}{1, "a", []interface{}{2.5}})
+	AssertResult(t, `[]`, []interface{}{})
+	AssertResult(t, `{"a": 1}`, MustEval(t, `{"a": 1}`))
+}
+
+func TestMismatch(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		want interface{}
+	}{
+		{`3`, 3.0},
+		{`3.0`, 3},
+		{`"3"`, 3},
+		{`3`, "3"},
+		{`"a"`, "b"},
+		{`[1, 2]`, []interface{}{1}},
+		{`[1, 2]`, []interface{}{1, "2"}},
+		{`1`, []interface{}{1}},
+		{`{"a": 1}`, basic.NewInt(1)},
+	} {
+		problem, err := mismatch(MustEval(t, test.src), test.want)
+		if err != nil || problem == "" {
+			t.Errorf("%q against %#v: got %q, %v, want a mismatch", test.src, test.want, problem, err)
+		}
+	}
+	if _, err := mismatch(MustEval(t, `1`), struct{}{}); err == nil {
+		t.Errorf("comparing against a struct: got no error")
+	}
+}
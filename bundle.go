@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go-basic/basic"
+	"os"
+	"strings"
+)
+
+// extension of bundle files. run takes any file that's a zip archive as a bundle, whatever it's called.
+const bundleExt = ".basx"
+
+// `go-basic bundle [--import-path DIR]... FILE [-o OUT]`: packages a program with every module it
+// IMPORTs into a single file that `go-basic run` runs. OUT is FILE with its extension changed
+// to .basx by default.
+func bundleCommand(args []string, opts basic.Options_t) int {
+	flags := flag.NewFlagSet("bundle", flag.ExitOnError)
+	var importPaths stringList_t
+	flags.Var(&importPaths, "import-path", "directory to search for IMPORTed modules after the program's own (can be repeated)")
+	out := flags.String("o", "", "file to write the bundle to")
+	flags.Parse(args)
+	if flags.NArg() > 0 { // flags can come after the program too, like bundle main.bas -o prog.basx
+		path := flags.Arg(0)
+		flags.Parse(flags.Args()[1:])
+		args = append([]string{path}, flags.Args()...)
+	} else {
+		args = nil
+	}
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: go-basic bundle [--import-path DIR]... FILE [-o OUT]")
+		return 2
+	}
+	path := args[0]
+	if *out == "" {
+		*out = strings.TrimSuffix(path, ".bas") + bundleExt
+	}
+
+	opts.ImportPaths = importPaths
+	bundle, err := basic.NewInterpreter(opts).Bundle(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 1
+	}
+	var data bytes.Buffer
+	if err := bundle.Write(&data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 2
+	}
+	if err := os.WriteFile(*out, data.Bytes(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 2
+	}
+	fmt.Printf("wrote %s with %d file(s), SHA-256 %s\n", *out, len(bundle.Files), sha256Hex(data.Bytes()))
+	return 0
+}
+
+// returns true if the contents of a file are a bundle rather than a program: a zip archive.
+func isBundle(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"go-basic/basic"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writes files under dir, making the directories they're in.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// runs `go-basic run` with args, capturing what it writes.
+func capturedRun(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	defer func(streams streams_t) { runStreams = streams }(runStreams)
+	return captured(t, func(args []string) int {
+		runStreams = streams_t{program: os.Stdout, results: os.Stderr, diagnostics: os.Stderr}
+		return runCommand(args, basic.Options_t{})
+	}, args...)
+}
+
+func TestBundleCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app/main.bas":   "IMPORT \"shapes.bas\"\nshapes[\"area\"](3)",
+		"app/shapes.bas": `{"area": LAMBDA(r, 3 * r * r)}`,
+	})
+	main, out := filepath.Join(dir, "app", "main.bas"), filepath.Join(dir, "prog.basx")
+	bundle := func(args []string) int { return bundleCommand(args, basic.Options_t{}) }
+	if status, stdout, stderr := captured(t, bundle, main, "-o", out); status != 0 || !strings.Contains(stdout, "2 file(s)") {
+		t.Fatalf("bundle: got status %d, stdout %q, stderr %q", status, stdout, stderr)
+	}
+
+	os.RemoveAll(filepath.Join(dir, "app")) // the bundle runs without the files it came from
+	if status, _, stderr := capturedRun(t, out); status != 0 || !strings.Contains(stderr, "Result: 27") {
+		t.Errorf("run: got status %d, stderr %q", status, stderr)
+	}
+}
+
+func TestBundleCommandErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.bas": `IMPORT "nowhere.bas"`})
+	bundle := func(args []string) int { return bundleCommand(args, basic.Options_t{}) }
+	if status, _, stderr := captured(t, bundle, filepath.Join(dir, "main.bas")); status != 1 || !strings.Contains(stderr, "nowhere.bas") {
+		t.Errorf("bundling a program whose module is missing: got status %d, stderr %q", status, stderr)
+	} else if _, err := os.Stat(filepath.Join(dir, "main.basx")); err == nil {
+		t.Errorf("a bundle was written anyway")
+	}
+	if status, _, _ := captured(t, bundle); status != 2 {
+		t.Errorf("bundle with no program: got status %d, want 2", status)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gets the SHA-256 of data, in lower case hex like sha256sum writes it.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checks the contents of the file at path have the SHA-256 want, in hex.
+func checkSHA256(path string, data []byte, want string) error {
+	if got := sha256Hex(data); !strings.EqualFold(got, strings.TrimSpace(want)) {
+		return fmt.Errorf("%s has SHA-256 %s, not %s, so it won't be run", path, got, strings.ToLower(strings.TrimSpace(want)))
+	}
+	return nil
+}
+
+// gets the SHA-256 a checksums file, in the format sha256sum writes, gives for the file at
+// path. The file can be listed by the path it's run with or just by its name.
+func listedSHA256(sumsPath string, path string) (string, error) {
+	data, err := os.ReadFile(sumsPath)
+	if err != nil {
+		return "", err
+	}
+	byName := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return "", fmt.Errorf("%s:%d: expected a SHA-256 and a file name", sumsPath, i+1)
+		}
+		name := strings.TrimPrefix(fields[1], "*") // sha256sum marks files it read in binary mode with a *
+		if filepath.Clean(name) == filepath.Clean(path) {
+			return fields[0], nil
+		} else if filepath.Base(name) == filepath.Base(path) && byName == "" {
+			byName = fields[0]
+		}
+	}
+	if byName == "" {
+		return "", fmt.Errorf("%s isn't listed in %s, so it won't be run", path, sumsPath)
+	}
+	return byName, nil
+}
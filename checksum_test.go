@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sumOf1 = "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b" // SHA-256 of "1"
+
+func TestCheckSHA256(t *testing.T) {
+	if err := checkSHA256("x.bas", []byte("1"), sumOf1); err != nil {
+		t.Errorf("the right sum: %s", err)
+	} else if err := checkSHA256("x.bas", []byte("1"), " "+strings.ToUpper(sumOf1)+"\n"); err != nil {
+		t.Errorf("the right sum in upper case: %s", err)
+	} else if err := checkSHA256("x.bas", []byte("2"), sumOf1); err == nil {
+		t.Errorf("the wrong sum: got no error")
+	}
+}
+
+func TestListedSHA256(t *testing.T) {
+	dir := t.TempDir()
+	sums := filepath.Join(dir, "SHA256SUMS")
+	writeFiles(t, dir, map[string]string{"SHA256SUMS": "# made by sha256sum\n" + sumOf1 + "  scripts/a.bas\n" + strings.Repeat("0", 64) + " *b.bas\n"})
+	for path, want := range map[string]string{"scripts/a.bas": sumOf1, "elsewhere/a.bas": sumOf1, "b.bas": strings.Repeat("0", 64)} {
+		if got, err := listedSHA256(sums, path); err != nil {
+			t.Errorf("%s: %s", path, err)
+		} else if got != want {
+			t.Errorf("%s: got %s, want %s", path, got, want)
+		}
+	}
+	if _, err := listedSHA256(sums, "c.bas"); err == nil {
+		t.Errorf("a file that isn't listed: got no error")
+	}
+	writeFiles(t, dir, map[string]string{"BAD": "not a sum line at all\n"})
+	if _, err := listedSHA256(filepath.Join(dir, "BAD"), "a.bas"); err == nil {
+		t.Errorf("a malformed checksums file: got no error")
+	}
+}
+
+func TestRunChecksum(t *testing.T) {
+	dir := t.TempDir()
+	prog := filepath.Join(dir, "a.bas")
+	writeFiles(t, dir, map[string]string{"a.bas": "1", "good": sumOf1 + "  a.bas\n", "bad": strings.Repeat("0", 64) + "  a.bas\n"})
+	for _, test := range []struct {
+		args []string
+		want int
+	}{
+		{[]string{"--sha256", sumOf1, prog}, 0},
+		{[]string{"--sha256", strings.Repeat("0", 64), prog}, 2},
+		{[]string{"--sha256-file", filepath.Join(dir, "good"), prog}, 0},
+		{[]string{"--sha256-file", filepath.Join(dir, "bad"), prog}, 2},
+		{[]string{"--sha256-file", filepath.Join(dir, "missing"), prog}, 2},
+	} {
+		status, _, stderr := capturedRun(t, test.args...)
+		if status != test.want {
+			t.Errorf("run %q: got status %d, want %d; stderr %q", test.args, status, test.want, stderr)
+		} else if ran := strings.Contains(stderr, "Result: 1"); ran != (test.want == 0) {
+			t.Errorf("run %q: the program ran %v; stderr %q", test.args, ran, stderr)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go-basic/basic"
+	"io"
+	"os"
+	"strings"
+)
+
+// `go-basic deps [--dot] FILE`: prints the named expressions in a file in an order they can be
+// evaluated in, each with the ones it reads. Each line of the file is NAME = EXPR, and blank
+// lines and lines starting with # are skipped. The exit status is 1 if there's a cycle or an expression doesn't parse.
+func depsCommand(args []string) int {
+	flags := flag.NewFlagSet("deps", flag.ExitOnError)
+	dot := flags.Bool("dot", false, "print the graph as a Graphviz digraph instead")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: go-basic deps [--dot] FILE")
+		return 2
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 2
+	}
+	defer file.Close()
+	exprs, err := readExprs(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s: %s\n", flags.Arg(0), err)
+		return 2
+	}
+	graph, err := basic.Dependencies(exprs)
+	if err != nil {
+		fmt.Printf("Error! %s\n", err)
+		return 1
+	}
+
+	if *dot {
+		writeDepsDOT(os.Stdout, graph)
+		return 0
+	}
+	for _, name := range graph.Order {
+		line := name
+		if deps := graph.Deps[name]; len(deps) > 0 {
+			line += " <- " + strings.Join(deps, ", ")
+		}
+		if inputs := graph.Inputs[name]; len(inputs) > 0 {
+			line += " (reads " + strings.Join(inputs, ", ") + ")"
+		}
+		fmt.Println(line)
+	}
+	return 0
+}
+
+// reads NAME = EXPR lines into a map of expressions by name.
+func readExprs(r io.Reader) (map[string]string, error) {
+	ret := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected NAME = EXPR", lineNo)
+		}
+		name := strings.TrimSpace(line[:eq])
+		if _, ok := ret[name]; ok {
+			return nil, fmt.Errorf("line %d: %s is defined twice", lineNo, name)
+		}
+		ret[name] = strings.TrimSpace(line[eq+1:])
+	}
+	return ret, scanner.Err()
+}
+
+// writes a dependency graph as a DOT digraph, with an edge from each expression to the ones that read it.
+func writeDepsDOT(w io.Writer, graph *basic.DepGraph_t) {
+	fmt.Fprintln(w, "digraph deps {")
+	for _, name := range graph.Order {
+		fmt.Fprintf(w, "\t%s;\n", dotQuote(name))
+	}
+	for _, name := range graph.Order {
+		for _, dep := range graph.Deps[name] {
+			fmt.Fprintf(w, "\t%s -> %s;\n", dotQuote(dep), dotQuote(name))
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
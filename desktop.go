@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// the programs :copy tries, in order, to put text on the system clipboard, by GOOS. Each reads
+// the text from stdin.
+var clipboardCommands = map[string][][]string{
+	"darwin":  {{"pbcopy"}},
+	"windows": {{"clip"}},
+	"linux":   {{"wl-copy"}, {"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}},
+}
+
+// puts text on the system clipboard with the first of clipboardCommands that's installed.
+func copyToClipboard(text string) error {
+	candidates := clipboardCommands[runtime.GOOS]
+	if candidates == nil {
+		candidates = clipboardCommands["linux"] // the BSDs have the same X11 and Wayland tools
+	}
+	names := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		names[i] = candidate[0]
+		if _, err := exec.LookPath(candidate[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(candidate[0], candidate[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %s %s", candidate[0], err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	return fmt.Errorf("can't find a clipboard program, install one of %s", strings.Join(names, ", "))
+}
+
+// runs the REPL's `:copy` command, putting the text of the last result on the clipboard.
+func copyCommand(last string, args string) error {
+	if strings.TrimSpace(args) != "" {
+		return fmt.Errorf("usage: :copy")
+	} else if last == "" {
+		return fmt.Errorf("there's no result to copy yet")
+	}
+	return copyToClipboard(last)
+}
+
+// reads the file the REPL's `:open FILE` command names, giving its source and path so it's
+// run like an input typed in, with its variables staying bound afterwards.
+func openCommand(args string) (string, string, error) {
+	path := strings.TrimSpace(args)
+	if path == "" {
+		return "", "", fmt.Errorf("usage: :open FILE")
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	return string(src), path, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-basic/basic"
+	"io"
+	"os"
+	"strings"
+)
+
+// prints the parse tree of an expression as a Graphviz digraph. With --with-values, the
+// expression is evaluated too and each node is labelled with its result.
+func graphCommand(args []string, opts basic.Options_t) int {
+	flags := flag.NewFlagSet("graph", flag.ExitOnError)
+	withValues := flags.Bool("with-values", false, "evaluate the expression and show each node's value")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: go-basic graph [--with-values] EXPR")
+		return 2
+	}
+	src := flags.Arg(0)
+
+	node, err := basic.Parse(src, "graph")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 2
+	}
+	var values map[*basic.Node_t]*basic.Result_t
+	if *withValues {
+		recorder := &valueRecorder_t{values: make(map[*basic.Node_t]*basic.Result_t)}
+		opts.Tracer = recorder
+		_, err = basic.NewInterpreter(opts).Run(src, "graph")
+		if err != nil {
+			// still draw the tree: the nodes worked out before the error keep their values
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		}
+		node, values = recorder.root, recorder.values
+	}
+	writeDOT(os.Stdout, node, values)
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// Tracer remembering the value of every node, and the root (the last node to finish).
+type valueRecorder_t struct {
+	values map[*basic.Node_t]*basic.Result_t
+	root   *basic.Node_t
+}
+
+func (recorder *valueRecorder_t) Enter(node *basic.Node_t) {}
+
+func (recorder *valueRecorder_t) Exit(node *basic.Node_t, res *basic.Result_t, err error) {
+	if res != nil {
+		recorder.values[node] = res
+	}
+	recorder.root = node
+}
+
+// writes the tree under node as a DOT digraph, numbering nodes in the order they're visited.
+// Nodes with an entry in values get it added to their label.
+func writeDOT(w io.Writer, node *basic.Node_t, values map[*basic.Node_t]*basic.Result_t) {
+	fmt.Fprintln(w, "digraph ast {")
+	fmt.Fprintln(w, "\tnode [shape=box, fontname=\"monospace\"];")
+	ids := make(map[*basic.Node_t]int)
+	basic.Walk(node, func(n *basic.Node_t) bool {
+		ids[n] = len(ids)
+		label := nodeLabel(n)
+		if value, ok := values[n]; ok && n.Kind() != basic.FACTOR {
+			label += "\n= " + value.ValueString()
+		}
+		fmt.Fprintf(w, "\tn%d [label=%s];\n", ids[n], dotQuote(label))
+		return true
+	})
+	basic.Walk(node, func(n *basic.Node_t) bool {
+		for _, child := range childNodes(n) {
+			fmt.Fprintf(w, "\tn%d -> n%d;\n", ids[n], ids[child])
+		}
+		return true
+	})
+	fmt.Fprintln(w, "}")
+}
+
+// quotes a label for DOT, where newlines are written as \n.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	return "\"" + strings.ReplaceAll(s, "\n", "\\n") + "\""
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"go-basic/basic"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the gRPC service in proto/basic.proto, written against net/http rather than a gRPC library.
+// gRPC is HTTP/2 with each message sent as a 5-byte prefix and its protobuf encoding, and the
+// outcome of the call in the grpc-status and grpc-message trailers. Go's server only speaks
+// HTTP/2 over TLS, so the service needs a certificate.
+const (
+	grpcService    = "/gobasic.Interpreter/"
+	grpcMaxMessage = 4 << 20  // bytes in one request message, gRPC's usual default
+	grpcMaxOutput  = 64 << 10 // bytes of what one program writes kept to send back
+)
+
+// gRPC status codes, from https://grpc.github.io/grpc/core/md_doc_statuscodes.html.
+const (
+	grpcOK                = 0
+	grpcCanceled          = 1
+	grpcInvalidArgument   = 3
+	grpcDeadlineExceeded  = 4
+	grpcResourceExhausted = 8
+	grpcUnimplemented     = 12
+	grpcInternal          = 13
+)
+
+// an error sent back to a client as a gRPC status.
+type grpcError_t struct {
+	code    int
+	message string
+}
+
+func (err *grpcError_t) Error() string {
+	return err.message
+}
+
+// the gRPC server. Every call gets its own interpreter, and so does every StreamRepl stream,
+// which keeps it for all the programs sent on the stream.
+type grpcServer_t struct {
+	opts basic.Options_t
+}
+
+// serves the gRPC service on addr until it fails.
+func grpcCommand(args []string, opts basic.Options_t) int {
+	flags := flag.NewFlagSet("grpc", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:50051", "address to listen on")
+	cert := flags.String("cert", "", "TLS certificate file")
+	key := flags.String("key", "", "TLS key file")
+	flags.Parse(args)
+	if *cert == "" || *key == "" {
+		fmt.Fprintln(os.Stderr, "usage: go-basic grpc [-addr A] -cert FILE -key FILE")
+		return 2
+	}
+
+	// no read or write timeouts, since a StreamRepl stream lasts as long as the client wants
+	server := &http.Server{
+		Addr:           *addr,
+		Handler:        newGRPCServer(opts),
+		MaxHeaderBytes: 8 << 10,
+	}
+	fmt.Fprintf(os.Stderr, "gRPC service listening on %s\n", *addr)
+	if err := server.ListenAndServeTLS(*cert, *key); err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// constructor for the gRPC server. Programs can't read the server's stdin, and what they
+// write is sent back with their results.
+func newGRPCServer(opts basic.Options_t) *grpcServer_t {
+	opts.Stdin = strings.NewReader("")
+	return &grpcServer_t{opts: opts}
+}
+
+func (srv *grpcServer_t) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "application/grpc" && !strings.HasPrefix(contentType, "application/grpc+proto") && !strings.HasPrefix(contentType, "application/grpc;") {
+		http.Error(w, "not a gRPC request", http.StatusUnsupportedMediaType)
+		return
+	}
+	ctx := r.Context()
+	if deadline, ok := grpcTimeout(r.Header.Get("Grpc-Timeout")); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+
+	w.Header().Set("Content-Type", "application/grpc+proto")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush() // so a streaming client can start sending
+
+	err := srv.call(ctx, strings.TrimPrefix(r.URL.Path, grpcService), r.Body, w)
+	code, message := grpcOK, ""
+	var grpcErr *grpcError_t
+	if errors.As(err, &grpcErr) {
+		code, message = grpcErr.code, grpcErr.message
+	} else if err != nil {
+		code, message = grpcInternal, err.Error()
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set("Grpc-Message", grpcEscape(message))
+	}
+}
+
+// runs one call of method, reading its requests from body and writing its responses to w.
+func (srv *grpcServer_t) call(ctx context.Context, method string, body io.Reader, w http.ResponseWriter) error {
+	switch method {
+	case "Evaluate":
+		var req evaluateRequest_t
+		if err := readUnary(body, &req); err != nil {
+			return err
+		}
+		resp, err := newGRPCSession(srv.opts).evaluate(ctx, req.source)
+		if err != nil {
+			return err
+		}
+		return writeGRPCMessage(w, resp.marshal())
+	case "Check":
+		var req checkRequest_t
+		if err := readUnary(body, &req); err != nil {
+			return err
+		}
+		return writeGRPCMessage(w, checkSource(req.source).marshal())
+	case "StreamRepl":
+		session := newGRPCSession(srv.opts)
+		for {
+			data, err := readGRPCMessage(body)
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			var req evaluateRequest_t
+			if err := req.unmarshal(data); err != nil {
+				return &grpcError_t{grpcInvalidArgument, err.Error()}
+			}
+			resp, err := session.evaluate(ctx, req.source)
+			if err != nil {
+				return err
+			}
+			if err := writeGRPCMessage(w, resp.marshal()); err != nil {
+				return err
+			}
+		}
+	}
+	return &grpcError_t{grpcUnimplemented, fmt.Sprintf("unknown method %q", method)}
+}
+
+// an interpreter and the buffer its programs write to, for one call or stream.
+type grpcSession_t struct {
+	interp *basic.Interpreter_t
+	output *cappedBuffer_t
+}
+
+func newGRPCSession(opts basic.Options_t) *grpcSession_t {
+	output := &cappedBuffer_t{limit: grpcMaxOutput}
+	opts.Stdout, opts.Stderr = output, output
+	return &grpcSession_t{interp: basic.NewInterpreter(opts), output: output}
+}
+
+// runs a program, stopping it if the call is cancelled or runs past its deadline, or past
+// --timeout. Errors in the program are sent back as diagnostics, only a stopped call fails.
+func (session *grpcSession_t) evaluate(ctx context.Context, source string) (*evaluateResponse_t, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	session.output.Reset()
+	session.output.truncated = false
+	res, err := session.interp.RunContext(ctx, source, "grpc")
+	if ctxErr := ctx.Err(); ctxErr == context.Canceled {
+		return nil, &grpcError_t{grpcCanceled, "call cancelled"}
+	} else if ctxErr == context.DeadlineExceeded && err != nil {
+		return nil, &grpcError_t{grpcDeadlineExceeded, "program ran past the deadline"}
+	}
+
+	resp := &evaluateResponse_t{output: session.output.String(), ok: err == nil}
+	if session.output.truncated {
+		resp.output += "\n[output cut off]\n"
+	}
+	if err != nil {
+		resp.diagnostics = basic.Diagnostics(err)
+		return resp, nil
+	}
+	for _, warning := range res.Warnings {
+		resp.diagnostics = append(resp.diagnostics, warning.Diagnostic())
+	}
+	resp.result, resp.typ = session.interp.Display(res), res.ResultType.String()
+	return resp, nil
+}
+
+// checks a program like `go-basic check --types` does.
+func checkSource(source string) *checkResponse_t {
+	node, err := basic.Parse(source, "grpc")
+	if err != nil {
+		return &checkResponse_t{diagnostics: basic.Diagnostics(err)}
+	}
+	findings := basic.CheckTypes(node)
+	return &checkResponse_t{ok: len(findings) == 0, diagnostics: findings}
+}
+
+// reads the one request message of a unary call.
+func readUnary(body io.Reader, req interface{ unmarshal([]byte) error }) error {
+	data, err := readGRPCMessage(body)
+	if err == io.EOF {
+		return &grpcError_t{grpcInvalidArgument, "no request message"}
+	} else if err != nil {
+		return err
+	}
+	if err := req.unmarshal(data); err != nil {
+		return &grpcError_t{grpcInvalidArgument, err.Error()}
+	}
+	return nil
+}
+
+// reads the next message of a call, or io.EOF once the client has sent them all.
+func readGRPCMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err == io.EOF {
+		return nil, err
+	} else if err != nil {
+		return nil, &grpcError_t{grpcInternal, "reading request: " + err.Error()}
+	}
+	if prefix[0] != 0 {
+		return nil, &grpcError_t{grpcUnimplemented, "compressed messages aren't supported"}
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > grpcMaxMessage {
+		return nil, &grpcError_t{grpcResourceExhausted, fmt.Sprintf("message of %d bytes is over the limit of %d", size, grpcMaxMessage)}
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, &grpcError_t{grpcInternal, "reading request: " + err.Error()}
+	}
+	return data, nil
+}
+
+// sends one response message, straight away, since a client streaming programs is waiting on it.
+func writeGRPCMessage(w http.ResponseWriter, msg []byte) error {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	if _, err := w.Write(append(frame, msg...)); err != nil {
+		return err
+	}
+	w.(http.Flusher).Flush()
+	return nil
+}
+
+// parses a grpc-timeout header, like 100m for 100 milliseconds.
+func grpcTimeout(header string) (time.Duration, bool) {
+	units := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second, 'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond}
+	if len(header) < 2 || len(header) > 9 {
+		return 0, false
+	}
+	unit, ok := units[header[len(header)-1]]
+	n, err := strconv.ParseInt(header[:len(header)-1], 10, 64)
+	if !ok || err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// percent-encodes a grpc-message trailer, which must be printable ASCII.
+func grpcEscape(message string) string {
+	var sb strings.Builder
+	for i := 0; i < len(message); i++ {
+		if c := message[i]; c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// the messages in proto/basic.proto.
+
+type evaluateRequest_t struct {
+	source string
+}
+
+func (req *evaluateRequest_t) unmarshal(data []byte) error {
+	return readFields(data, func(field int, wireType int, _ uint64, value []byte) error {
+		if field == 1 && wireType == wireBytes {
+			req.source = string(value)
+		}
+		return nil
+	})
+}
+
+type checkRequest_t struct {
+	source string
+}
+
+func (req *checkRequest_t) unmarshal(data []byte) error {
+	return readFields(data, func(field int, wireType int, _ uint64, value []byte) error {
+		if field == 1 && wireType == wireBytes {
+			req.source = string(value)
+		}
+		return nil
+	})
+}
+
+type evaluateResponse_t struct {
+	result      string
+	typ         string
+	diagnostics []basic.Diagnostic_t
+	output      string
+	ok          bool
+}
+
+func (resp *evaluateResponse_t) marshal() []byte {
+	b := appendString(nil, 1, resp.result)
+	b = appendString(b, 2, resp.typ)
+	for _, diagnostic := range resp.diagnostics {
+		b = appendMessage(b, 3, marshalDiagnostic(diagnostic))
+	}
+	b = appendString(b, 4, resp.output)
+	return appendBool(b, 5, resp.ok)
+}
+
+type checkResponse_t struct {
+	ok          bool
+	diagnostics []basic.Diagnostic_t
+}
+
+func (resp *checkResponse_t) marshal() []byte {
+	b := appendBool(nil, 1, resp.ok)
+	for _, diagnostic := range resp.diagnostics {
+		b = appendMessage(b, 2, marshalDiagnostic(diagnostic))
+	}
+	return b
+}
+
+func marshalDiagnostic(diagnostic basic.Diagnostic_t) []byte {
+	b := appendString(nil, 1, diagnostic.Code)
+	b = appendString(b, 2, diagnostic.Severity)
+	b = appendString(b, 3, diagnostic.Message)
+	b = appendString(b, 4, diagnostic.Filename)
+	b = appendInt(b, 5, diagnostic.Line)
+	b = appendInt(b, 6, diagnostic.Col)
+	b = appendInt(b, 7, diagnostic.Offset)
+	return appendInt(b, 8, diagnostic.End)
+}
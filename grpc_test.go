@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go-basic/basic"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// starts the gRPC server over HTTP/2, the way gRPC clients reach it.
+func startGRPC(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(newGRPCServer(basic.Options_t{}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// a request or response message with its 5-byte prefix.
+func grpcFrame(msg []byte) []byte {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	return append(frame, msg...)
+}
+
+// starts a call of method, sending what's written to the pipe it gives back as the request body.
+func grpcStart(t *testing.T, server *httptest.Server, method string, header http.Header) (*io.PipeWriter, *http.Response) {
+	t.Helper()
+	body, send := io.Pipe()
+	req, err := http.NewRequest(http.MethodPost, server.URL+grpcService+method, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("got HTTP/%d, want HTTP/2", resp.ProtoMajor)
+	}
+	return send, resp
+}
+
+// makes a unary call, giving back the response message, if there was one, and the call's status.
+func grpcUnary(t *testing.T, server *httptest.Server, method string, header http.Header, msg []byte) ([]byte, string, string) {
+	t.Helper()
+	send, resp := grpcStart(t, server, method, header)
+	go func() {
+		send.Write(grpcFrame(msg))
+		send.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []byte
+	if len(body) > 0 {
+		if out, err = readGRPCMessage(bytes.NewReader(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return out, resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+}
+
+func decodeDiagnostic(t *testing.T, data []byte) basic.Diagnostic_t {
+	t.Helper()
+	var diagnostic basic.Diagnostic_t
+	strs := []*string{1: &diagnostic.Code, 2: &diagnostic.Severity, 3: &diagnostic.Message, 4: &diagnostic.Filename}
+	ints := []*int{5: &diagnostic.Line, 6: &diagnostic.Col, 7: &diagnostic.Offset, 8: &diagnostic.End}
+	err := readFields(data, func(field int, wireType int, varint uint64, value []byte) error {
+		if wireType == wireBytes && field < len(strs) {
+			*strs[field] = string(value)
+		} else if wireType == wireVarint && field < len(ints) {
+			*ints[field] = int(int32(varint))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return diagnostic
+}
+
+func decodeEvaluateResponse(t *testing.T, data []byte) evaluateResponse_t {
+	t.Helper()
+	var resp evaluateResponse_t
+	err := readFields(data, func(field int, wireType int, varint uint64, value []byte) error {
+		switch field {
+		case 1:
+			resp.result = string(value)
+		case 2:
+			resp.typ = string(value)
+		case 3:
+			resp.diagnostics = append(resp.diagnostics, decodeDiagnostic(t, value))
+		case 4:
+			resp.output = string(value)
+		case 5:
+			resp.ok = varint != 0
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestProtoWire(t *testing.T) {
+	msg := appendString(nil, 1, "héllo")
+	msg = appendInt(msg, 2, 300)
+	msg = appendInt(msg, 3, -1)
+	msg = appendBool(msg, 4, true)
+	msg = appendMessage(msg, 5, nil)
+	msg = appendInt(msg, 6, 0)          // left out, like proto3 does
+	msg = append(msg, 0x3d, 1, 2, 3, 4) // field 7, a fixed32 the reader doesn't know about
+	if !bytes.HasPrefix(msg, []byte{0x0a, 6, 'h', 0xc3, 0xa9, 'l', 'l', 'o', 0x10, 0xac, 0x02}) {
+		t.Fatalf("got % x", msg)
+	}
+
+	type field_t struct {
+		field, wireType int
+		varint          uint64
+		bytes           string
+	}
+	var got []field_t
+	err := readFields(msg, func(field int, wireType int, varint uint64, bytes []byte) error {
+		got = append(got, field_t{field, wireType, varint, string(bytes)})
+		return nil
+	})
+	want := []field_t{
+		{1, wireBytes, 0, "héllo"},
+		{2, wireVarint, 300, ""},
+		{3, wireVarint, 1<<64 - 1, ""},
+		{4, wireVarint, 1, ""},
+		{5, wireBytes, 0, ""},
+		{7, wireFixed32, 0x04030201, ""},
+	}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, %v, want %+v", got, err, want)
+	}
+
+	for _, bad := range [][]byte{{0x0a, 5, 'a'}, {0x10}, {0x10, 0xff}, {0x0b}, {0x00, 1}} {
+		if err := readFields(bad, func(int, int, uint64, []byte) error { return nil }); err == nil {
+			t.Errorf("% x: no error", bad)
+		}
+	}
+}
+
+func TestGRPCEvaluate(t *testing.T) {
+	server := startGRPC(t)
+	tests := []struct {
+		source string
+		want   evaluateResponse_t
+	}{
+		{"1 + 2 * 3", evaluateResponse_t{result: "7", typ: "int", ok: true}},
+		{`"a" + "b"`, evaluateResponse_t{result: "ab", typ: "string", ok: true}},
+		{"[1, 2]", evaluateResponse_t{result: "[1, 2]", typ: "list", ok: true}},
+	}
+	for _, test := range tests {
+		data, status, message := grpcUnary(t, server, "Evaluate", nil, appendString(nil, 1, test.source))
+		if status != "0" {
+			t.Fatalf("%q: got status %s: %s", test.source, status, message)
+		}
+		if got := decodeEvaluateResponse(t, data); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %+v, want %+v", test.source, got, test.want)
+		}
+	}
+
+	// a program's errors are diagnostics, and the call still succeeds
+	data, status, _ := grpcUnary(t, server, "Evaluate", nil, appendString(nil, 1, "1 +\nnope"))
+	got := decodeEvaluateResponse(t, data)
+	if status != "0" || got.ok || got.result != "" || len(got.diagnostics) == 0 || got.diagnostics[0].Severity != "error" {
+		t.Errorf("got status %s, %+v, want an error diagnostic", status, got)
+	}
+	data, _, _ = grpcUnary(t, server, "Evaluate", nil, appendString(nil, 1, "undefinedVariable + 1"))
+	if got := decodeEvaluateResponse(t, data); len(got.diagnostics) != 1 || got.diagnostics[0].Code != string(basic.ERR_UNDEFINED_VAR) {
+		t.Errorf("got %+v, want %s", got, basic.ERR_UNDEFINED_VAR)
+	}
+
+	// calls don't share an interpreter
+	grpcUnary(t, server, "Evaluate", nil, appendString(nil, 1, "x, y = [1, 2]"))
+	data, _, _ = grpcUnary(t, server, "Evaluate", nil, appendString(nil, 1, "x"))
+	if got := decodeEvaluateResponse(t, data); got.ok {
+		t.Errorf("got %+v, want x to be undefined", got)
+	}
+}
+
+func TestGRPCCheck(t *testing.T) {
+	server := startGRPC(t)
+	data, status, _ := grpcUnary(t, server, "Check", nil, appendString(nil, 1, "1 AS INT"))
+	if status != "0" || !bytes.Equal(data, []byte{0x08, 1}) {
+		t.Errorf("got status %s, % x, want ok", status, data)
+	}
+
+	for _, source := range []string{`"one" AS INT`, "1 +"} {
+		data, status, _ := grpcUnary(t, server, "Check", nil, appendString(nil, 1, source))
+		var ok bool
+		var diagnostics []basic.Diagnostic_t
+		readFields(data, func(field int, wireType int, varint uint64, value []byte) error {
+			if field == 1 {
+				ok = varint != 0
+			} else if field == 2 {
+				diagnostics = append(diagnostics, decodeDiagnostic(t, value))
+			}
+			return nil
+		})
+		if status != "0" || ok || len(diagnostics) == 0 || diagnostics[0].Message == "" {
+			t.Errorf("%q: got status %s, ok %v, %+v, want diagnostics", source, status, ok, diagnostics)
+		}
+	}
+}
+
+func TestGRPCStreamRepl(t *testing.T) {
+	server := startGRPC(t)
+	send, resp := grpcStart(t, server, "StreamRepl", nil)
+	steps := []struct {
+		source string
+		result string
+	}{
+		{"x, y = [20, 22]", ""},
+		{"x + y", "42"},
+		{"double, z = [LAMBDA(n, n * 2), 0]", ""},
+		{"double(x + y)", "84"},
+	}
+	for _, step := range steps {
+		if _, err := send.Write(grpcFrame(appendString(nil, 1, step.source))); err != nil {
+			t.Fatal(err)
+		}
+		data, err := readGRPCMessage(resp.Body)
+		if err != nil {
+			t.Fatalf("%q: %s", step.source, err)
+		}
+		got := decodeEvaluateResponse(t, data)
+		if !got.ok || (step.result != "" && got.result != step.result) {
+			t.Errorf("%q: got %+v, want %s", step.source, got, step.result)
+		}
+	}
+	send.Close()
+	if rest, _ := io.ReadAll(resp.Body); len(rest) > 0 || resp.Trailer.Get("Grpc-Status") != "0" {
+		t.Errorf("got %q and status %q after the stream ended, want nothing more and 0", rest, resp.Trailer.Get("Grpc-Status"))
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	server := startGRPC(t)
+
+	_, status, message := grpcUnary(t, server, "Nope", nil, nil)
+	if status != "12" || message == "" {
+		t.Errorf("unknown method: got status %s %q, want 12", status, message)
+	}
+
+	// a compressed message
+	send, resp := grpcStart(t, server, "Evaluate", nil)
+	go func() {
+		frame := grpcFrame(appendString(nil, 1, "1"))
+		frame[0] = 1
+		send.Write(frame)
+		send.Close()
+	}()
+	io.ReadAll(resp.Body)
+	if status := resp.Trailer.Get("Grpc-Status"); status != "12" {
+		t.Errorf("compressed message: got status %s, want 12", status)
+	}
+
+	// no message at all
+	send, resp = grpcStart(t, server, "Evaluate", nil)
+	send.Close()
+	io.ReadAll(resp.Body)
+	if status := resp.Trailer.Get("Grpc-Status"); status != "3" {
+		t.Errorf("no message: got status %s, want 3", status)
+	}
+
+	slow := "FOLD(LAMBDA(a, b, FOLD(LAMBDA(c, d, c + d), 0, RANGE(0, 10000))), 0, RANGE(0, 10000))"
+	_, status, _ = grpcUnary(t, server, "Evaluate", http.Header{"Grpc-Timeout": {"50m"}}, appendString(nil, 1, slow))
+	if status != "4" {
+		t.Errorf("slow program: got status %s, want 4", status)
+	}
+
+	resp, err := server.Client().Post(server.URL+grpcService+"Evaluate", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("JSON request: got HTTP status %d, want %d", resp.StatusCode, http.StatusUnsupportedMediaType)
+	}
+}
+
+func TestGRPCTimeoutHeader(t *testing.T) {
+	tests := map[string]bool{"100m": true, "2S": true, "1H": true, "": false, "5": false, "5x": false, "-1S": false, "123456789S": false}
+	for header, ok := range tests {
+		if _, got := grpcTimeout(header); got != ok {
+			t.Errorf("%q: got %v, want %v", header, got, ok)
+		}
+	}
+	if got := grpcEscape("bad\n100%"); got != "bad%0A100%25" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,614 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go-basic/basic"
+	"hash"
+	"image/png"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// a Jupyter kernel, so notebooks can run go-basic cells. Jupyter starts it with a connection
+// file naming five sockets: shell and control for requests, stdin for asking the user for
+// input, iopub for broadcasting what cells print, and a heartbeat that echoes what it's sent.
+// Every cell runs in the same interpreter, so later cells see what earlier ones defined.
+// See https://jupyter-client.readthedocs.io/en/stable/messaging.html.
+const (
+	kernelProtocol  = "5.3"
+	kernelMaxOutput = 1 << 20 // bytes of what one cell writes to each stream kept to send back
+	kernelDelimiter = "<IDS|MSG>"
+)
+
+// what a connection file says.
+type connection_t struct {
+	Transport       string `json:"transport"` // tcp or ipc
+	IP              string `json:"ip"`
+	Key             string `json:"key"`
+	SignatureScheme string `json:"signature_scheme"`
+	ShellPort       int    `json:"shell_port"`
+	ControlPort     int    `json:"control_port"`
+	StdinPort       int    `json:"stdin_port"`
+	IOPubPort       int    `json:"iopub_port"`
+	HBPort          int    `json:"hb_port"`
+}
+
+// the header of a message. A reply carries its request's header as its parent header.
+type messageHeader_t struct {
+	MsgID    string `json:"msg_id"`
+	Session  string `json:"session"`
+	Username string `json:"username"`
+	Date     string `json:"date"`
+	MsgType  string `json:"msg_type"`
+	Version  string `json:"version"`
+}
+
+// a message as it's received.
+type jupyterMessage_t struct {
+	identities [][]byte
+	header     messageHeader_t
+	rawHeader  json.RawMessage // the header as sent, to send back as the parent header of replies
+	content    json.RawMessage
+}
+
+type kernel_t struct {
+	conn    connection_t
+	sign    func() hash.Hash // nil if messages aren't signed
+	session string
+
+	shell, control, stdin, iopub, heartbeat *zmtpSocket_t
+
+	interp         *basic.Interpreter_t
+	stdout, stderr *cappedBuffer_t
+	execMu         sync.Mutex // cells run one at a time
+	executionCount int
+
+	cancelMu sync.Mutex
+	cancel   context.CancelFunc // stops the cell that's running, if there is one
+
+	done     chan struct{}
+	shutdown sync.Once
+}
+
+// `go-basic kernel CONNECTION_FILE`: runs as a Jupyter kernel until told to shut down.
+// `go-basic kernel --install` registers it with Jupyter.
+func kernelCommand(args []string, opts basic.Options_t) int {
+	flags := flag.NewFlagSet("kernel", flag.ExitOnError)
+	install := flags.Bool("install", false, "register the kernel with Jupyter for the current user, instead of running it")
+	flags.Parse(args)
+	if *install {
+		dir, err := installKernel()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "installed the go-basic kernel in %s\n", dir)
+		return 0
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: go-basic kernel [--install] [CONNECTION_FILE]")
+		return 2
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 2
+	}
+	var conn connection_t
+	if err := json.Unmarshal(data, &conn); err != nil {
+		fmt.Fprintf(os.Stderr, "Error! bad connection file: %s\n", err)
+		return 2
+	}
+	kernel, err := newKernel(conn, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 1
+	}
+	kernel.serve()
+	return 0
+}
+
+// constructor for the kernel. It listens on the sockets conn names straight away; a port of 0
+// picks a free one, and the ports it picked are filled in in its conn.
+func newKernel(conn connection_t, opts basic.Options_t) (*kernel_t, error) {
+	kernel := &kernel_t{conn: conn, session: newMessageID(), done: make(chan struct{})}
+	switch conn.SignatureScheme {
+	case "hmac-sha256", "":
+		if conn.Key != "" {
+			key := []byte(conn.Key)
+			kernel.sign = func() hash.Hash { return hmac.New(sha256.New, key) }
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signature scheme %q", conn.SignatureScheme)
+	}
+
+	sockets := []struct {
+		sock *(*zmtpSocket_t)
+		kind string
+		port *int
+	}{
+		{&kernel.shell, "ROUTER", &kernel.conn.ShellPort},
+		{&kernel.control, "ROUTER", &kernel.conn.ControlPort},
+		{&kernel.stdin, "ROUTER", &kernel.conn.StdinPort},
+		{&kernel.iopub, "PUB", &kernel.conn.IOPubPort},
+		{&kernel.heartbeat, "REP", &kernel.conn.HBPort},
+	}
+	for _, s := range sockets {
+		var err error
+		switch conn.Transport {
+		case "tcp", "":
+			*s.sock, err = listenZMTP(s.kind, "tcp", net.JoinHostPort(conn.IP, strconv.Itoa(*s.port)))
+			if err == nil {
+				*s.port = (*s.sock).Addr().(*net.TCPAddr).Port
+			}
+		case "ipc": // ZeroMQ's ipc sockets are Unix sockets named after the ip and port
+			*s.sock, err = listenZMTP(s.kind, "unix", fmt.Sprintf("%s-%d", conn.IP, *s.port))
+		default:
+			err = fmt.Errorf("unsupported transport %q", conn.Transport)
+		}
+		if err != nil {
+			kernel.close()
+			return nil, err
+		}
+	}
+
+	kernel.stdout = &cappedBuffer_t{limit: kernelMaxOutput}
+	kernel.stderr = &cappedBuffer_t{limit: kernelMaxOutput}
+	opts.Stdin, opts.Stdout, opts.Stderr = strings.NewReader(""), kernel.stdout, kernel.stderr
+	kernel.interp = basic.NewInterpreter(opts)
+	return kernel, nil
+}
+
+// answers requests until a shutdown_request, then closes the sockets.
+func (kernel *kernel_t) serve() {
+	defer kernel.close()
+	kernel.publish(nil, "status", map[string]string{"execution_state": "starting"})
+	go kernel.echo()
+	go kernel.listen(kernel.control)
+	kernel.listen(kernel.shell)
+}
+
+func (kernel *kernel_t) close() {
+	for _, sock := range []*zmtpSocket_t{kernel.shell, kernel.control, kernel.stdin, kernel.iopub, kernel.heartbeat} {
+		if sock != nil {
+			sock.Close()
+		}
+	}
+}
+
+// sends every heartbeat back as it is, so Jupyter knows the kernel is alive.
+func (kernel *kernel_t) echo() {
+	for {
+		msg, err := kernel.heartbeat.recv()
+		if err != nil {
+			return
+		}
+		kernel.heartbeat.send(msg)
+	}
+}
+
+// answers the requests that come in on sock until the kernel shuts down.
+func (kernel *kernel_t) listen(sock *zmtpSocket_t) {
+	for {
+		select {
+		case frames := <-sock.messages:
+			msg, err := kernel.parse(frames)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error! dropped a message: %s\n", err)
+				continue
+			}
+			kernel.handle(sock, msg)
+		case <-kernel.done:
+			return
+		}
+	}
+}
+
+// answers one request, telling iopub the kernel is busy until it's done.
+func (kernel *kernel_t) handle(sock *zmtpSocket_t, msg *jupyterMessage_t) {
+	kernel.publish(msg, "status", map[string]string{"execution_state": "busy"})
+	defer kernel.publish(msg, "status", map[string]string{"execution_state": "idle"})
+
+	switch msg.header.MsgType {
+	case "kernel_info_request":
+		kernel.reply(sock, msg, "kernel_info_reply", map[string]interface{}{
+			"status":                 "ok",
+			"protocol_version":       kernelProtocol,
+			"implementation":         "go-basic",
+			"implementation_version": buildVersion(),
+			"language_info": map[string]string{
+				"name":           "basic",
+				"version":        buildVersion(),
+				"mimetype":       "text/x-basic",
+				"file_extension": ".bas",
+			},
+			"banner":     "go-basic",
+			"help_links": []string{},
+		})
+	case "execute_request":
+		kernel.execute(sock, msg)
+	case "is_complete_request":
+		var content struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal(msg.content, &content)
+		reply := map[string]string{"status": isComplete(content.Code)}
+		if reply["status"] == "incomplete" {
+			reply["indent"] = ""
+		}
+		kernel.reply(sock, msg, "is_complete_reply", reply)
+	case "comm_info_request":
+		kernel.reply(sock, msg, "comm_info_reply", map[string]interface{}{"status": "ok", "comms": map[string]string{}})
+	case "history_request":
+		kernel.reply(sock, msg, "history_reply", map[string]interface{}{"status": "ok", "history": []string{}})
+	case "interrupt_request":
+		kernel.cancelMu.Lock()
+		if kernel.cancel != nil {
+			kernel.cancel()
+		}
+		kernel.cancelMu.Unlock()
+		kernel.reply(sock, msg, "interrupt_reply", map[string]string{"status": "ok"})
+	case "shutdown_request":
+		var content struct {
+			Restart bool `json:"restart"`
+		}
+		json.Unmarshal(msg.content, &content)
+		kernel.reply(sock, msg, "shutdown_reply", map[string]interface{}{"status": "ok", "restart": content.Restart})
+		kernel.cancelMu.Lock()
+		if kernel.cancel != nil {
+			kernel.cancel()
+		}
+		kernel.cancelMu.Unlock()
+		kernel.shutdown.Do(func() { close(kernel.done) })
+	default: // requests for things the kernel can't do, like completion, go unanswered
+	}
+}
+
+// runs a cell, publishing what it wrote, what it drew, and its result or error.
+// Silent cells don't count or publish anything but the reply.
+func (kernel *kernel_t) execute(sock *zmtpSocket_t, msg *jupyterMessage_t) {
+	var content struct {
+		Code         string `json:"code"`
+		Silent       bool   `json:"silent"`
+		StoreHistory *bool  `json:"store_history"`
+		StopOnError  *bool  `json:"stop_on_error"`
+	}
+	if err := json.Unmarshal(msg.content, &content); err != nil {
+		fmt.Fprintf(os.Stderr, "Error! bad execute_request: %s\n", err)
+		return
+	}
+	kernel.execMu.Lock()
+	defer kernel.execMu.Unlock()
+	if !content.Silent && (content.StoreHistory == nil || *content.StoreHistory) {
+		kernel.executionCount++
+	}
+	count := kernel.executionCount
+	if !content.Silent {
+		kernel.publish(msg, "execute_input", map[string]interface{}{"code": content.Code, "execution_count": count})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 { // --timeout, like for REPL inputs
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	}
+	kernel.cancelMu.Lock()
+	kernel.cancel = cancel
+	kernel.cancelMu.Unlock()
+	kernel.stdout.Reset()
+	kernel.stderr.Reset()
+	kernel.stdout.truncated, kernel.stderr.truncated = false, false
+	before := canvasPixels(kernel.interp)
+	res, err := kernel.interp.RunContext(ctx, content.Code, "cell")
+	kernel.cancelMu.Lock()
+	kernel.cancel = nil
+	kernel.cancelMu.Unlock()
+	cancel()
+
+	if err == nil {
+		for _, warning := range res.Warnings {
+			fmt.Fprintf(kernel.stderr, "Warning! %s\n", warning)
+		}
+	}
+	if !content.Silent {
+		for _, stream := range []struct {
+			name string
+			buf  *cappedBuffer_t
+		}{{"stdout", kernel.stdout}, {"stderr", kernel.stderr}} {
+			text := stream.buf.String()
+			if stream.buf.truncated {
+				text += "\n[output cut off]\n"
+			}
+			if text != "" {
+				kernel.publish(msg, "stream", map[string]string{"name": stream.name, "text": text})
+			}
+		}
+		if canvas := kernel.interp.Canvas(); canvas != nil && !bytes.Equal(before, canvas.Pix) {
+			var img bytes.Buffer
+			png.Encode(&img, canvas)
+			kernel.publish(msg, "display_data", map[string]interface{}{
+				"data": map[string]string{
+					"image/png":  base64.StdEncoding.EncodeToString(img.Bytes()),
+					"text/plain": fmt.Sprintf("<canvas %dx%d>", canvas.Rect.Dx(), canvas.Rect.Dy()),
+				},
+				"metadata": map[string]string{},
+			})
+		}
+	}
+
+	if err != nil {
+		ename, evalue, traceback := describeError(err, content.Code)
+		if !content.Silent {
+			kernel.publish(msg, "error", map[string]interface{}{"ename": ename, "evalue": evalue, "traceback": traceback})
+		}
+		kernel.reply(sock, msg, "execute_reply", map[string]interface{}{
+			"status":          "error",
+			"execution_count": count,
+			"ename":           ename,
+			"evalue":          evalue,
+			"traceback":       traceback,
+		})
+		if content.StopOnError == nil || *content.StopOnError {
+			kernel.abortQueued(sock)
+		}
+		return
+	}
+	if !content.Silent {
+		kernel.publish(msg, "execute_result", map[string]interface{}{
+			"execution_count": count,
+			"data":            map[string]string{"text/plain": kernel.interp.Display(res)},
+			"metadata":        map[string]string{},
+		})
+	}
+	kernel.reply(sock, msg, "execute_reply", map[string]interface{}{
+		"status":           "ok",
+		"execution_count":  count,
+		"user_expressions": map[string]string{},
+		"payload":          []string{},
+	})
+}
+
+// answers the cells already waiting on sock as aborted, since they may need what the cell that
+// failed should have done. Other requests waiting are answered as usual.
+func (kernel *kernel_t) abortQueued(sock *zmtpSocket_t) {
+	for {
+		select {
+		case frames := <-sock.messages:
+			msg, err := kernel.parse(frames)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error! dropped a message: %s\n", err)
+			} else if msg.header.MsgType == "execute_request" {
+				kernel.publish(msg, "status", map[string]string{"execution_state": "busy"})
+				kernel.reply(sock, msg, "execute_reply", map[string]string{"status": "aborted"})
+				kernel.publish(msg, "status", map[string]string{"execution_state": "idle"})
+			} else {
+				kernel.handle(sock, msg)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// copies what's on the canvas, to tell afterwards whether a cell drew anything.
+func canvasPixels(interp *basic.Interpreter_t) []byte {
+	if interp.Canvas() == nil {
+		return nil
+	}
+	return append([]byte(nil), interp.Canvas().Pix...)
+}
+
+// turns the error that stopped a cell into what Jupyter shows for it: a name, the code of the
+// first error; a value, its message; and a traceback, which has every error with the line of
+// the cell it's on and a caret under where it is.
+func describeError(err error, code string) (string, string, []string) {
+	diagnostics := basic.Diagnostics(err)
+	if len(diagnostics) == 0 {
+		return "Error", err.Error(), []string{err.Error()}
+	}
+	lines := strings.Split(code, "\n")
+	var traceback []string
+	for _, d := range diagnostics {
+		name := d.Code
+		if name == "" {
+			name = "Error"
+		}
+		traceback = append(traceback, fmt.Sprintf("\x1b[0;31m%s\x1b[0m line %d, col %d: %s", name, d.Line+1, d.Col+1, d.Message))
+		if d.Filename == "cell" && d.Line >= 0 && d.Line < len(lines) {
+			line := lines[d.Line]
+			col := d.Col
+			if col > len(line) {
+				col = len(line)
+			}
+			caret := strings.Map(func(r rune) rune { // keep tabs, so the caret lines up under them
+				if r == '\t' {
+					return r
+				}
+				return ' '
+			}, line[:col])
+			traceback = append(traceback, "    "+line, "    "+caret+"\x1b[0;31m^\x1b[0m")
+		}
+	}
+	name := diagnostics[0].Code
+	if name == "" {
+		name = "Error"
+	}
+	return name, diagnostics[0].Message, traceback
+}
+
+// tells whether code is a whole program, "complete"; a program with something still open,
+// like a '(' or a FOR EACH without its NEXT, "incomplete"; or wrong, "invalid". A console
+// asks, to know whether Enter runs the cell or starts a new line.
+func isComplete(code string) string {
+	_, err := basic.Parse(code, "cell")
+	if err == nil {
+		return "complete"
+	}
+	end := len(strings.TrimRight(code, " \t\r\n"))
+	for _, d := range basic.Diagnostics(err) {
+		switch basic.ErrorCode_t(d.Code) {
+		case basic.ERR_EXPECTED_RPAREN, basic.ERR_EXPECTED_RBRACKET, basic.ERR_EXPECTED_RBRACE, basic.ERR_EXPECTED_NEXT:
+			return "incomplete"
+		}
+		if d.Offset >= end {
+			return "incomplete"
+		}
+	}
+	return "invalid"
+}
+
+// sends a reply to a request back to the client that sent it.
+func (kernel *kernel_t) reply(sock *zmtpSocket_t, parent *jupyterMessage_t, msgType string, content interface{}) {
+	sock.send(kernel.message(parent.identities, parent, msgType, content))
+}
+
+// broadcasts a message on iopub. Its topic is its type, like ipykernel's.
+func (kernel *kernel_t) publish(parent *jupyterMessage_t, msgType string, content interface{}) {
+	kernel.iopub.send(kernel.message([][]byte{[]byte("kernel." + kernel.session + "." + msgType)}, parent, msgType, content))
+}
+
+// makes the frames of a message: the identities it's routed by, the delimiter, the signature,
+// then the header, the parent header, the metadata and the content, all in JSON.
+func (kernel *kernel_t) message(identities [][]byte, parent *jupyterMessage_t, msgType string, content interface{}) [][]byte {
+	header, _ := json.Marshal(messageHeader_t{
+		MsgID:    newMessageID(),
+		Session:  kernel.session,
+		Username: "kernel",
+		Date:     time.Now().UTC().Format("2006-01-02T15:04:05.000000Z"),
+		MsgType:  msgType,
+		Version:  kernelProtocol,
+	})
+	parentHeader := json.RawMessage("{}")
+	if parent != nil {
+		parentHeader = parent.rawHeader
+	}
+	body, err := json.Marshal(content)
+	if err != nil {
+		body = []byte("{}")
+	}
+	parts := [][]byte{header, parentHeader, []byte("{}"), body}
+	msg := append(append([][]byte(nil), identities...), []byte(kernelDelimiter), kernel.signature(parts))
+	return append(msg, parts...)
+}
+
+// the HMAC of a message's header, parent header, metadata and content, in hex, or nothing if
+// messages aren't signed.
+func (kernel *kernel_t) signature(parts [][]byte) []byte {
+	if kernel.sign == nil {
+		return nil
+	}
+	mac := kernel.sign()
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return []byte(hex.EncodeToString(mac.Sum(nil)))
+}
+
+// splits the frames of a request into a message, checking its signature. Frames after the
+// content are binary buffers, which no request the kernel answers has.
+func (kernel *kernel_t) parse(frames [][]byte) (*jupyterMessage_t, error) {
+	delimiter := -1
+	for i, frame := range frames {
+		if string(frame) == kernelDelimiter {
+			delimiter = i
+			break
+		}
+	}
+	if delimiter < 0 || len(frames) < delimiter+6 {
+		return nil, errors.New("not a Jupyter message")
+	}
+	parts := frames[delimiter+2 : delimiter+6]
+	if want := kernel.signature(parts); !hmac.Equal(frames[delimiter+1], want) {
+		return nil, errors.New("bad signature")
+	}
+	msg := &jupyterMessage_t{identities: frames[:delimiter], rawHeader: parts[0], content: parts[3]}
+	if err := json.Unmarshal(parts[0], &msg.header); err != nil {
+		return nil, fmt.Errorf("bad header: %s", err)
+	}
+	return msg, nil
+}
+
+// gets the version go-basic was built as, if it was built from a tagged module.
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
+// makes a random id for a message or session, in the form of a UUID.
+func newMessageID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40 // version 4
+	id[8] = id[8]&0x3f | 0x80
+	s := hex.EncodeToString(id[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// writes a kernelspec that starts this executable, where Jupyter looks for the current user's.
+func installKernel() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	dir, err := jupyterDataDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "kernels", "go-basic")
+	spec, _ := json.MarshalIndent(map[string]interface{}{
+		"argv":           []string{exe, "kernel", "{connection_file}"},
+		"display_name":   "go-basic",
+		"language":       "basic",
+		"interrupt_mode": "message",
+	}, "", "  ")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, os.WriteFile(filepath.Join(dir, "kernel.json"), append(spec, '\n'), 0644)
+}
+
+// gets the directory Jupyter keeps the current user's data in, kernelspecs among it.
+func jupyterDataDir() (string, error) {
+	if dir := os.Getenv("JUPYTER_DATA_DIR"); dir != "" {
+		return dir, nil
+	}
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("APPDATA"); dir != "" {
+			return filepath.Join(dir, "jupyter"), nil
+		}
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "Jupyter"), nil
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "jupyter"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "jupyter"), nil
+}
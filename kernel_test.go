@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go-basic/basic"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const kernelTestKey = "secret"
+
+// one side of a ZMTP connection, like a Jupyter client's DEALER, SUB or REQ socket.
+type zmtpPeerConn_t struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialZMTP(t *testing.T, port int, kind string, identity string) *zmtpPeerConn_t {
+	t.Helper()
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	peer := &zmtpPeerConn_t{conn: conn, r: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := zmtpHandshake(conn, peer.r, kind, map[string]string{"Identity": identity}); err != nil {
+		t.Fatal(err)
+	}
+	return peer
+}
+
+func (peer *zmtpPeerConn_t) send(t *testing.T, msg ...[]byte) {
+	t.Helper()
+	if err := writeMessage(peer.conn, msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (peer *zmtpPeerConn_t) recv(t *testing.T) [][]byte {
+	t.Helper()
+	var msg [][]byte
+	for {
+		flags, body, err := readFrame(peer.r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if flags&zmtpCommand != 0 {
+			continue
+		}
+		msg = append(msg, body)
+		if flags&zmtpMore == 0 {
+			return msg
+		}
+	}
+}
+
+// a kernel and a client connected to its shell, iopub and heartbeat.
+type kernelClient_t struct {
+	kernel           *kernel_t
+	shell, iopub, hb *zmtpPeerConn_t
+	session          string
+	key              string
+}
+
+func startKernel(t *testing.T) *kernelClient_t {
+	t.Helper()
+	kernel, err := newKernel(connection_t{Transport: "tcp", IP: "127.0.0.1", Key: kernelTestKey, SignatureScheme: "hmac-sha256"}, basic.Options_t{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan struct{})
+	go func() {
+		kernel.serve()
+		close(served)
+	}()
+	t.Cleanup(func() {
+		kernel.shutdown.Do(func() { close(kernel.done) })
+		<-served
+	})
+
+	client := &kernelClient_t{kernel: kernel, session: newMessageID(), key: kernelTestKey}
+	client.shell = dialZMTP(t, kernel.conn.ShellPort, "DEALER", "client")
+	client.iopub = dialZMTP(t, kernel.conn.IOPubPort, "SUB", "")
+	client.hb = dialZMTP(t, kernel.conn.HBPort, "REQ", "")
+	client.iopub.send(t, []byte{1})                      // subscribe to everything
+	for deadline := time.Now().Add(5 * time.Second); ; { // and wait until the kernel's seen it
+		kernel.iopub.mu.Lock()
+		subscribed := false
+		for _, peer := range kernel.iopub.peers {
+			subscribed = subscribed || len(peer.subscriptions) > 0
+		}
+		kernel.iopub.mu.Unlock()
+		if subscribed {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("the subscription never arrived")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return client
+}
+
+func (client *kernelClient_t) sign(parts [][]byte) []byte {
+	mac := hmac.New(sha256.New, []byte(client.key))
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return []byte(hex.EncodeToString(mac.Sum(nil)))
+}
+
+// sends a request on shell, giving back its msg_id.
+func (client *kernelClient_t) request(t *testing.T, msgType string, content interface{}) string {
+	t.Helper()
+	id := newMessageID()
+	header, _ := json.Marshal(messageHeader_t{MsgID: id, Session: client.session, Username: "test", MsgType: msgType, Version: kernelProtocol})
+	body, _ := json.Marshal(content)
+	parts := [][]byte{header, []byte("{}"), []byte("{}"), body}
+	client.shell.send(t, append([][]byte{[]byte(kernelDelimiter), client.sign(parts)}, parts...)...)
+	return id
+}
+
+// a message the kernel sent, decoded.
+type received_t struct {
+	header  messageHeader_t
+	parent  messageHeader_t
+	content map[string]interface{}
+}
+
+// decodes a message the kernel sent, checking its signature.
+func (client *kernelClient_t) decode(t *testing.T, frames [][]byte) received_t {
+	t.Helper()
+	for i, frame := range frames {
+		if string(frame) != kernelDelimiter {
+			continue
+		}
+		parts := frames[i+2:]
+		if len(parts) != 4 {
+			t.Fatalf("got %d frames after the signature, want 4", len(parts))
+		}
+		if string(frames[i+1]) != string(client.sign(parts)) {
+			t.Fatalf("bad signature %q", frames[i+1])
+		}
+		var msg received_t
+		if err := json.Unmarshal(parts[0], &msg.header); err != nil {
+			t.Fatal(err)
+		}
+		json.Unmarshal(parts[1], &msg.parent)
+		if err := json.Unmarshal(parts[3], &msg.content); err != nil {
+			t.Fatal(err)
+		}
+		if msg.header.Session != client.kernel.session || msg.header.MsgID == "" || msg.header.Date == "" {
+			t.Fatalf("bad header %+v", msg.header)
+		}
+		return msg
+	}
+	t.Fatalf("no delimiter in %q", frames)
+	return received_t{}
+}
+
+// reads the reply to the request with msg_id id.
+func (client *kernelClient_t) reply(t *testing.T, id string, msgType string) map[string]interface{} {
+	t.Helper()
+	msg := client.decode(t, client.shell.recv(t))
+	if msg.parent.MsgID != id || msg.header.MsgType != msgType {
+		t.Fatalf("got a %s to %s, want a %s to %s", msg.header.MsgType, msg.parent.MsgID, msgType, id)
+	}
+	return msg.content
+}
+
+// reads what the kernel published about the request with msg_id id, up to its going idle again.
+func (client *kernelClient_t) published(t *testing.T, id string) []received_t {
+	t.Helper()
+	var msgs []received_t
+	for {
+		frames := client.iopub.recv(t)
+		msg := client.decode(t, frames)
+		if msg.parent.MsgID != id {
+			continue
+		}
+		if topic := "kernel." + client.kernel.session + "." + msg.header.MsgType; string(frames[0]) != topic {
+			t.Fatalf("got topic %q, want %q", frames[0], topic)
+		}
+		msgs = append(msgs, msg)
+		if msg.header.MsgType == "status" && msg.content["execution_state"] == "idle" {
+			return msgs
+		}
+	}
+}
+
+// runs a cell, giving back its reply and what was published about it, by type.
+func (client *kernelClient_t) execute(t *testing.T, code string) (map[string]interface{}, map[string]map[string]interface{}) {
+	t.Helper()
+	id := client.request(t, "execute_request", map[string]interface{}{"code": code, "silent": false})
+	reply := client.reply(t, id, "execute_reply")
+	byType := make(map[string]map[string]interface{})
+	for _, msg := range client.published(t, id) {
+		if msg.header.MsgType == "stream" {
+			msg.header.MsgType += "/" + msg.content["name"].(string)
+		}
+		byType[msg.header.MsgType] = msg.content
+	}
+	return reply, byType
+}
+
+func TestKernelInfo(t *testing.T) {
+	client := startKernel(t)
+	id := client.request(t, "kernel_info_request", map[string]string{})
+	reply := client.reply(t, id, "kernel_info_reply")
+	language, _ := reply["language_info"].(map[string]interface{})
+	if reply["status"] != "ok" || reply["protocol_version"] != kernelProtocol || language["name"] != "basic" {
+		t.Errorf("got %v", reply)
+	}
+	states := []string{}
+	for _, msg := range client.published(t, id) {
+		states = append(states, msg.content["execution_state"].(string))
+	}
+	if strings.Join(states, " ") != "busy idle" {
+		t.Errorf("got states %v, want busy then idle", states)
+	}
+
+	client.hb.send(t, []byte{}, []byte("ping"))
+	if got := client.hb.recv(t); len(got) != 2 || string(got[1]) != "ping" {
+		t.Errorf("heartbeat: got %q", got)
+	}
+}
+
+func TestKernelExecute(t *testing.T) {
+	client := startKernel(t)
+
+	reply, published := client.execute(t, "x, y = [20, 22]")
+	if reply["status"] != "ok" || reply["execution_count"] != 1.0 {
+		t.Errorf("got %v", reply)
+	}
+	if input := published["execute_input"]; input["code"] != "x, y = [20, 22]" || input["execution_count"] != 1.0 {
+		t.Errorf("got execute_input %v", input)
+	}
+
+	// cells share the interpreter
+	reply, published = client.execute(t, "x + y")
+	result := published["execute_result"]
+	data, _ := result["data"].(map[string]interface{})
+	if reply["status"] != "ok" || result["execution_count"] != 2.0 || data["text/plain"] != "42" {
+		t.Errorf("got reply %v, result %v, want 42 as cell 2", reply, result)
+	}
+
+	// what the program writes goes out as a stream
+	_, published = client.execute(t, "PLOT(x, x, 0, 1)")
+	if text, _ := published["stream/stdout"]["text"].(string); !strings.Contains(text, "*") {
+		t.Errorf("got stdout %q, want a chart", text)
+	}
+
+	// and what it draws as a PNG
+	_, published = client.execute(t, "SCREEN 8, 8\nPSET 1, 1")
+	display, _ := published["display_data"]["data"].(map[string]interface{})
+	if png, _ := display["image/png"].(string); !strings.HasPrefix(png, "iVBORw0KGgo") {
+		t.Errorf("got display_data %v, want a PNG", published["display_data"])
+	}
+	if _, published = client.execute(t, "1"); published["display_data"] != nil {
+		t.Error("a cell that didn't draw showed the canvas again")
+	}
+
+	// silent cells aren't counted or published
+	id := client.request(t, "execute_request", map[string]interface{}{"code": "1", "silent": true})
+	if reply := client.reply(t, id, "execute_reply"); reply["execution_count"] != 5.0 {
+		t.Errorf("got %v, want the count left at 5", reply)
+	}
+	for _, msg := range client.published(t, id) {
+		if msg.header.MsgType != "status" {
+			t.Errorf("a silent cell published %s", msg.header.MsgType)
+		}
+	}
+}
+
+func TestKernelErrors(t *testing.T) {
+	client := startKernel(t)
+	reply, published := client.execute(t, "1 + 1\n2 * nope")
+	traceback, _ := reply["traceback"].([]interface{})
+	if reply["status"] != "error" || reply["ename"] != string(basic.ERR_UNDEFINED_VAR) || len(traceback) != 3 {
+		t.Fatalf("got %v", reply)
+	}
+	if traceback[1] != "    2 * nope" || traceback[2] != "        \x1b[0;31m^\x1b[0m" {
+		t.Errorf("got traceback %q, want the line with a caret under nope", traceback)
+	}
+	if published["error"]["evalue"] != reply["evalue"] || published["execute_result"] != nil {
+		t.Errorf("got %v, want the error published and no result", published)
+	}
+
+	// a cell waiting behind one that fails is aborted
+	client.kernel.execMu.Lock()
+	failing := client.request(t, "execute_request", map[string]interface{}{"code": "nope"})
+	waiting := client.request(t, "execute_request", map[string]interface{}{"code": "1"})
+	time.Sleep(50 * time.Millisecond) // so both are queued before the first runs
+	client.kernel.execMu.Unlock()
+	if reply := client.reply(t, failing, "execute_reply"); reply["status"] != "error" {
+		t.Errorf("got %v", reply)
+	}
+	if reply := client.reply(t, waiting, "execute_reply"); reply["status"] != "aborted" {
+		t.Errorf("got %v, want the cell aborted", reply)
+	}
+
+	// a message with a bad signature is dropped
+	client.key = "wrong"
+	client.request(t, "kernel_info_request", map[string]string{})
+	client.key = kernelTestKey
+	id := client.request(t, "kernel_info_request", map[string]string{})
+	client.reply(t, id, "kernel_info_reply")
+}
+
+func TestKernelIsComplete(t *testing.T) {
+	client := startKernel(t)
+	for code, want := range map[string]string{
+		"1 + 1":                  "complete",
+		"(1 + 2":                 "incomplete",
+		"[1, 2,":                 "incomplete",
+		"1 +":                    "incomplete",
+		"FOR EACH x IN [1, 2]\n": "incomplete",
+		"1 )":                    "invalid",
+		`"abc`:                   "invalid",
+	} {
+		id := client.request(t, "is_complete_request", map[string]string{"code": code})
+		if got := client.reply(t, id, "is_complete_reply"); got["status"] != want {
+			t.Errorf("%q: got %v, want %s", code, got, want)
+		}
+	}
+}
+
+func TestKernelShutdown(t *testing.T) {
+	client := startKernel(t)
+	id := client.request(t, "shutdown_request", map[string]bool{"restart": false})
+	if reply := client.reply(t, id, "shutdown_reply"); reply["status"] != "ok" {
+		t.Errorf("got %v", reply)
+	}
+	select {
+	case <-client.kernel.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the kernel didn't shut down")
+	}
+}
+
+func TestKernelInstall(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv("JUPYTER_DATA_DIR", dir)
+	defer os.Unsetenv("JUPYTER_DATA_DIR")
+	if _, err := installKernel(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "kernels", "go-basic", "kernel.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Argv []string `json:"argv"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil || len(spec.Argv) != 3 || spec.Argv[1] != "kernel" || spec.Argv[2] != "{connection_file}" {
+		t.Errorf("got %s", data)
+	}
+}
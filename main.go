@@ -2,23 +2,522 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"go-basic/basic"
+	"image"
+	"image/png"
+	"io"
 	"os"
+	"strings"
+	"time"
 )
 
+// longest each evaluation may run, from --timeout. 0 means no limit.
+var timeout time.Duration
+
+// where the command line writes each kind of output, so they can be told apart: piping
+// `go-basic run prog.bas > data.txt` only captures what the program itself wrote.
+type streams_t struct {
+	program     io.Writer // what the program writes, like PLOT's charts
+	results     io.Writer // the Result: of each program or input
+	diagnostics io.Writer // errors and warnings
+}
+
+// the REPL's streams: results with the prompts on stdout, and diagnostics on stderr.
+var replStreams = streams_t{program: os.Stdout, results: os.Stdout, diagnostics: os.Stderr}
+
+// run's streams: only the program's own output goes to stdout.
+var runStreams = streams_t{program: os.Stdout, results: os.Stderr, diagnostics: os.Stderr}
+
+// writes an error the way the command line reports them.
+func (streams streams_t) error(err error) {
+	fmt.Fprintf(streams.diagnostics, "Error! %s\n", err)
+}
+
+// writes the warnings and the result of a program or input.
+func (streams streams_t) result(interp *basic.Interpreter_t, res *basic.Result_t) {
+	for _, warning := range res.Warnings {
+		fmt.Fprintf(streams.diagnostics, "Warning! %s\n", warning)
+	}
+	fmt.Fprintln(streams.results, "Result: "+interp.Display(res))
+}
+
+// what --json prints for each line of input.
+type jsonOutput_t struct {
+	Result      interface{}          `json:"result"` // null if there was an error
+	Diagnostics []basic.Diagnostic_t `json:"diagnostics"`
+	Output      string               `json:"output,omitempty"` // what the program wrote, like PLOT's charts, where that isn't the terminal
+}
+
 func main() {
-	fmt.Print("Welcome to go-basic! Input command\n >")
+	strict := flag.Bool("strict", false, "strict mode: turn warnings about sloppy code into errors")
+	jsonOut := flag.Bool("json", false, "print each result and its diagnostics as a line of JSON")
+	tracePath := flag.String("trace", "", "write a JSON trace of every evaluation to this file, one line each")
+	rounding := flag.String("rounding", "half-up", "how ROUND breaks ties: half-up or half-even (banker's rounding)")
+	notation := flag.String("notation", "plain", "how results are printed: plain, engineering (4.7e3) or si (4.7k)")
+	radix := flag.String("radix", "decimal", "base ints are printed in: decimal, hex (0xff), binary (0b101) or octal (0o17)")
+	grouping := flag.Bool("grouping", false, "print results with thousands separators, like 1,234,567")
+	decimals := flag.Int("decimals", 0, "print floats with this many decimals, like 2.500000 for 6 (0 means as few as read back the same)")
+	noPrelude := flag.Bool("no-prelude", false, "don't load the standard prelude of BASIC helpers like SUM, MAX and CLAMP")
+	lang := flag.String("lang", "", "language of error messages, like de, es or fr (default from BASIC_LANG or LANG)")
+	transcriptPath := flag.String("transcript", "", "write every REPL input, output and error to this Markdown file, for handouts")
+	symbolic := flag.Bool("symbolic", false, "simplify expressions with undefined variables, like 2*(x+3) to 2 * x + 6, instead of failing")
+	flag.DurationVar(&timeout, "timeout", 0, "stop each program or REPL input that runs longer than this, like 2s (0 means no limit)")
+	flag.Usage = usage
+	flag.Parse()
+	opts := basic.Options_t{Strict: *strict, Grouping: *grouping, Decimals: *decimals, Symbolic: *symbolic, NoPrelude: *noPrelude, Locale: *lang}
+	var err error
+	if opts.Rounding, err = basic.ParseRoundingMode(*rounding); err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		os.Exit(2)
+	}
+	if opts.Notation, err = basic.ParseNotation(*notation); err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		os.Exit(2)
+	}
+	if opts.Radix, err = basic.ParseRadix(*radix); err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		os.Exit(2)
+	}
+	if *tracePath != "" {
+		traceFile, err := os.Create(*tracePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			os.Exit(2)
+		}
+		defer traceFile.Close()
+		opts.Tracer = basic.NewJSONTracer(traceFile)
+	}
+
+	switch flag.Arg(0) {
+	case "": // no command, start the REPL
+		streams := replStreams
+		var transcript *transcript_t
+		if *transcriptPath != "" {
+			if transcript, err = newTranscript(*transcriptPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+				os.Exit(2)
+			}
+			defer transcript.Close()
+			streams = transcript.tee(streams)
+		}
+		opts.Stdout, opts.Stderr = streams.program, streams.diagnostics
+		repl(basic.NewInterpreter(opts), *jsonOut, streams, transcript)
+	case "run":
+		os.Exit(runCommand(flag.Args()[1:], opts))
+	case "eq":
+		os.Exit(eqCommand(flag.Args()[1:]))
+	case "diff":
+		os.Exit(diffCommand(flag.Args()[1:]))
+	case "fmt":
+		os.Exit(fmtCommand(flag.Args()[1:]))
+	case "vet":
+		os.Exit(vetCommand(flag.Args()[1:]))
+	case "check":
+		os.Exit(checkCommand(flag.Args()[1:]))
+	case "tui":
+		os.Exit(tuiCommand(flag.Args()[1:], opts))
+	case "graph":
+		os.Exit(graphCommand(flag.Args()[1:], opts))
+	case "deps":
+		os.Exit(depsCommand(flag.Args()[1:]))
+	case "bundle":
+		os.Exit(bundleCommand(flag.Args()[1:], opts))
+	case "serve":
+		os.Exit(serveCommand(flag.Args()[1:], opts))
+	case "grpc":
+		os.Exit(grpcCommand(flag.Args()[1:], opts))
+	case "kernel":
+		os.Exit(kernelCommand(flag.Args()[1:], opts))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+}
+
+// prints the help text for the command line.
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: go-basic [flags] [command]")
+	fmt.Fprintln(os.Stderr, "with no command, starts the interactive prompt. commands:")
+	fmt.Fprintln(os.Stderr, "  run [--plugin P.so]... [--import-path DIR]... [--png FILE] [--svg FILE] [--sha256 HEX | --sha256-file SUMS] FILE")
+	fmt.Fprintln(os.Stderr, "                    run a program or bundle, loading builtin libraries from Go plugins first,")
+	fmt.Fprintln(os.Stderr, "                    and only if it has the SHA-256 given")
+	fmt.Fprintln(os.Stderr, "  eq EXPR1 EXPR2    check whether two expressions are equivalent")
+	fmt.Fprintln(os.Stderr, "  diff EXPR VAR     print the derivative of an expression with respect to a variable")
+	fmt.Fprintln(os.Stderr, "  fmt [-w] FILE...  print files in the canonical format (-w rewrites them instead)")
+	fmt.Fprintln(os.Stderr, "  vet [-config F] FILE...")
+	fmt.Fprintln(os.Stderr, "                    report suspicious code (rules are set in .basicvet.json by default)")
+	fmt.Fprintln(os.Stderr, "  check [--types] FILE...")
+	fmt.Fprintln(os.Stderr, "                    check files parse, and with --types that values match their AS annotations")
+	fmt.Fprintln(os.Stderr, "  graph [--with-values] EXPR")
+	fmt.Fprintln(os.Stderr, "                    print the parse tree as a Graphviz digraph, optionally with each node's value")
+	fmt.Fprintln(os.Stderr, "  deps [--dot] FILE print the NAME = EXPR lines of a file in dependency order, finding cycles")
+	fmt.Fprintln(os.Stderr, "  bundle [--import-path DIR]... FILE [-o OUT]")
+	fmt.Fprintln(os.Stderr, "                    package a program and the modules it IMPORTs into one .basx file for run")
+	fmt.Fprintln(os.Stderr, "  tui               show tokens, parse tree and result in panes that update as you type")
+	fmt.Fprintln(os.Stderr, "  serve [-addr A]   run the sandboxed web playground, with shareable permalinks")
+	fmt.Fprintln(os.Stderr, "  grpc [-addr A] -cert FILE -key FILE")
+	fmt.Fprintln(os.Stderr, "                    serve the Evaluate, Check and StreamRepl calls in proto/basic.proto over gRPC")
+	fmt.Fprintln(os.Stderr, "  kernel [--install] [CONNECTION_FILE]")
+	fmt.Fprintln(os.Stderr, "                    run as a Jupyter kernel, or with --install register it with Jupyter")
+	fmt.Fprintln(os.Stderr, "flags:")
+	flag.PrintDefaults()
+}
+
+// reads lines from stdin and runs each one, printing the result.
+// Results and prompts go to stdout, and errors and warnings to stderr.
+// `:copy` puts the last result on the clipboard and `:open FILE` runs a file as if it was typed in.
+// With a transcript, each input is recorded with what it wrote to streams.
+func repl(interp *basic.Interpreter_t, jsonOut bool, streams streams_t, transcript *transcript_t) {
+	prompt := func(input string) { // ends an input, asking for the next one
+		if transcript != nil {
+			if err := transcript.record(input); err != nil {
+				fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			}
+		}
+		if !jsonOut {
+			fmt.Fprint(os.Stdout, " >")
+		}
+	}
+	if !jsonOut {
+		fmt.Fprint(os.Stdout, "Welcome to go-basic! Input command\n >")
+	}
 	scanner := bufio.NewScanner(os.Stdin)
+	history := history_t{}
+	// the text of the last result, for :copy
+	last := ""
 	for scanner.Scan() { // use `for scanner.Scan()` to keep reading
-		input := scanner.Text()
-		res, err := basic.Run(input, "stdin")
+		input, fn := scanner.Text(), "stdin"
+		if handled, err := sessionCommand(interp, &history, input, streams.results); handled {
+			if err != nil {
+				streams.error(err)
+			}
+			prompt(input)
+			continue
+		}
+		if strings.HasPrefix(input, ":plot") {
+			if err := plotCommand(interp, strings.TrimPrefix(input, ":plot"), streams.results); err != nil {
+				streams.error(err)
+			}
+			prompt(input)
+			continue
+		}
+		if strings.HasPrefix(input, ":copy") {
+			if err := copyCommand(last, strings.TrimPrefix(input, ":copy")); err != nil {
+				streams.error(err)
+			}
+			prompt(input)
+			continue
+		}
+		command := input
+		if strings.HasPrefix(input, ":open") {
+			src, path, err := openCommand(strings.TrimPrefix(input, ":open"))
+			if err != nil {
+				streams.error(err)
+				prompt(command)
+				continue
+			}
+			input, fn = src, path
+		}
+		history.push(interp)
+		res, err := run(interp, input, fn)
+		if err == nil {
+			last = interp.Display(res)
+		}
+		if jsonOut {
+			printJSON(streams.results, res, err)
+		} else if err != nil {
+			streams.error(err)
+		} else {
+			streams.result(interp, res)
+		}
+		prompt(command)
+	}
+}
+
+// flag that can be given more than once, collecting every value.
+type stringList_t []string
+
+func (list *stringList_t) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList_t) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+// `go-basic run [--plugin P.so]... FILE`: runs a program and prints its result.
+// With --png or --svg, whatever the program drew with the graphics statements is saved too.
+// Only what the program writes goes to stdout; its result, errors and warnings go to stderr.
+// FILE can be a bundle made by `go-basic bundle`, which runs from the files packaged in it.
+func runCommand(args []string, opts basic.Options_t) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	var plugins stringList_t
+	flags.Var(&plugins, "plugin", "Go plugin with builtins to load before running (can be repeated)")
+	var importPaths stringList_t
+	flags.Var(&importPaths, "import-path", "directory to search for IMPORTed modules after the program's own (can be repeated)")
+	pngPath := flags.String("png", "", "save what the program draws to this PNG file")
+	svgPath := flags.String("svg", "", "save what the program draws to this SVG file")
+	wantSum := flags.String("sha256", "", "only run the file if its SHA-256 is this, in hex")
+	sumsPath := flags.String("sha256-file", "", "only run the file if its SHA-256 is the one this checksums file (in sha256sum's format) lists for it")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: go-basic run [--plugin P.so]... [--import-path DIR]... [--png FILE] [--svg FILE] [--sha256 HEX | --sha256-file SUMS] FILE")
+		return 2
+	}
+
+	for _, path := range plugins {
+		if err := basic.LoadPlugin(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			return 2
+		}
+	}
+
+	path := flags.Arg(0)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 2
+	}
+	if *sumsPath != "" {
+		if *wantSum, err = listedSHA256(*sumsPath, path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			return 2
+		}
+	}
+	if *wantSum != "" {
+		if err := checkSHA256(path, src, *wantSum); err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			return 2
+		}
+	}
+	opts.ImportPaths = importPaths
+	if isBundle(src) {
+		bundle, err := basic.ReadBundle(src)
 		if err != nil {
-			fmt.Printf("Error! %s\n", err.Error())
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			return 2
+		}
+		opts = bundle.Options(opts)
+		src, path = []byte(bundle.Files[bundle.Main]), bundle.Main
+	}
+	opts.Stdout, opts.Stderr = runStreams.program, runStreams.diagnostics
+	interp := basic.NewInterpreter(opts)
+	res, err := run(interp, string(src), path)
+	if err != nil {
+		runStreams.error(err)
+		return 1
+	}
+	runStreams.result(interp, res)
+	if *pngPath != "" && interp.Canvas() != nil {
+		if err := writePNG(*pngPath, interp.Canvas()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			return 2
+		}
+	}
+	if *svgPath != "" && interp.Canvas() != nil {
+		if err := os.WriteFile(*svgPath, []byte(interp.SVG()), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			return 2
+		}
+	}
+	return 0
+}
+
+// runs a program, cancelling it if it goes on longer than --timeout. Anything it printed
+// before being cancelled has already been written.
+func run(interp *basic.Interpreter_t, src string, fn string) (*basic.Result_t, error) {
+	if timeout <= 0 {
+		return interp.Run(src, fn)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return interp.RunContext(ctx, src, fn)
+}
+
+// saves an image as a PNG file.
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// `go-basic eq EXPR1 EXPR2`: prints whether the expressions are equivalent.
+// The exit status is 0 if they are, 1 if they aren't and 2 if something went wrong.
+func eqCommand(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: go-basic eq EXPR1 EXPR2")
+		return 2
+	}
+	same, err := basic.Equivalent(args[0], args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 2
+	}
+	if !same {
+		fmt.Println("not equivalent")
+		return 1
+	}
+	fmt.Println("equivalent")
+	return 0
+}
+
+// `go-basic diff EXPR VAR`: prints the simplified derivative of the expression.
+func diffCommand(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: go-basic diff EXPR VAR")
+		return 2
+	}
+	node, err := basic.Parse(args[0], "expr")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 2
+	}
+	ret, err := basic.Differentiate(node, args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 2
+	}
+	fmt.Println(basic.Format(ret))
+	return 0
+}
+
+// `go-basic fmt [-w] FILE...`: formats each file, printing the result or writing it back with -w.
+func fmtCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result back to the file instead of printing it")
+	flags.Parse(args)
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			status = 2
+			continue
+		}
+		node, err := basic.Parse(string(src), path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			status = 2
+			continue
+		}
+		out := basic.Format(node) + "\n"
+		if *write {
+			err = os.WriteFile(path, []byte(out), 0644)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+				status = 2
+			}
 		} else {
-			fmt.Println(res.String())
+			fmt.Print(out)
+		}
+	}
+	return status
+}
+
+// `go-basic vet [-config FILE] FILE...`: lints each file. The exit status is 1 if anything was flagged.
+func vetCommand(args []string) int {
+	flags := flag.NewFlagSet("vet", flag.ExitOnError)
+	configPath := flags.String("config", "", "JSON file with the lint settings (default .basicvet.json, if it exists)")
+	flags.Parse(args)
+
+	config := basic.DefaultLintConfig()
+	var err error
+	if *configPath != "" {
+		config, err = basic.LoadLintConfig(*configPath)
+	} else if _, statErr := os.Stat(".basicvet.json"); statErr == nil {
+		config, err = basic.LoadLintConfig(".basicvet.json")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 2
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			status = 2
+			continue
+		}
+		node, err := basic.Parse(string(src), path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			status = 2
+			continue
+		}
+		for _, finding := range basic.Lint(node, config) {
+			fmt.Printf("%s:%d:%d: %s %s\n", finding.Filename, finding.Line+1, finding.Col+1, finding.Code, finding.Message)
+			if status == 0 {
+				status = 1
+			}
 		}
-		fmt.Print(" >")
 	}
+	return status
+}
 
+// checks files without running them: that they parse, and with --types, that the values in
+// them match the types they're annotated with, as far as that can be told before they run.
+func checkCommand(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	types := flags.Bool("types", false, "check values against their AS annotations")
+	flags.Parse(args)
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			status = 2
+			continue
+		}
+		node, err := basic.Parse(string(src), path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+			status = 2
+			continue
+		} else if !*types {
+			continue
+		}
+		for _, finding := range basic.CheckTypes(node) {
+			fmt.Printf("%s:%d:%d: %s %s\n", finding.Filename, finding.Line+1, finding.Col+1, finding.Code, finding.Message)
+			if status == 0 {
+				status = 1
+			}
+		}
+	}
+	return status
+}
+
+// prints the outcome of one evaluation as a line of JSON.
+func printJSON(out io.Writer, res *basic.Result_t, err error) {
+	line, _ := json.Marshal(newJSONOutput(res, err))
+	fmt.Fprintln(out, string(line))
+}
+
+// builds the JSON form of a result, or of the error that stopped it.
+func newJSONOutput(res *basic.Result_t, err error) jsonOutput_t {
+	out := jsonOutput_t{Diagnostics: []basic.Diagnostic_t{}}
+	if err != nil {
+		out.Diagnostics = basic.Diagnostics(err)
+	} else {
+		for _, warning := range res.Warnings {
+			out.Diagnostics = append(out.Diagnostics, warning.Diagnostic())
+		}
+		out.Result = res.Interface()
+	}
+	return out
 }
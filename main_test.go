@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+// runs a command with os.Stdout and os.Stderr captured, giving back its exit status and what it
+// wrote to each.
+func captured(t *testing.T, command func([]string) int, args ...string) (int, string, string) {
+	t.Helper()
+	files := [2]*os.File{}
+	for i := range files {
+		f, err := os.CreateTemp(t.TempDir(), "out")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files[i] = f
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = files[0], files[1]
+	status := command(args)
+	os.Stdout, os.Stderr = stdout, stderr
+
+	out := [2]string{}
+	for i, f := range files {
+		f.Seek(0, io.SeekStart)
+		data, _ := io.ReadAll(f)
+		out[i] = string(data)
+	}
+	return status, out[0], out[1]
+}
+
+func TestDiffCommand(t *testing.T) {
+	if status, stdout, stderr := captured(t, diffCommand, "x * x", "x"); status != 0 || stdout == "" || stderr != "" {
+		t.Errorf("diff x * x: got status %d, stdout %q, stderr %q", status, stdout, stderr)
+	}
+	for _, args := range [][]string{{"x *", "x"}, {"FOO(x)", "x"}} {
+		if status, stdout, stderr := captured(t, diffCommand, args...); status != 2 || stdout != "" || stderr == "" {
+			t.Errorf("diff %q: got status %d, stdout %q, stderr %q; want the error on stderr", args, status, stdout, stderr)
+		}
+	}
+}
+
+func TestEqCommandErrors(t *testing.T) {
+	if status, stdout, stderr := captured(t, eqCommand, "FOO(1)", "BAR(1)"); status == 0 || stdout != "" || stderr == "" {
+		t.Errorf("eq FOO(1) BAR(1): got status %d, stdout %q, stderr %q; want the error on stderr", status, stdout, stderr)
+	}
+}
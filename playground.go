@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-basic/basic"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// limits for code run by the playground. Every request gets a fresh interpreter, and
+// plugins are never loaded, so programs can't reach anything outside the interpreter.
+const (
+	playgroundMaxSource   = 16 << 10 // bytes
+	playgroundMaxTokens   = 4096
+	playgroundMaxSteps    = 100000
+	playgroundMaxList     = 100000   // elements in any list a program makes
+	playgroundMaxString   = 1 << 20  // bytes in any string a program makes
+	playgroundMaxAlloc    = 64 << 20 // bytes of strings and lists one run may make in all
+	playgroundMaxOutput   = 64 << 10 // bytes of what a program writes, like PLOT's charts, kept to send back
+	playgroundTimeout     = time.Second
+	playgroundMaxSnippets = 10000 // shared programs kept in memory before the oldest are dropped
+	playgroundRate        = 2     // runs per second allowed from one address, on average
+	playgroundBurst       = 10    // runs one address may make at once before being slowed down
+)
+
+// what the playground stores behind a permalink: the program and what it printed.
+type snippet_t struct {
+	Source string       `json:"source"`
+	Output jsonOutput_t `json:"output"`
+}
+
+// the playground HTTP server.
+type playground_t struct {
+	opts    basic.Options_t
+	limiter *rateLimiter_t
+
+	mu       sync.Mutex
+	snippets map[string]*snippet_t
+	order    []string // permalink ids, oldest first
+}
+
+// runs the playground on addr until it fails.
+func serveCommand(args []string, opts basic.Options_t) int {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	flags.Parse(args)
+
+	server := &http.Server{
+		Addr:           *addr,
+		Handler:        newPlayground(opts),
+		ReadTimeout:    10 * time.Second,
+		WriteTimeout:   10 * time.Second,
+		MaxHeaderBytes: 8 << 10,
+	}
+	fmt.Fprintf(os.Stderr, "playground listening on http://%s\n", *addr)
+	if err := server.ListenAndServe(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// constructor for the playground. The limits above override any set in opts.
+func newPlayground(opts basic.Options_t) *playground_t {
+	opts.MaxSourceBytes = playgroundMaxSource
+	opts.MaxTokens = playgroundMaxTokens
+	opts.MaxSteps = playgroundMaxSteps
+	opts.Timeout = playgroundTimeout
+	opts.MaxListLength = playgroundMaxList
+	opts.MaxStringLength = playgroundMaxString
+	opts.MaxAllocated = playgroundMaxAlloc
+	opts.DisableImports = true                           // programs come from anyone, so they mustn't read the server's files
+	opts.Permissions = &basic.Permissions_t{Clock: true} // or its environment, or the network
+	return &playground_t{
+		opts:     opts,
+		limiter:  newRateLimiter(playgroundRate, playgroundBurst),
+		snippets: make(map[string]*snippet_t),
+	}
+}
+
+// routes:
+//
+//	GET  /            the editor page
+//	GET  /p/ID        the editor page, loaded with a shared program
+//	POST /api/run     runs {"source": ...} and returns its output
+//	POST /api/share   runs {"source": ...}, stores it and returns {"id": ..., "output": ...}
+//	GET  /api/p/ID    the stored program and output behind a permalink
+func (pg *playground_t) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// the API is meant to be called from course pages on other sites
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/" || strings.HasPrefix(path, "/p/"):
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		playgroundPage.Execute(w, nil)
+	case path == "/api/run" || path == "/api/share":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !pg.limiter.allow(clientAddr(r), time.Now()) {
+			http.Error(w, "too many requests, slow down", http.StatusTooManyRequests)
+			return
+		}
+		source, err := readSource(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		snippet := &snippet_t{Source: source, Output: pg.run(source)}
+		if path == "/api/run" {
+			writeJSON(w, snippet.Output)
+			return
+		}
+		writeJSON(w, struct {
+			ID     string       `json:"id"`
+			Output jsonOutput_t `json:"output"`
+		}{pg.share(snippet), snippet.Output})
+	case strings.HasPrefix(path, "/api/p/"):
+		pg.mu.Lock()
+		snippet, ok := pg.snippets[strings.TrimPrefix(path, "/api/p/")]
+		pg.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, snippet)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// runs a program with its own Stdout and Stderr, so what it writes, like PLOT's charts or
+// BEEP's bell, is sent back with its result rather than written on the server's terminal.
+func (pg *playground_t) run(source string) jsonOutput_t {
+	opts := pg.opts
+	output := &cappedBuffer_t{limit: playgroundMaxOutput}
+	opts.Stdin, opts.Stdout, opts.Stderr = strings.NewReader(""), output, output
+	ret := newJSONOutput(basic.NewInterpreter(opts).Run(source, "playground"))
+	ret.Output = output.String()
+	if output.truncated {
+		ret.Output += "\n[output cut off]\n"
+	}
+	return ret
+}
+
+// a buffer that keeps the first limit bytes written to it and drops the rest.
+type cappedBuffer_t struct {
+	bytes.Buffer
+	limit     int
+	truncated bool // whether anything was dropped
+}
+
+func (buf *cappedBuffer_t) Write(p []byte) (int, error) {
+	if room := buf.limit - buf.Len(); len(p) > room {
+		buf.Buffer.Write(p[:room])
+		buf.truncated = true
+		return len(p), nil
+	}
+	return buf.Buffer.Write(p)
+}
+
+// stores a snippet and returns its permalink id. The id comes from the source, so sharing
+// the same program twice gives the same link.
+func (pg *playground_t) share(snippet *snippet_t) string {
+	sum := sha256.Sum256([]byte(snippet.Source))
+	id := hex.EncodeToString(sum[:])[:12]
+
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	if _, ok := pg.snippets[id]; !ok {
+		pg.order = append(pg.order, id)
+	}
+	pg.snippets[id] = snippet
+	for len(pg.order) > playgroundMaxSnippets {
+		delete(pg.snippets, pg.order[0])
+		pg.order = pg.order[1:]
+	}
+	return id
+}
+
+// reads the {"source": ...} body of an API request, refusing anything too big.
+func readSource(w http.ResponseWriter, r *http.Request) (string, error) {
+	var body struct {
+		Source string `json:"source"`
+	}
+	// leave room for JSON escaping, the interpreter enforces the real limit on the source
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 2*playgroundMaxSource))
+	if err != nil {
+		return "", fmt.Errorf("request body too large")
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return "", fmt.Errorf("bad request body: %s", err)
+	}
+	return body.Source, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// gets the address a request came from, without the port.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// a token bucket per client address. A bucket that's been idle long enough to fill up again is
+// no different from a new one, so those are swept away, and there are only ever buckets for the
+// addresses seen in the last few seconds.
+type rateLimiter_t struct {
+	mu        sync.Mutex
+	rate      float64 // tokens added per second
+	burst     float64 // most tokens a bucket can hold
+	buckets   map[string]*bucket_t
+	lastSweep time.Time
+}
+
+type bucket_t struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst float64) *rateLimiter_t {
+	return &rateLimiter_t{rate: rate, burst: burst, buckets: make(map[string]*bucket_t)}
+}
+
+// takes a token from addr's bucket, returning false if it's empty.
+func (limiter *rateLimiter_t) allow(addr string, now time.Time) bool {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	limiter.sweep(now)
+	b, ok := limiter.buckets[addr]
+	if !ok {
+		b = &bucket_t{tokens: limiter.burst, last: now}
+		limiter.buckets[addr] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * limiter.rate
+	if b.tokens > limiter.burst {
+		b.tokens = limiter.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// forgets the buckets that have filled up again since they were last used, at most once
+// every time it takes one to fill.
+func (limiter *rateLimiter_t) sweep(now time.Time) {
+	refill := time.Duration(limiter.burst / limiter.rate * float64(time.Second))
+	if now.Sub(limiter.lastSweep) < refill {
+		return
+	}
+	limiter.lastSweep = now
+	for addr, b := range limiter.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(limiter.buckets, addr)
+		}
+	}
+}
+
+// the editor page. It loads the program behind /p/ID if there is one.
+var playgroundPage = template.Must(template.New("playground").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>go-basic playground</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+textarea { width: 100%; height: 12em; font-family: monospace; }
+pre { background: #f4f4f4; padding: 0.5em; }
+</style>
+</head>
+<body>
+<textarea id="source" spellcheck="false">1 + 2 * 3</textarea>
+<p><button id="run">Run</button> <button id="share">Share</button> <span id="link"></span></p>
+<pre id="output"></pre>
+<script>
+const source = document.getElementById("source");
+const output = document.getElementById("output");
+const link = document.getElementById("link");
+
+function show(out) {
+	let lines = out.output ? [out.output.replace(/\n$/, "")] : [];
+	lines = lines.concat(out.diagnostics.map(d => d.severity + " " + d.code + " line " + (d.line + 1) + ", col " + (d.col + 1) + ": " + d.message));
+	if (out.result !== null) {
+		lines.push("Result: " + out.result);
+	}
+	output.textContent = lines.join("\n");
+	if (out.diagnostics.length > 0) { // put the cursor where the first problem is
+		const at = charIndex(out.diagnostics[0].offset);
+		source.setSelectionRange(at, at);
+	}
+}
+
+// turns a byte offset into the UTF-8 source into an index into the textarea's text.
+function charIndex(offset) {
+	const bytes = new TextEncoder().encode(source.value).slice(0, Math.max(offset, 0));
+	return new TextDecoder().decode(bytes).length;
+}
+
+async function call(path) {
+	const resp = await fetch(path, {method: "POST", headers: {"Content-Type": "application/json"}, body: JSON.stringify({source: source.value})});
+	if (!resp.ok) {
+		output.textContent = await resp.text();
+		return null;
+	}
+	return resp.json();
+}
+
+document.getElementById("run").onclick = async () => {
+	const out = await call("/api/run");
+	if (out) show(out);
+};
+
+document.getElementById("share").onclick = async () => {
+	const res = await call("/api/share");
+	if (!res) return;
+	show(res.output);
+	const url = location.origin + "/p/" + res.id;
+	history.replaceState(null, "", url);
+	link.textContent = url;
+};
+
+if (location.pathname.startsWith("/p/")) {
+	fetch("/api" + location.pathname).then(resp => resp.ok ? resp.json() : null).then(snippet => {
+		if (!snippet) {
+			output.textContent = "no program was shared at this link";
+			return;
+		}
+		source.value = snippet.source;
+		show(snippet.output);
+	});
+}
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"go-basic/basic"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// posts source to the playground's /api/run and decodes what it gives back.
+func playgroundRun(t *testing.T, pg *playground_t, source string) jsonOutput_t {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"source": source})
+	req := httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(string(body)))
+	resp := httptest.NewRecorder()
+	pg.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("%q: got status %d: %s", source, resp.Code, resp.Body.String())
+	}
+	var out jsonOutput_t
+	if err := json.Unmarshal(resp.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%q: bad response: %s", source, err)
+	}
+	return out
+}
+
+func TestPlaygroundMemoryLimits(t *testing.T) {
+	tests := []struct {
+		source string
+		code   string
+	}{
+		{`LEN(FOLD(LAMBDA(a, b, a + a), [1], RANGE(0, 24)))`, string(basic.ERR_LIST_LIMIT)},
+		{`LEN(FOLD(LAMBDA(a, b, a + a), "x", RANGE(0, 40)))`, string(basic.ERR_MEMORY_LIMIT)},
+		{`x, y = [RANGE(0, 90000), 0]` + "\n" + `LEN(MAP(LAMBDA(i, x + [i]), RANGE(0, 1000)))`, string(basic.ERR_MEMORY_LIMIT)},
+	}
+	for _, test := range tests {
+		start := time.Now()
+		out := playgroundRun(t, newPlayground(basic.Options_t{}), test.source)
+		if len(out.Diagnostics) == 0 || out.Diagnostics[0].Code != test.code {
+			t.Errorf("%q: got %+v, want error %s", test.source, out, test.code)
+		} else if elapsed := time.Since(start); elapsed > playgroundTimeout {
+			t.Errorf("%q: took %s to fail", test.source, elapsed)
+		}
+	}
+}
+
+func TestRateLimiterForgetsIdleAddresses(t *testing.T) {
+	limiter := newRateLimiter(playgroundRate, playgroundBurst)
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		limiter.allow(strings.Repeat("a", i%50)+string(rune('0'+i%10)), start)
+	}
+	if !limiter.allow("b", start) {
+		t.Fatal("a new address was refused")
+	}
+	for i := 0; i < playgroundBurst; i++ {
+		limiter.allow("b", start)
+	}
+	if limiter.allow("b", start) {
+		t.Error("an address was allowed past its burst")
+	}
+
+	later := start.Add(time.Minute)
+	if !limiter.allow("c", later) {
+		t.Fatal("a new address was refused")
+	}
+	if len(limiter.buckets) != 1 {
+		t.Errorf("got %d buckets after every other address went idle, want 1", len(limiter.buckets))
+	}
+	if !limiter.allow("b", later) {
+		t.Error("an idle address wasn't allowed again")
+	}
+}
+
+func TestPlaygroundSendsBackOutput(t *testing.T) {
+	pg := newPlayground(basic.Options_t{})
+	out := playgroundRun(t, pg, `PLOT(x, x, 0, 1)`)
+	if len(out.Diagnostics) != 0 {
+		t.Fatalf("got %+v", out.Diagnostics)
+	} else if !strings.Contains(out.Output, "*") {
+		t.Errorf("got output %q, want the chart", out.Output)
+	}
+	if out := playgroundRun(t, pg, "BEEP"); out.Output != "\a" {
+		t.Errorf("got output %q, want the bell", out.Output)
+	}
+	if out := playgroundRun(t, pg, "1 + 1"); out.Output != "" {
+		t.Errorf("got output %q from a program that writes nothing", out.Output)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"go-basic/basic"
+	"io"
+	"os"
+	"strings"
+)
+
+// size of the images written by :plot --out.
+const (
+	pngWidth  = 640
+	pngHeight = 400
+)
+
+// runs the REPL's `:plot EXPR, VAR=LO..HI [--out FILE.png]` command, printing an ASCII
+// chart of EXPR to stdout, or writing a PNG if --out is given. LO and HI can be expressions.
+func plotCommand(interp *basic.Interpreter_t, args string, stdout io.Writer) error {
+	out := ""
+	if i := strings.Index(args, "--out"); i >= 0 {
+		out = strings.TrimSpace(args[i+len("--out"):])
+		args = args[:i]
+		if out == "" {
+			return fmt.Errorf("--out needs a file name")
+		}
+	}
+
+	comma := strings.LastIndex(args, ",")
+	if comma < 0 {
+		return fmt.Errorf("usage: :plot EXPR, VAR=LO..HI [--out FILE.png]")
+	}
+	expr, rangeSpec := args[:comma], args[comma+1:]
+	eq := strings.Index(rangeSpec, "=")
+	dots := strings.Index(rangeSpec, "..")
+	if eq < 0 || dots < eq {
+		return fmt.Errorf("expected a range like x=-10..10, got %q", strings.TrimSpace(rangeSpec))
+	}
+	variable := strings.TrimSpace(rangeSpec[:eq])
+	lo, err := interp.Run(rangeSpec[eq+1:dots], "plot")
+	if err != nil {
+		return err
+	}
+	hi, err := interp.Run(rangeSpec[dots+2:], "plot")
+	if err != nil {
+		return err
+	}
+	if !lo.IsNumber() || !hi.IsNumber() {
+		return fmt.Errorf("the ends of the range have to be numbers")
+	}
+
+	if out == "" {
+		plot, err := interp.Plot(expr, variable, lo.Fres, hi.Fres, basic.PLOT_WIDTH)
+		if err != nil {
+			return err
+		}
+		io.WriteString(stdout, plot.ASCII(basic.PLOT_HEIGHT))
+		return nil
+	}
+	plot, err := interp.Plot(expr, variable, lo.Fres, hi.Fres, pngWidth)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := plot.PNG(file, pngHeight); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "wrote %s\n", out)
+	return nil
+}
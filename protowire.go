@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// protobuf's wire format, the little of it the gRPC service needs: varints, for ints and bools,
+// and length-delimited fields, for strings and messages. Fields left at their zero value aren't
+// written, the way proto3 does it. See https://protobuf.dev/programming-guides/encoding/.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// adds a varint to b: 7 bits a byte, lowest first, with the top bit set on all but the last.
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// adds the key of a field: its number and wire type.
+func appendTag(b []byte, field int, wireType int) []byte {
+	return appendVarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+// adds a string field, unless it's empty.
+func appendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = appendTag(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// adds an int32 field, unless it's 0. Negative numbers take ten bytes, like in any int32 field.
+func appendInt(b []byte, field int, v int) []byte {
+	if v == 0 {
+		return b
+	}
+	return appendVarint(appendTag(b, field, wireVarint), uint64(int64(int32(v))))
+}
+
+// adds a bool field, unless it's false.
+func appendBool(b []byte, field int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return appendVarint(appendTag(b, field, wireVarint), 1)
+}
+
+// adds a message field, even an empty one, since it's an element of a repeated field.
+func appendMessage(b []byte, field int, msg []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
+
+// error for a message that isn't valid protobuf.
+var errBadMessage = errors.New("message isn't valid protobuf")
+
+// reads a varint from the start of data, giving back how many bytes it took.
+func readVarint(data []byte) (uint64, int, error) {
+	var v uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		v |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i] < 0x80 {
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, errBadMessage
+}
+
+// calls fn with each field of a message in turn: its number and wire type, and its value,
+// which is in varint for varints and in bytes for length-delimited fields. Fixed-size fields
+// are passed in varint too. Stops at the first error fn returns.
+func readFields(data []byte, fn func(field int, wireType int, varint uint64, bytes []byte) error) error {
+	for len(data) > 0 {
+		key, n, err := readVarint(data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+		field, wireType := int(key>>3), int(key&7)
+		if field == 0 {
+			return errBadMessage
+		}
+		var varint uint64
+		var bytes []byte
+		switch wireType {
+		case wireVarint:
+			if varint, n, err = readVarint(data); err != nil {
+				return err
+			}
+		case wireFixed64, wireFixed32:
+			n = 8
+			if wireType == wireFixed32 {
+				n = 4
+			}
+			if len(data) < n {
+				return errBadMessage
+			} else if n == 8 {
+				varint = binary.LittleEndian.Uint64(data)
+			} else {
+				varint = uint64(binary.LittleEndian.Uint32(data))
+			}
+		case wireBytes:
+			length, m, err := readVarint(data)
+			if err != nil {
+				return err
+			} else if length > uint64(len(data)-m) {
+				return errBadMessage
+			}
+			bytes, n = data[m:m+int(length)], m+int(length)
+		default: // groups, which proto3 doesn't have
+			return fmt.Errorf("%w: field %d has wire type %d", errBadMessage, field, wireType)
+		}
+		data = data[n:]
+		if err := fn(field, wireType, varint, bytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
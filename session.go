@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-basic/basic"
+	"io"
+	"os"
+	"strings"
+)
+
+// most inputs :undo can go back through.
+const maxUndo = 100
+
+// the REPL's variables before each input, most recent last, for :undo.
+type history_t []*basic.Snapshot_t
+
+// remembers the variables before an input runs. Variables that can't be saved, like functions
+// from a plugin made with NewFunction, just mean there's nothing to undo to.
+func (history *history_t) push(interp *basic.Interpreter_t) {
+	snap, err := interp.Snapshot()
+	if err != nil {
+		*history = nil
+		return
+	}
+	*history = append(*history, snap)
+	if len(*history) > maxUndo {
+		*history = (*history)[1:]
+	}
+}
+
+// runs the REPL's `:undo`, `:save FILE`, `:load FILE` and `:help [NAME]` commands, returning
+// false if input isn't one of them. :save writes the variables as JSON, and :load reads them
+// back, in this session or another one. :help says what HELP does about NAME, or lists the
+// builtins without one, writing to out.
+func sessionCommand(interp *basic.Interpreter_t, history *history_t, input string, out io.Writer) (bool, error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	switch fields[0] {
+	case ":undo":
+		if len(*history) == 0 {
+			return true, fmt.Errorf("nothing to undo")
+		}
+		snap := (*history)[len(*history)-1]
+		*history = (*history)[:len(*history)-1]
+		return true, interp.Restore(snap)
+	case ":save":
+		if len(fields) != 2 {
+			return true, fmt.Errorf("usage: :save FILE")
+		}
+		snap, err := interp.Snapshot()
+		if err != nil {
+			return true, err
+		}
+		data, err := json.MarshalIndent(snap, "", "  ")
+		if err != nil {
+			return true, err
+		}
+		return true, os.WriteFile(fields[1], data, 0644)
+	case ":load":
+		if len(fields) != 2 {
+			return true, fmt.Errorf("usage: :load FILE")
+		}
+		data, err := os.ReadFile(fields[1])
+		if err != nil {
+			return true, err
+		}
+		var snap basic.Snapshot_t
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return true, err
+		}
+		history.push(interp)
+		return true, interp.Restore(&snap)
+	case ":help":
+		if len(fields) == 1 {
+			fmt.Fprintln(out, strings.Join(basic.Builtins(), " "))
+			return true, nil
+		} else if len(fields) != 2 {
+			return true, fmt.Errorf("usage: :help [NAME]")
+		}
+		text, err := interp.Help(fields[1])
+		if err != nil {
+			return true, err
+		}
+		fmt.Fprintln(out, text)
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// a Markdown record of a REPL session, for --transcript: each input in a fenced code block,
+// followed by everything it wrote, results, errors and all, in another.
+type transcript_t struct {
+	file   *os.File
+	output bytes.Buffer // what the current input has written so far
+}
+
+// constructor for transcript objects. Starts the file with a heading saying when the session was.
+func newTranscript(path string) (*transcript_t, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	transcript := &transcript_t{file: file}
+	if _, err := fmt.Fprintf(file, "# go-basic session, %s\n", time.Now().Format("2006-01-02 15:04")); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return transcript, nil
+}
+
+// gets streams that write to the terminal like the ones given, and to the transcript too.
+func (transcript *transcript_t) tee(streams streams_t) streams_t {
+	return streams_t{
+		program:     io.MultiWriter(streams.program, &transcript.output),
+		results:     io.MultiWriter(streams.results, &transcript.output),
+		diagnostics: io.MultiWriter(streams.diagnostics, &transcript.output),
+	}
+}
+
+// writes an input and what it wrote out to the file.
+func (transcript *transcript_t) record(input string) error {
+	output := strings.TrimRight(transcript.output.String(), "\n")
+	transcript.output.Reset()
+	text := "\n" + fenced("basic", input)
+	if output != "" {
+		text += "\n" + fenced("text", output)
+	}
+	_, err := io.WriteString(transcript.file, text)
+	return err
+}
+
+// finishes the file.
+func (transcript *transcript_t) Close() error {
+	return transcript.file.Close()
+}
+
+// writes text as a fenced code block in a language, with a fence longer than any run of
+// backticks in the text, so the text can't close it.
+func fenced(lang string, text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + text + "\n" + fence + "\n"
+}
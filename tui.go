@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"go-basic/basic"
+	"os"
+	"strings"
+)
+
+// the state of the workbench: what has been typed so far.
+type workbench_t struct {
+	input []byte
+	opts  basic.Options_t
+}
+
+// runs the terminal workbench: the input line, token stream, parse tree and
+// result are redrawn in separate panes after every keystroke.
+func tuiCommand(args []string, opts basic.Options_t) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: go-basic tui")
+		return 2
+	}
+	restore, err := rawMode(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error! %s\n", err)
+		return 1
+	}
+	defer restore()
+
+	bench := &workbench_t{opts: opts}
+	buf := make([]byte, 16)
+	for {
+		os.Stdout.WriteString(bench.render(terminalWidth(os.Stdout)))
+		n, err := os.Stdin.Read(buf)
+		if err != nil || n == 0 {
+			break
+		}
+		if !bench.key(buf[:n]) {
+			break
+		}
+	}
+	os.Stdout.WriteString("\x1b[2J\x1b[H")
+	return 0
+}
+
+// handles one read from the terminal, returning false when the user wants to quit.
+func (bench *workbench_t) key(keys []byte) bool {
+	if keys[0] == 0x1b { // escape sequence, like an arrow key. a lone ESC quits
+		return len(keys) > 1
+	}
+	for _, c := range keys {
+		switch {
+		case c == 3 || c == 4: // Ctrl-C, Ctrl-D
+			return false
+		case c == 127 || c == 8: // backspace
+			if len(bench.input) > 0 {
+				bench.input = bench.input[:len(bench.input)-1]
+			}
+		case c == '\r' || c == '\n':
+			bench.input = append(bench.input, '\n')
+		case c >= ' ':
+			bench.input = append(bench.input, c)
+		}
+	}
+	return true
+}
+
+// draws the whole screen for the current input.
+func (bench *workbench_t) render(width int) string {
+	src := string(bench.input)
+	var out strings.Builder
+	out.WriteString("\x1b[2J\x1b[H") // clear the screen, cursor to the top left
+
+	pane(&out, "Input", width)
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		if i == len(lines)-1 {
+			line += "_"
+		}
+		out.WriteString(" > " + line + "\r\n")
+	}
+
+	pane(&out, "Tokens", width)
+	tokens, err := basic.Tokenize(src, "tui")
+	names := make([]string, len(tokens))
+	for i, token := range tokens {
+		names[i] = "[" + token.String() + "]"
+	}
+	out.WriteString(" " + strings.Join(names, " ") + "\r\n")
+	if err != nil {
+		out.WriteString(" Error! " + err.Error() + "\r\n")
+	}
+
+	pane(&out, "Parse tree", width)
+	node, perr := basic.Parse(src, "tui")
+	if perr != nil {
+		for _, line := range strings.Split(perr.Error(), "\n") {
+			out.WriteString(" Error! " + line + "\r\n")
+		}
+	} else if len(strings.TrimSpace(src)) > 0 {
+		drawTree(&out, node, " ", "", true, true)
+	}
+
+	pane(&out, "Result", width)
+	if perr == nil && len(strings.TrimSpace(src)) > 0 {
+		// every redraw gets a fresh interpreter, so nothing typed earlier leaks in
+		interp := basic.NewInterpreter(bench.opts)
+		res, err := interp.Run(src, "tui")
+		if err != nil {
+			out.WriteString(" Error! " + err.Error() + "\r\n")
+		} else {
+			for _, warning := range res.Warnings {
+				out.WriteString(" Warning! " + warning.String() + "\r\n")
+			}
+			out.WriteString(" Result: " + interp.Display(res) + "\r\n")
+		}
+	}
+	out.WriteString("\r\n (Ctrl-C or ESC to quit)")
+	return out.String()
+}
+
+// writes the title bar of a pane, padded with dashes to the terminal width.
+func pane(out *strings.Builder, title string, width int) {
+	bar := "── " + title + " "
+	if pad := width - len([]rune(bar)); pad > 0 {
+		bar += strings.Repeat("─", pad)
+	}
+	out.WriteString("\r\n" + bar + "\r\n")
+}
+
+// draws a node and its children with box-drawing branches.
+func drawTree(out *strings.Builder, node *basic.Node_t, indent string, prefix string, last bool, root bool) {
+	branch, childIndent := "├── ", prefix+"│   "
+	if last {
+		branch, childIndent = "└── ", prefix+"    "
+	}
+	if root {
+		branch, childIndent = "", prefix
+	}
+	out.WriteString(indent + prefix + branch + nodeLabel(node) + "\r\n")
+
+	children := childNodes(node)
+	for i, child := range children {
+		drawTree(out, child, indent, childIndent, i == len(children)-1, false)
+	}
+}
+
+// gets the nodes directly under node, in the order Walk visits them.
+func childNodes(node *basic.Node_t) []*basic.Node_t {
+	var children []*basic.Node_t
+	for _, child := range append([]*basic.Node_t{node.Left(), node.Right()}, append(node.Statements(), node.Args()...)...) {
+		if child != nil { // like the bounds left out of a slice
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// describes a single node for the tree, without its children.
+func nodeLabel(node *basic.Node_t) string {
+	switch node.Kind() {
+	case basic.FACTOR:
+		return basic.Format(node)
+	case basic.VAR_ACCESS:
+		return "VAR " + node.Name()
+	case basic.CALL:
+		return "CALL " + node.Name()
+	case basic.FOR_EACH:
+		return "FOR EACH " + node.Name()
+	case basic.STATEMENTS, basic.LIST, basic.INDEX, basic.SLICE, basic.DICT:
+		return node.Kind().String()
+	default:
+		return node.Kind().String() + " " + node.Token().String()
+	}
+}
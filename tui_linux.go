@@ -0,0 +1,45 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// switches the terminal to raw mode so keys arrive one at a time without echo.
+// the returned function puts the terminal back the way it was.
+func rawMode(tty *os.File) (func(), error) {
+	var old syscall.Termios
+	if err := ioctl(tty.Fd(), syscall.TCGETS, unsafe.Pointer(&old)); err != nil {
+		return nil, err
+	}
+	raw := old
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(tty.Fd(), syscall.TCSETS, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+	return func() { ioctl(tty.Fd(), syscall.TCSETS, unsafe.Pointer(&old)) }, nil
+}
+
+// gets the width of the terminal in columns, or 80 if it can't be found.
+func terminalWidth(tty *os.File) int {
+	var size struct{ rows, cols, xpixel, ypixel uint16 }
+	if err := ioctl(tty.Fd(), syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil || size.cols == 0 {
+		return 80
+	}
+	return int(size.cols)
+}
+
+func ioctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
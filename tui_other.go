@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// raw terminal mode is only implemented for linux so far.
+func rawMode(tty *os.File) (func(), error) {
+	return nil, errors.New("the tui command is not supported on this platform")
+}
+
+func terminalWidth(tty *os.File) int {
+	return 80
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// ZMTP 3.0, the wire protocol of ZeroMQ, which Jupyter talks to its kernels over. This is the
+// part of it a kernel needs: the NULL security mechanism, and ROUTER, PUB and REP sockets that
+// listen for peers. See https://rfc.zeromq.org/spec/23/.
+//
+// Every message is one or more frames. A frame is a flags byte, its size, in one byte or, with
+// zmtpLong, in eight, and its body. Commands, like the READY both sides send after the greeting,
+// are frames with zmtpCommand set.
+const (
+	zmtpMore     = 0x01 // more frames of the same message follow
+	zmtpLong     = 0x02 // the size is 8 bytes
+	zmtpCommand  = 0x04 // the frame is a command, not part of a message
+	zmtpMaxFrame = 64 << 20
+)
+
+// a listening socket. ROUTER sockets give back each message with the identity of the peer
+// that sent it in front, and send each message to the peer whose identity is in front of it.
+// REP sockets here work the same way, which is all a heartbeat that echoes messages needs.
+// PUB sockets send each message to every peer subscribed to a prefix of its first frame.
+type zmtpSocket_t struct {
+	kind     string // ROUTER, PUB or REP, as told to peers
+	listener net.Listener
+	messages chan [][]byte
+	closed   chan struct{}
+
+	mu     sync.Mutex
+	peers  map[string]*zmtpPeer_t // by identity
+	nextID uint32
+	done   bool
+}
+
+// a connection to a peer.
+type zmtpPeer_t struct {
+	conn          net.Conn
+	identity      string
+	writeMu       sync.Mutex
+	subscriptions [][]byte // prefixes of the messages a PUB socket sends it. Guarded by the socket's mu
+}
+
+// error for a peer that doesn't speak the protocol.
+var errZMTP = errors.New("not a ZMTP 3 peer using the NULL mechanism")
+
+// starts listening, accepting peers until the socket is closed.
+func listenZMTP(kind string, network string, address string) (*zmtpSocket_t, error) {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	sock := &zmtpSocket_t{
+		kind:     kind,
+		listener: listener,
+		messages: make(chan [][]byte, 16),
+		closed:   make(chan struct{}),
+		peers:    make(map[string]*zmtpPeer_t),
+	}
+	go sock.accept()
+	return sock, nil
+}
+
+func (sock *zmtpSocket_t) accept() {
+	for {
+		conn, err := sock.listener.Accept()
+		if err != nil {
+			return
+		}
+		go sock.serve(conn)
+	}
+}
+
+// stops listening and drops every peer.
+func (sock *zmtpSocket_t) Close() error {
+	sock.mu.Lock()
+	defer sock.mu.Unlock()
+	if sock.done {
+		return nil
+	}
+	sock.done = true
+	close(sock.closed)
+	for _, peer := range sock.peers {
+		peer.conn.Close()
+	}
+	return sock.listener.Close()
+}
+
+// does the handshake with a new peer, then reads its messages until it goes away.
+func (sock *zmtpSocket_t) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	props, err := zmtpHandshake(conn, r, sock.kind, nil)
+	if err != nil {
+		return
+	}
+	peer := sock.add(conn, props["Identity"])
+	if peer == nil {
+		return
+	}
+	defer sock.remove(peer)
+
+	var msg [][]byte
+	for {
+		flags, body, err := readFrame(r)
+		if err != nil {
+			return
+		} else if flags&zmtpCommand != 0 {
+			sock.command(peer, body)
+			continue
+		}
+		msg = append(msg, body)
+		if flags&zmtpMore != 0 {
+			continue
+		}
+
+		if sock.kind == "PUB" { // all a subscriber sends is its subscriptions
+			if len(msg) == 1 && len(body) > 0 {
+				sock.subscribe(peer, body[0] == 1, body[1:])
+			}
+		} else {
+			select {
+			case sock.messages <- append([][]byte{[]byte(peer.identity)}, msg...):
+			case <-sock.closed:
+				return
+			}
+		}
+		msg = nil
+	}
+}
+
+// registers a peer under the identity it asked for, or a new one like ZeroMQ makes if it
+// didn't ask or that one's taken. Gives back nil if the socket is closed.
+func (sock *zmtpSocket_t) add(conn net.Conn, identity string) *zmtpPeer_t {
+	sock.mu.Lock()
+	defer sock.mu.Unlock()
+	if sock.done {
+		return nil
+	}
+	if _, taken := sock.peers[identity]; identity == "" || taken {
+		id := make([]byte, 5)
+		for {
+			sock.nextID++
+			binary.BigEndian.PutUint32(id[1:], sock.nextID)
+			if _, taken := sock.peers[string(id)]; !taken {
+				break
+			}
+		}
+		identity = string(id)
+	}
+	peer := &zmtpPeer_t{conn: conn, identity: identity}
+	sock.peers[identity] = peer
+	return peer
+}
+
+func (sock *zmtpSocket_t) remove(peer *zmtpPeer_t) {
+	sock.mu.Lock()
+	defer sock.mu.Unlock()
+	if sock.peers[peer.identity] == peer {
+		delete(sock.peers, peer.identity)
+	}
+}
+
+// handles a command from a peer after the handshake. ZMTP 3.1 peers subscribe with commands,
+// rather than messages, and may ping.
+func (sock *zmtpSocket_t) command(peer *zmtpPeer_t, body []byte) {
+	name, data, ok := parseCommand(body)
+	switch {
+	case !ok:
+	case name == "SUBSCRIBE" || name == "CANCEL":
+		if sock.kind == "PUB" {
+			sock.subscribe(peer, name == "SUBSCRIBE", data)
+		}
+	case name == "PING" && len(data) >= 2: // the context after the TTL goes back in the PONG
+		peer.writeMu.Lock()
+		peer.conn.Write(appendCommand(nil, "PONG", data[2:]))
+		peer.writeMu.Unlock()
+	}
+}
+
+// adds or cancels one of a subscriber's subscriptions.
+func (sock *zmtpSocket_t) subscribe(peer *zmtpPeer_t, add bool, prefix []byte) {
+	sock.mu.Lock()
+	defer sock.mu.Unlock()
+	if add {
+		peer.subscriptions = append(peer.subscriptions, append([]byte(nil), prefix...))
+		return
+	}
+	for i, sub := range peer.subscriptions {
+		if bytes.Equal(sub, prefix) {
+			peer.subscriptions = append(peer.subscriptions[:i], peer.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+// gets the next message a peer sent, with its identity in front.
+func (sock *zmtpSocket_t) recv() ([][]byte, error) {
+	select {
+	case msg := <-sock.messages:
+		return msg, nil
+	case <-sock.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// sends a message. Like ZeroMQ, a message for a peer that isn't connected, or that no one is
+// subscribed to, is dropped.
+func (sock *zmtpSocket_t) send(msg [][]byte) {
+	var to []*zmtpPeer_t
+	sock.mu.Lock()
+	if sock.kind == "PUB" {
+		for _, peer := range sock.peers {
+			for _, sub := range peer.subscriptions {
+				if len(msg) > 0 && bytes.HasPrefix(msg[0], sub) {
+					to = append(to, peer)
+					break
+				}
+			}
+		}
+	} else if len(msg) > 1 {
+		if peer, ok := sock.peers[string(msg[0])]; ok {
+			to = append(to, peer)
+		}
+		msg = msg[1:]
+	}
+	sock.mu.Unlock()
+
+	for _, peer := range to {
+		peer.writeMu.Lock()
+		if err := writeMessage(peer.conn, msg); err != nil {
+			peer.conn.Close() // serve sees it and removes the peer
+		}
+		peer.writeMu.Unlock()
+	}
+}
+
+// the address the socket is listening on.
+func (sock *zmtpSocket_t) Addr() net.Addr {
+	return sock.listener.Addr()
+}
+
+// swaps greetings with a peer, then READY commands, giving back the properties the peer's
+// READY had, like its Socket-Type. Both sides of a connection use it the same way.
+func zmtpHandshake(conn io.Writer, r io.Reader, kind string, props map[string]string) (map[string]string, error) {
+	greeting := make([]byte, 64)
+	greeting[0], greeting[9], greeting[10] = 0xff, 0x7f, 3 // signature, then version 3.0
+	copy(greeting[12:], "NULL")
+	if _, err := conn.Write(greeting); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, greeting); err != nil {
+		return nil, err
+	}
+	if greeting[0] != 0xff || greeting[9] != 0x7f || greeting[10] < 3 || string(bytes.TrimRight(greeting[12:32], "\x00")) != "NULL" {
+		return nil, errZMTP
+	}
+
+	var ready []byte
+	for _, name := range []string{"Socket-Type", "Identity"} {
+		value := props[name]
+		if name == "Socket-Type" {
+			value = kind
+		} else if value == "" {
+			continue
+		}
+		ready = append(ready, byte(len(name)))
+		ready = append(ready, name...)
+		ready = append(ready, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(ready[len(ready)-4:], uint32(len(value)))
+		ready = append(ready, value...)
+	}
+	if _, err := conn.Write(appendCommand(nil, "READY", ready)); err != nil {
+		return nil, err
+	}
+
+	flags, body, err := readFrame(r)
+	if err != nil {
+		return nil, err
+	}
+	name, data, ok := parseCommand(body)
+	if flags&zmtpCommand == 0 || !ok || name != "READY" {
+		return nil, errZMTP
+	}
+	peerProps := make(map[string]string)
+	for len(data) > 0 {
+		n := int(data[0])
+		if len(data) < 1+n+4 {
+			return nil, errZMTP
+		}
+		name := string(data[1 : 1+n])
+		size := binary.BigEndian.Uint32(data[1+n:])
+		data = data[1+n+4:]
+		if uint64(size) > uint64(len(data)) {
+			return nil, errZMTP
+		}
+		peerProps[name], data = string(data[:size]), data[size:]
+	}
+	return peerProps, nil
+}
+
+// reads one frame, giving back its flags and body.
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var head [9]byte
+	if _, err := io.ReadFull(r, head[:2]); err != nil {
+		return 0, nil, err
+	}
+	flags, size := head[0], uint64(head[1])
+	if flags&zmtpLong != 0 {
+		if _, err := io.ReadFull(r, head[2:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(head[1:])
+	}
+	if size > zmtpMaxFrame {
+		return 0, nil, fmt.Errorf("frame of %d bytes is over the limit of %d", size, zmtpMaxFrame)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return flags, body, nil
+}
+
+// adds a frame with the given flags to b, setting zmtpLong if the body needs it.
+func appendFrame(b []byte, flags byte, body []byte) []byte {
+	if len(body) > 255 {
+		b = append(b, flags|zmtpLong, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(b[len(b)-8:], uint64(len(body)))
+	} else {
+		b = append(b, flags, byte(len(body)))
+	}
+	return append(b, body...)
+}
+
+// adds a command frame: the length of its name, its name and its data.
+func appendCommand(b []byte, name string, data []byte) []byte {
+	body := append([]byte{byte(len(name))}, name...)
+	return appendFrame(b, zmtpCommand, append(body, data...))
+}
+
+// splits a command frame into its name and data.
+func parseCommand(body []byte) (string, []byte, bool) {
+	if len(body) == 0 || len(body) < 1+int(body[0]) {
+		return "", nil, false
+	}
+	return string(body[1 : 1+body[0]]), body[1+body[0]:], true
+}
+
+// writes a message as its frames, all at once.
+func writeMessage(w io.Writer, msg [][]byte) error {
+	var b []byte
+	for i, frame := range msg {
+		flags := byte(zmtpMore)
+		if i == len(msg)-1 {
+			flags = 0
+		}
+		b = appendFrame(b, flags, frame)
+	}
+	_, err := w.Write(b)
+	return err
+}